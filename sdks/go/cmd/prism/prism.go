@@ -40,6 +40,8 @@ var (
 	jobManagerEndpoint  = flag.String("jm_override", "", "set to only stand up a web ui that refers to a seperate JobManagement endpoint")
 	serveHTTP           = flag.Bool("serve_http", true, "enable or disable the web ui")
 	idleShutdownTimeout = flag.Duration("idle_shutdown_timeout", -1, "duration that prism will wait for a new job before shutting itself down. Negative durations disable auto shutdown. Defaults to never shutting down.")
+	checkpointDir       = flag.String("checkpoint_dir", "", "directory where jobs write snapshots of their execution state as bundles commit, in a subdirectory per job. Snapshots are written every 10s by default, which jobs may change with the prism_checkpoint_interval=<duration> and prism_checkpoint_bundles=<n> experiments. Defaults to not writing snapshots.")
+	restoreFrom         = flag.String("restore_from", "", "job snapshot directory, such as <checkpoint_dir>/job-001, that the first submitted job resumes from. The same pipeline must be submitted, and later jobs are rejected.")
 )
 
 // Logging flags
@@ -100,6 +102,8 @@ func main() {
 			Port:                *jobPort,
 			IdleShutdownTimeout: *idleShutdownTimeout,
 			CancelFn:            cancel,
+			CheckpointDir:       *checkpointDir,
+			RestoreFrom:         *restoreFrom,
 		},
 		*jobManagerEndpoint)
 	if err != nil {
@@ -0,0 +1,325 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coder
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+	"github.com/google/uuid"
+)
+
+// MicrosInstant is a timestamp that is encoded in schema rows with microsecond
+// precision, as the beam:logical_type:micros_instant:v1 logical type.
+// Plain time.Time fields are encoded with millisecond precision, as the
+// beam:logical_type:millis_instant:v1 logical type.
+type MicrosInstant time.Time
+
+// FixedBytes is a byte slice that is encoded in schema rows as the
+// beam:logical_type:fixed_bytes:v1 logical type. Its length is given by the
+// length option of the field's beam tag, such as `beam:"digest,length=32"`.
+type FixedBytes []byte
+
+// VarBytes is a byte slice that is encoded in schema rows as the
+// beam:logical_type:var_bytes:v1 logical type. Its maximum length may be given
+// by the length option of the field's beam tag.
+type VarBytes []byte
+
+// FixedChar is a string that is encoded in schema rows as the
+// beam:logical_type:fixed_char:v1 logical type. Its length in characters is
+// given by the length option of the field's beam tag.
+type FixedChar string
+
+var (
+	timeType          = reflect.TypeOf((*time.Time)(nil)).Elem()
+	microsInstantType = reflect.TypeOf((*MicrosInstant)(nil)).Elem()
+	ratType           = reflect.TypeOf((*big.Rat)(nil)).Elem()
+	uuidType          = reflect.TypeOf((*uuid.UUID)(nil)).Elem()
+	fixedBytesType    = reflect.TypeOf((*FixedBytes)(nil)).Elem()
+	varBytesType      = reflect.TypeOf((*VarBytes)(nil)).Elem()
+	fixedCharType     = reflect.TypeOf((*FixedChar)(nil)).Elem()
+)
+
+// The encodings of Beam's standard logical types, so that they may be decoded
+// by other SDKs.
+func init() {
+	RegisterSchemaProviders(timeType, millisInstantEnc, millisInstantDec)
+	RegisterSchemaProviders(microsInstantType, microsInstantEnc, microsInstantDec)
+	RegisterSchemaProviders(ratType, decimalEnc, decimalDec)
+	RegisterSchemaProviders(uuidType, uuidEnc, uuidDec)
+	RegisterSchemaProviders(fixedBytesType, fixedBytesEnc, fixedBytesDec)
+	RegisterSchemaProviders(varBytesType, varBytesEnc, varBytesDec)
+	RegisterSchemaProviders(fixedCharType, fixedCharEnc, fixedCharDec)
+}
+
+// millisInstantEnc encodes a time.Time as the milliseconds since the epoch,
+// shifted in the same way as event times.
+func millisInstantEnc(reflect.Type) (func(any, io.Writer) error, error) {
+	return func(iface any, w io.Writer) error {
+		millis := iface.(time.Time).UnixMilli()
+		return EncodeUint64(uint64(millis-math.MinInt64), w)
+	}, nil
+}
+
+func millisInstantDec(reflect.Type) (func(io.Reader) (any, error), error) {
+	return func(r io.Reader) (any, error) {
+		v, err := DecodeUint64(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding millis_instant")
+		}
+		return time.UnixMilli(int64(v) + math.MinInt64).UTC(), nil
+	}, nil
+}
+
+// microsInstantEnc encodes a MicrosInstant as a row of the seconds since the
+// epoch, and the microseconds within that second.
+func microsInstantEnc(reflect.Type) (func(any, io.Writer) error, error) {
+	return func(iface any, w io.Writer) error {
+		t := time.Time(iface.(MicrosInstant))
+		if err := WriteSimpleRowHeader(2, w); err != nil {
+			return errors.Wrap(err, "encoding micros_instant")
+		}
+		if err := EncodeVarInt(t.Unix(), w); err != nil {
+			return err
+		}
+		return EncodeVarInt(int64(t.Nanosecond()/1000), w)
+	}, nil
+}
+
+func microsInstantDec(reflect.Type) (func(io.Reader) (any, error), error) {
+	return func(r io.Reader) (any, error) {
+		if err := ReadSimpleRowHeader(2, r); err != nil {
+			return nil, errors.Wrap(err, "decoding micros_instant")
+		}
+		secs, err := DecodeVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		micros, err := DecodeVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		return MicrosInstant(time.Unix(secs, micros*1000).UTC()), nil
+	}, nil
+}
+
+var (
+	big2  = big.NewInt(2)
+	big5  = big.NewInt(5)
+	big10 = big.NewInt(10)
+)
+
+// decimalEnc encodes a big.Rat as its scale, followed by the big endian two's
+// complement bytes of its unscaled value, like Java's BigDecimal coder. Only
+// rationals with a finite decimal expansion may be encoded.
+func decimalEnc(reflect.Type) (func(any, io.Writer) error, error) {
+	return func(iface any, w io.Writer) error {
+		v := iface.(big.Rat)
+		unscaled, scale, err := ratToDecimal(&v)
+		if err != nil {
+			return err
+		}
+		if err := EncodeVarInt(int64(scale), w); err != nil {
+			return err
+		}
+		return EncodeBytes(twosComplementBytes(unscaled), w)
+	}, nil
+}
+
+func decimalDec(reflect.Type) (func(io.Reader) (any, error), error) {
+	return func(r io.Reader) (any, error) {
+		scale, err := DecodeVarInt(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding decimal scale")
+		}
+		b, err := DecodeBytes(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding decimal value")
+		}
+		// Scales are Java ints, so negative scales are encoded as 32 bit varints.
+		s := int64(int32(scale))
+		var v big.Rat
+		v.SetInt(fromTwosComplementBytes(b))
+		if s >= 0 {
+			v.Quo(&v, new(big.Rat).SetInt(new(big.Int).Exp(big10, big.NewInt(s), nil)))
+		} else {
+			v.Mul(&v, new(big.Rat).SetInt(new(big.Int).Exp(big10, big.NewInt(-s), nil)))
+		}
+		return v, nil
+	}, nil
+}
+
+// ratToDecimal returns the unscaled value and the scale of the smallest
+// decimal equal to v, or an error if v has no finite decimal expansion.
+func ratToDecimal(v *big.Rat) (*big.Int, int32, error) {
+	if v.IsInt() {
+		return new(big.Int).Set(v.Num()), 0, nil
+	}
+	// The denominator of a decimal only has factors of 2 and 5.
+	d := new(big.Int).Set(v.Denom())
+	var twos, fives int32
+	var m big.Int
+	for m.Mod(d, big2).Sign() == 0 {
+		d.Quo(d, big2)
+		twos++
+	}
+	for m.Mod(d, big5).Sign() == 0 {
+		d.Quo(d, big5)
+		fives++
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return nil, 0, errors.Errorf("decimal %v has no finite decimal expansion", v.RatString())
+	}
+	scale := twos
+	if fives > scale {
+		scale = fives
+	}
+	unscaled := new(big.Int).Exp(big10, big.NewInt(int64(scale)), nil)
+	unscaled.Mul(unscaled, v.Num())
+	unscaled.Quo(unscaled, v.Denom())
+	return unscaled, scale, nil
+}
+
+// twosComplementBytes returns the minimal big endian two's complement
+// representation of v.
+func twosComplementBytes(v *big.Int) []byte {
+	if v.Sign() >= 0 {
+		b := v.Bytes()
+		if len(b) == 0 || b[0]&0x80 != 0 {
+			b = append([]byte{0}, b...)
+		}
+		return b
+	}
+	// -v-1 has the complemented bits of v.
+	c := new(big.Int).Neg(v)
+	c.Sub(c, big.NewInt(1))
+	b := c.Bytes()
+	for i := range b {
+		b[i] = ^b[i]
+	}
+	if len(b) == 0 || b[0]&0x80 == 0 {
+		b = append([]byte{0xff}, b...)
+	}
+	return b
+}
+
+// fromTwosComplementBytes is the inverse of twosComplementBytes.
+func fromTwosComplementBytes(b []byte) *big.Int {
+	if len(b) == 0 || b[0]&0x80 == 0 {
+		return new(big.Int).SetBytes(b)
+	}
+	c := make([]byte, len(b))
+	for i := range b {
+		c[i] = ^b[i]
+	}
+	v := new(big.Int).SetBytes(c)
+	v.Add(v, big.NewInt(1))
+	return v.Neg(v)
+}
+
+// uuidEnc encodes a uuid.UUID as a row of its most and least significant
+// 64 bits, like Java's UUID logical type.
+func uuidEnc(reflect.Type) (func(any, io.Writer) error, error) {
+	return func(iface any, w io.Writer) error {
+		u := iface.(uuid.UUID)
+		if err := WriteSimpleRowHeader(2, w); err != nil {
+			return errors.Wrap(err, "encoding uuid")
+		}
+		if err := EncodeVarInt(int64(binary.BigEndian.Uint64(u[:8])), w); err != nil {
+			return err
+		}
+		return EncodeVarInt(int64(binary.BigEndian.Uint64(u[8:])), w)
+	}, nil
+}
+
+func uuidDec(reflect.Type) (func(io.Reader) (any, error), error) {
+	return func(r io.Reader) (any, error) {
+		if err := ReadSimpleRowHeader(2, r); err != nil {
+			return nil, errors.Wrap(err, "decoding uuid")
+		}
+		msb, err := DecodeVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		lsb, err := DecodeVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		var u uuid.UUID
+		binary.BigEndian.PutUint64(u[:8], uint64(msb))
+		binary.BigEndian.PutUint64(u[8:], uint64(lsb))
+		return u, nil
+	}, nil
+}
+
+// fixedBytesEnc encodes a FixedBytes as its representation, a length prefixed
+// byte slice. Other SDKs pad or reject values of the wrong length, so it's
+// left to the user to provide values of the field's length.
+func fixedBytesEnc(reflect.Type) (func(any, io.Writer) error, error) {
+	return func(iface any, w io.Writer) error {
+		return EncodeBytes(iface.(FixedBytes), w)
+	}, nil
+}
+
+func fixedBytesDec(reflect.Type) (func(io.Reader) (any, error), error) {
+	return func(r io.Reader) (any, error) {
+		b, err := DecodeBytes(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding fixed_bytes")
+		}
+		return FixedBytes(b), nil
+	}, nil
+}
+
+// varBytesEnc encodes a VarBytes as its representation, a length prefixed
+// byte slice.
+func varBytesEnc(reflect.Type) (func(any, io.Writer) error, error) {
+	return func(iface any, w io.Writer) error {
+		return EncodeBytes(iface.(VarBytes), w)
+	}, nil
+}
+
+func varBytesDec(reflect.Type) (func(io.Reader) (any, error), error) {
+	return func(r io.Reader) (any, error) {
+		b, err := DecodeBytes(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding var_bytes")
+		}
+		return VarBytes(b), nil
+	}, nil
+}
+
+// fixedCharEnc encodes a FixedChar as its representation, a UTF-8 string.
+func fixedCharEnc(reflect.Type) (func(any, io.Writer) error, error) {
+	return func(iface any, w io.Writer) error {
+		return EncodeStringUTF8(string(iface.(FixedChar)), w)
+	}, nil
+}
+
+func fixedCharDec(reflect.Type) (func(io.Reader) (any, error), error) {
+	return func(r io.Reader) (any, error) {
+		s, err := DecodeStringUTF8(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "decoding fixed_char")
+		}
+		return FixedChar(s), nil
+	}, nil
+}
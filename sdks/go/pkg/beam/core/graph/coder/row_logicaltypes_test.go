@@ -0,0 +1,175 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package coder
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+type logicalTypes struct {
+	Millis  time.Time
+	Micros  MicrosInstant
+	Decimal big.Rat
+	NilDec  *big.Rat
+	PtrDec  *big.Rat
+	UUID    uuid.UUID
+	Fixed   FixedBytes
+	Var     VarBytes
+	Char    FixedChar
+}
+
+var logicalTypeCmpOpts = []cmp.Option{
+	cmp.Comparer(func(a, b big.Rat) bool { return a.Cmp(&b) == 0 }),
+	cmp.Comparer(func(a, b MicrosInstant) bool { return time.Time(a).Equal(time.Time(b)) }),
+}
+
+func TestRowCoder_LogicalTypes(t *testing.T) {
+	rt := reflect.TypeOf((*logicalTypes)(nil)).Elem()
+	enc, err := RowEncoderForStruct(rt)
+	if err != nil {
+		t.Fatalf("RowEncoderForStruct(%v) = %v, want nil error", rt, err)
+	}
+	dec, err := RowDecoderForStruct(rt)
+	if err != nil {
+		t.Fatalf("RowDecoderForStruct(%v) = %v, want nil error", rt, err)
+	}
+	want := logicalTypes{
+		Millis:  time.Date(2020, 8, 13, 14, 14, 14, 123000000, time.UTC),
+		Micros:  MicrosInstant(time.Date(1969, 7, 20, 20, 17, 40, 123456000, time.UTC)),
+		Decimal: *big.NewRat(-100123, 1000),
+		PtrDec:  big.NewRat(1, 8),
+		UUID:    uuid.MustParse("3b241101-e2bb-4255-8caf-4136c566a962"),
+		Fixed:   FixedBytes{1, 2, 3, 4},
+		Var:     VarBytes("var"),
+		Char:    FixedChar("abc"),
+	}
+	var buf bytes.Buffer
+	if err := enc(want, &buf); err != nil {
+		t.Fatalf("enc(%v) = %v, want nil error", want, err)
+	}
+	got, err := dec(&buf)
+	if err != nil {
+		t.Fatalf("dec() = %v, want nil error", err)
+	}
+	if d := cmp.Diff(want, got, logicalTypeCmpOpts...); d != "" {
+		t.Errorf("round trip diff(-want,+got): %v", d)
+	}
+}
+
+func TestLogicalTypeEncodings(t *testing.T) {
+	tests := []struct {
+		name string
+		rt   reflect.Type
+		v    any
+		want []byte
+	}{
+		{
+			name: "millis_instant",
+			rt:   timeType,
+			v:    time.UnixMilli(1597328054123),
+			want: []byte("\x80\x00\x01s\xe8+\xd7k"),
+		}, {
+			name: "millis_instant_negative",
+			rt:   timeType,
+			v:    time.UnixMilli(-1),
+			want: []byte("\x7f\xff\xff\xff\xff\xff\xff\xff"),
+		}, {
+			name: "micros_instant",
+			rt:   microsInstantType,
+			v:    MicrosInstant(time.Unix(1597328054, 123456000)),
+			want: []byte("\x02\x00\xb6\x95\xd5\xf9\x05\xc0\xc4\x07"),
+		}, {
+			name: "decimal",
+			rt:   ratType,
+			v:    *big.NewRat(31415, 10000),
+			want: []byte("\x04\x02z\xb7"),
+		}, {
+			name: "decimal_negative",
+			rt:   ratType,
+			v:    *big.NewRat(-100123, 1000),
+			want: []byte("\x03\x03\xfex\xe5"),
+		}, {
+			name: "decimal_sign_byte",
+			rt:   ratType,
+			v:    *big.NewRat(128, 1),
+			want: []byte("\x00\x02\x00\x80"),
+		}, {
+			name: "uuid",
+			rt:   uuidType,
+			v:    uuid.UUID{0, 0, 0, 0, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			want: []byte("\x02\x00\x01\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01"),
+		}, {
+			name: "fixed_bytes",
+			rt:   fixedBytesType,
+			v:    FixedBytes{0xca, 0xfe},
+			want: []byte("\x02\xca\xfe"),
+		}, {
+			name: "var_bytes",
+			rt:   varBytesType,
+			v:    VarBytes("abc"),
+			want: []byte("\x03abc"),
+		}, {
+			name: "fixed_char",
+			rt:   fixedCharType,
+			v:    FixedChar("héllo"),
+			want: []byte("\x06h\xc3\xa9llo"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encf, _, err := defaultEnc.customFunc(test.rt)
+			if err != nil || encf == nil {
+				t.Fatalf("no encoder for %v: %v", test.rt, err)
+			}
+			var buf bytes.Buffer
+			if err := encf(test.v, &buf); err != nil {
+				t.Fatalf("encode(%v) = %v, want nil error", test.v, err)
+			}
+			if got := buf.Bytes(); !bytes.Equal(got, test.want) {
+				t.Errorf("encode(%v) = %q, want %q", test.v, got, test.want)
+			}
+			decf, _, err := defaultDec.customFunc(test.rt)
+			if err != nil || decf == nil {
+				t.Fatalf("no decoder for %v: %v", test.rt, err)
+			}
+			got, err := decf(bytes.NewReader(test.want))
+			if err != nil {
+				t.Fatalf("decode(%q) = %v, want nil error", test.want, err)
+			}
+			if d := cmp.Diff(test.v, got, logicalTypeCmpOpts...); d != "" {
+				t.Errorf("decode(%q) diff(-want,+got): %v", test.want, d)
+			}
+		})
+	}
+}
+
+func TestDecimal_NotDecimal(t *testing.T) {
+	encf, err := decimalEnc(ratType)
+	if err != nil {
+		t.Fatalf("decimalEnc() = %v, want nil error", err)
+	}
+	var buf bytes.Buffer
+	if err := encf(*big.NewRat(1, 3), &buf); err == nil {
+		t.Errorf("encode(1/3) = nil error, want error for a value with no finite decimal expansion")
+	}
+}
@@ -439,6 +439,33 @@ func newDoFnNode(op Opcode, g *Graph, s *Scope, u *DoFn, in []*Node, rc *coder.C
 	return edge, nil
 }
 
+// AddErrorOutput adds an output of ErrorRecords to the given ParDo edge, as
+// its last output. The ParDo outputs the elements that fail processing to it,
+// instead of failing the bundle.
+func AddErrorOutput(g *Graph, edge *MultiEdge) (*Node, error) {
+	if edge.Op != ParDo {
+		return nil, errors.Errorf("error output requires a ParDo: %v", edge)
+	}
+	if edge.DoFn.IsSplittable() {
+		return nil, errors.Errorf("error output of DoFn %v: splittable DoFns are not supported", edge.DoFn.Name())
+	}
+	in := edge.Input[0].From
+	n := g.NewNode(typex.New(typex.ErrorRecordType), in.WindowingStrategy(), in.Bounded())
+	edge.Output = append(edge.Output, &Outbound{To: n, Type: n.Type()})
+	return n, nil
+}
+
+// HasErrorOutput returns whether the outputs of a ParDo of the given DoFn
+// end with an output added by AddErrorOutput.
+func HasErrorOutput(u *DoFn, out []*Outbound) bool {
+	fn := u.ProcessElementFn()
+	n := len(fn.Params(funcx.FnEmit))
+	if len(fn.Returns(funcx.RetValue)) > 0 {
+		n++
+	}
+	return len(out) == n+1 && out[n].Type.Type() == typex.ErrorRecordType
+}
+
 // CombinePerKeyScope is the Go SDK canonical name for the combine composite
 // scope. With Beam Portability, "primitive" composite transforms like
 // combine have their URNs & payloads attached to a high level scope, with a
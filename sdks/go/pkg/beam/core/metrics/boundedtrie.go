@@ -0,0 +1,309 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultBoundedTrieBound is the maximum number of paths retained by a
+// BoundedTrie, matching the other Beam SDKs.
+const defaultBoundedTrieBound = 100
+
+// BoundedTrieValue is the value of a BoundedTrie metric.
+type BoundedTrieValue struct {
+	// Bound is the maximum number of paths retained.
+	Bound int
+	// Paths are the retained paths, in lexicographic order.
+	Paths []BoundedTriePath
+}
+
+// BoundedTriePath is a single path of a BoundedTrie metric.
+type BoundedTriePath struct {
+	Segments []string
+	// Truncated is set if the path stands in for all paths with Segments as
+	// a prefix, as the trie exceeded its bound.
+	Truncated bool
+}
+
+func (p BoundedTriePath) String() string {
+	s := strings.Join(p.Segments, "/")
+	if p.Truncated {
+		s += "/..."
+	}
+	return s
+}
+
+// Merge returns the combination of the paths of both values, truncated to
+// the smaller of their bounds.
+func (v BoundedTrieValue) Merge(o BoundedTrieValue) BoundedTrieValue {
+	if len(o.Paths) == 0 {
+		return v
+	}
+	if len(v.Paths) == 0 {
+		return o
+	}
+	bound := v.Bound
+	if o.Bound < bound {
+		bound = o.Bound
+	}
+	root := trieFromPaths(v.Paths)
+	root.merge(trieFromPaths(o.Paths))
+	for root.size > bound {
+		root.trim()
+	}
+	return BoundedTrieValue{Bound: bound, Paths: root.flatten()}
+}
+
+// trieNode is a node of a bounded trie. The size of a node is the number of
+// paths below it, and is at least 1, for the path ending at the node itself.
+type trieNode struct {
+	size      int
+	children  map[string]*trieNode
+	truncated bool
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{size: 1}
+}
+
+// trieFromPaths builds the trie of previously flattened paths.
+func trieFromPaths(paths []BoundedTriePath) *trieNode {
+	root := newTrieNode()
+	for _, p := range paths {
+		n := root
+		for _, s := range p.Segments {
+			if n.children == nil {
+				n.children = make(map[string]*trieNode)
+			}
+			c, ok := n.children[s]
+			if !ok {
+				c = newTrieNode()
+				n.children[s] = c
+			}
+			n = c
+		}
+		if p.Truncated {
+			n.truncated = true
+			n.children = nil
+		}
+	}
+	root.resize()
+	return root
+}
+
+// resize recomputes the sizes of the node and its descendants.
+func (n *trieNode) resize() int {
+	if n.truncated || len(n.children) == 0 {
+		n.size = 1
+		return n.size
+	}
+	n.size = 0
+	for _, c := range n.children {
+		n.size += c.resize()
+	}
+	return n.size
+}
+
+// add adds the path of segments below the node, and returns the change in
+// the size of the node.
+func (n *trieNode) add(segments []string) int {
+	if n.truncated || len(segments) == 0 {
+		return 0
+	}
+	head, tail := segments[0], segments[1:]
+	delta := 0
+	c, ok := n.children[head]
+	if !ok {
+		if len(n.children) > 0 {
+			delta = 1
+		}
+		if n.children == nil {
+			n.children = make(map[string]*trieNode)
+		}
+		c = newTrieNode()
+		n.children[head] = c
+	}
+	if len(tail) > 0 {
+		delta += c.add(tail)
+	}
+	n.size += delta
+	return delta
+}
+
+// trim reduces the size of the node by descending into its largest children
+// until reaching a node whose children are all leaves, which is truncated.
+// It returns the change in the size of the node.
+func (n *trieNode) trim() int {
+	if len(n.children) == 0 {
+		return 0
+	}
+	var maxKey string
+	var maxChild *trieNode
+	for k, c := range n.children {
+		if maxChild == nil || c.size > maxChild.size || (c.size == maxChild.size && k < maxKey) {
+			maxKey, maxChild = k, c
+		}
+	}
+	var delta int
+	if maxChild.size == 1 {
+		delta = 1 - n.size
+		n.truncated = true
+		n.children = nil
+	} else {
+		delta = maxChild.trim()
+	}
+	n.size += delta
+	return delta
+}
+
+// merge adds the paths below o to the node, and returns the change in the
+// size of the node.
+func (n *trieNode) merge(o *trieNode) int {
+	var delta int
+	switch {
+	case n.truncated:
+	case o.truncated:
+		delta = 1 - n.size
+		n.truncated = true
+		n.children = nil
+	case len(o.children) == 0:
+	case len(n.children) == 0:
+		n.children = make(map[string]*trieNode, len(o.children))
+		for k, c := range o.children {
+			n.children[k] = c.clone()
+		}
+		delta = o.size - n.size
+	default:
+		for k, oc := range o.children {
+			if c, ok := n.children[k]; ok {
+				delta += c.merge(oc)
+			} else {
+				n.children[k] = oc.clone()
+				delta += oc.size
+			}
+		}
+	}
+	n.size += delta
+	return delta
+}
+
+func (n *trieNode) clone() *trieNode {
+	c := &trieNode{size: n.size, truncated: n.truncated}
+	if n.children != nil {
+		c.children = make(map[string]*trieNode, len(n.children))
+		for k, child := range n.children {
+			c.children[k] = child.clone()
+		}
+	}
+	return c
+}
+
+// flatten returns the paths below the node in lexicographic order.
+func (n *trieNode) flatten() []BoundedTriePath {
+	var paths []BoundedTriePath
+	n.flattenTo(nil, &paths)
+	return paths
+}
+
+func (n *trieNode) flattenTo(prefix []string, paths *[]BoundedTriePath) {
+	if n.truncated || len(n.children) == 0 {
+		segments := append([]string(nil), prefix...)
+		*paths = append(*paths, BoundedTriePath{Segments: segments, Truncated: n.truncated})
+		return
+	}
+	keys := make([]string, 0, len(n.children))
+	for k := range n.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		n.children[k].flattenTo(append(prefix, k), paths)
+	}
+}
+
+// BoundedTrie is a metric that records a bounded set of paths, such as the
+// lineage of the files or tables read by a pipeline. Once more than the
+// bound of paths have been added, paths sharing the longest common prefixes
+// are truncated to those prefixes.
+type BoundedTrie struct {
+	name name
+	hash nameHash
+}
+
+func (m *BoundedTrie) String() string {
+	return fmt.Sprintf("BoundedTrie metric %s", m.name)
+}
+
+// NewBoundedTrie returns the BoundedTrie with the given namespace and name.
+func NewBoundedTrie(ns, n string) *BoundedTrie {
+	return &BoundedTrie{
+		name: newName(ns, n),
+		hash: hashName(ns, n),
+	}
+}
+
+// Add adds the path of segments to the trie within the given PTransform context.
+func (m *BoundedTrie) Add(ctx context.Context, segments ...string) {
+	cs := getCounterSet(ctx)
+	if cs == nil {
+		return
+	}
+	if t, ok := cs.boundedTries[m.hash]; ok {
+		t.add(segments)
+		return
+	}
+	// We're the first to create this metric!
+	t := &boundedTrie{bound: defaultBoundedTrieBound, root: newTrieNode()}
+	t.root.add(segments)
+	cs.boundedTries[m.hash] = t
+	GetStore(ctx).storeMetric(cs.pid, m.name, t)
+}
+
+// boundedTrie is a metric cell for bounded trie values.
+type boundedTrie struct {
+	mu    sync.Mutex
+	bound int
+	root  *trieNode
+}
+
+func (m *boundedTrie) add(segments []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.root.add(segments)
+	for m.root.size > m.bound {
+		m.root.trim()
+	}
+}
+
+func (m *boundedTrie) kind() kind {
+	return kindBoundedTrie
+}
+
+func (m *boundedTrie) String() string {
+	v := m.get()
+	return fmt.Sprintf("bound: %d paths: %v", v.Bound, v.Paths)
+}
+
+func (m *boundedTrie) get() BoundedTrieValue {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return BoundedTrieValue{Bound: m.bound, Paths: m.root.flatten()}
+}
@@ -0,0 +1,149 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func path(segments ...string) BoundedTriePath {
+	return BoundedTriePath{Segments: segments}
+}
+
+func truncated(segments ...string) BoundedTriePath {
+	return BoundedTriePath{Segments: segments, Truncated: true}
+}
+
+func TestBoundedTrie_Add(t *testing.T) {
+	tests := []struct {
+		name  string
+		bound int
+		adds  [][]string
+		want  []BoundedTriePath
+	}{
+		{
+			name:  "single",
+			bound: 10,
+			adds:  [][]string{{"a", "b", "c"}},
+			want:  []BoundedTriePath{path("a", "b", "c")},
+		}, {
+			name:  "sorted",
+			bound: 10,
+			adds:  [][]string{{"b"}, {"a", "c"}, {"a", "b"}, {"a", "b"}},
+			want:  []BoundedTriePath{path("a", "b"), path("a", "c"), path("b")},
+		}, {
+			name:  "truncated",
+			bound: 2,
+			adds:  [][]string{{"a", "b"}, {"a", "c"}, {"d"}},
+			want:  []BoundedTriePath{truncated("a"), path("d")},
+		}, {
+			name:  "truncated largest",
+			bound: 3,
+			adds:  [][]string{{"a", "b"}, {"c", "d"}, {"c", "e"}, {"c", "f"}},
+			want:  []BoundedTriePath{path("a", "b"), truncated("c")},
+		}, {
+			name:  "truncated root",
+			bound: 1,
+			adds:  [][]string{{"a"}, {"b"}},
+			want:  []BoundedTriePath{truncated()},
+		}, {
+			name:  "add below truncated",
+			bound: 2,
+			adds:  [][]string{{"a", "b"}, {"a", "c"}, {"d"}, {"a", "e"}},
+			want:  []BoundedTriePath{truncated("a"), path("d")},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &boundedTrie{bound: test.bound, root: newTrieNode()}
+			for _, segments := range test.adds {
+				m.add(segments)
+			}
+			got := m.get()
+			if d := cmp.Diff(BoundedTrieValue{Bound: test.bound, Paths: test.want}, got); d != "" {
+				t.Errorf("boundedTrie.add(%v) diff (-want +got):\n%v", test.adds, d)
+			}
+			if got, want := m.root.size, len(test.want); got != want {
+				t.Errorf("boundedTrie.add(%v) size = %v, want %v", test.adds, got, want)
+			}
+		})
+	}
+}
+
+func TestBoundedTrieValue_Merge(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b BoundedTrieValue
+		want BoundedTrieValue
+	}{
+		{
+			name: "empty",
+			a:    BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{path("a")}},
+			b:    BoundedTrieValue{},
+			want: BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{path("a")}},
+		}, {
+			name: "union",
+			a:    BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{path("a", "b"), path("c")}},
+			b:    BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{path("a", "b"), path("a", "d")}},
+			want: BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{path("a", "b"), path("a", "d"), path("c")}},
+		}, {
+			name: "truncated",
+			a:    BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{path("a", "b"), path("c")}},
+			b:    BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{truncated("a")}},
+			want: BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{truncated("a"), path("c")}},
+		}, {
+			name: "smaller bound",
+			a:    BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{path("a", "b"), path("c")}},
+			b:    BoundedTrieValue{Bound: 2, Paths: []BoundedTriePath{path("a", "d")}},
+			want: BoundedTrieValue{Bound: 2, Paths: []BoundedTriePath{truncated("a"), path("c")}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.a.Merge(test.b)
+			if d := cmp.Diff(test.want, got); d != "" {
+				t.Errorf("%v.Merge(%v) diff (-want +got):\n%v", test.a, test.b, d)
+			}
+		})
+	}
+}
+
+func TestBoundedTrie_Metric(t *testing.T) {
+	ctxA := ctxWith(bID, "A")
+	ctxB := ctxWith(bID, "B")
+	m := NewBoundedTrie("ns", "lineage")
+	m.Add(ctxA, "gcs", "bucket", "a.txt")
+	m.Add(ctxA, "gcs", "bucket", "b.txt")
+	m.Add(ctxB, "gcs", "other", "c.txt")
+
+	tests := []struct {
+		ctx  context.Context
+		want []BoundedTriePath
+	}{
+		{ctx: ctxA, want: []BoundedTriePath{path("gcs", "bucket", "a.txt"), path("gcs", "bucket", "b.txt")}},
+		{ctx: ctxB, want: []BoundedTriePath{path("gcs", "other", "c.txt")}},
+	}
+	for _, test := range tests {
+		cs := getCounterSet(test.ctx)
+		got := cs.boundedTries[m.hash].get()
+		if d := cmp.Diff(BoundedTrieValue{Bound: defaultBoundedTrieBound, Paths: test.want}, got); d != "" {
+			t.Errorf("BoundedTrie in %v diff (-want +got):\n%v", test.ctx, d)
+		}
+	}
+}
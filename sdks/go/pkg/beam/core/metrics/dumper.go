@@ -75,6 +75,16 @@ func dumperExtractor(store *Store, p func(format string, args ...any)) {
 			m[l] = &gauge{v: v, t: t}
 		},
 		MsecsInt64: func(labels string, e *[4]ExecutionState) {},
+		StringSet: func(l Labels, v []string) {
+			set := make(map[string]struct{}, len(v))
+			for _, s := range v {
+				set[s] = struct{}{}
+			}
+			m[l] = &stringSet{set: set}
+		},
+		BoundedTrie: func(l Labels, v BoundedTrieValue) {
+			m[l] = &boundedTrie{bound: v.Bound, root: trieFromPaths(v.Paths)}
+		},
 	}
 	e.ExtractFrom(store)
 	dumpTo(m, p)
@@ -34,12 +34,18 @@ func TestDumperExtractor(t *testing.T) {
 	store.storeMetric("pid", newName("ns", "counter"), &counter{value: 1})
 	store.storeMetric("pid", newName("ns", "distribution"), &distribution{count: 1, sum: 2, min: 3, max: 4})
 	store.storeMetric("pid", newName("ns", "gauge"), &gauge{v: 1, t: now})
+	store.storeMetric("pid", newName("ns", "stringset"), &stringSet{set: map[string]struct{}{"b": {}, "a": {}}})
+	trie := &boundedTrie{bound: 10, root: newTrieNode()}
+	trie.add([]string{"a", "b"})
+	store.storeMetric("pid", newName("ns", "trie"), trie)
 
 	expected := []string{
 		"PTransformID: \"pid\"",
 		"	ns.counter - value: 1",
 		"	ns.distribution - count: 1 sum: 2 min: 3 max: 4",
 		"	ns.gauge - Gauge time: 2019-01-01 00:00:00 +0000 UTC value: 1",
+		"	ns.stringset - values: [\"a\" \"b\"]",
+		"	ns.trie - bound: 10 paths: [a/b]",
 	}
 
 	dumperExtractor(store, printer)
@@ -101,6 +101,8 @@ func (ctx *beamCtx) Value(key any) any {
 					counters:      make(map[nameHash]*counter),
 					distributions: make(map[nameHash]*distribution),
 					gauges:        make(map[nameHash]*gauge),
+					stringSets:    make(map[nameHash]*stringSet),
+					boundedTries:  make(map[nameHash]*boundedTrie),
 				}
 				ctx.store.css = append(ctx.store.css, cs)
 				ctx.cs = cs
@@ -214,6 +216,8 @@ const (
 	kindDistribution
 	kindGauge
 	kindDoFnMsec
+	kindStringSet
+	kindBoundedTrie
 )
 
 func (t kind) String() string {
@@ -226,6 +230,10 @@ func (t kind) String() string {
 		return "Gauge"
 	case kindDoFnMsec:
 		return "DoFnMsec"
+	case kindStringSet:
+		return "StringSet"
+	case kindBoundedTrie:
+		return "BoundedTrie"
 	default:
 		panic(fmt.Sprintf("Unknown metric type value: %v", uint8(t)))
 	}
@@ -503,6 +511,74 @@ type GaugeValue struct {
 	Timestamp time.Time
 }
 
+// StringSet is a metric that records a set of unique strings.
+type StringSet struct {
+	name name
+	hash nameHash
+}
+
+func (m *StringSet) String() string {
+	return fmt.Sprintf("StringSet metric %s", m.name)
+}
+
+// NewStringSet returns the StringSet with the given namespace and name.
+func NewStringSet(ns, n string) *StringSet {
+	return &StringSet{
+		name: newName(ns, n),
+		hash: hashName(ns, n),
+	}
+}
+
+// Add adds v to the set within the given PTransform context.
+func (m *StringSet) Add(ctx context.Context, v string) {
+	cs := getCounterSet(ctx)
+	if cs == nil {
+		return
+	}
+	if s, ok := cs.stringSets[m.hash]; ok {
+		s.add(v)
+		return
+	}
+	// We're the first to create this metric!
+	s := &stringSet{
+		set: map[string]struct{}{v: {}},
+	}
+	cs.stringSets[m.hash] = s
+	GetStore(ctx).storeMetric(cs.pid, m.name, s)
+}
+
+// stringSet is a metric cell for string set values.
+type stringSet struct {
+	mu  sync.Mutex
+	set map[string]struct{}
+}
+
+func (m *stringSet) add(v string) {
+	m.mu.Lock()
+	m.set[v] = struct{}{}
+	m.mu.Unlock()
+}
+
+func (m *stringSet) kind() kind {
+	return kindStringSet
+}
+
+func (m *stringSet) String() string {
+	return fmt.Sprintf("values: %q", m.get())
+}
+
+// get returns the values of the set in sorted order.
+func (m *stringSet) get() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vs := make([]string, 0, len(m.set))
+	for v := range m.set {
+		vs = append(vs, v)
+	}
+	sort.Strings(vs)
+	return vs
+}
+
 type executionState struct {
 	state *[4]ExecutionState
 }
@@ -534,16 +610,31 @@ type Results struct {
 	gauges        []GaugeResult
 	msecs         []MsecResult
 	pCols         []PColResult
+	stringSets    []StringSetResult
+	boundedTries  []BoundedTrieResult
 }
 
-// NewResults creates a new Results.
+// NewResults creates a new Results. StringSet and BoundedTrie results are
+// added with WithStringSets and WithBoundedTries.
 func NewResults(
 	counters []CounterResult,
 	distributions []DistributionResult,
 	gauges []GaugeResult,
 	msecs []MsecResult,
 	pCols []PColResult) *Results {
-	return &Results{counters, distributions, gauges, msecs, pCols}
+	return &Results{counters: counters, distributions: distributions, gauges: gauges, msecs: msecs, pCols: pCols}
+}
+
+// WithStringSets returns a copy of the Results with the given StringSet results.
+func (mr Results) WithStringSets(stringSets []StringSetResult) *Results {
+	mr.stringSets = stringSets
+	return &mr
+}
+
+// WithBoundedTries returns a copy of the Results with the given BoundedTrie results.
+func (mr Results) WithBoundedTries(boundedTries []BoundedTrieResult) *Results {
+	mr.boundedTries = boundedTries
+	return &mr
 }
 
 // AllMetrics returns all metrics from a Results instance.
@@ -569,6 +660,8 @@ func (mr Results) Query(f func(SingleResult) bool) QueryResults {
 	gauges := []GaugeResult{}
 	msecs := []MsecResult{}
 	pCols := []PColResult{}
+	stringSets := []StringSetResult{}
+	boundedTries := []BoundedTrieResult{}
 
 	for _, counter := range mr.counters {
 		if f(counter) {
@@ -595,7 +688,17 @@ func (mr Results) Query(f func(SingleResult) bool) QueryResults {
 			pCols = append(pCols, pCol)
 		}
 	}
-	return QueryResults{counters: counters, distributions: distributions, gauges: gauges, msecs: msecs, pCols: pCols}
+	for _, stringSet := range mr.stringSets {
+		if f(stringSet) {
+			stringSets = append(stringSets, stringSet)
+		}
+	}
+	for _, boundedTrie := range mr.boundedTries {
+		if f(boundedTrie) {
+			boundedTries = append(boundedTries, boundedTrie)
+		}
+	}
+	return QueryResults{counters: counters, distributions: distributions, gauges: gauges, msecs: msecs, pCols: pCols, stringSets: stringSets, boundedTries: boundedTries}
 }
 
 // QueryResults is the result of a query. Allows accessing all of the
@@ -606,6 +709,8 @@ type QueryResults struct {
 	gauges        []GaugeResult
 	msecs         []MsecResult
 	pCols         []PColResult
+	stringSets    []StringSetResult
+	boundedTries  []BoundedTrieResult
 }
 
 // Counters returns a slice of counter metrics.
@@ -643,6 +748,20 @@ func (qr QueryResults) PCols() []PColResult {
 	return out
 }
 
+// StringSets returns a slice of string set metrics.
+func (qr QueryResults) StringSets() []StringSetResult {
+	out := make([]StringSetResult, len(qr.stringSets))
+	copy(out, qr.stringSets)
+	return out
+}
+
+// BoundedTries returns a slice of bounded trie metrics.
+func (qr QueryResults) BoundedTries() []BoundedTrieResult {
+	out := make([]BoundedTrieResult, len(qr.boundedTries))
+	copy(out, qr.boundedTries)
+	return out
+}
+
 // CounterResult is an attempted and a commited value of a counter metric plus
 // key.
 type CounterResult struct {
@@ -920,6 +1039,114 @@ func MergeMsecs(
 	return res
 }
 
+// StringSetResult is an attempted and a commited value of a string set
+// metric plus key.
+type StringSetResult struct {
+	Attempted, Committed []string
+	Key                  StepKey
+}
+
+// Result returns committed metrics. Falls back to attempted metrics if committed
+// are not populated (e.g. due to not being supported on a given runner).
+func (r StringSetResult) Result() []string {
+	if len(r.Committed) != 0 {
+		return r.Committed
+	}
+	return r.Attempted
+}
+
+// Name returns the Name of this StringSet.
+func (r StringSetResult) Name() string {
+	return r.Key.Name
+}
+
+// Namespace returns the Namespace of this StringSet.
+func (r StringSetResult) Namespace() string {
+	return r.Key.Namespace
+}
+
+// Transform returns the Transform step for this StringSetResult.
+func (r StringSetResult) Transform() string { return r.Key.Step }
+
+// MergeStringSets combines string set metrics that share a common key.
+func MergeStringSets(
+	attempted map[StepKey][]string,
+	committed map[StepKey][]string) []StringSetResult {
+	res := make([]StringSetResult, 0)
+	merged := map[StepKey]StringSetResult{}
+
+	for k, v := range attempted {
+		merged[k] = StringSetResult{Attempted: v, Key: k}
+	}
+	for k, v := range committed {
+		m, ok := merged[k]
+		if ok {
+			merged[k] = StringSetResult{Attempted: m.Attempted, Committed: v, Key: k}
+		} else {
+			merged[k] = StringSetResult{Committed: v, Key: k}
+		}
+	}
+
+	for _, v := range merged {
+		res = append(res, v)
+	}
+	return res
+}
+
+// BoundedTrieResult is an attempted and a commited value of a bounded trie
+// metric plus key.
+type BoundedTrieResult struct {
+	Attempted, Committed BoundedTrieValue
+	Key                  StepKey
+}
+
+// Result returns committed metrics. Falls back to attempted metrics if committed
+// are not populated (e.g. due to not being supported on a given runner).
+func (r BoundedTrieResult) Result() BoundedTrieValue {
+	if len(r.Committed.Paths) != 0 {
+		return r.Committed
+	}
+	return r.Attempted
+}
+
+// Name returns the Name of this BoundedTrie.
+func (r BoundedTrieResult) Name() string {
+	return r.Key.Name
+}
+
+// Namespace returns the Namespace of this BoundedTrie.
+func (r BoundedTrieResult) Namespace() string {
+	return r.Key.Namespace
+}
+
+// Transform returns the Transform step for this BoundedTrieResult.
+func (r BoundedTrieResult) Transform() string { return r.Key.Step }
+
+// MergeBoundedTries combines bounded trie metrics that share a common key.
+func MergeBoundedTries(
+	attempted map[StepKey]BoundedTrieValue,
+	committed map[StepKey]BoundedTrieValue) []BoundedTrieResult {
+	res := make([]BoundedTrieResult, 0)
+	merged := map[StepKey]BoundedTrieResult{}
+
+	for k, v := range attempted {
+		merged[k] = BoundedTrieResult{Attempted: v, Key: k}
+	}
+	for k, v := range committed {
+		m, ok := merged[k]
+		if ok {
+			merged[k] = BoundedTrieResult{Attempted: m.Attempted, Committed: v, Key: k}
+		} else {
+			merged[k] = BoundedTrieResult{Committed: v, Key: k}
+		}
+	}
+
+	for _, v := range merged {
+		res = append(res, v)
+	}
+	return res
+}
+
 // ResultsExtractor extracts the metrics.Results from Store using ctx.
 // This is same as what metrics.dumperExtractor and metrics.dumpTo would do together.
 func ResultsExtractor(ctx context.Context) Results {
@@ -938,6 +1165,12 @@ func ResultsExtractor(ctx context.Context) Results {
 		MsecsInt64: func(labels string, e *[4]ExecutionState) {
 			m[PTransformLabels(labels)] = &executionState{state: e}
 		},
+		StringSet: func(l Labels, v []string) {
+			m[l] = v
+		},
+		BoundedTrie: func(l Labels, v BoundedTrieValue) {
+			m[l] = v
+		},
 	}
 	e.ExtractFrom(store)
 
@@ -961,7 +1194,7 @@ func ResultsExtractor(ctx context.Context) Results {
 		return false
 	})
 
-	r := Results{counters: []CounterResult{}, distributions: []DistributionResult{}, gauges: []GaugeResult{}, msecs: []MsecResult{}}
+	r := Results{counters: []CounterResult{}, distributions: []DistributionResult{}, gauges: []GaugeResult{}, msecs: []MsecResult{}, stringSets: []StringSetResult{}, boundedTries: []BoundedTrieResult{}}
 	for _, l := range ls {
 		key := StepKey{Step: l.transform, Name: l.name, Namespace: l.namespace}
 		switch opt := m[l]; opt.(type) {
@@ -990,6 +1223,18 @@ func ResultsExtractor(ctx context.Context) Results {
 			es := opt.(*executionState).state
 			committed[key] = MsecValue{Start: es[0].TotalTime, Process: es[1].TotalTime, Finish: es[2].TotalTime, Total: es[3].TotalTime}
 			r.msecs = append(r.msecs, MergeMsecs(attempted, committed)...)
+		case []string:
+			attempted := make(map[StepKey][]string)
+			committed := make(map[StepKey][]string)
+			attempted[key] = nil
+			committed[key] = opt.([]string)
+			r.stringSets = append(r.stringSets, MergeStringSets(attempted, committed)...)
+		case BoundedTrieValue:
+			attempted := make(map[StepKey]BoundedTrieValue)
+			committed := make(map[StepKey]BoundedTrieValue)
+			attempted[key] = BoundedTrieValue{}
+			committed[key] = opt.(BoundedTrieValue)
+			r.boundedTries = append(r.boundedTries, MergeBoundedTries(attempted, committed)...)
 		}
 	}
 	return r
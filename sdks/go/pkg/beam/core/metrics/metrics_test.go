@@ -239,6 +239,37 @@ func TestGauge_Set(t *testing.T) {
 	}
 }
 
+func TestStringSet_Add(t *testing.T) {
+	ctxA := ctxWith(bID, "A")
+	ctxB := ctxWith(bID, "B")
+	tests := []struct {
+		ns, n string // StringSet name
+		ctx   context.Context
+		v     string
+		want  []string
+	}{
+		{ns: "add1", n: "tables", ctx: ctxA, v: "b", want: []string{"b"}},
+		{ns: "add1", n: "tables", ctx: ctxA, v: "a", want: []string{"a", "b"}},
+		{ns: "add1", n: "tables", ctx: ctxA, v: "b", want: []string{"a", "b"}},
+		{ns: "add1", n: "files", ctx: ctxA, v: "c", want: []string{"c"}},
+		{ns: "add1", n: "tables", ctx: ctxB, v: "c", want: []string{"c"}},
+		{ns: "add2", n: "tables", ctx: ctxA, v: "d", want: []string{"d"}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("add %s.%s[%v] %q", test.ns, test.n, test.ctx, test.v),
+			func(t *testing.T) {
+				m := NewStringSet(test.ns, test.n)
+				m.Add(test.ctx, test.v)
+
+				cs := getCounterSet(test.ctx)
+				if got, want := cs.stringSets[m.hash].get(), test.want; !cmp.Equal(got, want) {
+					t.Errorf("GetStringSet(%q,%q).Add(%v, %q) got %v, want %v", test.ns, test.n, test.ctx, test.v, got, want)
+				}
+			})
+	}
+}
+
 func TestNameCollisions(t *testing.T) {
 	ns, c, d, g := "collisions", "counter", "distribution", "gauge"
 	// Checks that user code panics if a counter attempts to be defined in the same PTransform
@@ -674,3 +705,21 @@ func BenchmarkMetrics(b *testing.B) {
 		})
 	}
 }
+
+func TestResults_WithStringSetsAndBoundedTries(t *testing.T) {
+	key := StepKey{Step: "sumFn", Name: "set"}
+	sets := []StringSetResult{{Attempted: []string{"a"}, Committed: []string{"b"}, Key: key}}
+	tries := []BoundedTrieResult{{Key: key}}
+
+	res := NewResults(nil, nil, nil, nil, nil)
+	got := res.WithStringSets(sets).WithBoundedTries(tries).AllMetrics()
+	if d := cmp.Diff(sets, got.StringSets()); d != "" {
+		t.Errorf("StringSets() diff (-want, +got):\n%v", d)
+	}
+	if got, want := len(got.BoundedTries()), 1; got != want {
+		t.Errorf("len(BoundedTries()) = %v, want %v", got, want)
+	}
+	if got := res.AllMetrics().StringSets(); len(got) != 0 {
+		t.Errorf("WithStringSets() modified the original Results, got StringSets() = %v", got)
+	}
+}
@@ -98,6 +98,11 @@ type Extractor struct {
 	// MsecsInt64 extracts data from StateRegistry of ExecutionState.
 	// Extraction of Msec counters is experimental and subject to change.
 	MsecsInt64 func(labels string, e *[4]ExecutionState)
+
+	// StringSet extracts data from StringSet metrics, in sorted order.
+	StringSet func(labels Labels, v []string)
+	// BoundedTrie extracts data from BoundedTrie metrics.
+	BoundedTrie func(labels Labels, v BoundedTrieValue)
 }
 
 // ExtractFrom the given metrics Store all the metrics for
@@ -107,7 +112,8 @@ func (e Extractor) ExtractFrom(store *Store) error {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
 
-	if e.SumInt64 == nil && e.DistributionInt64 == nil && e.GaugeInt64 == nil {
+	if e.SumInt64 == nil && e.DistributionInt64 == nil && e.GaugeInt64 == nil &&
+		e.StringSet == nil && e.BoundedTrie == nil {
 		return fmt.Errorf("no Extractor fields were set")
 	}
 
@@ -128,6 +134,14 @@ func (e Extractor) ExtractFrom(store *Store) error {
 				v, t := um.(*gauge).get()
 				e.GaugeInt64(l, v, t)
 			}
+		case kindStringSet:
+			if e.StringSet != nil {
+				e.StringSet(l, um.(*stringSet).get())
+			}
+		case kindBoundedTrie:
+			if e.BoundedTrie != nil {
+				e.BoundedTrie(l, um.(*boundedTrie).get())
+			}
 		}
 	}
 	if e.MsecsInt64 != nil {
@@ -155,6 +169,8 @@ type ptCounterSet struct {
 	counters      map[nameHash]*counter
 	distributions map[nameHash]*distribution
 	gauges        map[nameHash]*gauge
+	stringSets    map[nameHash]*stringSet
+	boundedTries  map[nameHash]*boundedTrie
 }
 
 type bundleProcState int
@@ -35,6 +35,12 @@ func TestStore(t *testing.T) {
 			m[l] = &gauge{v: v, t: t}
 		},
 		MsecsInt64: func(labels string, e *[4]ExecutionState) {},
+		StringSet: func(l Labels, v []string) {
+			m[l] = v
+		},
+		BoundedTrie: func(l Labels, v BoundedTrieValue) {
+			m[l] = v
+		},
 	}
 
 	now := time.Now()
@@ -42,6 +48,10 @@ func TestStore(t *testing.T) {
 	store.storeMetric("pid", newName("ns", "counter"), &counter{value: 1})
 	store.storeMetric("pid", newName("ns", "distribution"), &distribution{count: 1, sum: 2, min: 3, max: 4})
 	store.storeMetric("pid", newName("ns", "gauge"), &gauge{v: 1, t: now})
+	store.storeMetric("pid", newName("ns", "stringset"), &stringSet{set: map[string]struct{}{"b": {}, "a": {}}})
+	trie := &boundedTrie{bound: 10, root: newTrieNode()}
+	trie.add([]string{"a", "b"})
+	store.storeMetric("pid", newName("ns", "boundedtrie"), trie)
 
 	// storing the same metric twice doesn't change anything
 	store.storeMetric("pid", newName("ns", "counter"), &counter{value: 2})
@@ -55,6 +65,8 @@ func TestStore(t *testing.T) {
 		{transform: "pid", namespace: "ns", name: "counter"}:      &counter{value: 1},
 		{transform: "pid", namespace: "ns", name: "distribution"}: &distribution{count: 1, sum: 2, min: 3, max: 4},
 		{transform: "pid", namespace: "ns", name: "gauge"}:        &gauge{v: 1, t: now},
+		{transform: "pid", namespace: "ns", name: "stringset"}:    []string{"a", "b"},
+		{transform: "pid", namespace: "ns", name: "boundedtrie"}:  BoundedTrieValue{Bound: 10, Paths: []BoundedTriePath{{Segments: []string{"a", "b"}}}},
 	}
 	if !reflect.DeepEqual(m, expected) {
 		t.Errorf("e.ExtractFrom(store) = %v, want %v", m, expected)
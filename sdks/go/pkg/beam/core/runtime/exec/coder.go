@@ -249,8 +249,13 @@ func MakeElementDecoder(c *coder.Coder) ElementDecoder {
 
 	// Note: Iterables in CoGBK are handled in datasource.go instead.
 	case coder.Iterable:
+		t := c.T.Type()
+		if c.Components[0].Kind == coder.IW {
+			// Interval windows decode as windows, rather than the coder's placeholder type.
+			t = reflect.TypeOf([]typex.Window(nil))
+		}
 		return &iterableDecoder{
-			t:   c.T.Type(),
+			t:   t,
 			dec: MakeElementDecoder(c.Components[0]),
 		}
 
@@ -695,6 +700,13 @@ func (c *iterableEncoder) Encode(val *FullValue, w io.Writer) error {
 	}
 	var e FullValue
 	for i := 0; i < size; i++ {
+		// Nested KVs are held as *FullValues.
+		if fv, ok := rv.Index(i).Interface().(*FullValue); ok {
+			if err := c.enc.Encode(fv, w); err != nil {
+				return err
+			}
+			continue
+		}
 		e.Elm = rv.Index(i).Interface()
 		err := c.enc.Encode(&e, w)
 		if err != nil {
@@ -116,6 +116,37 @@ func TestLiftedCombine(t *testing.T) {
 
 }
 
+// TestCombine_Pane verifies that combined outputs keep the pane of their grouped
+// input, so runners can tell the firings of triggered aggregations apart.
+func TestCombine_Pane(t *testing.T) {
+	pane := typex.PaneInfo{Timing: typex.PaneEarly, Index: 2, NonSpeculativeIndex: -1}
+	edge := getCombineEdge(t, &MyCombine{}, reflectx.Int, intCoder(reflectx.Int64))
+
+	t.Run("Combine", func(t *testing.T) {
+		out := &CaptureNode{UID: 1}
+		combine := &Combine{UID: 2, Fn: edge.CombineFn, Out: out}
+		in := makeKeyedInput(42, 1, 2, 3)
+		in[0].Key.Pane = pane
+		n := &FixedRoot{UID: 3, Elements: in, Out: combine}
+		constructAndExecutePlan(t, []Unit{n, combine, out})
+		if got := out.Elements[0].Pane; got != pane {
+			t.Errorf("combine output pane = %v, want %v", got, pane)
+		}
+	})
+	t.Run("MergeAccumulators", func(t *testing.T) {
+		out := &CaptureNode{UID: 1}
+		extract := &ExtractOutput{Combine: &Combine{UID: 2, Fn: edge.CombineFn, Out: out}}
+		merge := &MergeAccumulators{Combine: &Combine{UID: 3, Fn: edge.CombineFn, Out: extract}}
+		in := makeKeyedInput(42, int64(1), int64(2))
+		in[0].Key.Pane = pane
+		n := &FixedRoot{UID: 4, Elements: in, Out: merge}
+		constructAndExecutePlan(t, []Unit{n, merge, extract, out})
+		if got := out.Elements[0].Pane; got != pane {
+			t.Errorf("extract output pane = %v, want %v", got, pane)
+		}
+	})
+}
+
 // pigeonHasher only returns 0 for even hashes, and 1 for odd hashes
 // nearly guaranteeing that overflow behavior must be tested for small sets.
 type pigeonHasher struct {
@@ -57,7 +57,9 @@ type SideCache interface {
 // DataManager manages external data byte streams. Each data stream can be
 // opened by one consumer only.
 type DataManager interface {
-	// OpenElementChan opens a channel for data and timers.
+	// OpenElementChan opens a channel for data and timers. The expected timer
+	// transforms hold a transform for each of its timer families, as each
+	// family's stream of timers is terminated separately.
 	OpenElementChan(ctx context.Context, id StreamID, expectedTimerTransforms []string) (<-chan Elements, error)
 	// OpenWrite opens a closable byte stream for data writing.
 	OpenWrite(ctx context.Context, id StreamID) (io.WriteCloser, error)
@@ -89,6 +91,14 @@ type StateReader interface {
 	OpenMultimapKeysUserStateReader(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte) (io.ReadCloser, error)
 	// OpenMultimapKeysUserStateClearer opens a byte stream for clearing all keys of user multimap state.
 	OpenMultimapKeysUserStateClearer(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte) (io.Writer, error)
+	// OpenOrderedListUserStateReader opens a byte stream for reading user ordered list state
+	// with sort keys in [start, end).
+	OpenOrderedListUserStateReader(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte, start, end int64) (io.ReadCloser, error)
+	// OpenOrderedListUserStateAppender opens a byte stream for appending user ordered list state.
+	OpenOrderedListUserStateAppender(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte) (io.Writer, error)
+	// OpenOrderedListUserStateClearer opens a byte stream for clearing user ordered list state
+	// with sort keys in [start, end).
+	OpenOrderedListUserStateClearer(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte, start, end int64) (io.Writer, error)
 	// GetSideInputCache returns the SideInputCache being used at the harness level.
 	GetSideInputCache() SideCache
 }
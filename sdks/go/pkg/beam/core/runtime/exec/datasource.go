@@ -31,7 +31,6 @@ import (
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/ioutilx"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
-	"golang.org/x/exp/maps"
 )
 
 // DataSource is a Root execution unit.
@@ -111,13 +110,26 @@ func (n *DataSource) StartBundle(ctx context.Context, id string, data DataContex
 	return n.Out.StartBundle(ctx, id, data)
 }
 
+// expectedTimerTransforms returns the transforms with OnTimer callbacks, once
+// for each of their timer families, as the runner terminates the timers of
+// each family separately.
+func (n *DataSource) expectedTimerTransforms() []string {
+	var pids []string
+	for pid, pd := range n.OnTimerTransforms {
+		for range pd.TimerTracker.familyToSpec {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
 // process handles converting elements from the data source to timers.
 //
 // The data and timer callback functions must return an io.EOF if the reader terminates to signal that an additional
 // buffer is desired.
 func (n *DataSource) process(ctx context.Context, data func(bcr *byteCountReader, ptransformID string) error, timer func(bcr *byteCountReader, ptransformID, timerFamilyID string) error) error {
 	// The SID contains this instruction's expected data processing transform (this one).
-	elms, err := n.source.OpenElementChan(ctx, n.SID, maps.Keys(n.OnTimerTransforms))
+	elms, err := n.source.OpenElementChan(ctx, n.SID, n.expectedTimerTransforms())
 	if err != nil {
 		return err
 	}
@@ -244,6 +244,21 @@ func TestDataSource_Iterators(t *testing.T) {
 	}
 }
 
+func TestDataSource_ExpectedTimerTransforms(t *testing.T) {
+	source := &DataSource{
+		OnTimerTransforms: map[string]*ParDo{
+			"stateful": {TimerTracker: newUserTimerAdapter(StreamID{}, map[string]timerFamilySpec{
+				"eventTime":      {},
+				"processingTime": {},
+			})},
+		},
+	}
+	got := source.expectedTimerTransforms()
+	if want := []string{"stateful", "stateful"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expectedTimerTransforms() = %v, want %v, once for each timer family", got, want)
+	}
+}
+
 func TestDataSource_Split(t *testing.T) {
 	elements := []any{int64(1), int64(2), int64(3), int64(4), int64(5)}
 	initSourceTest := func(name string) (*DataSource, *CaptureNode, chan Elements) {
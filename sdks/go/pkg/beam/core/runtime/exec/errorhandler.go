@@ -0,0 +1,126 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"runtime/debug"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+)
+
+// ErrorHandler outputs the elements that fail processing in a ParDo as
+// ErrorRecords, instead of failing the bundle. Errors and panics from the
+// ParDo's downstream nodes still fail the bundle.
+//
+// Outputs emitted for an element before it failed aren't retracted, so a
+// failed element may have both outputs and an ErrorRecord.
+type ErrorHandler struct {
+	// Out is the output of ErrorRecords, which must also be the last output
+	// of the ParDo.
+	Out Node
+	// Coder encodes the main input elements of the failed elements. For
+	// grouped main inputs, it's the key coder, as only the key is encoded.
+	Coder *coder.Coder
+	// Transform is the unique name of the ParDo's transform.
+	Transform string
+
+	enc      ElementEncoder
+	buf      bytes.Buffer
+	outErr   error
+	outPanic bool
+}
+
+func (h *ErrorHandler) init() {
+	h.enc = MakeElementEncoder(h.Coder)
+}
+
+// trackOutputs wraps the given outputs of the ParDo, so that their errors
+// and panics are distinguished from those of the DoFn.
+func (h *ErrorHandler) trackOutputs(out []Node) []Node {
+	ret := make([]Node, 0, len(out))
+	for _, n := range out {
+		ret = append(ret, &errorTrackingNode{Node: n, h: h})
+	}
+	return ret
+}
+
+// invoke calls fn, which processes the main input. If it returns an error or
+// panics, an ErrorRecord for the main input is output instead. Panics from
+// the outputs are propagated.
+func (h *ErrorHandler) invoke(ctx context.Context, mainIn *MainInput, fn func() (*FullValue, error)) (val *FullValue, err error) {
+	h.outErr = nil
+	h.outPanic = false
+	var stack string
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if h.outPanic {
+					panic(r)
+				}
+				err = errors.Errorf("panic: %v", r)
+				stack = string(debug.Stack())
+			}
+		}()
+		val, err = fn()
+	}()
+	if h.outErr != nil {
+		return nil, h.outErr
+	}
+	if err == nil {
+		return val, nil
+	}
+	return nil, h.output(ctx, &mainIn.Key, err, stack)
+}
+
+func (h *ErrorHandler) output(ctx context.Context, elm *FullValue, procErr error, stack string) error {
+	h.buf.Reset()
+	if err := h.enc.Encode(&FullValue{Elm: elm.Elm, Elm2: elm.Elm2}, &h.buf); err != nil {
+		return errors.Wrapf(err, "encoding failed element of %v", h.Transform)
+	}
+	rec := typex.ErrorRecord{
+		Element:   bytes.Clone(h.buf.Bytes()),
+		Error:     procErr.Error(),
+		Stack:     stack,
+		Transform: h.Transform,
+	}
+	return h.Out.ProcessElement(ctx, &FullValue{Elm: rec, Timestamp: elm.Timestamp, Windows: elm.Windows, Pane: elm.Pane})
+}
+
+// errorTrackingNode records the errors and panics of an output of a ParDo
+// with an ErrorHandler.
+type errorTrackingNode struct {
+	Node
+	h *ErrorHandler
+}
+
+func (n *errorTrackingNode) ProcessElement(ctx context.Context, elm *FullValue, values ...ReStream) error {
+	panicked := true
+	defer func() {
+		if panicked {
+			n.h.outPanic = true
+		}
+	}()
+	err := n.Node.ProcessElement(ctx, elm, values...)
+	panicked = false
+	if err != nil {
+		n.h.outErr = err
+	}
+	return err
+}
@@ -43,6 +43,7 @@ type ParDo struct {
 	Side         []SideInputAdapter
 	UState       UserStateAdapter
 	TimerTracker *userTimerAdapter
+	ErrorHandler *ErrorHandler
 	Out          []Node
 
 	PID      string
@@ -106,7 +107,12 @@ func (n *ParDo) Up(ctx context.Context) error {
 		return n.fail(err)
 	}
 
-	emitters, err := makeEmitters(n.Fn.ProcessElementFn(), n.Out)
+	out := n.Out
+	if n.ErrorHandler != nil {
+		n.ErrorHandler.init()
+		out = n.ErrorHandler.trackOutputs(out[:len(out)-1])
+	}
+	emitters, err := makeEmitters(n.Fn.ProcessElementFn(), out)
 	if err != nil {
 		return n.fail(err)
 	}
@@ -191,7 +197,15 @@ func (n *ParDo) processMainInput(mainIn *MainInput) error {
 // each individual window by exploding the windows first.
 func (n *ParDo) processSingleWindow(mainIn *MainInput) (sdf.ProcessContinuation, error) {
 	elm := &mainIn.Key
-	val, err := n.invokeProcessFn(n.ctx, elm.Pane, elm.Windows, elm.Timestamp, mainIn)
+	var val *FullValue
+	var err error
+	if n.ErrorHandler != nil {
+		val, err = n.ErrorHandler.invoke(n.ctx, mainIn, func() (*FullValue, error) {
+			return n.invokeProcessFn(n.ctx, elm.Pane, elm.Windows, elm.Timestamp, mainIn)
+		})
+	} else {
+		val, err = n.invokeProcessFn(n.ctx, elm.Pane, elm.Windows, elm.Timestamp, mainIn)
+	}
 	if err != nil {
 		return nil, n.fail(err)
 	}
@@ -353,6 +353,150 @@ func TestProcessSingleWindow_errorCase(t *testing.T) {
 	}
 }
 
+func failOnBad(a string, emit func(string)) error {
+	switch a {
+	case "error":
+		return errReturned
+	case "panic":
+		panic("a unique panic")
+	}
+	emit(a)
+	return nil
+}
+
+// TestParDo_ErrorHandler verifies that the ParDo outputs failed elements to
+// the ErrorHandler, instead of failing.
+func TestParDo_ErrorHandler(t *testing.T) {
+	fn, err := graph.NewDoFn(failOnBad)
+	if err != nil {
+		t.Fatalf("invalid function %v", err)
+	}
+	g := graph.New()
+	nN := g.NewNode(typex.New(reflectx.String), window.DefaultWindowingStrategy(), true)
+
+	edge, err := graph.NewParDo(g, g.Root(), fn, []*graph.Node{nN}, nil, nil)
+	if err != nil {
+		t.Fatalf("invalid pardo: %v", err)
+	}
+	if _, err := graph.AddErrorOutput(g, edge); err != nil {
+		t.Fatalf("AddErrorOutput failed: %v", err)
+	}
+	if !graph.HasErrorOutput(edge.DoFn, edge.Output) {
+		t.Fatalf("HasErrorOutput(%v) = false, want true", edge)
+	}
+	out := &CaptureNode{UID: 1}
+	errs := &CaptureNode{UID: 2}
+	pardo := &ParDo{UID: 3, Fn: edge.DoFn, Inbound: edge.Input, Out: []Node{out, errs},
+		ErrorHandler: &ErrorHandler{Out: errs, Coder: coder.NewString(), Transform: "failOnBad"}}
+	n := &FixedRoot{UID: 4, Elements: makeInput("a", "error", "panic", "b"), Out: pardo}
+
+	p, err := NewPlan("a", []Unit{n, pardo, out, errs})
+	if err != nil {
+		t.Fatalf("failed to construct plan: %v", err)
+	}
+	if err := p.Execute(context.Background(), "1", DataContext{}); err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if err := p.Down(context.Background()); err != nil {
+		t.Fatalf("down failed: %v", err)
+	}
+
+	if expected := makeValues("a", "b"); !equalList(out.Elements, expected) {
+		t.Errorf("pardo(failOnBad) = %v, want %v", extractValues(out.Elements...), extractValues(expected...))
+	}
+	if got, want := len(errs.Elements), 2; got != want {
+		t.Fatalf("pardo(failOnBad) errors = %v, want %v errors", extractValues(errs.Elements...), want)
+	}
+	dec := MakeElementDecoder(coder.NewString())
+	for i, want := range []struct {
+		elm, err string
+		stack    bool
+	}{
+		{"error", errReturned.Error(), false},
+		{"panic", "a unique panic", true},
+	} {
+		rec := errs.Elements[i].Elm.(typex.ErrorRecord)
+		elm, err := dec.Decode(bytes.NewReader(rec.Element))
+		if err != nil {
+			t.Fatalf("decoding error record element failed: %v", err)
+		}
+		if elm.Elm != want.elm {
+			t.Errorf("error record %v element = %v, want %v", i, elm.Elm, want.elm)
+		}
+		if !strings.Contains(rec.Error, want.err) {
+			t.Errorf("error record %v error = %v, want %v", i, rec.Error, want.err)
+		}
+		if got := rec.Stack != ""; got != want.stack {
+			t.Errorf("error record %v has stack = %v, want %v", i, got, want.stack)
+		}
+		if got, want := rec.Transform, "failOnBad"; got != want {
+			t.Errorf("error record %v transform = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func alwaysPanic(a string) string {
+	panic("a downstream panic")
+}
+
+// TestParDo_ErrorHandlerDownstreamFailure verifies that errors and panics of
+// downstream nodes still fail a ParDo with an ErrorHandler.
+func TestParDo_ErrorHandlerDownstreamFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		downFn  any
+		wantErr string
+	}{
+		{name: "error", downFn: alwaysError, wantErr: errReturned.Error()},
+		{name: "panic", downFn: alwaysPanic, wantErr: "a downstream panic"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := graph.New()
+			nN := g.NewNode(typex.New(reflectx.String), window.DefaultWindowingStrategy(), true)
+
+			fn, err := graph.NewDoFn(failOnBad)
+			if err != nil {
+				t.Fatalf("invalid function %v", err)
+			}
+			edge, err := graph.NewParDo(g, g.Root(), fn, []*graph.Node{nN}, nil, nil)
+			if err != nil {
+				t.Fatalf("invalid pardo: %v", err)
+			}
+			downFn, err := graph.NewDoFn(test.downFn)
+			if err != nil {
+				t.Fatalf("invalid function %v", err)
+			}
+			downEdge, err := graph.NewParDo(g, g.Root(), downFn, []*graph.Node{edge.Output[0].To}, nil, nil)
+			if err != nil {
+				t.Fatalf("invalid pardo: %v", err)
+			}
+
+			out := &CaptureNode{UID: 1}
+			down := &ParDo{UID: 2, Fn: downEdge.DoFn, Inbound: downEdge.Input, Out: []Node{out}}
+			errs := &CaptureNode{UID: 3}
+			pardo := &ParDo{UID: 4, Fn: edge.DoFn, Inbound: edge.Input, Out: []Node{down, errs},
+				ErrorHandler: &ErrorHandler{Out: errs, Coder: coder.NewString(), Transform: "failOnBad"}}
+			n := &FixedRoot{UID: 5, Elements: makeInput("a"), Out: pardo}
+
+			p, err := NewPlan("a", []Unit{n, pardo, down, out, errs})
+			if err != nil {
+				t.Fatalf("failed to construct plan: %v", err)
+			}
+			err = p.Execute(context.Background(), "1", DataContext{})
+			if err == nil {
+				t.Fatalf("plan execution succeeded when it should have failed")
+			}
+			if got, want := err.Error(), test.wantErr; !strings.Contains(got, want) {
+				t.Errorf("got error %v, want error %v", got, want)
+			}
+			if len(errs.Elements) != 0 {
+				t.Errorf("got %v error records, want 0", len(errs.Elements))
+			}
+		})
+	}
+}
+
 func makeInputsWithUnfinishedRestrictions(values ...any) []MainInput {
 	initial := makeInput(values...)
 	var restrictedIns []MainInput
@@ -526,6 +526,7 @@ func (n *ProcessSizedElementsAndRestrictions) ProcessElement(ctx context.Context
 			Windows:   elm.Windows,
 		}
 	}
+	n.PDo.TimerTracker.SetCurrentKey(mainIn)
 
 	if n.cweInv != nil {
 		n.PDo.we = n.cweInv.Invoke(elm.Elm.(*FullValue).Elm2.(*FullValue).Elm2)
@@ -507,7 +507,7 @@ func (b *builder) makeLink(from string, id linkID) (Node, error) {
 
 		switch tpUrn := tp.GetUrn(); tpUrn {
 		case graphx.URNDoFn:
-			op, fn, _, in, _, err := graphx.DecodeMultiEdge(tp.GetEdge())
+			op, fn, _, in, outbound, err := graphx.DecodeMultiEdge(tp.GetEdge())
 			if err != nil {
 				return nil, err
 			}
@@ -531,6 +531,17 @@ func (b *builder) makeLink(from string, id linkID) (Node, error) {
 
 					input := unmarshalKeyedValues(transform.GetInputs())
 
+					if graph.HasErrorOutput(dofn, outbound) {
+						ec, _, err := b.makeCoderForPCollection(input[0])
+						if err != nil {
+							return nil, err
+						}
+						if typex.IsCoGBK(in[0].Type) {
+							ec = ec.Components[0]
+						}
+						n.ErrorHandler = &ErrorHandler{Out: out[len(out)-1], Coder: ec, Transform: transform.GetUniqueName()}
+					}
+
 					if len(userState) > 0 {
 						stateIDToCoder := make(map[string]*coder.Coder)
 						stateIDToKeyCoder := make(map[string]*coder.Coder)
@@ -563,6 +574,8 @@ func (b *builder) makeLink(from string, id linkID) (Node, error) {
 								kcID = ms.KeyCoderId
 							} else if ss := spec.GetSetSpec(); ss != nil {
 								kcID = ss.ElementCoderId
+							} else if ols := spec.GetOrderedListSpec(); ols != nil {
+								cID = ols.ElementCoderId
 							} else {
 								return nil, errors.Errorf("Unrecognized state type %v", spec)
 							}
@@ -593,6 +606,11 @@ func (b *builder) makeLink(from string, id linkID) (Node, error) {
 							if err != nil {
 								return nil, err
 							}
+							if urn == urnProcessSizedElementsAndRestrictions {
+								// State is keyed by the original element, not the sized restriction
+								// wrapping it: KV<KV<Element, KV<Restriction, WatermarkState>>, Size>.
+								ec = ec.Components[0].Components[0]
+							}
 							n.UState = NewUserStateAdapter(sid, coder.NewW(ec, wc), stateIDToCoder, stateIDToKeyCoder, stateIDToCombineFn)
 						}
 					}
@@ -800,6 +818,17 @@ func (b *builder) makeLink(from string, id linkID) (Node, error) {
 		}
 		u = &MapWindows{UID: b.idgen.New(), Fn: mapper, Out: out[0], FnUrn: fn.GetUrn()}
 
+	case graphx.URNMergeWindows:
+		var fn pipepb.FunctionSpec
+		if err := proto.Unmarshal(payload, &fn); err != nil {
+			return nil, errors.Wrapf(err, "invalid MergeWindows payload for %v", transform)
+		}
+		wfn, err := unmarshalWindowFn(&fn)
+		if err != nil {
+			return nil, err
+		}
+		u = &MergeWindows{UID: b.idgen.New(), Fn: wfn, Out: out[0]}
+
 	case graphx.URNFlatten:
 		u = &Flatten{UID: b.idgen.New(), N: len(transform.Inputs), Out: out[0]}
 
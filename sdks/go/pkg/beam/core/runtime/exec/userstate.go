@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
@@ -142,6 +143,9 @@ func (s *stateProvider) ReadBagState(userStateID string) ([]any, []state.Transac
 			}
 		}
 		s.initialBagByKey[userStateID] = initialValue
+		// Appends and clears are sent to the runner as they're made, so
+		// the values read from the runner already include them.
+		delete(s.transactionsByKey, userStateID)
 	}
 
 	transactions, ok := s.transactionsByKey[userStateID]
@@ -328,6 +332,66 @@ func (s *stateProvider) ClearMapState(val state.Transaction) error {
 	return nil
 }
 
+// ReadOrderedListState reads the values of an ordered list state in the given range.
+// Appends and clears are sent to the runner as they're made, so the values are always
+// read from the runner.
+func (s *stateProvider) ReadOrderedListState(userStateID string, r state.Range) ([]state.TimestampedValue[any], error) {
+	start, end := r.Millis()
+	rw, err := s.sr.OpenOrderedListUserStateReader(s.ctx, s.SID, userStateID, s.elementKey, s.window, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rw.Close()
+	values := []state.TimestampedValue[any]{}
+	dec := MakeElementDecoder(coder.SkipW(s.codersByKey[userStateID]))
+	for {
+		ms, err := coder.DecodeVarInt(rw)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		resp, err := dec.Decode(rw)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, state.TimestampedValue[any]{Timestamp: time.UnixMilli(ms), Value: resp.Elm})
+	}
+	return values, nil
+}
+
+// WriteOrderedListState adds a timestamped value to an ordered list state.
+func (s *stateProvider) WriteOrderedListState(val state.Transaction) error {
+	ap, err := s.getOrderedListAppender(val.Key)
+	if err != nil {
+		return err
+	}
+	// Each append must hold whole entries of the millisecond sort key followed by the value,
+	// so the entry is encoded before it is written.
+	var b bytes.Buffer
+	if err := coder.EncodeVarInt(val.MapKey.(time.Time).UnixMilli(), &b); err != nil {
+		return err
+	}
+	fv := FullValue{Elm: val.Val}
+	enc := MakeElementEncoder(coder.SkipW(s.codersByKey[val.Key]))
+	if err := enc.Encode(&fv, &b); err != nil {
+		return err
+	}
+	_, err = ap.Write(b.Bytes())
+	return err
+}
+
+// ClearOrderedListState removes the values of an ordered list state in a range.
+func (s *stateProvider) ClearOrderedListState(val state.Transaction) error {
+	start, end := val.MapKey.(state.Range).Millis()
+	cl, err := s.sr.OpenOrderedListUserStateClearer(s.ctx, s.SID, val.Key, s.elementKey, s.window, start, end)
+	if err != nil {
+		return err
+	}
+	_, err = cl.Write([]byte{})
+	return err
+}
+
 func (s *stateProvider) CreateAccumulatorFn(userStateID string) reflectx.Func {
 	a := s.combineFnsByKey[userStateID]
 	if ca := a.CreateAccumulatorFn(); ca != nil {
@@ -454,6 +518,18 @@ func (s *stateProvider) getMultiMapKeyReader(userStateID string) (io.ReadCloser,
 	return s.readersByKey[userStateID], nil
 }
 
+func (s *stateProvider) getOrderedListAppender(userStateID string) (io.Writer, error) {
+	if w, ok := s.appendersByKey[userStateID]; ok {
+		return w, nil
+	}
+	w, err := s.sr.OpenOrderedListUserStateAppender(s.ctx, s.SID, userStateID, s.elementKey, s.window)
+	if err != nil {
+		return nil, err
+	}
+	s.appendersByKey[userStateID] = w
+	return s.appendersByKey[userStateID], nil
+}
+
 func (s *stateProvider) encodeKey(userStateID string, key any) ([]byte, error) {
 	fv := FullValue{Elm: key}
 	enc := MakeElementEncoder(coder.SkipW(s.keyCodersByID[userStateID]))
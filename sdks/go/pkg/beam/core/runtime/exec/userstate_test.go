@@ -16,11 +16,14 @@
 package exec
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
@@ -203,3 +206,182 @@ func TestNewUserStateAdapter(t *testing.T) {
 		})
 	}
 }
+
+// orderedListStateReader stores ordered list state like a runner, as entries of a
+// varint millisecond sort key followed by the encoded value.
+type orderedListStateReader struct {
+	testStateReader
+	entries [][]byte
+}
+
+type orderedListWriter func(b []byte) (int, error)
+
+func (w orderedListWriter) Write(b []byte) (int, error) {
+	return w(b)
+}
+
+func sortKey(entry []byte) int64 {
+	ms, err := coder.DecodeVarInt(bytes.NewReader(entry))
+	if err != nil {
+		panic(err)
+	}
+	return ms
+}
+
+func (t *orderedListStateReader) OpenOrderedListUserStateReader(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte, start, end int64) (io.ReadCloser, error) {
+	var b []byte
+	for _, e := range t.entries {
+		if ms := sortKey(e); ms >= start && ms < end {
+			b = append(b, e...)
+		}
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (t *orderedListStateReader) OpenOrderedListUserStateAppender(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte) (io.Writer, error) {
+	return orderedListWriter(func(b []byte) (int, error) {
+		t.entries = append(t.entries, bytes.Clone(b))
+		sort.SliceStable(t.entries, func(i, j int) bool {
+			return sortKey(t.entries[i]) < sortKey(t.entries[j])
+		})
+		return len(b), nil
+	}), nil
+}
+
+func (t *orderedListStateReader) OpenOrderedListUserStateClearer(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte, start, end int64) (io.Writer, error) {
+	return orderedListWriter(func(b []byte) (int, error) {
+		var kept [][]byte
+		for _, e := range t.entries {
+			if ms := sortKey(e); ms < start || ms >= end {
+				kept = append(kept, e)
+			}
+		}
+		t.entries = kept
+		return len(b), nil
+	}), nil
+}
+
+func TestOrderedListState(t *testing.T) {
+	intCoder, err := makeIntCoder()
+	if err != nil {
+		t.Fatalf("Failed to construct int coder with error: %v", err)
+	}
+	sr := &orderedListStateReader{}
+	sp := buildStateProvider()
+	sp.sr = sr
+	sp.codersByKey["list"] = intCoder
+
+	ol := state.MakeOrderedListState[int]("list")
+	read := func(r state.Range) []int {
+		t.Helper()
+		vals, _, err := ol.Read(&sp, r)
+		if err != nil {
+			t.Fatalf("OrderedList.Read(%v) returned error: %v", r, err)
+		}
+		got := []int{}
+		for _, v := range vals {
+			if ts := v.Timestamp.UnixMilli(); ts != int64(v.Value) {
+				t.Errorf("OrderedList.Read(%v) returned value %v with timestamp %v, want %v", r, v.Value, ts, v.Value)
+			}
+			got = append(got, v.Value)
+		}
+		return got
+	}
+
+	for _, v := range []int{3, -2, 1, 0} {
+		if err := ol.Add(&sp, time.UnixMilli(int64(v)), v); err != nil {
+			t.Fatalf("OrderedList.Add(%v) returned error: %v", v, err)
+		}
+	}
+	if got, want := len(sr.entries), 4; got != want {
+		t.Fatalf("OrderedList.Add() appended %v entries, want %v", got, want)
+	}
+	if got, want := read(state.Range{}), []int{-2, 0, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedList.Read()=%v, want %v", got, want)
+	}
+	r := state.Range{Start: time.UnixMilli(0), End: time.UnixMilli(3)}
+	if got, want := read(r), []int{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedList.Read(%v)=%v, want %v", r, got, want)
+	}
+	if err := ol.ClearRange(&sp, r); err != nil {
+		t.Fatalf("OrderedList.ClearRange(%v) returned error: %v", r, err)
+	}
+	if got, want := read(state.Range{}), []int{-2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedList.Read() after ClearRange(%v)=%v, want %v", r, got, want)
+	}
+	if err := ol.Clear(&sp); err != nil {
+		t.Fatalf("OrderedList.Clear() returned error: %v", err)
+	}
+	if got, want := read(state.Range{}), []int{}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedList.Read() after Clear()=%v, want %v", got, want)
+	}
+}
+
+// bagStateReader stores bag state like a runner, as the concatenated encoded values.
+type bagStateReader struct {
+	testStateReader
+	data []byte
+}
+
+func (t *bagStateReader) OpenBagUserStateReader(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(bytes.Clone(t.data))), nil
+}
+
+func (t *bagStateReader) OpenBagUserStateAppender(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte) (io.Writer, error) {
+	return orderedListWriter(func(b []byte) (int, error) {
+		t.data = append(t.data, b...)
+		return len(b), nil
+	}), nil
+}
+
+func (t *bagStateReader) OpenBagUserStateClearer(ctx context.Context, id StreamID, userStateID string, key []byte, w []byte) (io.Writer, error) {
+	return orderedListWriter(func(b []byte) (int, error) {
+		t.data = nil
+		return len(b), nil
+	}), nil
+}
+
+func TestBagState_ReadAfterAdd(t *testing.T) {
+	intCoder, err := makeIntCoder()
+	if err != nil {
+		t.Fatalf("Failed to construct int coder with error: %v", err)
+	}
+	sr := &bagStateReader{}
+	sp := buildStateProvider()
+	sp.sr = sr
+	sp.codersByKey["bag"] = intCoder
+
+	bag := state.MakeBagState[int]("bag")
+	read := func() []int {
+		t.Helper()
+		vals, _, err := bag.Read(&sp)
+		if err != nil {
+			t.Fatalf("Bag.Read() returned error: %v", err)
+		}
+		return vals
+	}
+
+	for _, v := range []int{1, 2} {
+		if err := bag.Add(&sp, v); err != nil {
+			t.Fatalf("Bag.Add(%v) returned error: %v", v, err)
+		}
+	}
+	if got, want := read(), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bag.Read()=%v, want %v", got, want)
+	}
+	if err := bag.Add(&sp, 3); err != nil {
+		t.Fatalf("Bag.Add(3) returned error: %v", err)
+	}
+	if got, want := read(), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bag.Read() after Add(3)=%v, want %v", got, want)
+	}
+	if err := bag.Clear(&sp); err != nil {
+		t.Fatalf("Bag.Clear() returned error: %v", err)
+	}
+	if err := bag.Add(&sp, 4); err != nil {
+		t.Fatalf("Bag.Add(4) returned error: %v", err)
+	}
+	if got, want := read(), []int{4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Bag.Read() after Clear()=%v, want %v", got, want)
+	}
+}
@@ -18,6 +18,7 @@ package exec
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
@@ -181,3 +182,99 @@ func (f *windowMapper) MapWindow(w typex.Window) (typex.Window, error) {
 	// Sliding windows append the latest window first in assignWindows.
 	return candidates[len(candidates)-1], nil
 }
+
+// MergeWindows merges the windows of each element with a merging WindowFn.
+// Elements are a nonce keying an iterable of windows, and are output as the
+// nonce keying the windows that didn't merge, and each merged window with the
+// windows it consumed. Used by runners to delegate window merging to the SDK.
+type MergeWindows struct {
+	UID UnitID
+	Fn  *window.Fn
+	Out Node
+}
+
+// ID returns the UnitID for this unit.
+func (m *MergeWindows) ID() UnitID {
+	return m.UID
+}
+
+// Up does nothing
+func (m *MergeWindows) Up(_ context.Context) error {
+	return nil
+}
+
+func (m *MergeWindows) StartBundle(ctx context.Context, id string, data DataContext) error {
+	return m.Out.StartBundle(ctx, id, data)
+}
+
+func (m *MergeWindows) ProcessElement(ctx context.Context, elm *FullValue, values ...ReStream) error {
+	ws, ok := elm.Elm2.([]typex.Window)
+	if !ok {
+		return errors.Errorf("not a slice of Windows, got %T", elm.Elm2)
+	}
+	unmerged, merged, err := mergeWindows(m.Fn, ws)
+	if err != nil {
+		return err
+	}
+	out := &FullValue{
+		Elm:       elm.Elm,
+		Elm2:      &FullValue{Elm: unmerged, Elm2: merged},
+		Timestamp: elm.Timestamp,
+		Windows:   elm.Windows,
+		Pane:      elm.Pane,
+	}
+	return m.Out.ProcessElement(ctx, out, values...)
+}
+
+// FinishBundle propagates finish bundle to downstream nodes.
+func (m *MergeWindows) FinishBundle(ctx context.Context) error {
+	return m.Out.FinishBundle(ctx)
+}
+
+// Down does nothing.
+func (m *MergeWindows) Down(_ context.Context) error {
+	return nil
+}
+
+func (m *MergeWindows) String() string {
+	return fmt.Sprintf("MergeWindows[%v]. Out:%v", m.Fn, m.Out.ID())
+}
+
+// mergeWindows merges the given windows with the WindowFn, returning the windows
+// that didn't merge, and KVs of each merged window with the windows it consumed.
+func mergeWindows(wfn *window.Fn, ws []typex.Window) ([]typex.Window, []*FullValue, error) {
+	if wfn.Kind != window.Sessions {
+		return nil, nil, errors.Errorf("unable to merge windows with WindowFn %v", wfn)
+	}
+	ordered := make([]window.IntervalWindow, 0, len(ws))
+	for _, w := range ws {
+		iw, ok := w.(window.IntervalWindow)
+		if !ok {
+			return nil, nil, errors.Errorf("tried to merge non-interval window type %T", w)
+		}
+		ordered = append(ordered, iw)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Start < ordered[j].Start
+	})
+
+	unmerged := []typex.Window{}
+	merged := []*FullValue{}
+	for i := 0; i < len(ordered); {
+		cur := ordered[i]
+		consumed := []typex.Window{ordered[i]}
+		j := i + 1
+		// Sessions merge when they overlap or are adjacent.
+		for ; j < len(ordered) && ordered[j].Start <= cur.End; j++ {
+			cur.End = mtime.Max(cur.End, ordered[j].End)
+			consumed = append(consumed, ordered[j])
+		}
+		if len(consumed) == 1 {
+			unmerged = append(unmerged, cur)
+		} else {
+			merged = append(merged, &FullValue{Elm: cur, Elm2: consumed})
+		}
+		i = j
+	}
+	return unmerged, merged, nil
+}
@@ -16,14 +16,17 @@
 package exec
 
 import (
+	"bytes"
 	"context"
 	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/google/go-cmp/cmp"
 )
 
 // TestAssignWindow tests that each window fn assigns the
@@ -219,6 +222,89 @@ func TestMapWindows(t *testing.T) {
 	}
 }
 
+func TestMergeWindows(t *testing.T) {
+	iw := func(start, end mtime.Time) window.IntervalWindow {
+		return window.IntervalWindow{Start: start, End: end}
+	}
+	tests := []struct {
+		name         string
+		in           []typex.Window
+		wantUnmerged []typex.Window
+		wantMerged   []*FullValue
+	}{
+		{
+			name:         "disjoint",
+			in:           []typex.Window{iw(20, 30), iw(0, 10)},
+			wantUnmerged: []typex.Window{iw(0, 10), iw(20, 30)},
+			wantMerged:   []*FullValue{},
+		}, {
+			name:         "overlapping",
+			in:           []typex.Window{iw(5, 15), iw(0, 10), iw(40, 50)},
+			wantUnmerged: []typex.Window{iw(40, 50)},
+			wantMerged:   []*FullValue{{Elm: iw(0, 15), Elm2: []typex.Window{iw(0, 10), iw(5, 15)}}},
+		}, {
+			name:         "adjacent",
+			in:           []typex.Window{iw(0, 10), iw(10, 20)},
+			wantUnmerged: []typex.Window{},
+			wantMerged:   []*FullValue{{Elm: iw(0, 20), Elm2: []typex.Window{iw(0, 10), iw(10, 20)}}},
+		}, {
+			name:         "contained",
+			in:           []typex.Window{iw(2, 4), iw(0, 10)},
+			wantUnmerged: []typex.Window{},
+			wantMerged:   []*FullValue{{Elm: iw(0, 10), Elm2: []typex.Window{iw(0, 10), iw(2, 4)}}},
+		},
+	}
+	inC := coder.NewKV([]*coder.Coder{coder.NewBytes(), coder.NewI(coder.NewIntervalWindowCoder())})
+	mergedC := coder.NewKV([]*coder.Coder{coder.NewIntervalWindowCoder(), coder.NewI(coder.NewIntervalWindowCoder())})
+	outC := coder.NewKV([]*coder.Coder{coder.NewBytes(), coder.NewKV([]*coder.Coder{coder.NewI(coder.NewIntervalWindowCoder()), coder.NewI(mergedC)})})
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Round trip the input through its coder, as the windows would be received from a runner.
+			var buf bytes.Buffer
+			if err := MakeElementEncoder(inC).Encode(&FullValue{Elm: []byte("nonce"), Elm2: test.in}, &buf); err != nil {
+				t.Fatalf("failed to encode input: %v", err)
+			}
+			in, err := MakeElementDecoder(inC).Decode(&buf)
+			if err != nil {
+				t.Fatalf("failed to decode input: %v", err)
+			}
+
+			out := &CaptureNode{UID: 1}
+			unit := &MergeWindows{UID: 2, Fn: window.NewSessions(10 * time.Millisecond), Out: out}
+			a := &FixedRoot{UID: 3, Elements: []MainInput{{Key: *in}}, Out: unit}
+
+			p, err := NewPlan(test.name, []Unit{a, unit, out})
+			if err != nil {
+				t.Fatalf("failed to construct plan: %s", err)
+			}
+			ctx := context.Background()
+			if err := p.Execute(ctx, "1", DataContext{}); err != nil {
+				t.Fatalf("execute failed: %s", err)
+			}
+			if err := p.Down(ctx); err != nil {
+				t.Fatalf("down failed: %s", err)
+			}
+			if got, want := len(out.Elements), 1; got != want {
+				t.Fatalf("merge_windows returned %v elements, want %v", got, want)
+			}
+			got := out.Elements[0]
+			want := &FullValue{Elm: test.wantUnmerged, Elm2: test.wantMerged}
+			if d := cmp.Diff(want, got.Elm2); d != "" {
+				t.Errorf("merge_windows diff (-want, +got):\n%v", d)
+			}
+			if err := MakeElementEncoder(outC).Encode(&got, &buf); err != nil {
+				t.Errorf("failed to encode output: %v", err)
+			}
+		})
+	}
+}
+
+func TestMergeWindows_NonMerging(t *testing.T) {
+	if _, _, err := mergeWindows(window.NewFixedWindows(time.Second), nil); err == nil {
+		t.Error("mergeWindows(FixedWindows) succeeded, want error")
+	}
+}
+
 func makeNoncedWindowValues(in []typex.Window, expect []typex.Window) ([]MainInput, []FullValue) {
 	if len(in) != len(expect) {
 		panic("provided window slices must be the same length")
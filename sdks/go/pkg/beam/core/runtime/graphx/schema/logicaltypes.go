@@ -17,11 +17,17 @@ package schema
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"sync"
+	"time"
 
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 var (
@@ -162,6 +168,57 @@ func ToLogicalType(identifier string, goType, storageType reflect.Type) LogicalT
 	return LogicalType{identifier: identifier, goT: goType, storageT: storageType}
 }
 
+func logicalTypeURN(e pipepb.LogicalTypes_Enum) string {
+	v := e.Descriptor().Values().ByNumber(protoreflect.EnumNumber(e))
+	return proto.GetExtension(v.Options(), pipepb.E_BeamUrn).(string)
+}
+
+// Standard logical types shared with other SDKs.
+var (
+	millisInstantURN = logicalTypeURN(pipepb.LogicalTypes_MILLIS_INSTANT)
+	microsInstantURN = logicalTypeURN(pipepb.LogicalTypes_MICROS_INSTANT)
+	decimalURN       = logicalTypeURN(pipepb.LogicalTypes_DECIMAL)
+	fixedBytesURN    = logicalTypeURN(pipepb.LogicalTypes_FIXED_BYTES)
+	varBytesURN      = logicalTypeURN(pipepb.LogicalTypes_VAR_BYTES)
+	fixedCharURN     = logicalTypeURN(pipepb.LogicalTypes_FIXED_CHAR)
+	varCharURN       = logicalTypeURN(pipepb.LogicalTypes_VAR_CHAR)
+	uuidURN          = "beam:logical_type:uuid:v1"
+
+	timeType          = reflect.TypeOf((*time.Time)(nil)).Elem()
+	microsInstantType = reflect.TypeOf((*coder.MicrosInstant)(nil)).Elem()
+	ratType           = reflect.TypeOf((*big.Rat)(nil)).Elem()
+	uuidType          = reflect.TypeOf((*uuid.UUID)(nil)).Elem()
+	fixedBytesType    = reflect.TypeOf((*coder.FixedBytes)(nil)).Elem()
+	varBytesType      = reflect.TypeOf((*coder.VarBytes)(nil)).Elem()
+	fixedCharType     = reflect.TypeOf((*coder.FixedChar)(nil)).Elem()
+
+	microsInstantStorageType = reflect.TypeOf((*struct {
+		Seconds int64 `beam:"seconds"`
+		Micros  int64 `beam:"micros"`
+	})(nil)).Elem()
+	uuidStorageType = reflect.TypeOf((*struct {
+		MostSignificantBits  int64
+		LeastSignificantBits int64
+	})(nil)).Elem()
+)
+
+// passthroughLogicalTypes are the standard logical types that are encoded
+// as their representation types. Their arguments, such as lengths, aren't
+// enforced in Go, so they're converted to fields of their representation
+// types, rather than being registered to Go types.
+var passthroughLogicalTypes = map[string]bool{
+	varCharURN: true,
+}
+
+// lengthLogicalTypes are the registered logical types that take a length
+// argument, which is set from the length option of a field's beam tag.
+// Fields of fixed length types must have the option.
+var lengthLogicalTypes = map[string]bool{
+	fixedBytesURN: true,
+	varBytesURN:   true,
+	fixedCharURN:  true,
+}
+
 func preRegLogicalTypes(r *Registry) {
 	r.RegisterLogicalType(ToLogicalType("int", reflectx.Int, reflectx.Int64))
 	r.RegisterLogicalType(ToLogicalType("int8", reflectx.Int8, reflectx.Int64))
@@ -169,6 +226,15 @@ func preRegLogicalTypes(r *Registry) {
 	r.RegisterLogicalType(ToLogicalType("uint32", reflectx.Uint32, reflectx.Int32))
 	r.RegisterLogicalType(ToLogicalType("uint64", reflectx.Uint64, reflectx.Int64))
 	r.RegisterLogicalType(ToLogicalType("uint", reflectx.Uint, reflectx.Int64))
+
+	// The row encodings of these Go types are registered by the coder package.
+	r.RegisterLogicalType(ToLogicalType(millisInstantURN, timeType, reflectx.Int64))
+	r.RegisterLogicalType(ToLogicalType(microsInstantURN, microsInstantType, microsInstantStorageType))
+	r.RegisterLogicalType(ToLogicalType(decimalURN, ratType, reflectx.ByteSlice))
+	r.RegisterLogicalType(ToLogicalType(uuidURN, uuidType, uuidStorageType))
+	r.RegisterLogicalType(ToLogicalType(fixedBytesURN, fixedBytesType, reflectx.ByteSlice))
+	r.RegisterLogicalType(ToLogicalType(varBytesURN, varBytesType, reflectx.ByteSlice))
+	r.RegisterLogicalType(ToLogicalType(fixedCharURN, fixedCharType, reflectx.String))
 }
 
 func init() {
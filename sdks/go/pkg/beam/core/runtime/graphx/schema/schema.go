@@ -29,7 +29,9 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
@@ -552,13 +554,17 @@ func (r *Registry) structToSchema(t reflect.Type) (*pipepb.Schema, error) {
 
 func (r *Registry) structFieldToField(sf reflect.StructField) (*pipepb.Field, error) {
 	name := sf.Name
+	var opts options
 	if tag := sf.Tag.Get("beam"); tag != "" {
-		name, _ = parseTag(tag)
+		name, opts = parseTag(tag)
 	}
 	ftype, err := r.reflectTypeToFieldType(sf.Type)
 	if err != nil {
 		return nil, err
 	}
+	if err := setLengthArgument(ftype, opts); err != nil {
+		return nil, errors.Wrapf(err, "invalid field %v", sf.Name)
+	}
 	return &pipepb.Field{
 		Name: name,
 		Type: ftype,
@@ -576,7 +582,7 @@ func (r *Registry) reflectTypeToFieldType(ot reflect.Type) (*pipepb.FieldType, e
 				LogicalType: &pipepb.LogicalType{
 					Urn:            lID,
 					Representation: ftype,
-					// TODO(BEAM-9615): Handle type Arguments.
+					// Length arguments are set from the field's tag by structFieldToField.
 				},
 			},
 		}, nil
@@ -739,13 +745,79 @@ func (r *Registry) fieldToStructField(sf *pipepb.Field) (reflect.StructField, er
 		Name: strings.ToUpper(name[:1]) + name[1:], // Go field name must be capitalized for export and encoding.
 		Type: rt,
 	}
-	// Add a name tag if they don't match.
-	if name != rsf.Name {
+	// Add a name tag if they don't match, or to retain a length argument.
+	if length, ok := lengthArgument(sf.GetType()); ok {
+		rsf.Tag = reflect.StructTag(fmt.Sprintf("beam:\"%s,length=%d\"", name, length))
+	} else if name != rsf.Name {
 		rsf.Tag = reflect.StructTag(fmt.Sprintf("beam:\"%s\"", name))
 	}
 	return rsf, nil
 }
 
+// setLengthArgument sets the length arguments of the length logical types
+// in ft from the length option of the field's tag.
+func setLengthArgument(ft *pipepb.FieldType, opts options) error {
+	switch ti := ft.GetTypeInfo().(type) {
+	case *pipepb.FieldType_ArrayType:
+		return setLengthArgument(ti.ArrayType.GetElementType(), opts)
+	case *pipepb.FieldType_MapType:
+		if err := setLengthArgument(ti.MapType.GetKeyType(), opts); err != nil {
+			return err
+		}
+		return setLengthArgument(ti.MapType.GetValueType(), opts)
+	case *pipepb.FieldType_LogicalType:
+		lt := ti.LogicalType
+		if !lengthLogicalTypes[lt.GetUrn()] {
+			return nil
+		}
+		length := int64(math.MaxInt32)
+		if v, ok := opts.get("length"); ok {
+			var err error
+			if length, err = strconv.ParseInt(v, 10, 32); err != nil || length < 0 {
+				return errors.Errorf("invalid length option %q for logical type %v", v, lt.GetUrn())
+			}
+		} else if lt.GetUrn() != varBytesURN {
+			return errors.Errorf("logical type %v requires a length option, such as `beam:\"name,length=16\"`", lt.GetUrn())
+		}
+		lt.ArgumentType = &pipepb.FieldType{
+			TypeInfo: &pipepb.FieldType_AtomicType{AtomicType: pipepb.AtomicType_INT32},
+		}
+		lt.Argument = &pipepb.FieldValue{
+			FieldValue: &pipepb.FieldValue_AtomicValue{
+				AtomicValue: &pipepb.AtomicTypeValue{
+					Value: &pipepb.AtomicTypeValue_Int32{Int32: int32(length)},
+				},
+			},
+		}
+	}
+	return nil
+}
+
+// lengthArgument returns the length argument of a length logical type in ft,
+// if there is one that isn't the default.
+func lengthArgument(ft *pipepb.FieldType) (int32, bool) {
+	switch ti := ft.GetTypeInfo().(type) {
+	case *pipepb.FieldType_ArrayType:
+		return lengthArgument(ti.ArrayType.GetElementType())
+	case *pipepb.FieldType_MapType:
+		if length, ok := lengthArgument(ti.MapType.GetKeyType()); ok {
+			return length, true
+		}
+		return lengthArgument(ti.MapType.GetValueType())
+	case *pipepb.FieldType_LogicalType:
+		lt := ti.LogicalType
+		if !lengthLogicalTypes[lt.GetUrn()] || lt.GetArgument() == nil {
+			return 0, false
+		}
+		length := lt.GetArgument().GetAtomicValue().GetInt32()
+		if lt.GetUrn() == varBytesURN && length == math.MaxInt32 {
+			return 0, false
+		}
+		return length, true
+	}
+	return 0, false
+}
+
 var atomicTypeToReflectType = map[pipepb.AtomicType]reflect.Type{
 	pipepb.AtomicType_BYTE:    reflectx.Uint8,
 	pipepb.AtomicType_INT16:   reflectx.Int16,
@@ -794,11 +866,18 @@ func (r *Registry) fieldTypeToReflectType(sft *pipepb.FieldType, opts []*pipepb.
 	case *pipepb.FieldType_LogicalType:
 		lst := sft.GetLogicalType()
 		identifier := lst.GetUrn()
-		lt, ok := r.logicalTypes[identifier]
-		if !ok {
+		if lt, ok := r.logicalTypes[identifier]; ok {
+			t = lt.GoType()
+			break
+		}
+		if !passthroughLogicalTypes[identifier] {
 			return nil, errors.Errorf("unknown logical type: %v", identifier)
 		}
-		t = lt.GoType()
+		rt, err := r.fieldTypeToReflectType(lst.GetRepresentation(), nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to convert representation of logical type %v", identifier)
+		}
+		t = rt
 
 	default:
 		return nil, errors.Errorf("unknown fieldtype: %T", sft.GetTypeInfo())
@@ -820,4 +899,13 @@ func parseTag(tag string) (string, options) {
 
 type options string
 
-// TODO(BEAM-9615): implement looking up specific options from the tags.
+// get returns the value of the named option, such as "16" for the length
+// option of `beam:"name,length=16"`.
+func (o options) get(name string) (string, bool) {
+	for _, opt := range strings.Split(string(o), ",") {
+		if k, v, ok := strings.Cut(opt, "="); ok && k == name {
+			return v, true
+		}
+	}
+	return "", false
+}
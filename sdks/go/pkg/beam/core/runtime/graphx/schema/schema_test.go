@@ -19,12 +19,17 @@ package schema
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
 	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/testing/protocmp"
 )
@@ -829,3 +834,141 @@ func hasEmbeddedField(t reflect.Type) bool {
 	}
 	return false
 }
+
+func TestStandardLogicalTypes(t *testing.T) {
+	reg := NewRegistry()
+	preRegLogicalTypes(reg)
+
+	rt := reflect.TypeOf(struct {
+		Millis  time.Time
+		Micros  coder.MicrosInstant
+		Decimal *big.Rat
+		UUID    uuid.UUID
+		Fixed   coder.FixedBytes `beam:"fixed,length=16"`
+		Var     coder.VarBytes
+		Char    []coder.FixedChar `beam:"char,length=2"`
+	}{})
+	st, err := reg.FromType(rt)
+	if err != nil {
+		t.Fatalf("FromType(%v) = %v", rt, err)
+	}
+	wantURNs := []string{
+		"beam:logical_type:millis_instant:v1",
+		"beam:logical_type:micros_instant:v1",
+		"beam:logical_type:decimal:v1",
+		"beam:logical_type:uuid:v1",
+		"beam:logical_type:fixed_bytes:v1",
+		"beam:logical_type:var_bytes:v1",
+		"beam:logical_type:fixed_char:v1",
+	}
+	for i, f := range st.GetFields() {
+		ft := f.GetType()
+		if et := ft.GetArrayType().GetElementType(); et != nil {
+			ft = et
+		}
+		if got, want := ft.GetLogicalType().GetUrn(), wantURNs[i]; got != want {
+			t.Errorf("FromType(%v) field %v has logical type %q, want %q", rt, f.GetName(), got, want)
+		}
+	}
+	wantLengths := map[string]int32{"fixed": 16, "Var": math.MaxInt32, "char": 2}
+	for _, f := range st.GetFields()[4:] {
+		ft := f.GetType()
+		if et := ft.GetArrayType().GetElementType(); et != nil {
+			ft = et
+		}
+		lt := ft.GetLogicalType()
+		if got, want := lt.GetArgumentType().GetAtomicType(), pipepb.AtomicType_INT32; got != want {
+			t.Errorf("FromType(%v) field %v has argument type %v, want %v", rt, f.GetName(), got, want)
+		}
+		if got, want := lt.GetArgument().GetAtomicValue().GetInt32(), wantLengths[f.GetName()]; got != want {
+			t.Errorf("FromType(%v) field %v has length argument %v, want %v", rt, f.GetName(), got, want)
+		}
+	}
+	if got, want := st.GetFields()[0].GetType().GetLogicalType().GetRepresentation().GetAtomicType(), pipepb.AtomicType_INT64; got != want {
+		t.Errorf("millis_instant representation = %v, want %v", got, want)
+	}
+	if got, want := st.GetFields()[2].GetType().GetLogicalType().GetRepresentation().GetAtomicType(), pipepb.AtomicType_BYTES; got != want {
+		t.Errorf("decimal representation = %v, want %v", got, want)
+	}
+	if !st.GetFields()[2].GetType().GetNullable() {
+		t.Errorf("*big.Rat field isn't nullable")
+	}
+	got, err := reg.ToType(st)
+	if err != nil {
+		t.Fatalf("ToType(%v) = %v", prototext.Format(st), err)
+	}
+	if !rt.AssignableTo(got) {
+		t.Errorf("%v not assignable to %v", rt, got)
+	}
+}
+
+func TestLengthLogicalTypes_MissingLength(t *testing.T) {
+	reg := NewRegistry()
+	preRegLogicalTypes(reg)
+
+	rt := reflect.TypeOf(struct {
+		Fixed coder.FixedBytes
+	}{})
+	if _, err := reg.FromType(rt); err == nil {
+		t.Errorf("FromType(%v) = nil error, want error for a fixed_bytes field without a length", rt)
+	}
+}
+
+func TestLengthLogicalTypes(t *testing.T) {
+	reg := NewRegistry()
+	preRegLogicalTypes(reg)
+
+	passthrough := func(urn string, rep pipepb.AtomicType, nullable bool) *pipepb.FieldType {
+		return &pipepb.FieldType{
+			Nullable: nullable,
+			TypeInfo: &pipepb.FieldType_LogicalType{
+				LogicalType: &pipepb.LogicalType{
+					Urn: urn,
+					Representation: &pipepb.FieldType{
+						TypeInfo: &pipepb.FieldType_AtomicType{AtomicType: rep},
+					},
+					ArgumentType: &pipepb.FieldType{
+						TypeInfo: &pipepb.FieldType_AtomicType{AtomicType: pipepb.AtomicType_INT32},
+					},
+					Argument: &pipepb.FieldValue{
+						FieldValue: &pipepb.FieldValue_AtomicValue{
+							AtomicValue: &pipepb.AtomicTypeValue{Value: &pipepb.AtomicTypeValue_Int32{Int32: 5}},
+						},
+					},
+				},
+			},
+		}
+	}
+	st := &pipepb.Schema{
+		Fields: []*pipepb.Field{
+			{Name: "FixedChar", Type: passthrough("beam:logical_type:fixed_char:v1", pipepb.AtomicType_STRING, false)},
+			{Name: "VarChar", Type: passthrough("beam:logical_type:var_char:v1", pipepb.AtomicType_STRING, true)},
+			{Name: "FixedBytes", Type: passthrough("beam:logical_type:fixed_bytes:v1", pipepb.AtomicType_BYTES, false)},
+			{Name: "VarBytes", Type: passthrough("beam:logical_type:var_bytes:v1", pipepb.AtomicType_BYTES, false)},
+		},
+	}
+	got, err := reg.ToType(st)
+	if err != nil {
+		t.Fatalf("ToType(%v) = %v", prototext.Format(st), err)
+	}
+	want := reflect.TypeOf(struct {
+		FixedChar  coder.FixedChar `beam:"FixedChar,length=5"`
+		VarChar    *string
+		FixedBytes coder.FixedBytes `beam:"FixedBytes,length=5"`
+		VarBytes   coder.VarBytes   `beam:"VarBytes,length=5"`
+	}{})
+	if got != want {
+		t.Errorf("ToType(%v) = %v, want %v", prototext.Format(st), got, want)
+	}
+	// The length arguments are retained by the field tags.
+	if rst, err := reg.FromType(got); err != nil {
+		t.Errorf("FromType(%v) = %v", got, err)
+	} else if d := cmp.Diff(st.GetFields()[0].GetType(), rst.GetFields()[0].GetType(), protocmp.Transform()); d != "" {
+		t.Errorf("FromType(%v) fixed_char diff(-want,+got): %v", got, d)
+	}
+
+	st.Fields[0].Type.GetLogicalType().Urn = "beam:logical_type:unknown:v1"
+	if _, err := reg.ToType(st); err == nil {
+		t.Errorf("ToType with an unknown logical type = nil error, want error")
+	}
+}
@@ -49,6 +49,7 @@ const (
 	URNCombinePerKey = "beam:transform:combine_per_key:v1"
 	URNWindow        = "beam:transform:window_into:v1"
 	URNMapWindows    = "beam:transform:map_windows:v1"
+	URNMergeWindows  = "beam:transform:merge_windows:v1"
 	URNToString      = "beam:transform:to_string:v1"
 
 	URNIterableSideInput = "beam:side_input:iterable:v1"
@@ -95,8 +96,9 @@ const (
 	URNEnvDocker   = "beam:env:docker:v1"
 
 	// Userstate URNs.
-	URNBagUserState      = "beam:user_state:bag:v1"
-	URNMultiMapUserState = "beam:user_state:multimap:v1"
+	URNBagUserState         = "beam:user_state:bag:v1"
+	URNMultiMapUserState    = "beam:user_state:multimap:v1"
+	URNOrderedListUserState = "beam:user_state:ordered_list:v1"
 
 	// Base version URNs are to allow runners to make distinctions between different releases
 	// in a way that won't change based on actual releases, in particular for FnAPI behaviors.
@@ -112,6 +114,7 @@ func goCapabilities() []string {
 		URNMonitoringInfoShortID,
 		URNBaseVersionGo,
 		URNToString,
+		URNMergeWindows,
 		URNDataSampling,
 		URNSDKConsumingReceivedData,
 	}
@@ -601,6 +604,17 @@ func (m *marshaller) addMultiEdge(edge NamedEdge) ([]string, error) {
 							Urn: URNMultiMapUserState,
 						},
 					}
+				case state.TypeOrderedList:
+					stateSpecs[ps.StateKey()] = &pipepb.StateSpec{
+						Spec: &pipepb.StateSpec_OrderedListSpec{
+							OrderedListSpec: &pipepb.OrderedListStateSpec{
+								ElementCoderId: coderID,
+							},
+						},
+						Protocol: &pipepb.FunctionSpec{
+							Urn: URNOrderedListUserState,
+						},
+					}
 				default:
 					return nil, errors.Errorf("State type %v not recognized for state %v", ps.StateKey(), ps)
 				}
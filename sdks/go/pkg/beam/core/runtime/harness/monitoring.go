@@ -160,6 +160,38 @@ func monitoring(p *exec.Plan, store *metrics.Store, supportShortID bool) ([]*pip
 					})
 			}
 		},
+		StringSet: func(l metrics.Labels, v []string) {
+			payload, err := metricsx.StringSet(v)
+			if err != nil {
+				panic(err)
+			}
+			payloads[getShortID(l, metricsx.UrnUserStringSet)] = payload
+			if !supportShortID {
+				monitoringInfo = append(monitoringInfo,
+					&pipepb.MonitoringInfo{
+						Urn:     metricsx.UrnToString(metricsx.UrnUserStringSet),
+						Type:    metricsx.UrnToType(metricsx.UrnUserStringSet),
+						Labels:  l.Map(),
+						Payload: payload,
+					})
+			}
+		},
+		BoundedTrie: func(l metrics.Labels, v metrics.BoundedTrieValue) {
+			payload, err := metricsx.BoundedTrie(v)
+			if err != nil {
+				panic(err)
+			}
+			payloads[getShortID(l, metricsx.UrnUserBoundedTrie)] = payload
+			if !supportShortID {
+				monitoringInfo = append(monitoringInfo,
+					&pipepb.MonitoringInfo{
+						Urn:     metricsx.UrnToString(metricsx.UrnUserBoundedTrie),
+						Type:    metricsx.UrnToType(metricsx.UrnUserBoundedTrie),
+						Labels:  l.Map(),
+						Payload: payload,
+					})
+			}
+		},
 		MsecsInt64: func(l string, states *[4]metrics.ExecutionState) {
 			label := map[string]string{"PTRANSFORM": l}
 			for i, v := range states {
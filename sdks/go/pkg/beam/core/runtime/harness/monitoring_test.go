@@ -95,6 +95,18 @@ func TestGetShortID(t *testing.T) {
 			labels:       metrics.PCollectionLabels("myPCol"),
 			expectedUrn:  "beam:metric:element_count:v1",
 			expectedType: "beam:metrics:sum_int64:v1",
+		}, {
+			id:           "b",
+			urn:          metricsx.UrnUserStringSet,
+			labels:       metrics.UserLabels("myT", "harness", "metricNumber7"),
+			expectedUrn:  "beam:metric:user:set_string:v1",
+			expectedType: "beam:metrics:set_string:v1",
+		}, {
+			id:           "c",
+			urn:          metricsx.UrnUserBoundedTrie,
+			labels:       metrics.UserLabels("myT", "harness", "metricNumber7"),
+			expectedUrn:  "beam:metric:user:bounded_trie:v1",
+			expectedType: "beam:metrics:bounded_trie:v1",
 		},
 	}
 	cache := newShortIDCache()
@@ -18,8 +18,8 @@ package metricsx
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"log/slog"
+	"sort"
 	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
@@ -30,10 +30,12 @@ import (
 // FromMonitoringInfos extracts metrics from monitored states and
 // groups them into counters, distributions and gauges.
 func FromMonitoringInfos(p *pipepb.Pipeline, attempted []*pipepb.MonitoringInfo, committed []*pipepb.MonitoringInfo) *metrics.Results {
-	ac, ad, ag, am, ap := groupByType(p, attempted)
-	cc, cd, cg, cm, cp := groupByType(p, committed)
+	ac, ad, ag, am, ap, as, ab := groupByType(p, attempted)
+	cc, cd, cg, cm, cp, cs, cb := groupByType(p, committed)
 
-	return metrics.NewResults(metrics.MergeCounters(ac, cc), metrics.MergeDistributions(ad, cd), metrics.MergeGauges(ag, cg), metrics.MergeMsecs(am, cm), metrics.MergePCols(ap, cp))
+	return metrics.NewResults(metrics.MergeCounters(ac, cc), metrics.MergeDistributions(ad, cd), metrics.MergeGauges(ag, cg), metrics.MergeMsecs(am, cm), metrics.MergePCols(ap, cp)).
+		WithStringSets(metrics.MergeStringSets(as, cs)).
+		WithBoundedTries(metrics.MergeBoundedTries(ab, cb))
 }
 
 func groupByType(p *pipepb.Pipeline, minfos []*pipepb.MonitoringInfo) (
@@ -41,12 +43,16 @@ func groupByType(p *pipepb.Pipeline, minfos []*pipepb.MonitoringInfo) (
 	map[metrics.StepKey]metrics.DistributionValue,
 	map[metrics.StepKey]metrics.GaugeValue,
 	map[metrics.StepKey]metrics.MsecValue,
-	map[metrics.StepKey]metrics.PColValue) {
+	map[metrics.StepKey]metrics.PColValue,
+	map[metrics.StepKey][]string,
+	map[metrics.StepKey]metrics.BoundedTrieValue) {
 	counters := make(map[metrics.StepKey]int64)
 	distributions := make(map[metrics.StepKey]metrics.DistributionValue)
 	gauges := make(map[metrics.StepKey]metrics.GaugeValue)
 	msecs := make(map[metrics.StepKey]metrics.MsecValue)
 	pcols := make(map[metrics.StepKey]metrics.PColValue)
+	stringSets := make(map[metrics.StepKey][]string)
+	boundedTries := make(map[metrics.StepKey]metrics.BoundedTrieValue)
 
 	// extract pcol for a PTransform into a map from pipeline proto.
 	pcolToTransform := make(map[string]string)
@@ -93,6 +99,20 @@ func groupByType(p *pipepb.Pipeline, minfos []*pipepb.MonitoringInfo) (
 				continue
 			}
 			gauges[key] = value
+		case UrnToString(UrnUserStringSet):
+			value, err := extractStringSetValue(r)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			stringSets[key] = value
+		case UrnToString(UrnUserBoundedTrie):
+			value, err := DecodeBoundedTrie(minfo.GetPayload())
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			boundedTries[key] = value
 		case
 			UrnToString(UrnStartBundle),
 			UrnToString(UrnProcessBundle),
@@ -136,13 +156,14 @@ func groupByType(p *pipepb.Pipeline, minfos []*pipepb.MonitoringInfo) (
 		case UrnToString(UrnDataChannelReadIndex):
 			// Ignore runtime progress metrics.
 		default:
-			log.Println("unknown metric type", minfo.GetUrn())
+			// Runners may report their own metrics, such as prism's bundle concurrency.
+			slog.Debug("unknown metric type", "urn", minfo.GetUrn())
 		}
 	}
 	if len(errs) > 0 {
 		slog.Debug("errors during metrics processing", "count", len(errs), "errors", errs)
 	}
-	return counters, distributions, gauges, msecs, pcols
+	return counters, distributions, gauges, msecs, pcols, stringSets, boundedTries
 }
 
 func extractKey(mi *pipepb.MonitoringInfo, pcolToTransform map[string]string) (metrics.StepKey, error) {
@@ -190,6 +211,23 @@ func extractGaugeValue(reader *bytes.Reader) (metrics.GaugeValue, error) {
 	return metrics.GaugeValue{Timestamp: time.Unix(0, values[0]*int64(time.Millisecond)), Value: values[1]}, nil
 }
 
+func extractStringSetValue(reader *bytes.Reader) ([]string, error) {
+	n, err := coder.DecodeInt32(reader)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, n)
+	for i := int32(0); i < n; i++ {
+		v, err := coder.DecodeStringUTF8(reader)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values, nil
+}
+
 func newLabels(miLabels map[string]string) *metrics.Labels {
 	if miLabels["PTRANSFORM"] != "" {
 		labels := metrics.UserLabels(miLabels["PTRANSFORM"], miLabels["NAMESPACE"], miLabels["NAME"])
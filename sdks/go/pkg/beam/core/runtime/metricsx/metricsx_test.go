@@ -22,6 +22,7 @@ import (
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/metrics"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
 	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestFromMonitoringInfos_Counters(t *testing.T) {
@@ -345,3 +346,105 @@ func TestFromMonitoringInfos_Gauges(t *testing.T) {
 			got[0], want, d)
 	}
 }
+
+func TestFromMonitoringInfos_StringSets(t *testing.T) {
+	want := metrics.StringSetResult{
+		Attempted: []string{"a", "b"},
+		Key: metrics.StepKey{
+			Step:      "main.customDoFn",
+			Name:      "customStringSet",
+			Namespace: "customDoFn",
+		}}
+
+	payload, err := StringSet([]string{"b", "a"})
+	if err != nil {
+		t.Fatalf("Failed to encode StringSet: %v", err)
+	}
+
+	labels := map[string]string{
+		"PTRANSFORM": "main.customDoFn",
+		"NAMESPACE":  "customDoFn",
+		"NAME":       "customStringSet",
+	}
+
+	mInfo := &pipepb.MonitoringInfo{
+		Urn:     UrnToString(UrnUserStringSet),
+		Type:    UrnToType(UrnUserStringSet),
+		Labels:  labels,
+		Payload: payload,
+	}
+
+	attempted := []*pipepb.MonitoringInfo{mInfo}
+	committed := []*pipepb.MonitoringInfo{}
+	p := &pipepb.Pipeline{}
+
+	got := FromMonitoringInfos(p, attempted, committed).AllMetrics().StringSets()
+	size := len(got)
+	if size != 1 {
+		t.Fatalf("Invalid array's size: got: %v, want: %v", size, 1)
+	}
+	if d := cmp.Diff(want, got[0]); d != "" {
+		t.Fatalf("Invalid string set: got: %v, want: %v, diff(-want,+got):\n %v",
+			got[0], want, d)
+	}
+}
+
+func TestFromMonitoringInfos_BoundedTries(t *testing.T) {
+	trie := metrics.BoundedTrieValue{
+		Bound: 100,
+		Paths: []metrics.BoundedTriePath{
+			{Segments: []string{"gcs", "bucket", "a.txt"}},
+			{Segments: []string{"gcs", "other"}, Truncated: true},
+		},
+	}
+	payload, err := BoundedTrie(trie)
+	if err != nil {
+		t.Fatalf("Failed to encode BoundedTrie: %v", err)
+	}
+	// Other SDKs encode tries of a single path as singletons.
+	singleton, err := proto.Marshal(&pipepb.BoundedTrie{Bound: 100, Singleton: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Failed to encode singleton BoundedTrie: %v", err)
+	}
+	want := metrics.BoundedTrieResult{
+		Attempted: trie,
+		Committed: metrics.BoundedTrieValue{
+			Bound: 100,
+			Paths: []metrics.BoundedTriePath{{Segments: []string{"a", "b"}}},
+		},
+		Key: metrics.StepKey{
+			Step:      "main.customDoFn",
+			Name:      "customBoundedTrie",
+			Namespace: "customDoFn",
+		}}
+
+	labels := map[string]string{
+		"PTRANSFORM": "main.customDoFn",
+		"NAMESPACE":  "customDoFn",
+		"NAME":       "customBoundedTrie",
+	}
+
+	attempted := []*pipepb.MonitoringInfo{{
+		Urn:     UrnToString(UrnUserBoundedTrie),
+		Type:    UrnToType(UrnUserBoundedTrie),
+		Labels:  labels,
+		Payload: payload,
+	}}
+	committed := []*pipepb.MonitoringInfo{{
+		Urn:     UrnToString(UrnUserBoundedTrie),
+		Type:    UrnToType(UrnUserBoundedTrie),
+		Labels:  labels,
+		Payload: singleton,
+	}}
+	p := &pipepb.Pipeline{}
+
+	got := FromMonitoringInfos(p, attempted, committed).AllMetrics().BoundedTries()
+	size := len(got)
+	if size != 1 {
+		t.Fatalf("Invalid array's size: got: %v, want: %v", size, 1)
+	}
+	if d := cmp.Diff(want, got[0]); d != "" {
+		t.Fatalf("Invalid bounded trie: got: %v, want: %v, diff(-want,+got):\n %v",
+			got[0], want, d)
+	}
+}
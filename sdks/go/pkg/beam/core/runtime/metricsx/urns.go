@@ -18,10 +18,14 @@ package metricsx
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/metrics"
+	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
+	"google.golang.org/protobuf/proto"
 )
 
 // Urn is an enum type for representing urns of metrics and monitored states.
@@ -39,6 +43,8 @@ var sUrns = [...]string{
 	"beam:metric:user:top_n_double:v1",
 	"beam:metric:user:bottom_n_int64:v1",
 	"beam:metric:user:bottom_n_double:v1",
+	"beam:metric:user:set_string:v1",
+	"beam:metric:user:bounded_trie:v1",
 
 	"beam:metric:element_count:v1",
 	"beam:metric:sampled_byte_size:v1",
@@ -67,6 +73,8 @@ const (
 	UrnUserTopNFloat64
 	UrnUserBottomNInt64
 	UrnUserBottomNFloat64
+	UrnUserStringSet
+	UrnUserBoundedTrie
 
 	UrnElementCount
 	UrnSampledByteSize
@@ -112,6 +120,10 @@ func UrnToType(u Urn) string {
 		return "beam:metrics:bottom_n_int64:v1"
 	case UrnUserBottomNFloat64:
 		return "beam:metrics:bottom_n_double:v1"
+	case UrnUserStringSet:
+		return "beam:metrics:set_string:v1"
+	case UrnUserBoundedTrie:
+		return "beam:metrics:bounded_trie:v1"
 
 	case UrnProgressRemaining, UrnProgressCompleted:
 		return "beam:metrics:progress:v1"
@@ -170,6 +182,72 @@ func Int64Distribution(count, sum, min, max int64) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// StringSet returns an encoded payload of the set of strings.
+func StringSet(v []string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := coder.EncodeInt32(int32(len(v)), &buf); err != nil {
+		return nil, err
+	}
+	for _, s := range v {
+		if err := coder.EncodeStringUTF8(s, &buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// BoundedTrie returns an encoded payload of the bounded trie.
+func BoundedTrie(v metrics.BoundedTrieValue) ([]byte, error) {
+	root := &pipepb.BoundedTrieNode{}
+	for _, p := range v.Paths {
+		n := root
+		for _, s := range p.Segments {
+			if n.Children == nil {
+				n.Children = make(map[string]*pipepb.BoundedTrieNode)
+			}
+			c, ok := n.Children[s]
+			if !ok {
+				c = &pipepb.BoundedTrieNode{}
+				n.Children[s] = c
+			}
+			n = c
+		}
+		n.Truncated = p.Truncated
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(&pipepb.BoundedTrie{Bound: int32(v.Bound), Root: root})
+}
+
+// DecodeBoundedTrie decodes the payload of a bounded trie.
+func DecodeBoundedTrie(payload []byte) (metrics.BoundedTrieValue, error) {
+	var t pipepb.BoundedTrie
+	if err := proto.Unmarshal(payload, &t); err != nil {
+		return metrics.BoundedTrieValue{}, err
+	}
+	v := metrics.BoundedTrieValue{Bound: int(t.GetBound())}
+	switch {
+	case t.GetRoot() != nil:
+		flattenBoundedTrie(t.GetRoot(), nil, &v.Paths)
+	case len(t.GetSingleton()) > 0:
+		v.Paths = []metrics.BoundedTriePath{{Segments: t.GetSingleton()}}
+	}
+	return v, nil
+}
+
+func flattenBoundedTrie(n *pipepb.BoundedTrieNode, prefix []string, paths *[]metrics.BoundedTriePath) {
+	if n.GetTruncated() || len(n.GetChildren()) == 0 {
+		*paths = append(*paths, metrics.BoundedTriePath{Segments: append([]string(nil), prefix...), Truncated: n.GetTruncated()})
+		return
+	}
+	keys := make([]string, 0, len(n.GetChildren()))
+	for k := range n.GetChildren() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		flattenBoundedTrie(n.GetChildren()[k], append(prefix, k), paths)
+	}
+}
+
 // ExecutionMsecUrn returns the Urn for the bundle state
 func ExecutionMsecUrn(i int) Urn {
 	switch i {
@@ -18,7 +18,10 @@ package state
 
 import (
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
 )
@@ -46,6 +49,8 @@ const (
 	TypeMap TypeEnum = 3
 	// TypeSet represents a set state
 	TypeSet TypeEnum = 4
+	// TypeOrderedList represents an ordered list state
+	TypeOrderedList TypeEnum = 5
 )
 
 var (
@@ -84,6 +89,9 @@ type Provider interface {
 	WriteMapState(val Transaction) error
 	ClearMapStateKey(val Transaction) error
 	ClearMapState(val Transaction) error
+	ReadOrderedListState(userStateID string, r Range) ([]TimestampedValue[any], error)
+	WriteOrderedListState(val Transaction) error
+	ClearOrderedListState(val Transaction) error
 }
 
 // PipelineState is an interface representing different kinds of PipelineState (currently just state.Value).
@@ -684,3 +692,122 @@ func MakeSetState[K comparable](k string) Set[K] {
 		Key: k,
 	}
 }
+
+// TimestampedValue is a value of ordered list state along with the timestamp it is sorted by.
+type TimestampedValue[T any] struct {
+	Timestamp time.Time
+	Value     T
+}
+
+// Range is a range of timestamps of ordered list state, including Start and excluding End.
+// A zero Start or End leaves the range unbounded on that side, so the zero Range covers
+// all values.
+type Range struct {
+	Start, End time.Time
+}
+
+// Millis returns the bounds of the range in milliseconds since the epoch, which is the
+// resolution of the sort keys of ordered list state.
+func (r Range) Millis() (start, end int64) {
+	start, end = math.MinInt64, math.MaxInt64
+	if !r.Start.IsZero() {
+		start = r.Start.UnixMilli()
+	}
+	if !r.End.IsZero() {
+		end = r.End.UnixMilli()
+	}
+	return start, end
+}
+
+// contains reports whether the timestamp is in the range.
+func (r Range) contains(ts time.Time) bool {
+	start, end := r.Millis()
+	ms := ts.UnixMilli()
+	return ms >= start && ms < end
+}
+
+// OrderedList is used to read and write global pipeline state representing a list of values
+// sorted by timestamp. Timestamps are truncated to milliseconds, and values with equal
+// timestamps keep the order they were added in.
+// Key represents the key used to lookup this state.
+type OrderedList[T any] struct {
+	Key string
+}
+
+// Add is used to add a value with the given timestamp to the ordered list pipeline state.
+func (s *OrderedList[T]) Add(p Provider, ts time.Time, val T) error {
+	return p.WriteOrderedListState(Transaction{
+		Key:    s.Key,
+		Type:   TransactionTypeAppend,
+		MapKey: ts,
+		Val:    val,
+	})
+}
+
+// Read is used to read the values of this instance of global ordered list state with
+// timestamps in the given range, sorted by timestamp. Use the zero Range to read all values.
+// When no value is found, returns an empty list and false.
+//
+// Unlike the other state types, ordered list writes aren't buffered, since Add and
+// ClearRange are sent to the runner as they're made, so the values are always read
+// from the runner.
+func (s *OrderedList[T]) Read(p Provider, r Range) ([]TimestampedValue[T], bool, error) {
+	values, err := p.ReadOrderedListState(s.Key, r)
+	if err != nil {
+		return []TimestampedValue[T]{}, false, err
+	}
+	cur := []TimestampedValue[T]{}
+	for _, v := range values {
+		cur = append(cur, TimestampedValue[T]{Timestamp: v.Timestamp, Value: v.Value.(T)})
+	}
+	sort.SliceStable(cur, func(i, j int) bool {
+		return cur[i].Timestamp.UnixMilli() < cur[j].Timestamp.UnixMilli()
+	})
+	if len(cur) == 0 {
+		return cur, false, nil
+	}
+	return cur, true, nil
+}
+
+// ClearRange is used to remove the values with timestamps in the given range from this
+// instance of global ordered list state.
+func (s *OrderedList[T]) ClearRange(p Provider, r Range) error {
+	return p.ClearOrderedListState(Transaction{
+		Key:    s.Key,
+		Type:   TransactionTypeClear,
+		MapKey: r,
+	})
+}
+
+// Clear is used to clear this instance of global ordered list state.
+func (s *OrderedList[T]) Clear(p Provider) error {
+	return s.ClearRange(p, Range{})
+}
+
+// StateKey returns the key for this pipeline state entry.
+func (s OrderedList[T]) StateKey() string {
+	return s.Key
+}
+
+// KeyCoderType returns nil since OrderedList types aren't keyed.
+func (s OrderedList[T]) KeyCoderType() reflect.Type {
+	return nil
+}
+
+// CoderType returns the type of the ordered list state which should be used for a coder.
+func (s OrderedList[T]) CoderType() reflect.Type {
+	var t T
+	return reflect.TypeOf(t)
+}
+
+// StateType returns the type of the state (in this case always OrderedList).
+func (s OrderedList[T]) StateType() TypeEnum {
+	return TypeOrderedList
+}
+
+// MakeOrderedListState is a factory function to create an instance of OrderedListState with the given key.
+func MakeOrderedListState[T any](k string) OrderedList[T] {
+	return OrderedList[T]{
+		Key: k,
+	}
+}
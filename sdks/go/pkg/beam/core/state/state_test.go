@@ -17,7 +17,10 @@ package state
 
 import (
 	"errors"
+	"math"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
 )
@@ -30,6 +33,7 @@ type fakeProvider struct {
 	initialState      map[string]any
 	initialBagState   map[string][]any
 	initialMapState   map[string]map[string]any
+	orderedListState  map[string][]TimestampedValue[any]
 	transactions      map[string][]Transaction
 	err               map[string]error
 	createAccumForKey map[string]bool
@@ -177,6 +181,40 @@ func (s *fakeProvider) ClearMapState(val Transaction) error {
 	return nil
 }
 
+// Ordered list writes are applied immediately, as they're sent to the runner as they're made.
+func (s *fakeProvider) ReadOrderedListState(userStateID string, r Range) ([]TimestampedValue[any], error) {
+	if err, ok := s.err[userStateID]; ok {
+		return nil, err
+	}
+	var values []TimestampedValue[any]
+	for _, v := range s.orderedListState[userStateID] {
+		if r.contains(v.Timestamp) {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+func (s *fakeProvider) WriteOrderedListState(val Transaction) error {
+	if s.orderedListState == nil {
+		s.orderedListState = make(map[string][]TimestampedValue[any])
+	}
+	s.orderedListState[val.Key] = append(s.orderedListState[val.Key], TimestampedValue[any]{Timestamp: val.MapKey.(time.Time), Value: val.Val})
+	return nil
+}
+
+func (s *fakeProvider) ClearOrderedListState(val Transaction) error {
+	cleared := val.MapKey.(Range)
+	var kept []TimestampedValue[any]
+	for _, v := range s.orderedListState[val.Key] {
+		if !cleared.contains(v.Timestamp) {
+			kept = append(kept, v)
+		}
+	}
+	s.orderedListState[val.Key] = kept
+	return nil
+}
+
 func TestValueRead(t *testing.T) {
 	is := make(map[string]any)
 	ts := make(map[string][]Transaction)
@@ -1200,3 +1238,204 @@ func TestSetClear(t *testing.T) {
 		}
 	}
 }
+
+func ms(n int64) time.Time {
+	return time.UnixMilli(n)
+}
+
+func TestRangeMillis(t *testing.T) {
+	var tests = []struct {
+		r          Range
+		start, end int64
+	}{
+		{Range{}, math.MinInt64, math.MaxInt64},
+		{Range{Start: ms(-5)}, -5, math.MaxInt64},
+		{Range{End: ms(0)}, math.MinInt64, 0},
+		{Range{Start: ms(1), End: ms(1000)}, 1, 1000},
+	}
+
+	for _, tt := range tests {
+		start, end := tt.r.Millis()
+		if start != tt.start || end != tt.end {
+			t.Errorf("%v.Millis()=(%v, %v), want (%v, %v)", tt.r, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestOrderedListRead(t *testing.T) {
+	is := make(map[string][]TimestampedValue[any])
+	ts := make(map[string][]Transaction)
+	es := make(map[string]error)
+	is["no_transactions"] = []TimestampedValue[any]{{ms(1), 1}, {ms(2), 2}}
+	is["basic_add"] = []TimestampedValue[any]{{ms(1), 1}}
+	ts["basic_add"] = []Transaction{{Key: "basic_add", Type: TransactionTypeAppend, MapKey: ms(0), Val: 0}}
+	is["multi_add"] = []TimestampedValue[any]{{ms(2), 2}}
+	ts["multi_add"] = []Transaction{{Key: "multi_add", Type: TransactionTypeAppend, MapKey: ms(3), Val: 3}, {Key: "multi_add", Type: TransactionTypeAppend, MapKey: ms(1), Val: 1}, {Key: "multi_add", Type: TransactionTypeAppend, MapKey: ms(2), Val: 4}}
+	is["basic_clear"] = []TimestampedValue[any]{{ms(1), 1}}
+	ts["basic_clear"] = []Transaction{{Key: "basic_clear", Type: TransactionTypeClear, MapKey: Range{}}}
+	is["clear_range"] = []TimestampedValue[any]{{ms(1), 1}, {ms(2), 2}, {ms(3), 3}}
+	ts["clear_range"] = []Transaction{{Key: "clear_range", Type: TransactionTypeClear, MapKey: Range{Start: ms(2), End: ms(3)}}}
+	is["add_then_clear_then_add"] = []TimestampedValue[any]{{ms(1), 1}}
+	ts["add_then_clear_then_add"] = []Transaction{{Key: "add_then_clear_then_add", Type: TransactionTypeAppend, MapKey: ms(2), Val: 2}, {Key: "add_then_clear_then_add", Type: TransactionTypeClear, MapKey: Range{}}, {Key: "add_then_clear_then_add", Type: TransactionTypeAppend, MapKey: ms(3), Val: 3}}
+	is["read_range"] = []TimestampedValue[any]{{ms(1), 1}, {ms(2), 2}, {ms(3), 3}}
+	ts["read_range"] = []Transaction{{Key: "read_range", Type: TransactionTypeAppend, MapKey: ms(0), Val: 0}, {Key: "read_range", Type: TransactionTypeAppend, MapKey: ms(2), Val: 4}}
+	is["err"] = []TimestampedValue[any]{{ms(1), 1}}
+	es["err"] = errFake
+
+	f := fakeProvider{
+		orderedListState: is,
+		err:              es,
+	}
+	for _, trans := range ts {
+		for _, t := range trans {
+			if t.Type == TransactionTypeClear {
+				f.ClearOrderedListState(t)
+			} else {
+				f.WriteOrderedListState(t)
+			}
+		}
+	}
+
+	var tests = []struct {
+		vs  OrderedList[int]
+		r   Range
+		val []int
+		ok  bool
+		err error
+	}{
+		{MakeOrderedListState[int]("no_transactions"), Range{}, []int{1, 2}, true, nil},
+		{MakeOrderedListState[int]("basic_add"), Range{}, []int{0, 1}, true, nil},
+		{MakeOrderedListState[int]("multi_add"), Range{}, []int{1, 2, 4, 3}, true, nil},
+		{MakeOrderedListState[int]("basic_clear"), Range{}, []int{}, false, nil},
+		{MakeOrderedListState[int]("clear_range"), Range{}, []int{1, 3}, true, nil},
+		{MakeOrderedListState[int]("add_then_clear_then_add"), Range{}, []int{3}, true, nil},
+		{MakeOrderedListState[int]("read_range"), Range{Start: ms(1), End: ms(3)}, []int{1, 2, 4}, true, nil},
+		{MakeOrderedListState[int]("read_range"), Range{End: ms(2)}, []int{0, 1}, true, nil},
+		{MakeOrderedListState[int]("err"), Range{}, []int{}, false, errFake},
+	}
+
+	for _, tt := range tests {
+		vals, ok, err := tt.vs.Read(&f, tt.r)
+		if err != nil && tt.err == nil {
+			t.Errorf("OrderedList.Read() returned error %v for state key %v when it shouldn't have", err, tt.vs.Key)
+			continue
+		} else if err == nil && tt.err != nil {
+			t.Errorf("OrderedList.Read() returned no error for state key %v when it should have returned %v", tt.vs.Key, tt.err)
+			continue
+		} else if ok != tt.ok {
+			t.Errorf("OrderedList.Read() returned ok %v for state key %v, want %v", ok, tt.vs.Key, tt.ok)
+		}
+		got := []int{}
+		for i, v := range vals {
+			if i > 0 && v.Timestamp.Before(vals[i-1].Timestamp) {
+				t.Errorf("OrderedList.Read()=%v for state key %v is not sorted by timestamp", vals, tt.vs.Key)
+			}
+			got = append(got, v.Value)
+		}
+		if !reflect.DeepEqual(got, tt.val) {
+			t.Errorf("OrderedList.Read()=%v, want %v for state key %v in range %v", got, tt.val, tt.vs.Key, tt.r)
+		}
+	}
+}
+
+func TestOrderedListAdd(t *testing.T) {
+	var tests = []struct {
+		writes []int64
+		val    []int64
+		ok     bool
+	}{
+		{[]int64{}, []int64{}, false},
+		{[]int64{3}, []int64{3}, true},
+		{[]int64{5, -1, 3}, []int64{-1, 3, 5}, true},
+	}
+
+	for _, tt := range tests {
+		f := fakeProvider{
+			transactions: make(map[string][]Transaction),
+			err:          make(map[string]error),
+		}
+		vs := MakeOrderedListState[int64]("vs")
+		for _, val := range tt.writes {
+			vs.Add(&f, ms(val), val)
+		}
+		vals, ok, err := vs.Read(&f, Range{})
+		if err != nil {
+			t.Errorf("OrderedList.Read() returned error %v when it shouldn't have after writing: %v", err, tt.writes)
+			continue
+		} else if ok != tt.ok {
+			t.Errorf("OrderedList.Read() returned ok %v after writing: %v, want %v", ok, tt.writes, tt.ok)
+		}
+		got := []int64{}
+		for _, v := range vals {
+			if v.Timestamp.UnixMilli() != v.Value {
+				t.Errorf("OrderedList.Read() returned value %v with timestamp %v after writing: %v", v.Value, v.Timestamp, tt.writes)
+			}
+			got = append(got, v.Value)
+		}
+		if !reflect.DeepEqual(got, tt.val) {
+			t.Errorf("OrderedList.Read()=%v, want %v after writing: %v", got, tt.val, tt.writes)
+		}
+	}
+}
+
+func TestOrderedListClearRange(t *testing.T) {
+	var tests = []struct {
+		writes []int64
+		clears []Range
+		val    []int64
+	}{
+		{[]int64{1, 2, 3}, []Range{{}}, []int64{}},
+		{[]int64{1, 2, 3}, []Range{{Start: ms(2)}}, []int64{1}},
+		{[]int64{1, 2, 3}, []Range{{End: ms(2)}}, []int64{2, 3}},
+		{[]int64{1, 2, 3}, []Range{{Start: ms(2), End: ms(3)}}, []int64{1, 3}},
+		{[]int64{1, 2, 3}, []Range{{End: ms(2)}, {Start: ms(3)}}, []int64{2}},
+	}
+
+	for _, tt := range tests {
+		f := fakeProvider{
+			transactions: make(map[string][]Transaction),
+			err:          make(map[string]error),
+		}
+		vs := MakeOrderedListState[int64]("vs")
+		for _, val := range tt.writes {
+			vs.Add(&f, ms(val), val)
+		}
+		for _, r := range tt.clears {
+			if err := vs.ClearRange(&f, r); err != nil {
+				t.Errorf("OrderedList.ClearRange(%v) returned error %v", r, err)
+			}
+		}
+		vals, _, err := vs.Read(&f, Range{})
+		if err != nil {
+			t.Errorf("OrderedList.Read() returned error %v when it shouldn't have after writing: %v", err, tt.writes)
+			continue
+		}
+		got := []int64{}
+		for _, v := range vals {
+			got = append(got, v.Value)
+		}
+		if !reflect.DeepEqual(got, tt.val) {
+			t.Errorf("OrderedList.Read()=%v, want %v after writing %v and clearing %v", got, tt.val, tt.writes, tt.clears)
+		}
+	}
+}
+
+func TestOrderedListClear(t *testing.T) {
+	f := fakeProvider{
+		transactions: make(map[string][]Transaction),
+		err:          make(map[string]error),
+	}
+	vs := MakeOrderedListState[int]("vs")
+	vs.Add(&f, ms(1), 1)
+	if err := vs.Clear(&f); err != nil {
+		t.Errorf("OrderedList.Clear() returned error %v", err)
+	}
+	vs.Add(&f, ms(2), 2)
+	vals, ok, err := vs.Read(&f, Range{})
+	if err != nil {
+		t.Fatalf("OrderedList.Read() returned error %v", err)
+	}
+	if !ok || len(vals) != 1 || vals[0].Value != 2 {
+		t.Errorf("OrderedList.Read()=%v, %v, want only the value added after clearing", vals, ok)
+	}
+}
@@ -44,6 +44,7 @@ var (
 	CoGBKType              = reflect.TypeOf((*CoGBK)(nil)).Elem()
 	WindowedValueType      = reflect.TypeOf((*WindowedValue)(nil)).Elem()
 	BundleFinalizationType = reflect.TypeOf((*BundleFinalization)(nil)).Elem()
+	ErrorRecordType        = reflect.TypeOf((*ErrorRecord)(nil)).Elem()
 )
 
 // T, U, V, W, X, Y, Z are universal types. They play the role of generic
@@ -128,6 +129,24 @@ type Timers struct {
 	Pane                         PaneInfo
 }
 
+// ErrorRecord describes an element that failed processing in a ParDo with
+// an error handler, or a record an IO failed to write, instead of failing the
+// bundle.
+type ErrorRecord struct {
+	// Element is the failed element, encoded with the element coder of the
+	// main input. For grouped main inputs, only the key is encoded. For
+	// records an IO failed to write, it's the record encoded with the coder
+	// of its type.
+	Element []byte
+	// Error is the message of the returned error, or of the panic.
+	Error string
+	// Stack is the stack trace of the panic, if the DoFn panicked.
+	Stack string
+	// Transform is the unique name of the failed transform, or the name of
+	// the IO that failed to write the record.
+	Transform string
+}
+
 // KV, Nullable, CoGBK, WindowedValue represent composite generic types. They are not used
 // directly in user code signatures, but only in FullTypes.
 
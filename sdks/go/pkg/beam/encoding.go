@@ -17,7 +17,6 @@ package beam
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"reflect"
 	"time"
@@ -26,7 +25,6 @@ import (
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/graphx"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/graphx/schema"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
-	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
 )
 
 var (
@@ -48,9 +46,6 @@ func init() {
 	coder.RegisterSchemaProviders(encodedTypeType, encodedTypeEnc, encodedTypeDec)
 	coder.RegisterSchemaProviders(encodedFuncType, encodedFuncEnc, encodedFuncDec)
 	coder.RegisterSchemaProviders(encodedCoderType, encodedCoderEnc, encodedCoderDec)
-
-	schema.RegisterLogicalType(schema.ToLogicalType("time.Time", timeType, encodedStorageType))
-	coder.RegisterSchemaProviders(timeType, timeEnc, timeDec)
 }
 
 // EncodedType is a serialization wrapper around a type for convenience.
@@ -261,40 +256,3 @@ func encodedCoderDec(reflect.Type) (func(io.Reader) (any, error), error) {
 		},
 		nil
 }
-
-func timeEnc(reflect.Type) (func(any, io.Writer) error, error) {
-	return func(iface any, w io.Writer) error {
-		if err := coder.WriteSimpleRowHeader(1, w); err != nil {
-			return errors.Wrap(err, "encoding time.Time schema override")
-		}
-		t := iface.(time.Time)
-		// We use the text marshalling rather than the binary marshalling
-		// since it has more precision. Apparently some info isn't included
-		// in the binary marshal.
-		data, err := t.MarshalText()
-		if err != nil {
-			return fmt.Errorf("marshalling time: %v", err)
-		}
-		if err := coder.EncodeBytes(data, w); err != nil {
-			return err
-		}
-		return nil
-	}, nil
-}
-
-func timeDec(reflect.Type) (func(io.Reader) (any, error), error) {
-	return func(r io.Reader) (any, error) {
-		if err := coder.ReadSimpleRowHeader(1, r); err != nil {
-			return nil, errors.Wrap(err, "decoding time.Time schema override")
-		}
-		data, err := coder.DecodeBytes(r)
-		if err != nil {
-			return nil, errors.Wrap(err, "retrieving time data: %v")
-		}
-		t := time.Time{}
-		if err := t.UnmarshalText(data); err != nil {
-			return nil, errors.Wrap(err, "decoding time: %v")
-		}
-		return t, nil
-	}, nil
-}
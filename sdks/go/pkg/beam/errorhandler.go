@@ -0,0 +1,108 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var errorRecordType = reflect.TypeOf((*ErrorRecord)(nil)).Elem()
+
+func init() {
+	RegisterType(errorRecordType)
+}
+
+// ErrorHandler collects the elements that fail processing in ParDos, instead
+// of failing the bundle. A ParDo uses an ErrorHandler when it's passed the
+// WithErrorHandler option. Elements fail when ProcessElement returns an error
+// or panics, and are then output as ErrorRecords. Failures in other DoFn
+// methods, such as StartBundle or OnTimer, or in downstream transforms still
+// fail the bundle. Outputs emitted by a failed element before it failed
+// aren't retracted.
+//
+// IO writers may also add the records they fail to write to an ErrorHandler,
+// with Add.
+//
+// Example use:
+//
+//	eh := beam.NewErrorHandler()
+//	parsed := beam.ParDo(s, parseFn, lines, beam.WithErrorHandler(eh))
+//	written := beam.ParDo(s, writeFn, parsed, beam.WithErrorHandler(eh))
+//	beam.ParDo0(s, logErrorFn, eh.Errors(s))
+//
+// The ErrorRecords of ParDos with differently windowed main inputs can't be
+// flattened together, so such ParDos need separate ErrorHandlers.
+type ErrorHandler struct {
+	errors []PCollection
+}
+
+// NewErrorHandler returns a new ErrorHandler without any ParDos.
+func NewErrorHandler() *ErrorHandler {
+	return &ErrorHandler{}
+}
+
+// Add adds the given PCollection<ErrorRecord> to the errors of the ErrorHandler.
+func (h *ErrorHandler) Add(errors PCollection) {
+	if !errors.IsValid() {
+		panic("invalid PCollection of errors")
+	}
+	if t := errors.Type().Type(); t != errorRecordType {
+		panic(fmt.Sprintf("errors must be a PCollection<ErrorRecord>, got PCollection<%v>", t))
+	}
+	h.errors = append(h.errors, errors)
+}
+
+// Errors returns a PCollection<ErrorRecord> of the failed elements of all
+// ParDos using the ErrorHandler so far.
+func (h *ErrorHandler) Errors(s Scope) PCollection {
+	switch len(h.errors) {
+	case 0:
+		return CreateList(s, []ErrorRecord(nil))
+	case 1:
+		return h.errors[0]
+	default:
+		return Flatten(s, h.errors...)
+	}
+}
+
+type errorHandlerOption struct {
+	h *ErrorHandler
+}
+
+func (errorHandlerOption) private() {}
+
+// WithErrorHandler returns an Option for ParDo, which outputs the elements
+// that fail processing to the given ErrorHandler, instead of failing the
+// bundle. Splittable DoFns are not supported.
+func WithErrorHandler(h *ErrorHandler) Option {
+	return errorHandlerOption{h: h}
+}
+
+// parseErrorHandler returns the ErrorHandler of the options, if any, and the
+// other options.
+func parseErrorHandler(opts []Option) (*ErrorHandler, []Option) {
+	var h *ErrorHandler
+	var rest []Option
+	for _, opt := range opts {
+		if eh, ok := opt.(errorHandlerOption); ok {
+			h = eh.h
+			continue
+		}
+		rest = append(rest, opt)
+	}
+	return h, rest
+}
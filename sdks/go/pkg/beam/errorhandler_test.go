@@ -0,0 +1,40 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package beam
+
+import (
+	"testing"
+)
+
+func TestErrorHandler_Add(t *testing.T) {
+	_, s := NewPipelineWithRoot()
+	eh := NewErrorHandler()
+	eh.Add(CreateList(s, []ErrorRecord{{Error: "failed"}}))
+	eh.Add(CreateList(s, []ErrorRecord{{Error: "failed again"}}))
+	if got, want := len(eh.errors), 2; got != want {
+		t.Fatalf("len(errors) = %v, want %v", got, want)
+	}
+	if got := eh.Errors(s).Type().Type(); got != errorRecordType {
+		t.Errorf("Errors() type = %v, want %v", got, errorRecordType)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Add(PCollection<string>) didn't panic")
+		}
+	}()
+	eh.Add(Create(s, "not an error record"))
+}
@@ -207,6 +207,10 @@ type Window = typex.Window
 // See typex.BundleFinalization for more details.
 type BundleFinalization = typex.BundleFinalization
 
+// ErrorRecord describes an element that failed processing in a ParDo with
+// an ErrorHandler. See typex.ErrorRecord for more details.
+type ErrorRecord = typex.ErrorRecord
+
 // These are the reflect.Type instances of the universal types, which are used
 // when binding actual types to "generic" DoFns that use Universal Types.
 var (
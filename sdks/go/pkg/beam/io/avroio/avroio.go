@@ -14,14 +14,22 @@
 // limitations under the License.
 
 // Package avroio contains transforms for reading and writing avro files.
+//
+// Read and Write convert records through JSON, while ReadRecords and
+// WriteRecords convert them directly between binary Avro and Go structs,
+// with schemas derived by SchemaFromType.
 package avroio
 
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"reflect"
+	"strings"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
@@ -31,9 +39,52 @@ import (
 
 func init() {
 	register.DoFn3x1[context.Context, fileio.ReadableFile, func(beam.X), error]((*avroReadFn)(nil))
-	register.DoFn3x1[context.Context, int, func(*string) bool, error]((*writeAvroFn)(nil))
+	register.DoFn3x1[context.Context, fileio.ReadableFile, func(beam.X), error]((*readRecordsFn)(nil))
 	register.Emitter1[beam.X]()
-	register.Iter1[string]()
+
+	beam.RegisterType(reflect.TypeOf((*avroSink)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*recordSink)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*fixedNaming)(nil)).Elem())
+}
+
+// Codec is the compression codec of the blocks of an Avro file.
+type Codec string
+
+const (
+	// NullCodec writes uncompressed blocks.
+	NullCodec Codec = goavro.CompressionNullLabel
+	// DeflateCodec compresses blocks with deflate.
+	DeflateCodec Codec = goavro.CompressionDeflateLabel
+	// SnappyCodec compresses blocks with snappy. It's the default codec.
+	SnappyCodec Codec = goavro.CompressionSnappyLabel
+)
+
+type writeOption struct {
+	Codec Codec
+}
+
+// WriteOptionFn is a function that can be passed to Write and WriteRecords
+// to configure options for writing Avro files.
+type WriteOptionFn func(*writeOption)
+
+// WriteCodec sets the compression codec of the blocks of the written file.
+func WriteCodec(codec Codec) WriteOptionFn {
+	return func(o *writeOption) {
+		o.Codec = codec
+	}
+}
+
+func newWriteOption(opts []WriteOptionFn) *writeOption {
+	option := &writeOption{Codec: SnappyCodec}
+	for _, opt := range opts {
+		opt(option)
+	}
+	switch option.Codec {
+	case NullCodec, DeflateCodec, SnappyCodec:
+	default:
+		panic(fmt.Sprintf("avroio: unsupported codec %q", option.Codec))
+	}
+	return option
 }
 
 // Read reads a set of files and returns lines as a PCollection<elem>
@@ -113,65 +164,199 @@ func (f *avroReadFn) ProcessElement(ctx context.Context, file fileio.ReadableFil
 // Write expects a JSON string with a matching AVRO schema.
 // the process will fail if the schema does not match the JSON
 // provided
-func Write(s beam.Scope, filename, schema string, col beam.PCollection) {
+func Write(s beam.Scope, filename, schema string, col beam.PCollection, opts ...WriteOptionFn) {
 	s = s.Scope("avroio.Write")
 	filesystem.ValidateScheme(filename)
-	pre := beam.AddFixedKey(s, col)
-	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeAvroFn{Schema: schema, Filename: filename}, post)
+	option := newWriteOption(opts)
+
+	// All records are written by a single shard, which is named after the file.
+	dir, name := splitPath(filename)
+	sink := &avroSink{Schema: schema, Codec: option.Codec}
+	fileio.WriteFiles(s, dir, sink, col, fileio.WriteNaming(fixedNaming{Name: name}))
+}
+
+// splitPath splits a file path into its directory and file name. The directory of a
+// path without one is the current directory.
+func splitPath(filename string) (dir, name string) {
+	i := strings.LastIndexAny(filename, "/\\")
+	if i < 0 {
+		return ".", filename
+	}
+	return filename[:i+1], filename[i+1:]
+}
+
+// fixedNaming is a fileio.FileNaming that always returns the same file name.
+type fixedNaming struct {
+	Name string `json:"name"`
 }
 
-type writeAvroFn struct {
-	Schema   string `json:"schema"`
-	Filename string `json:"filename"`
+func (n fixedNaming) Filename(_ beam.Window, _ beam.PaneInfo, _, _ int) string {
+	return n.Name
 }
 
-func (w *writeAvroFn) ProcessElement(ctx context.Context, _ int, lines func(*string) bool) (err error) {
-	log.Infof(ctx, "writing AVRO to %s", w.Filename)
-	fs, err := filesystem.New(ctx, w.Filename)
+// blockSize is the number of records appended to an Avro file at a time,
+// which are compressed together.
+const blockSize = 1000
+
+// avroSink is a fileio.Sink that writes JSON string elements as the records
+// of an Avro file with the given schema.
+type avroSink struct {
+	Schema string `json:"schema"`
+	Codec  Codec  `json:"codec"`
+
+	codec *goavro.Codec
+	ocfw  *goavro.OCFWriter
+	block []any
+}
+
+func (a *avroSink) Open(_ context.Context, w io.Writer) error {
+	codec, err := goavro.NewCodec(a.Schema)
 	if err != nil {
-		return
+		return errors.Wrap(err, "creating avro codec")
 	}
-	defer fs.Close()
+	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		Codec:           codec,
+		CompressionName: string(a.Codec),
+		W:               w,
+	})
+	if err != nil {
+		return errors.Wrap(err, "creating avro writer")
+	}
+	a.codec, a.ocfw, a.block = codec, ocfw, make([]any, 0, blockSize)
+	return nil
+}
 
-	fd, err := fs.OpenWrite(ctx, w.Filename)
+func (a *avroSink) Write(ctx context.Context, elm any) error {
+	native, _, err := a.codec.NativeFromTextual([]byte(elm.(string)))
 	if err != nil {
-		return
+		return errors.Wrap(err, "converting JSON to avro")
 	}
+	return a.append(ctx, native)
+}
 
-	defer fd.Close()
+// append adds the native record to the block, and writes the block once it's full.
+func (a *avroSink) append(ctx context.Context, native any) error {
+	a.block = append(a.block, native)
+	if len(a.block) < blockSize {
+		return nil
+	}
+	return a.Flush(ctx)
+}
 
-	codec, err := goavro.NewCodec(w.Schema)
-	if err != nil {
-		log.Errorf(ctx, "error creating avro codec: %v", err)
-		return
+func (a *avroSink) Flush(_ context.Context) error {
+	if len(a.block) == 0 {
+		return nil
+	}
+	if err := a.ocfw.Append(a.block); err != nil {
+		return errors.Wrap(err, "writing avro records")
 	}
+	a.block = a.block[:0]
+	return nil
+}
 
-	ocfw, err := goavro.NewOCFWriter(goavro.OCFConfig{
-		Codec:           codec,
-		CompressionName: goavro.CompressionSnappyLabel,
-		Schema:          w.Schema,
-		W:               fd,
-	})
+// ReadRecords reads a set of Avro files and returns their records as a
+// PCollection<T> of the given struct type, decoded directly from the binary
+// Avro data. Record fields are matched to the fields of the type by the names
+// of SchemaFromType, and fields missing from either side are ignored.
+// Nullable fields can be read into pointer fields, bytes into []byte, and
+// timestamps into time.Time.
+func ReadRecords(s beam.Scope, glob string, t reflect.Type) beam.PCollection {
+	s = s.Scope("avroio.ReadRecords")
+	filesystem.ValidateScheme(glob)
+	if _, err := SchemaFromType(t); err != nil {
+		panic(fmt.Sprintf("avroio.ReadRecords: %v", err))
+	}
+	matches := fileio.MatchAll(s, beam.Create(s, glob), fileio.MatchEmptyAllow())
+	files := fileio.ReadMatches(s, matches, fileio.ReadUncompressed())
+	return beam.ParDo(s,
+		&readRecordsFn{Type: beam.EncodedType{T: t}},
+		files,
+		beam.TypeDefinition{Var: beam.XType, T: t},
+	)
+}
 
+type readRecordsFn struct {
+	Type  beam.EncodedType `json:"type"`
+	codec *avroCodec
+}
+
+func (f *readRecordsFn) Setup() error {
+	var err error
+	f.codec, err = newAvroCodec(f.Type.T)
+	return err
+}
+
+func (f *readRecordsFn) ProcessElement(ctx context.Context, file fileio.ReadableFile, emit func(beam.X)) error {
+	log.Infof(ctx, "Reading AVRO records from %v", file.Metadata.Path)
+
+	fd, err := file.Open(ctx)
 	if err != nil {
-		log.Errorf(ctx, "error creating avro writer: %v", err)
-		return
+		return err
 	}
+	defer fd.Close()
 
-	var j string
-	for lines(&j) {
-		native, _, err := codec.NativeFromTextual([]byte(j))
+	ar, err := goavro.NewOCFReader(fd)
+	if err != nil {
+		return errors.Wrapf(err, "reading avro file %v", file.Metadata.Path)
+	}
+	for ar.Scan() {
+		native, err := ar.Read()
 		if err != nil {
-			log.Errorf(ctx, "error reading native avro: %v", err)
-			return err
+			return errors.Wrapf(err, "reading avro record of %v", file.Metadata.Path)
 		}
-
-		if err := ocfw.Append([]any{native}); err != nil {
-			log.Errorf(ctx, "error writing avro: %v", err)
-			return err
+		val := reflect.New(f.Type.T).Elem()
+		if err := f.codec.fromNative(native, val); err != nil {
+			return errors.WithContextf(err, "decoding avro record of %v", file.Metadata.Path)
 		}
+		emit(val.Interface())
+	}
+	return ar.Err()
+}
+
+// WriteRecords writes a PCollection<T> of a struct type to an Avro file, with
+// the schema derived from the type by SchemaFromType. Elements are encoded
+// directly to binary Avro, preserving bytes, nullable fields and timestamps.
+func WriteRecords(s beam.Scope, filename string, col beam.PCollection, opts ...WriteOptionFn) {
+	s = s.Scope("avroio.WriteRecords")
+	filesystem.ValidateScheme(filename)
+	option := newWriteOption(opts)
+	t := col.Type().Type()
+	schema, err := SchemaFromType(t)
+	if err != nil {
+		panic(fmt.Sprintf("avroio.WriteRecords: %v", err))
+	}
+
+	// All records are written by a single shard, which is named after the file.
+	dir, name := splitPath(filename)
+	sink := &recordSink{
+		avroSink: avroSink{Schema: schema, Codec: option.Codec},
+		Type:     beam.EncodedType{T: t},
 	}
+	fileio.WriteFiles(s, dir, sink, col, fileio.WriteNaming(fixedNaming{Name: name}))
+}
+
+// recordSink is a fileio.Sink that writes struct elements as the records of
+// an Avro file with the schema of their type.
+type recordSink struct {
+	avroSink
+	Type beam.EncodedType `json:"type"`
+
+	rc *avroCodec
+}
+
+func (r *recordSink) Open(ctx context.Context, w io.Writer) error {
+	rc, err := newAvroCodec(r.Type.T)
+	if err != nil {
+		return err
+	}
+	r.rc = rc
+	return r.avroSink.Open(ctx, w)
+}
 
-	return
+func (r *recordSink) Write(ctx context.Context, elm any) error {
+	native, err := r.rc.toNative(reflect.ValueOf(elm))
+	if err != nil {
+		return errors.WithContext(err, "encoding avro record")
+	}
+	return r.append(ctx, native)
 }
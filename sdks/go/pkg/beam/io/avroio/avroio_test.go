@@ -20,8 +20,10 @@ import (
 	"encoding/json"
 	"errors"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
@@ -41,6 +43,8 @@ func init() {
 	beam.RegisterType(reflect.TypeOf((*NullableFloat64)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*NullableString)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*NullableTweet)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*TypedTweet)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*Event)(nil)).Elem())
 	register.Function2x0(toJSONString)
 }
 
@@ -189,3 +193,79 @@ func TestWrite(t *testing.T) {
 		t.Fatalf("User.User=%v, want %v", got, want)
 	}
 }
+
+// TypedTweet reads the records of tweetwithnulls.avro, whose fields are all
+// nullable.
+type TypedTweet struct {
+	Stamp *float64 `beam:"timestamp"`
+	Tweet *string  `beam:"tweet"`
+	User  *string  `beam:"username"`
+}
+
+func TestReadRecords(t *testing.T) {
+	avroFile := "../../../../data/tweetwithnulls.avro"
+	stamp1, stamp2 := 20.0, 21.0
+	tweet1, tweet2 := "Hello twitter", "Hello twitter again"
+	user1 := "user1"
+
+	p, s := beam.NewPipelineWithRoot()
+	tweets := ReadRecords(s, avroFile, reflect.TypeOf(TypedTweet{}))
+	passert.Equals(s, tweets,
+		TypedTweet{Stamp: &stamp1, Tweet: &tweet1, User: &user1},
+		TypedTweet{Stamp: &stamp2, Tweet: &tweet2},
+	)
+	ptest.RunAndValidate(t, p)
+}
+
+type Event struct {
+	ID      string
+	Payload []byte
+	At      time.Time
+	Parent  *string
+	Labels  map[string]string
+}
+
+func TestWriteRecords(t *testing.T) {
+	parent := "a"
+	events := []Event{
+		{ID: "a", Payload: []byte{0, 1}, At: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Labels: map[string]string{"k": "v"}},
+		{ID: "b", Payload: []byte{2}, At: time.Date(2024, 1, 1, 0, 0, 1, 5000000, time.UTC), Parent: &parent},
+	}
+	for _, codec := range []Codec{NullCodec, DeflateCodec, SnappyCodec} {
+		t.Run(string(codec), func(t *testing.T) {
+			avroFile := filepath.Join(t.TempDir(), "events.avro")
+
+			p, s, col := ptest.CreateList(events)
+			WriteRecords(s, avroFile, col, WriteCodec(codec))
+			ptest.RunAndValidate(t, p)
+
+			f, err := os.Open(avroFile)
+			if err != nil {
+				t.Fatalf("Failed to read avro file: %v", err)
+			}
+			defer f.Close()
+			ocf, err := goavro.NewOCFReader(f)
+			if err != nil {
+				t.Fatalf("Failed to make OCF Reader: %v", err)
+			}
+			if got, want := ocf.CompressionName(), string(codec); got != want {
+				t.Errorf("CompressionName() = %v, want %v", got, want)
+			}
+
+			p, s = beam.NewPipelineWithRoot()
+			read := ReadRecords(s, avroFile, reflect.TypeOf(Event{}))
+			passert.Equals(s, read, events[0], events[1])
+			ptest.RunAndValidate(t, p)
+		})
+	}
+}
+
+func TestWriteRecords_UnsupportedCodecPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic for an unsupported codec")
+		}
+	}()
+	_, s, col := ptest.CreateList([]Event{{ID: "a"}})
+	WriteRecords(s, "events.avro", col, WriteCodec("zstandard"))
+}
@@ -0,0 +1,668 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avroio
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/graphx/schema"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
+	"github.com/google/uuid"
+	"github.com/linkedin/goavro/v2"
+)
+
+var (
+	timeType = reflect.TypeOf((*time.Time)(nil)).Elem()
+	uuidType = reflect.TypeOf((*uuid.UUID)(nil)).Elem()
+)
+
+// The logical types of Beam schemas of Go types that have Avro equivalents.
+const (
+	millisInstantURN = "beam:logical_type:millis_instant:v1"
+	microsInstantURN = "beam:logical_type:micros_instant:v1"
+	uuidURN          = "beam:logical_type:uuid:v1"
+	fixedBytesURN    = "beam:logical_type:fixed_bytes:v1"
+	varBytesURN      = "beam:logical_type:var_bytes:v1"
+	fixedCharURN     = "beam:logical_type:fixed_char:v1"
+	varCharURN       = "beam:logical_type:var_char:v1"
+)
+
+// SchemaFromType returns the Avro schema of records of the given struct type,
+// converted from the Beam schema of the type, so records have the same fields
+// as the schema rows of the type:
+//
+//   - Fields become record fields, named as in the Beam schema.
+//   - Nullable fields become unions with "null".
+//   - Bytes become bytes, arrays and iterables arrays, and maps with string keys maps.
+//   - Nested rows become nested records, named after their Go type, or their
+//     field if the type has no name.
+//   - time.Time becomes a long with the timestamp-millis logical type, and
+//     coder.MicrosInstant one with the timestamp-micros logical type.
+//   - coder.FixedBytes becomes fixed, of the length of the field.
+//   - uuid.UUID becomes a string with the uuid logical type.
+//
+// Signed integers of up to 32 bits become int, and int, int64 and uint32 long.
+// Types without an equivalent Avro type are rejected, including uint and uint64,
+// whose values don't all fit in a long, and decimals, whose scale isn't fixed.
+// Go has no union types, so the only unions are nullable ones, and ReadRecords
+// fails on values of other union branches than the type of the field.
+func SchemaFromType(t reflect.Type) (string, error) {
+	_, s, err := newSchemaBuilder().recordSchema(t)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", errors.Wrapf(err, "encoding avro schema of %v", t)
+	}
+	return string(b), nil
+}
+
+// schemaBuilder converts Beam schemas to Avro schemas, defining each record
+// and fixed type once and referring to it by name afterwards.
+type schemaBuilder struct {
+	// names are the names of the records of Beam schema IDs.
+	names map[string]string
+	// fixedNames are the names of the fixed types of each size.
+	fixedNames map[int32]string
+	// branches are the union branch names of the values of nullable field types.
+	branches map[*pipepb.FieldType]string
+	used     map[string]bool
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		names:      map[string]string{},
+		fixedNames: map[int32]string{},
+		branches:   map[*pipepb.FieldType]string{},
+		used:       map[string]bool{},
+	}
+}
+
+// recordSchema returns the Beam schema of the struct type, and its Avro schema.
+func (b *schemaBuilder) recordSchema(t reflect.Type) (*pipepb.Schema, any, error) {
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil, nil, errors.Errorf("avro records require a struct type, got %v", t)
+	}
+	st, err := schema.FromType(t)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to convert %v to avro schema", t)
+	}
+	s, err := b.rowSchema(t.Name(), st)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "unable to convert %v to avro schema", t)
+	}
+	return st, s, nil
+}
+
+// rowSchema returns the Avro record schema of a Beam schema, which is named
+// after its Go type, or the given name if the type has none.
+func (b *schemaBuilder) rowSchema(name string, st *pipepb.Schema) (any, error) {
+	if name, ok := b.names[st.GetId()]; ok {
+		return name, nil
+	}
+	if rt, err := schema.ToType(st); err == nil && rt.Name() != "" {
+		name = rt.Name()
+	}
+	name = b.newName(name, "Record")
+	b.names[st.GetId()] = name
+	fields := []any{}
+	for _, f := range st.GetFields() {
+		s, err := b.schema(f.GetName(), f.GetType())
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to convert field %v", f.GetName())
+		}
+		fields = append(fields, map[string]any{"name": f.GetName(), "type": s})
+	}
+	return map[string]any{"type": "record", "name": name, "fields": fields}, nil
+}
+
+// schema returns the Avro schema of the Beam type of the named field.
+func (b *schemaBuilder) schema(name string, ft *pipepb.FieldType) (any, error) {
+	s, err := b.valueSchema(name, ft)
+	if err != nil {
+		return nil, err
+	}
+	if !ft.GetNullable() {
+		return s, nil
+	}
+	b.branches[ft] = unionName(s)
+	return []any{"null", s}, nil
+}
+
+// valueSchema returns the Avro schema of the non-null values of a Beam type.
+func (b *schemaBuilder) valueSchema(name string, ft *pipepb.FieldType) (any, error) {
+	switch ti := ft.GetTypeInfo().(type) {
+	case *pipepb.FieldType_AtomicType:
+		if name, ok := atomicNames[ti.AtomicType]; ok {
+			return name, nil
+		}
+		return nil, errors.Errorf("unable to convert unsupported type %v to avro schema", ti.AtomicType)
+	case *pipepb.FieldType_ArrayType:
+		s, err := b.schema(name, ti.ArrayType.GetElementType())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": s}, nil
+	case *pipepb.FieldType_IterableType:
+		s, err := b.schema(name, ti.IterableType.GetElementType())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": s}, nil
+	case *pipepb.FieldType_MapType:
+		if kt := ti.MapType.GetKeyType(); kt.GetAtomicType() != pipepb.AtomicType_STRING || kt.GetNullable() {
+			return nil, errors.Errorf("unable to convert map type to avro schema: keys must be strings")
+		}
+		s, err := b.schema(name, ti.MapType.GetValueType())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "map", "values": s}, nil
+	case *pipepb.FieldType_RowType:
+		return b.rowSchema(name, ti.RowType.GetSchema())
+	case *pipepb.FieldType_LogicalType:
+		return b.logicalSchema(ti.LogicalType)
+	}
+	return nil, errors.Errorf("unable to convert unsupported type %T to avro schema", ft.GetTypeInfo())
+}
+
+// logicalSchema returns the Avro schema of a Beam logical type.
+func (b *schemaBuilder) logicalSchema(lt *pipepb.LogicalType) (any, error) {
+	switch lt.GetUrn() {
+	case millisInstantURN:
+		return map[string]any{"type": "long", "logicalType": "timestamp-millis"}, nil
+	case microsInstantURN:
+		return map[string]any{"type": "long", "logicalType": "timestamp-micros"}, nil
+	case uuidURN:
+		return map[string]any{"type": "string", "logicalType": "uuid"}, nil
+	case fixedBytesURN:
+		size := lt.GetArgument().GetAtomicValue().GetInt32()
+		if name, ok := b.fixedNames[size]; ok {
+			return name, nil
+		}
+		name := b.newName(fmt.Sprintf("Fixed%d", size), "")
+		b.fixedNames[size] = name
+		return map[string]any{"type": "fixed", "name": name, "size": size}, nil
+	case varBytesURN:
+		return "bytes", nil
+	case fixedCharURN, varCharURN:
+		return "string", nil
+	}
+	if name, ok := goIntNames[lt.GetUrn()]; ok {
+		return name, nil
+	}
+	return nil, errors.Errorf("unable to convert unsupported logical type %v to avro schema", lt.GetUrn())
+}
+
+// newName returns a new unique Avro name for a record or fixed type, replacing
+// characters that aren't valid, and prefixing names that don't start with a
+// letter or an underscore with the given prefix.
+func (b *schemaBuilder) newName(name, prefix string) string {
+	base := strings.Map(func(r rune) rune {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+	if base == "" || '0' <= base[0] && base[0] <= '9' {
+		base = prefix + base
+	}
+	name = base
+	for i := 1; b.used[name]; i++ {
+		name = fmt.Sprintf("%v%d", base, i)
+	}
+	b.used[name] = true
+	return name
+}
+
+// unionName returns the name goavro gives the union branch of an Avro schema.
+func unionName(s any) string {
+	switch s := s.(type) {
+	case string:
+		return s
+	case map[string]any:
+		switch s["type"] {
+		case "record", "fixed":
+			return s["name"].(string)
+		}
+		switch lt := s["logicalType"]; lt {
+		case "timestamp-millis", "timestamp-micros":
+			return fmt.Sprintf("%v.%v", s["type"], lt)
+		}
+		return s["type"].(string)
+	}
+	panic(fmt.Sprintf("avroio: invalid avro schema %v", s))
+}
+
+// atomicNames are the names of the Avro primitive types of Beam atomic types.
+var atomicNames = map[pipepb.AtomicType]string{
+	pipepb.AtomicType_BYTE:    "int",
+	pipepb.AtomicType_INT16:   "int",
+	pipepb.AtomicType_INT32:   "int",
+	pipepb.AtomicType_INT64:   "long",
+	pipepb.AtomicType_FLOAT:   "float",
+	pipepb.AtomicType_DOUBLE:  "double",
+	pipepb.AtomicType_STRING:  "string",
+	pipepb.AtomicType_BOOLEAN: "boolean",
+	pipepb.AtomicType_BYTES:   "bytes",
+}
+
+// goIntNames are the names of the Avro primitive types of the logical types of
+// Go integer types, which hold all their values. There are none for uint and
+// uint64.
+var goIntNames = map[string]string{
+	"int":    "long",
+	"int8":   "int",
+	"uint16": "int",
+	"uint32": "long",
+}
+
+// avroCodec converts between Go values of a type and the native
+// representation of goavro, following the Beam schema of the type.
+type avroCodec struct {
+	schema *pipepb.Schema
+	// branches are the union branch names of the values of nullable field types.
+	branches map[*pipepb.FieldType]string
+	// fields are the indices of the Go fields of each Beam schema field, by name.
+	fields map[reflect.Type]map[string][]int
+}
+
+func newAvroCodec(t reflect.Type) (*avroCodec, error) {
+	b := newSchemaBuilder()
+	st, _, err := b.recordSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	return &avroCodec{schema: st, branches: b.branches, fields: map[reflect.Type]map[string][]int{}}, nil
+}
+
+// field returns the Go field of the struct value with the given schema name.
+func (c *avroCodec) field(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	fields, ok := c.fields[t]
+	if !ok {
+		fields = map[string][]int{}
+		for i := 0; i < t.NumField(); i++ {
+			if sf := t.Field(i); sf.IsExported() {
+				fields[fieldName(sf)] = sf.Index
+			}
+		}
+		c.fields[t] = fields
+	}
+	index, ok := fields[name]
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return v.FieldByIndex(index), true
+}
+
+// fieldName returns the Beam schema name of a struct field.
+func fieldName(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("beam"); tag != "" {
+		name, _, _ := strings.Cut(tag, ",")
+		return name
+	}
+	return sf.Name
+}
+
+// toNative converts a record of the struct type to its goavro native representation.
+func (c *avroCodec) toNative(v reflect.Value) (any, error) {
+	return c.rowToNative(c.schema, v)
+}
+
+func (c *avroCodec) rowToNative(st *pipepb.Schema, v reflect.Value) (any, error) {
+	record := make(map[string]any, len(st.GetFields()))
+	for _, f := range st.GetFields() {
+		fv, ok := c.field(v, f.GetName())
+		if !ok {
+			return nil, errors.Errorf("no exported field of %v for schema field %v", v.Type(), f.GetName())
+		}
+		native, err := c.fieldToNative(f.GetType(), fv)
+		if err != nil {
+			return nil, errors.WithContextf(err, "field %v of %v", f.GetName(), v.Type())
+		}
+		record[f.GetName()] = native
+	}
+	return record, nil
+}
+
+func (c *avroCodec) fieldToNative(ft *pipepb.FieldType, v reflect.Value) (any, error) {
+	if !ft.GetNullable() {
+		return c.valueToNative(ft, v)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	native, err := c.valueToNative(ft, v)
+	if err != nil {
+		return nil, err
+	}
+	return goavro.Union(c.branches[ft], native), nil
+}
+
+func (c *avroCodec) valueToNative(ft *pipepb.FieldType, v reflect.Value) (any, error) {
+	switch ti := ft.GetTypeInfo().(type) {
+	case *pipepb.FieldType_AtomicType:
+		switch ti.AtomicType {
+		case pipepb.AtomicType_BYTE:
+			return int32(v.Uint()), nil
+		case pipepb.AtomicType_INT16, pipepb.AtomicType_INT32:
+			return int32(v.Int()), nil
+		case pipepb.AtomicType_INT64:
+			return v.Int(), nil
+		case pipepb.AtomicType_FLOAT:
+			return float32(v.Float()), nil
+		case pipepb.AtomicType_DOUBLE:
+			return v.Float(), nil
+		case pipepb.AtomicType_STRING:
+			return v.String(), nil
+		case pipepb.AtomicType_BOOLEAN:
+			return v.Bool(), nil
+		case pipepb.AtomicType_BYTES:
+			return v.Bytes(), nil
+		}
+	case *pipepb.FieldType_ArrayType:
+		return c.itemsToNative(ti.ArrayType.GetElementType(), v)
+	case *pipepb.FieldType_IterableType:
+		return c.itemsToNative(ti.IterableType.GetElementType(), v)
+	case *pipepb.FieldType_MapType:
+		values := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			native, err := c.fieldToNative(ti.MapType.GetValueType(), iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			values[iter.Key().String()] = native
+		}
+		return values, nil
+	case *pipepb.FieldType_RowType:
+		return c.rowToNative(ti.RowType.GetSchema(), v)
+	case *pipepb.FieldType_LogicalType:
+		switch ti.LogicalType.GetUrn() {
+		case millisInstantURN, microsInstantURN:
+			return v.Convert(timeType).Interface(), nil
+		case uuidURN:
+			return v.Convert(uuidType).Interface().(uuid.UUID).String(), nil
+		case fixedBytesURN, varBytesURN:
+			return v.Bytes(), nil
+		case fixedCharURN, varCharURN:
+			return v.String(), nil
+		case "int":
+			return v.Int(), nil
+		case "int8":
+			return int32(v.Int()), nil
+		case "uint16":
+			return int32(v.Uint()), nil
+		case "uint32":
+			return int64(v.Uint()), nil
+		}
+	}
+	return nil, errors.Errorf("unable to convert %v to avro", v.Type())
+}
+
+func (c *avroCodec) itemsToNative(et *pipepb.FieldType, v reflect.Value) (any, error) {
+	items := make([]any, v.Len())
+	for i := range items {
+		native, err := c.fieldToNative(et, v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		items[i] = native
+	}
+	return items, nil
+}
+
+// fromNative sets the struct value to the goavro native representation of an
+// Avro record. Record fields are matched by name, and fields missing from
+// either side are ignored. Empty bytes, arrays and maps are left nil, like
+// the zero values of Go types.
+func (c *avroCodec) fromNative(native any, v reflect.Value) error {
+	return c.rowFromNative(c.schema, native, v)
+}
+
+func (c *avroCodec) rowFromNative(st *pipepb.Schema, native any, v reflect.Value) error {
+	record, ok := native.(map[string]any)
+	if !ok {
+		return errors.Errorf("cannot convert avro %T to %v", native, v.Type())
+	}
+	for _, f := range st.GetFields() {
+		fv, ok := record[f.GetName()]
+		if !ok {
+			continue
+		}
+		gv, ok := c.field(v, f.GetName())
+		if !ok {
+			continue
+		}
+		if err := c.fieldFromNative(f.GetType(), fv, gv); err != nil {
+			return errors.WithContextf(err, "field %v of %v", f.GetName(), v.Type())
+		}
+	}
+	return nil
+}
+
+func (c *avroCodec) fieldFromNative(ft *pipepb.FieldType, native any, v reflect.Value) error {
+	if native == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if u, ok := unionValue(ft, native); ok {
+		native = u
+	}
+	// Nullable values are held by pointers, which are allocated as needed.
+	for v.Kind() == reflect.Ptr && ft.GetNullable() {
+		p := reflect.New(v.Type().Elem())
+		v.Set(p)
+		v = p.Elem()
+	}
+	return c.valueFromNative(ft, native, v)
+}
+
+func (c *avroCodec) valueFromNative(ft *pipepb.FieldType, native any, v reflect.Value) error {
+	t := v.Type()
+	switch ti := ft.GetTypeInfo().(type) {
+	case *pipepb.FieldType_AtomicType:
+		if ti.AtomicType == pipepb.AtomicType_BYTES {
+			return setBytes(native, v)
+		}
+		return setPrimitive(native, v)
+	case *pipepb.FieldType_ArrayType:
+		return c.itemsFromNative(ti.ArrayType.GetElementType(), native, v)
+	case *pipepb.FieldType_IterableType:
+		return c.itemsFromNative(ti.IterableType.GetElementType(), native, v)
+	case *pipepb.FieldType_MapType:
+		values, ok := native.(map[string]any)
+		if !ok {
+			return errors.Errorf("cannot convert avro %T to %v", native, t)
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		m := reflect.MakeMapWithSize(t, len(values))
+		for k, value := range values {
+			e := reflect.New(t.Elem()).Elem()
+			if err := c.fieldFromNative(ti.MapType.GetValueType(), value, e); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), e)
+		}
+		v.Set(m)
+		return nil
+	case *pipepb.FieldType_RowType:
+		return c.rowFromNative(ti.RowType.GetSchema(), native, v)
+	case *pipepb.FieldType_LogicalType:
+		switch ti.LogicalType.GetUrn() {
+		case millisInstantURN, microsInstantURN:
+			ts, ok := native.(time.Time)
+			if !ok {
+				return errors.Errorf("cannot convert avro %T to %v", native, t)
+			}
+			v.Set(reflect.ValueOf(ts).Convert(t))
+			return nil
+		case uuidURN:
+			s, ok := native.(string)
+			if !ok {
+				return errors.Errorf("cannot convert avro %T to %v", native, t)
+			}
+			u, err := uuid.Parse(s)
+			if err != nil {
+				return errors.Wrapf(err, "cannot convert avro string %q to %v", s, t)
+			}
+			v.Set(reflect.ValueOf(u).Convert(t))
+			return nil
+		case fixedBytesURN, varBytesURN:
+			return setBytes(native, v)
+		}
+		return setPrimitive(native, v)
+	}
+	return errors.Errorf("cannot convert avro %T to %v", native, t)
+}
+
+func (c *avroCodec) itemsFromNative(et *pipepb.FieldType, native any, v reflect.Value) error {
+	items, ok := native.([]any)
+	if !ok {
+		return errors.Errorf("cannot convert avro %T to %v", native, v.Type())
+	}
+	if v.Kind() == reflect.Array {
+		for i := 0; i < len(items) && i < v.Len(); i++ {
+			if err := c.fieldFromNative(et, items[i], v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	s := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := c.fieldFromNative(et, item, s.Index(i)); err != nil {
+			return err
+		}
+	}
+	v.Set(s)
+	return nil
+}
+
+// setBytes sets the byte slice value to avro bytes or fixed.
+func setBytes(native any, v reflect.Value) error {
+	b, ok := native.([]byte)
+	if !ok {
+		return errors.Errorf("cannot convert avro %T to %v", native, v.Type())
+	}
+	if len(b) > 0 {
+		v.SetBytes(b)
+	}
+	return nil
+}
+
+// setPrimitive sets the value of a Go primitive kind to an avro primitive.
+func setPrimitive(native any, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		switch n := native.(type) {
+		case string:
+			v.SetString(n)
+			return nil
+		case []byte:
+			v.SetString(string(n))
+			return nil
+		}
+	case reflect.Bool:
+		if b, ok := native.(bool); ok {
+			v.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := native.(type) {
+		case int32:
+			v.SetInt(int64(n))
+			return nil
+		case int64:
+			v.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch n := native.(type) {
+		case int32:
+			v.SetUint(uint64(n))
+			return nil
+		case int64:
+			v.SetUint(uint64(n))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := native.(type) {
+		case float32:
+			v.SetFloat(float64(n))
+			return nil
+		case float64:
+			v.SetFloat(n)
+			return nil
+		case int32:
+			v.SetFloat(float64(n))
+			return nil
+		case int64:
+			v.SetFloat(float64(n))
+			return nil
+		}
+	}
+	return errors.Errorf("cannot convert avro %T to %v", native, v.Type())
+}
+
+// unionValue returns the value of a non-null union datum, which goavro
+// represents as a map from the name of its branch to the value, if the
+// datum isn't rather a map or record value of the Beam type.
+func unionValue(ft *pipepb.FieldType, native any) (any, bool) {
+	m, ok := native.(map[string]any)
+	if !ok || len(m) != 1 {
+		return nil, false
+	}
+	for name, value := range m {
+		if ft.GetNullable() {
+			return value, true
+		}
+		switch ti := ft.GetTypeInfo().(type) {
+		case *pipepb.FieldType_MapType:
+			return nil, false
+		case *pipepb.FieldType_RowType:
+			// A record with a single field of the same name as its union branch
+			// is ambiguous, and taken as the record.
+			if _, isRecord := value.(map[string]any); !isRecord {
+				return nil, false
+			}
+			for _, f := range ti.RowType.GetSchema().GetFields() {
+				if f.GetName() == name {
+					return nil, false
+				}
+			}
+			return value, true
+		default:
+			return value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,177 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package avroio
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/linkedin/goavro/v2"
+)
+
+type Point struct {
+	X, Y int32
+}
+
+type Shape struct {
+	Name     string `beam:"name"`
+	Data     []byte
+	Hash     coder.FixedBytes `beam:"Hash,length=4"`
+	Bits     [2]byte
+	Origin   Point
+	Center   *Point
+	Points   []Point
+	Tags     map[string]string
+	Created  time.Time
+	Updated  *time.Time
+	Expires  coder.MicrosInstant
+	ID       uuid.UUID
+	Weight   *float64
+	Count    int
+	Small    uint16
+	Visible  bool
+	internal string
+}
+
+func TestSchemaFromType(t *testing.T) {
+	got, err := SchemaFromType(reflect.TypeOf(Shape{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type": "record", "name": "Shape", "fields": [
+		{"name": "name", "type": "string"},
+		{"name": "Data", "type": "bytes"},
+		{"name": "Hash", "type": {"type": "fixed", "name": "Fixed4", "size": 4}},
+		{"name": "Bits", "type": {"type": "array", "items": "int"}},
+		{"name": "Origin", "type": {"type": "record", "name": "Point", "fields": [
+			{"name": "X", "type": "int"},
+			{"name": "Y", "type": "int"}
+		]}},
+		{"name": "Center", "type": ["null", "Point"]},
+		{"name": "Points", "type": {"type": "array", "items": "Point"}},
+		{"name": "Tags", "type": {"type": "map", "values": "string"}},
+		{"name": "Created", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "Updated", "type": ["null", {"type": "long", "logicalType": "timestamp-millis"}]},
+		{"name": "Expires", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+		{"name": "ID", "type": {"type": "string", "logicalType": "uuid"}},
+		{"name": "Weight", "type": ["null", "double"]},
+		{"name": "Count", "type": "long"},
+		{"name": "Small", "type": "int"},
+		{"name": "Visible", "type": "boolean"}
+	]}`
+	var gotJSON, wantJSON any
+	if err := json.Unmarshal([]byte(got), &gotJSON); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantJSON); err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(wantJSON, gotJSON); d != "" {
+		t.Errorf("SchemaFromType() mismatch (-want, +got):\n%v", d)
+	}
+	if _, err := goavro.NewCodec(got); err != nil {
+		t.Errorf("SchemaFromType() returned an invalid schema: %v", err)
+	}
+}
+
+func TestSchemaFromType_Unsupported(t *testing.T) {
+	tests := []any{
+		"not a struct",
+		struct{ M map[int]string }{},
+		struct{ F func() }{},
+		struct{ U uint }{},
+		struct{ U uint64 }{},
+		struct{ D big.Rat }{},
+	}
+	for _, test := range tests {
+		if _, err := SchemaFromType(reflect.TypeOf(test)); err == nil {
+			t.Errorf("SchemaFromType(%T) succeeded, want error", test)
+		}
+	}
+}
+
+func TestAvroCodec_RoundTrip(t *testing.T) {
+	weight := 2.5
+	updated := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	in := Shape{
+		Name:    "triangle",
+		Data:    []byte{0, 1, 2},
+		Hash:    coder.FixedBytes{1, 2, 3, 4},
+		Bits:    [2]byte{5, 6},
+		Origin:  Point{X: 1, Y: 2},
+		Center:  &Point{X: 3, Y: 4},
+		Points:  []Point{{X: 5}, {Y: 6}},
+		Tags:    map[string]string{"color": "red"},
+		Created: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Updated: &updated,
+		Expires: coder.MicrosInstant(time.Date(2024, 1, 3, 0, 0, 0, 7000, time.UTC)),
+		ID:      uuid.MustParse("3b241101-e2bb-4255-8caf-4136c566a962"),
+		Weight:  &weight,
+		Count:   3,
+		Small:   65535,
+		Visible: true,
+	}
+	schema, err := SchemaFromType(reflect.TypeOf(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ac, err := goavro.NewCodec(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newAvroCodec(reflect.TypeOf(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty := Shape{
+		Name:    "empty",
+		Hash:    coder.FixedBytes{0, 0, 0, 0},
+		Created: time.Unix(0, 0).UTC(),
+		Expires: coder.MicrosInstant(time.Unix(0, 0).UTC()),
+	}
+	for _, want := range []Shape{in, empty} {
+		native, err := c.toNative(reflect.ValueOf(want))
+		if err != nil {
+			t.Fatalf("converting %+v: %v", want, err)
+		}
+		b, err := ac.BinaryFromNative(nil, native)
+		if err != nil {
+			t.Fatalf("encoding %+v: %v", want, err)
+		}
+		decoded, _, err := ac.NativeFromBinary(b)
+		if err != nil {
+			t.Fatalf("decoding %+v: %v", want, err)
+		}
+		var got Shape
+		if err := c.fromNative(decoded, reflect.ValueOf(&got).Elem()); err != nil {
+			t.Fatal(err)
+		}
+		if d := cmp.Diff(want, got, cmp.AllowUnexported(Shape{}), microsInstantCmp); d != "" {
+			t.Errorf("round trip mismatch (-want, +got):\n%v", d)
+		}
+	}
+}
+
+var microsInstantCmp = cmp.Comparer(func(a, b coder.MicrosInstant) bool {
+	return time.Time(a).Equal(time.Time(b))
+})
@@ -18,6 +18,7 @@
 package bigqueryio
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
@@ -51,6 +52,7 @@ const bigQueryTag = "bigquery"
 func init() {
 	beam.RegisterType(reflect.TypeOf((*queryFn)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*writeFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*writeWithErrorsFn)(nil)).Elem())
 }
 
 // QualifiedTableName is a fully qualified name of a bigquery table.
@@ -229,6 +231,8 @@ func mustParseTable(table string) QualifiedTableName {
 type writeOptions struct {
 	// CreateDisposition specifies the circumstances under which destination table will be created
 	CreateDisposition bigquery.TableCreateDisposition
+	// errorHandler receives the rows that fail to be written, if set.
+	errorHandler *beam.ErrorHandler
 }
 
 // newWriteOptions creates a new instance of WriteOptions
@@ -248,6 +252,17 @@ func WithCreateDisposition(cd bigquery.TableCreateDisposition) WriteOption {
 	}
 }
 
+// WithErrorHandler outputs the rows that BigQuery rejects to the given
+// ErrorHandler, instead of failing the pipeline. The other rows of their
+// batches are still written. Failures to access the table or to send the
+// rows still fail the pipeline.
+func WithErrorHandler(eh *beam.ErrorHandler) WriteOption {
+	return func(wo *writeOptions) error {
+		wo.errorHandler = eh
+		return nil
+	}
+}
+
 // Write writes the elements of the given PCollection<T> to bigquery. T is required
 // to be the schema type.
 func Write(s beam.Scope, project, table string, col beam.PCollection, options ...func(*writeOptions) error) {
@@ -264,10 +279,16 @@ func Write(s beam.Scope, project, table string, col beam.PCollection, options ..
 		}
 	}
 
+	fn := writeFn{Project: project, Table: qn, Type: beam.EncodedType{T: t}, Options: writeOptions}
+
 	// TODO(BEAM-3860) 3/15/2018: use side input instead of GBK.
 	pre := beam.AddFixedKey(s, col)
 	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeFn{Project: project, Table: qn, Type: beam.EncodedType{T: t}, Options: writeOptions}, post)
+	if eh := writeOptions.errorHandler; eh != nil {
+		eh.Add(beam.ParDo(s, &writeWithErrorsFn{writeFn: fn}, post))
+		return
+	}
+	beam.ParDo0(s, &fn, post)
 }
 
 // Add in additional field (CreateDisposition), Bool
@@ -310,37 +331,44 @@ func getInsertSize(v any, schema bigquery.Schema) (int, error) {
 }
 
 func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool) error {
+	return f.write(ctx, iter, nil)
+}
+
+// write writes the rows in batches. If rejected is set, the rows BigQuery
+// rejects are passed to it, and the other rows of their batches are written.
+// Otherwise, rejected rows fail the write.
+func (f *writeFn) write(ctx context.Context, iter func(*beam.X) bool, rejected func(row any, err error) error) error {
 	client, err := bigquery.NewClient(ctx, f.Project)
 	if err != nil {
 		return err
 	}
 	defer client.Close()
 
-	// TODO(herohde) 7/14/2017: should we create datasets? For now, "no".
-
-	dataset := client.DatasetInProject(f.Table.Project, f.Table.Dataset)
-	if _, err := dataset.Metadata(ctx); err != nil {
+	table, schema, err := f.openTable(ctx, client)
+	if err != nil {
 		return err
 	}
 
-	schema := mustInferSchema(f.Type.T)
-	table := dataset.Table(f.Table.Table)
-	if _, err := table.Metadata(ctx); err != nil {
-		if !isNotFound(err) {
-			return err
-		}
-		if f.Options.CreateDisposition == bigquery.CreateNever {
-			return fmt.Errorf("table does not exist and create disposition is 'CreateNever': %v", err)
-		}
-		if err := table.Create(ctx, &bigquery.TableMetadata{Schema: schema}); err != nil {
-			return err
-		}
-	}
-
 	var data []reflect.Value
 	// This stores the running byte size estimate of a BQ request.
 	size := writeOverheadBytes
 
+	flush := func() error {
+		err := put(ctx, table, f.Type.T, data, rejected != nil)
+		if rowErrs, ok := err.(bigquery.PutMultiError); ok && rejected != nil {
+			for _, rowErr := range rowErrs {
+				if err := rejected(data[rowErr.RowIndex].Interface(), rowErr.Errors); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "bigquery write error [len=%d, size=%d]", len(data), size)
+		}
+		return nil
+	}
+
 	var val beam.X
 	for iter(&val) {
 		current, err := getInsertSize(val.(any), schema)
@@ -349,8 +377,8 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 		}
 		if len(data)+1 > writeRowLimit || size+current > writeSizeLimit {
 			// Write rows in batches to comply with BQ limits.
-			if err := put(ctx, table, f.Type.T, data); err != nil {
-				return errors.Wrapf(err, "bigquery write error [len=%d, size=%d]", len(data), size)
+			if err := flush(); err != nil {
+				return err
 			}
 			data = nil
 			size = writeOverheadBytes
@@ -361,20 +389,67 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 	if len(data) == 0 {
 		return nil
 	}
-	if err := put(ctx, table, f.Type.T, data); err != nil {
-		return errors.Wrapf(err, "bigquery write error [len=%d, size=%d]", len(data), size)
+	return flush()
+}
+
+// openTable returns the table to write to, creating it if needed, and its schema.
+func (f *writeFn) openTable(ctx context.Context, client *bigquery.Client) (*bigquery.Table, bigquery.Schema, error) {
+	// TODO(herohde) 7/14/2017: should we create datasets? For now, "no".
+
+	dataset := client.DatasetInProject(f.Table.Project, f.Table.Dataset)
+	if _, err := dataset.Metadata(ctx); err != nil {
+		return nil, nil, err
 	}
-	return nil
+
+	schema := mustInferSchema(f.Type.T)
+	table := dataset.Table(f.Table.Table)
+	if _, err := table.Metadata(ctx); err != nil {
+		if !isNotFound(err) {
+			return nil, nil, err
+		}
+		if f.Options.CreateDisposition == bigquery.CreateNever {
+			return nil, nil, fmt.Errorf("table does not exist and create disposition is 'CreateNever': %v", err)
+		}
+		if err := table.Create(ctx, &bigquery.TableMetadata{Schema: schema}); err != nil {
+			return nil, nil, err
+		}
+	}
+	return table, schema, nil
+}
+
+// writeWithErrorsFn writes rows like writeFn, but outputs the rows that
+// BigQuery rejects as ErrorRecords, instead of failing.
+type writeWithErrorsFn struct {
+	writeFn
+
+	enc beam.ElementEncoder
+}
+
+func (f *writeWithErrorsFn) Setup() {
+	f.enc = beam.NewElementEncoder(f.Type.T)
+}
+
+func (f *writeWithErrorsFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool, emit func(beam.ErrorRecord)) error {
+	return f.write(ctx, iter, func(row any, rowErr error) error {
+		var buf bytes.Buffer
+		if err := f.enc.Encode(row, &buf); err != nil {
+			return errors.Wrapf(err, "encoding rejected row")
+		}
+		emit(beam.ErrorRecord{Element: buf.Bytes(), Error: rowErr.Error(), Transform: "bigquery.Write"})
+		return nil
+	})
 }
 
-func put(ctx context.Context, table *bigquery.Table, t reflect.Type, data []reflect.Value) error {
+func put(ctx context.Context, table *bigquery.Table, t reflect.Type, data []reflect.Value, skipInvalidRows bool) error {
 	// list : []T to allow Put to infer the schema
 	list := reflectx.MakeSlice(t, data...).Interface()
 
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
 
-	return table.Uploader().Put(ctx, list)
+	inserter := table.Inserter()
+	inserter.SkipInvalidRows = skipInvalidRows
+	return inserter.Put(ctx, list)
 }
 
 func isNotFound(err error) bool {
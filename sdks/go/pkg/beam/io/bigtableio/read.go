@@ -0,0 +1,558 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtableio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigtable"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn3x1[context.Context, []byte, func(tablets), error](&sampleRowKeysFn{})
+	register.DoFn4x1[context.Context, *sdf.LockRTracker, tablets, func(Row), error](&readFn{})
+	register.Emitter1[tablets]()
+	register.Emitter1[Row]()
+	beam.RegisterType(reflect.TypeOf((*Row)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*tablets)(nil)).Elem())
+}
+
+// Row represents a necessary serializable wrapper analogue to bigtable.Row,
+// holding the cells of a row grouped by family and column.
+type Row struct {
+	Key      string
+	Families []Family
+}
+
+// Family holds the columns of a column family within a Row, in column order.
+type Family struct {
+	Name    string
+	Columns []Column
+}
+
+// Column holds the cells of a column within a Family, newest first.
+type Column struct {
+	Qualifier string
+	Cells     []Cell
+}
+
+// Cell is a single timestamped value of a Column.
+type Cell struct {
+	Ts     bigtable.Timestamp
+	Value  []byte
+	Labels []string
+}
+
+// RowRange represents a serializable analogue to bigtable.RowRange,
+// containing the row keys in [Start, End). An empty Start or End is unbounded.
+type RowRange struct {
+	Start string
+	End   string
+}
+
+// NewRange returns the RowRange [begin, end), analogue to bigtable.NewRange().
+func NewRange(begin, end string) RowRange {
+	return RowRange{Start: begin, End: end}
+}
+
+// PrefixRange returns a RowRange consisting of all keys starting with the prefix,
+// analogue to bigtable.PrefixRange().
+func PrefixRange(prefix string) RowRange {
+	return RowRange{Start: prefix, End: prefixSuccessor(prefix)}
+}
+
+// InfiniteRange returns the RowRange consisting of all keys at least as
+// large as start, analogue to bigtable.InfiniteRange().
+func InfiniteRange(start string) RowRange {
+	return RowRange{Start: start}
+}
+
+// RowSet represents a serializable analogue to bigtable.RowSet, as a
+// list of row ranges. An empty RowSet contains every row of the table.
+type RowSet struct {
+	Ranges []RowRange
+}
+
+// RowRangeList returns a RowSet of the given ranges, analogue to bigtable.RowRangeList.
+func RowRangeList(ranges ...RowRange) RowSet {
+	return RowSet{Ranges: ranges}
+}
+
+// RowList returns a RowSet of the given row keys, analogue to bigtable.RowList.
+func RowList(keys ...string) RowSet {
+	var rs RowSet
+	for _, k := range keys {
+		rs.Ranges = append(rs.Ranges, RowRange{Start: k, End: k + "\x00"})
+	}
+	return rs
+}
+
+// Filter represents a serializable analogue to bigtable.Filter. Filters
+// are built with the functions in this package named after their bigtable
+// counterparts, such as FamilyFilter and ChainFilters.
+type Filter struct {
+	Kind    string
+	Pattern string             `json:",omitempty"`
+	N       int                `json:",omitempty"`
+	Family  string             `json:",omitempty"`
+	Start   string             `json:",omitempty"`
+	End     string             `json:",omitempty"`
+	StartTs bigtable.Timestamp `json:",omitempty"`
+	EndTs   bigtable.Timestamp `json:",omitempty"`
+	Filters []Filter           `json:",omitempty"`
+}
+
+const (
+	chainFilterKind       = "chain"
+	interleaveFilterKind  = "interleave"
+	rowKeyFilterKind      = "rowKey"
+	familyFilterKind      = "family"
+	columnFilterKind      = "column"
+	valueFilterKind       = "value"
+	latestNFilterKind     = "latestN"
+	timestampRangeKind    = "timestampRange"
+	columnRangeFilterKind = "columnRange"
+	valueRangeFilterKind  = "valueRange"
+	stripValueFilterKind  = "stripValue"
+	cellsPerRowLimitKind  = "cellsPerRowLimit"
+	cellsPerRowOffsetKind = "cellsPerRowOffset"
+	passAllFilterKind     = "passAll"
+	blockAllFilterKind    = "blockAll"
+)
+
+// ChainFilters returns a filter that applies a sequence of filters, analogue to bigtable.ChainFilters().
+func ChainFilters(sub ...Filter) Filter {
+	return Filter{Kind: chainFilterKind, Filters: sub}
+}
+
+// InterleaveFilters returns a filter that applies a set of filters in parallel
+// and interleaves the results, analogue to bigtable.InterleaveFilters().
+func InterleaveFilters(sub ...Filter) Filter {
+	return Filter{Kind: interleaveFilterKind, Filters: sub}
+}
+
+// RowKeyFilter returns a filter that matches cells from rows whose
+// key matches the provided RE2 pattern, analogue to bigtable.RowKeyFilter().
+func RowKeyFilter(pattern string) Filter {
+	return Filter{Kind: rowKeyFilterKind, Pattern: pattern}
+}
+
+// FamilyFilter returns a filter that matches cells whose family name
+// matches the provided RE2 pattern, analogue to bigtable.FamilyFilter().
+func FamilyFilter(pattern string) Filter {
+	return Filter{Kind: familyFilterKind, Pattern: pattern}
+}
+
+// ColumnFilter returns a filter that matches cells whose column name
+// matches the provided RE2 pattern, analogue to bigtable.ColumnFilter().
+func ColumnFilter(pattern string) Filter {
+	return Filter{Kind: columnFilterKind, Pattern: pattern}
+}
+
+// ValueFilter returns a filter that matches cells whose value
+// matches the provided RE2 pattern, analogue to bigtable.ValueFilter().
+func ValueFilter(pattern string) Filter {
+	return Filter{Kind: valueFilterKind, Pattern: pattern}
+}
+
+// LatestNFilter returns a filter that matches the most recent N cells in each column,
+// analogue to bigtable.LatestNFilter().
+func LatestNFilter(n int) Filter {
+	return Filter{Kind: latestNFilterKind, N: n}
+}
+
+// TimestampRangeFilterMicros returns a filter that matches any cells whose timestamp
+// is within the given time bounds, analogue to bigtable.TimestampRangeFilterMicros().
+func TimestampRangeFilterMicros(startTime, endTime bigtable.Timestamp) Filter {
+	return Filter{Kind: timestampRangeKind, StartTs: startTime, EndTs: endTime}
+}
+
+// ColumnRangeFilter returns a filter that matches cells in the given family
+// with columns in [start, end), analogue to bigtable.ColumnRangeFilter().
+func ColumnRangeFilter(family, start, end string) Filter {
+	return Filter{Kind: columnRangeFilterKind, Family: family, Start: start, End: end}
+}
+
+// ValueRangeFilter returns a filter that matches cells with values in
+// [start, end), analogue to bigtable.ValueRangeFilter().
+func ValueRangeFilter(start, end []byte) Filter {
+	return Filter{Kind: valueRangeFilterKind, Start: string(start), End: string(end)}
+}
+
+// StripValueFilter returns a filter that replaces each value with the empty string,
+// analogue to bigtable.StripValueFilter().
+func StripValueFilter() Filter {
+	return Filter{Kind: stripValueFilterKind}
+}
+
+// CellsPerRowLimitFilter returns a filter that matches only the first N cells of each row,
+// analogue to bigtable.CellsPerRowLimitFilter().
+func CellsPerRowLimitFilter(n int) Filter {
+	return Filter{Kind: cellsPerRowLimitKind, N: n}
+}
+
+// CellsPerRowOffsetFilter returns a filter that skips the first N cells of each row,
+// analogue to bigtable.CellsPerRowOffsetFilter().
+func CellsPerRowOffsetFilter(n int) Filter {
+	return Filter{Kind: cellsPerRowOffsetKind, N: n}
+}
+
+// PassAllFilter returns a filter that matches everything, analogue to bigtable.PassAllFilter().
+func PassAllFilter() Filter {
+	return Filter{Kind: passAllFilterKind}
+}
+
+// BlockAllFilter returns a filter that matches nothing, analogue to bigtable.BlockAllFilter().
+func BlockAllFilter() Filter {
+	return Filter{Kind: blockAllFilterKind}
+}
+
+// bigtableFilter returns the bigtable.Filter the Filter represents.
+func (f Filter) bigtableFilter() (bigtable.Filter, error) {
+	subs := func() ([]bigtable.Filter, error) {
+		var ret []bigtable.Filter
+		for _, sub := range f.Filters {
+			bf, err := sub.bigtableFilter()
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, bf)
+		}
+		return ret, nil
+	}
+
+	switch f.Kind {
+	case chainFilterKind:
+		s, err := subs()
+		if err != nil {
+			return nil, err
+		}
+		return bigtable.ChainFilters(s...), nil
+	case interleaveFilterKind:
+		s, err := subs()
+		if err != nil {
+			return nil, err
+		}
+		return bigtable.InterleaveFilters(s...), nil
+	case rowKeyFilterKind:
+		return bigtable.RowKeyFilter(f.Pattern), nil
+	case familyFilterKind:
+		return bigtable.FamilyFilter(f.Pattern), nil
+	case columnFilterKind:
+		return bigtable.ColumnFilter(f.Pattern), nil
+	case valueFilterKind:
+		return bigtable.ValueFilter(f.Pattern), nil
+	case latestNFilterKind:
+		return bigtable.LatestNFilter(f.N), nil
+	case timestampRangeKind:
+		return bigtable.TimestampRangeFilterMicros(f.StartTs, f.EndTs), nil
+	case columnRangeFilterKind:
+		return bigtable.ColumnRangeFilter(f.Family, f.Start, f.End), nil
+	case valueRangeFilterKind:
+		return bigtable.ValueRangeFilter([]byte(f.Start), []byte(f.End)), nil
+	case stripValueFilterKind:
+		return bigtable.StripValueFilter(), nil
+	case cellsPerRowLimitKind:
+		return bigtable.CellsPerRowLimitFilter(f.N), nil
+	case cellsPerRowOffsetKind:
+		return bigtable.CellsPerRowOffsetFilter(f.N), nil
+	case passAllFilterKind:
+		return bigtable.PassAllFilter(), nil
+	case blockAllFilterKind:
+		return bigtable.BlockAllFilter(), nil
+	default:
+		return nil, fmt.Errorf("unknown bigtableio.Filter kind %q", f.Kind)
+	}
+}
+
+type readOption struct {
+	RowSet RowSet
+	Filter *Filter
+}
+
+// ReadOptionFn is a function that can be passed to Read to configure options for
+// reading from bigtable.
+type ReadOptionFn func(*readOption)
+
+// ReadRowSet restricts the rows read to those in the given RowSet.
+// By default, the entire table is read.
+func ReadRowSet(rs RowSet) ReadOptionFn {
+	return func(o *readOption) {
+		o.RowSet = rs
+	}
+}
+
+// ReadFilter applies the given Filter to the rows read.
+func ReadFilter(f Filter) ReadOptionFn {
+	return func(o *readOption) {
+		o.Filter = &f
+	}
+}
+
+// Read reads the rows of the given table, and returns a PCollection<bigtableio.Row>.
+//
+// The table is split into ranges at the keys returned by bigtable's SampleRowKeys,
+// which are read in parallel, and may be split further dynamically by the runner.
+// Ranges within the RowSet given with ReadRowSet should not overlap,
+// or rows in the overlap are read multiple times.
+func Read(s beam.Scope, project, instanceID, table string, opts ...ReadOptionFn) beam.PCollection {
+	s = s.Scope("bigtable.Read")
+
+	option := &readOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+	var filter []byte
+	if option.Filter != nil {
+		if _, err := option.Filter.bigtableFilter(); err != nil {
+			panic(err)
+		}
+		var err error
+		if filter, err = json.Marshal(option.Filter); err != nil {
+			panic(err)
+		}
+	}
+
+	imp := beam.Impulse(s)
+	sampled := beam.ParDo(s, &sampleRowKeysFn{Project: project, InstanceID: instanceID, TableName: table}, imp)
+	return beam.ParDo(s, &readFn{
+		Project:    project,
+		InstanceID: instanceID,
+		TableName:  table,
+		RowSet:     option.RowSet,
+		Filter:     filter,
+	}, sampled)
+}
+
+// tablets holds the sorted keys that split a table into contiguous ranges,
+// which are claimed by index as the restriction of readFn. Range i holds the
+// keys in [Bounds[i-1], Bounds[i]), where the first and last ranges are unbounded.
+type tablets struct {
+	Bounds []string
+}
+
+// size returns the number of ranges.
+func (t tablets) size() int64 {
+	return int64(len(t.Bounds)) + 1
+}
+
+// rowRange returns the i-th range.
+func (t tablets) rowRange(i int64) RowRange {
+	var r RowRange
+	if i > 0 {
+		r.Start = t.Bounds[i-1]
+	}
+	if i < int64(len(t.Bounds)) {
+		r.End = t.Bounds[i]
+	}
+	return r
+}
+
+// newTablets returns the tablets split at the given sample row keys.
+func newTablets(keys []string) tablets {
+	seen := map[string]bool{}
+	var bounds []string
+	for _, k := range keys {
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		bounds = append(bounds, k)
+	}
+	sort.Strings(bounds)
+	return tablets{Bounds: bounds}
+}
+
+type sampleRowKeysFn struct {
+	// Project is the project
+	Project string `json:"project"`
+	// InstanceID is the bigtable instanceID
+	InstanceID string `json:"instanceId"`
+	// TableName is the qualified table identifier.
+	TableName string `json:"tableName"`
+}
+
+func (f *sampleRowKeysFn) ProcessElement(ctx context.Context, _ []byte, emit func(tablets)) error {
+	client, err := bigtable.NewClient(ctx, f.Project, f.InstanceID)
+	if err != nil {
+		return fmt.Errorf("could not create data operations client: %v", err)
+	}
+	defer client.Close()
+
+	keys, err := client.Open(f.TableName).SampleRowKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("could not sample row keys of table %v: %v", f.TableName, err)
+	}
+	emit(newTablets(keys))
+	return nil
+}
+
+type readFn struct {
+	// Project is the project
+	Project string `json:"project"`
+	// InstanceID is the bigtable instanceID
+	InstanceID string `json:"instanceId"`
+	// Client is the bigtable.Client
+	client *bigtable.Client `json:"-"`
+	// TableName is the qualified table identifier.
+	TableName string `json:"tableName"`
+	// Table is a bigtable.Table instance with an eventual open connection
+	table *bigtable.Table `json:"-"`
+	// RowSet restricts the rows read.
+	RowSet RowSet `json:"rowSet"`
+	// Filter is the JSON encoded Filter applied to the rows read, if set.
+	// It's encoded, as schemas can't represent the recursive Filter type.
+	Filter []byte `json:"filter,omitempty"`
+
+	opts []bigtable.ReadOption
+}
+
+func (f *readFn) Setup(ctx context.Context) error {
+	var err error
+	f.client, err = bigtable.NewClient(ctx, f.Project, f.InstanceID)
+	if err != nil {
+		return fmt.Errorf("could not create data operations client: %v", err)
+	}
+	f.table = f.client.Open(f.TableName)
+
+	if f.Filter != nil {
+		var bf Filter
+		if err := json.Unmarshal(f.Filter, &bf); err != nil {
+			return fmt.Errorf("could not decode filter: %v", err)
+		}
+		filter, err := bf.bigtableFilter()
+		if err != nil {
+			return err
+		}
+		f.opts = append(f.opts, bigtable.RowFilter(filter))
+	}
+	return nil
+}
+
+func (f *readFn) Teardown() error {
+	if err := f.client.Close(); err != nil {
+		return fmt.Errorf("could not close data operations client: %v", err)
+	}
+	return nil
+}
+
+func (f *readFn) CreateInitialRestriction(t tablets) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: t.size()}
+}
+
+func (f *readFn) SplitRestriction(_ tablets, rest offsetrange.Restriction) []offsetrange.Restriction {
+	return rest.SizedSplits(1)
+}
+
+func (f *readFn) RestrictionSize(_ tablets, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+func (f *readFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+}
+
+func (f *readFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, t tablets, emit func(Row)) error {
+	for i := rt.GetRestriction().(offsetrange.Restriction).Start; rt.TryClaim(i); i++ {
+		ranges := intersectRanges(f.RowSet, t.rowRange(i))
+		if len(ranges) == 0 {
+			continue
+		}
+		var rs bigtable.RowRangeList
+		for _, r := range ranges {
+			rs = append(rs, bigtable.NewRange(r.Start, r.End))
+		}
+		err := f.table.ReadRows(ctx, rs, func(row bigtable.Row) bool {
+			emit(newRow(row))
+			return true
+		}, f.opts...)
+		if err != nil {
+			return fmt.Errorf("could not read rows of table %v: %v", f.TableName, err)
+		}
+	}
+	return nil
+}
+
+// intersectRanges returns the non empty intersections of the ranges of the
+// RowSet with the given range.
+func intersectRanges(rs RowSet, with RowRange) []RowRange {
+	if len(rs.Ranges) == 0 {
+		return []RowRange{with}
+	}
+	var ret []RowRange
+	for _, r := range rs.Ranges {
+		start := r.Start
+		if with.Start > start {
+			start = with.Start
+		}
+		end := r.End
+		if end == "" || (with.End != "" && with.End < end) {
+			end = with.End
+		}
+		if end != "" && start >= end {
+			continue
+		}
+		ret = append(ret, RowRange{Start: start, End: end})
+	}
+	return ret
+}
+
+// newRow converts a bigtable.Row into a Row, with families sorted by name.
+func newRow(r bigtable.Row) Row {
+	row := Row{Key: r.Key()}
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fam := Family{Name: name}
+		for _, item := range r[name] {
+			qualifier := strings.TrimPrefix(item.Column, name+":")
+			if n := len(fam.Columns); n == 0 || fam.Columns[n-1].Qualifier != qualifier {
+				fam.Columns = append(fam.Columns, Column{Qualifier: qualifier})
+			}
+			col := &fam.Columns[len(fam.Columns)-1]
+			col.Cells = append(col.Cells, Cell{Ts: item.Timestamp, Value: item.Value, Labels: item.Labels})
+		}
+		row.Families = append(row.Families, fam)
+	}
+	return row
+}
+
+// prefixSuccessor returns the lexically smallest string greater than the
+// prefix, if it exists, or "" otherwise.
+func prefixSuccessor(prefix string) string {
+	n := len(prefix)
+	for n > 0 && prefix[n-1] == 0xff {
+		n--
+	}
+	if n == 0 {
+		return ""
+	}
+	ans := []byte(prefix[:n])
+	ans[n-1]++
+	return string(ans)
+}
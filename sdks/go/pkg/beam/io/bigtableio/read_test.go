@@ -0,0 +1,207 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bigtableio
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/bigtable"
+	"cloud.google.com/go/bigtable/bttest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// initTestTable starts an in-memory bigtable emulator with a table of the
+// given rows, each with a cell in column "f:c" holding the row key, and a
+// cell in column "g:d".
+func initTestTable(t *testing.T, table string, keys ...string) {
+	t.Helper()
+	srv, err := bttest.NewServer("localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	t.Setenv("BIGTABLE_EMULATOR_HOST", srv.Addr)
+
+	ctx := context.Background()
+	admin, err := bigtable.NewAdminClient(ctx, "project", "instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	if err := admin.CreateTable(ctx, table); err != nil {
+		t.Fatal(err)
+	}
+	for _, fam := range []string{"f", "g"} {
+		if err := admin.CreateColumnFamily(ctx, table, fam); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client, err := bigtable.NewClient(ctx, "project", "instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	tbl := client.Open(table)
+	for _, k := range keys {
+		mut := bigtable.NewMutation()
+		mut.Set("f", "c", 1000, []byte(k))
+		mut.Set("g", "d", 1000, []byte("other"))
+		if err := tbl.Apply(ctx, k, mut); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	var keys []string
+	var want []any
+	for i := 0; i < 50; i++ {
+		k := fmt.Sprintf("row%02d", i)
+		keys = append(keys, k)
+		want = append(want, Row{Key: k, Families: []Family{
+			{Name: "f", Columns: []Column{{Qualifier: "c", Cells: []Cell{{Ts: 1000, Value: []byte(k)}}}}},
+			{Name: "g", Columns: []Column{{Qualifier: "d", Cells: []Cell{{Ts: 1000, Value: []byte("other")}}}}},
+		}})
+	}
+	initTestTable(t, "table", keys...)
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, "project", "instance", "table")
+	passert.Equals(s, rows, want...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_RowSetAndFilter(t *testing.T) {
+	initTestTable(t, "table", "a1", "a2", "b1", "b2", "c1")
+
+	p, s := beam.NewPipelineWithRoot()
+	rows := Read(s, "project", "instance", "table",
+		ReadRowSet(RowRangeList(PrefixRange("a"), NewRange("b2", "c"))),
+		ReadFilter(ChainFilters(FamilyFilter("f"), StripValueFilter())),
+	)
+	row := func(k string) Row {
+		return Row{Key: k, Families: []Family{
+			{Name: "f", Columns: []Column{{Qualifier: "c", Cells: []Cell{{Ts: 1000}}}}},
+		}}
+	}
+	passert.Equals(s, rows, row("a1"), row("a2"), row("b2"))
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_InvalidFilterPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic for an unknown filter kind")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	Read(s, "project", "instance", "table", ReadFilter(ChainFilters(Filter{Kind: "unknown"})))
+}
+
+func TestTablets(t *testing.T) {
+	tab := newTablets([]string{"m", "", "d", "m"})
+	if got, want := tab.size(), int64(3); got != want {
+		t.Fatalf("size() = %v, want %v", got, want)
+	}
+	want := []RowRange{{End: "d"}, {Start: "d", End: "m"}, {Start: "m"}}
+	for i, w := range want {
+		if got := tab.rowRange(int64(i)); got != w {
+			t.Errorf("rowRange(%v) = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestIntersectRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		rs   RowSet
+		with RowRange
+		want []RowRange
+	}{
+		{
+			name: "empty row set",
+			with: RowRange{Start: "b", End: "d"},
+			want: []RowRange{{Start: "b", End: "d"}},
+		},
+		{
+			name: "overlapping",
+			rs:   RowRangeList(NewRange("a", "c"), InfiniteRange("c1")),
+			with: RowRange{Start: "b", End: "d"},
+			want: []RowRange{{Start: "b", End: "c"}, {Start: "c1", End: "d"}},
+		},
+		{
+			name: "unbounded",
+			rs:   RowRangeList(InfiniteRange("c")),
+			with: RowRange{Start: "b"},
+			want: []RowRange{{Start: "c"}},
+		},
+		{
+			name: "disjoint",
+			rs:   RowList("a", "e"),
+			with: RowRange{Start: "b", End: "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := cmp.Diff(tt.want, intersectRanges(tt.rs, tt.with)); d != "" {
+				t.Errorf("intersectRanges() mismatch (-want, +got):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestPrefixRange(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   RowRange
+	}{
+		{"abc", RowRange{Start: "abc", End: "abd"}},
+		{"a\xff", RowRange{Start: "a\xff", End: "b"}},
+		{"\xff", RowRange{Start: "\xff"}},
+	}
+	for _, tt := range tests {
+		if got := PrefixRange(tt.prefix); got != tt.want {
+			t.Errorf("PrefixRange(%q) = %+v, want %+v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestNewRow(t *testing.T) {
+	in := bigtable.Row{
+		"g": {{Row: "k", Column: "g:x", Timestamp: 5, Value: []byte("gx")}},
+		"f": {
+			{Row: "k", Column: "f:a", Timestamp: 2, Value: []byte("a2")},
+			{Row: "k", Column: "f:a", Timestamp: 1, Value: []byte("a1")},
+			{Row: "k", Column: "f:b", Timestamp: 1, Value: []byte("b1"), Labels: []string{"l"}},
+		},
+	}
+	want := Row{Key: "k", Families: []Family{
+		{Name: "f", Columns: []Column{
+			{Qualifier: "a", Cells: []Cell{{Ts: 2, Value: []byte("a2")}, {Ts: 1, Value: []byte("a1")}}},
+			{Qualifier: "b", Cells: []Cell{{Ts: 1, Value: []byte("b1"), Labels: []string{"l"}}}},
+		}},
+		{Name: "g", Columns: []Column{{Qualifier: "x", Cells: []Cell{{Ts: 5, Value: []byte("gx")}}}}},
+	}}
+	if d := cmp.Diff(want, newRow(in)); d != "" {
+		t.Errorf("newRow() mismatch (-want, +got):\n%v", d)
+	}
+}
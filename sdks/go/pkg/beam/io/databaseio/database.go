@@ -18,6 +18,7 @@
 package databaseio
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
@@ -32,6 +33,7 @@ import (
 func init() {
 	beam.RegisterType(reflect.TypeOf((*queryFn)(nil)).Elem())
 	beam.RegisterType(reflect.TypeOf((*writeFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*writeWithErrorsFn)(nil)).Elem())
 }
 
 // writeSizeLimit is the maximum number of rows allowed to a write.
@@ -66,29 +68,40 @@ type queryFn struct {
 	Query string `json:"query"`
 	// Type is the encoded schema type.
 	Type beam.EncodedType `json:"type"`
+	// db is the shared connection pool to the database.
+	db *sql.DB
+}
+
+func (f *queryFn) Setup() error {
+	var err error
+	f.db, err = openDB(f.Driver, f.Dsn)
+	return err
+}
+
+func (f *queryFn) Teardown() error {
+	return releaseDB(f.Driver, f.Dsn)
 }
 
 func (f *queryFn) ProcessElement(ctx context.Context, _ []byte, emit func(beam.X)) error {
-	//TODO move DB Open and Close to Setup and Teardown methods or StartBundle and FinishBundle
-	db, err := sql.Open(f.Driver, f.Dsn)
-	if err != nil {
-		return errors.Wrapf(err, "failed to open database: %v", f.Driver)
-	}
-	defer db.Close()
-	statement, err := db.PrepareContext(ctx, f.Query)
+	return readRows(ctx, f.db, f.Type.T, f.Query, nil, emit)
+}
+
+// readRows runs the query with the given arguments, and emits its rows as values of type t.
+func readRows(ctx context.Context, db *sql.DB, t reflect.Type, query string, args []any, emit func(beam.X)) error {
+	statement, err := db.PrepareContext(ctx, query)
 	if err != nil {
-		return errors.Wrapf(err, "failed to prepare query: %v", f.Query)
+		return errors.Wrapf(err, "failed to prepare query: %v", query)
 	}
 	defer statement.Close()
-	rows, err := statement.QueryContext(ctx)
+	rows, err := statement.QueryContext(ctx, args...)
 	if err != nil {
-		return errors.Wrapf(err, "failed to run query: %v", f.Query)
+		return errors.Wrapf(err, "failed to run query: %v", query)
 	}
 	defer rows.Close()
 	var mapper rowMapper
 	var columns []string
 	for rows.Next() {
-		reflectRow := reflect.New(f.Type.T)
+		reflectRow := reflect.New(t)
 		row := reflectRow.Interface() // row : *T
 		if mapper == nil {
 			columns, err = rows.Columns()
@@ -96,7 +109,7 @@ func (f *queryFn) ProcessElement(ctx context.Context, _ []byte, emit func(beam.X
 				return err
 			}
 			columnsTypes, _ := rows.ColumnTypes()
-			if mapper, err = newQueryMapper(columns, columnsTypes, f.Type.T); err != nil {
+			if mapper, err = newQueryMapper(columns, columnsTypes, t); err != nil {
 				return errors.WithContext(err, "creating rowValues mapper")
 			}
 		}
@@ -106,7 +119,7 @@ func (f *queryFn) ProcessElement(ctx context.Context, _ []byte, emit func(beam.X
 		}
 		err = rows.Scan(rowValues...)
 		if err != nil {
-			return errors.Wrapf(err, "failed to scan %v", f.Query)
+			return errors.Wrapf(err, "failed to scan %v", query)
 		}
 		if loader, ok := row.(MapLoader); ok {
 			asDereferenceSlice(rowValues)
@@ -117,25 +130,91 @@ func (f *queryFn) ProcessElement(ctx context.Context, _ []byte, emit func(beam.X
 		}
 		emit(reflect.ValueOf(row).Elem().Interface()) // emit(*row)
 	}
-	return nil
+	return rows.Err()
+}
+
+type writeOption struct {
+	Mode          WriteMode
+	KeyColumns    []string
+	Transactional bool
+	ErrorHandler  *beam.ErrorHandler
+}
+
+// WriteOptionFn is a function that can be passed to Write and WriteWithBatchSize
+// to configure options for writing to a database.
+type WriteOptionFn func(*writeOption)
+
+// WriteUpsert replaces the existing rows that conflict with rows written, so that
+// retried writes don't produce duplicate rows. The key columns identify the
+// conflicting rows; if none are given, the columns of the fields of the element
+// type tagged `key:"true"` are used. See UpsertMode for the supported drivers.
+func WriteUpsert(keyColumns ...string) WriteOptionFn {
+	return func(o *writeOption) {
+		o.Mode = UpsertMode
+		o.KeyColumns = keyColumns
+	}
+}
+
+// WriteInsertIgnore skips the rows written that conflict with existing rows,
+// identified like WriteUpsert. See InsertIgnoreMode for the supported drivers.
+func WriteInsertIgnore(keyColumns ...string) WriteOptionFn {
+	return func(o *writeOption) {
+		o.Mode = InsertIgnoreMode
+		o.KeyColumns = keyColumns
+	}
+}
+
+// WriteTransactional writes the rows of each bundle in a single transaction,
+// which is committed when the bundle finishes, and rolled back if it fails.
+func WriteTransactional() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Transactional = true
+	}
+}
+
+// WriteErrorHandler outputs the rows that fail to be written to the given
+// ErrorHandler, instead of failing the pipeline. Rows are still written in
+// batches, and the rows of a failed batch are retried individually to find
+// the failing rows. Failures to reach the database still fail the pipeline.
+// WriteTransactional is ignored, as failed rows abort a transaction.
+func WriteErrorHandler(eh *beam.ErrorHandler) WriteOptionFn {
+	return func(o *writeOption) {
+		o.ErrorHandler = eh
+	}
 }
 
 // Write writes the elements of the given PCollection<T> to database, if columns left empty all table columns are used to insert into, otherwise selected
-func Write(s beam.Scope, driver, dsn, table string, columns []string, col beam.PCollection) {
-	t := col.Type().Type()
-	s = s.Scope(driver + ".Write")
-	pre := beam.AddFixedKey(s, col)
-	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeFn{Driver: driver, Dsn: dsn, Table: table, Columns: columns, BatchSize: writeRowLimit, Type: beam.EncodedType{T: t}}, post)
+func Write(s beam.Scope, driver, dsn, table string, columns []string, col beam.PCollection, opts ...WriteOptionFn) {
+	WriteWithBatchSize(s, writeRowLimit, driver, dsn, table, columns, col, opts...)
 }
 
 // WriteWithBatchSize writes the elements of the given PCollection<T> to database with custom batch size. Batch size control number of elements in the batch INSERT statement.
-func WriteWithBatchSize(s beam.Scope, batchSize int, driver, dsn, table string, columns []string, col beam.PCollection) {
+func WriteWithBatchSize(s beam.Scope, batchSize int, driver, dsn, table string, columns []string, col beam.PCollection, opts ...WriteOptionFn) {
+	option := &writeOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
 	t := col.Type().Type()
 	s = s.Scope(driver + ".Write")
+	fn := writeFn{
+		Driver:        driver,
+		Dsn:           dsn,
+		Table:         table,
+		Columns:       columns,
+		BatchSize:     batchSize,
+		Mode:          option.Mode,
+		KeyColumns:    option.KeyColumns,
+		Transactional: option.Transactional,
+		Type:          beam.EncodedType{T: t},
+	}
 	pre := beam.AddFixedKey(s, col)
 	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeFn{Driver: driver, Dsn: dsn, Table: table, Columns: columns, BatchSize: batchSize, Type: beam.EncodedType{T: t}}, post)
+	if option.ErrorHandler != nil {
+		fn.Transactional = false
+		option.ErrorHandler.Add(beam.ParDo(s, &writeWithErrorsFn{writeFn: fn}, post))
+		return
+	}
+	beam.ParDo0(s, &fn, post)
 }
 
 type writeFn struct {
@@ -149,17 +228,128 @@ type writeFn struct {
 	Columns []string `json:"columns"`
 	//BatchSize size
 	BatchSize int `json:"batchSize"`
+	// Mode determines how conflicting rows are handled.
+	Mode WriteMode `json:"mode"`
+	// KeyColumns identify conflicting rows, if empty then the key tagged fields of Type.
+	KeyColumns []string `json:"keyColumns"`
+	// Transactional writes each bundle in a transaction.
+	Transactional bool `json:"transactional"`
 	// Type is the encoded schema type.
 	Type beam.EncodedType `json:"type"`
+	// db is the shared connection pool to the database.
+	db *sql.DB
+	// tx is the transaction of the current bundle, if Transactional.
+	tx *sql.Tx
 }
 
-func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool) error {
-	//TODO move DB Open and Close to Setup and Teardown methods or StartBundle and FinishBundle
-	db, err := sql.Open(f.Driver, f.Dsn)
+func (f *writeFn) Setup() error {
+	var err error
+	f.db, err = openDB(f.Driver, f.Dsn)
+	return err
+}
+
+func (f *writeFn) StartBundle(_ context.Context) error {
+	// Discard the writes of a previous bundle that failed.
+	return f.rollback()
+}
+
+func (f *writeFn) FinishBundle(_ context.Context) error {
+	if f.tx == nil {
+		return nil
+	}
+	err := f.tx.Commit()
+	f.tx = nil
 	if err != nil {
-		return errors.Wrapf(err, "failed to open database: %v", f.Driver)
+		return errors.Wrapf(err, "failed to commit writes to: %v", f.Table)
+	}
+	return nil
+}
+
+func (f *writeFn) Teardown() error {
+	if err := f.rollback(); err != nil {
+		return err
 	}
-	defer db.Close()
+	return releaseDB(f.Driver, f.Dsn)
+}
+
+// rollback rolls back the transaction of the current bundle, if any.
+func (f *writeFn) rollback() error {
+	if f.tx == nil {
+		return nil
+	}
+	err := f.tx.Rollback()
+	f.tx = nil
+	if err != nil && err != sql.ErrTxDone {
+		return errors.Wrapf(err, "failed to roll back writes to: %v", f.Table)
+	}
+	return nil
+}
+
+// execer returns where to execute the writes of the current bundle.
+func (f *writeFn) execer() (execer, error) {
+	if !f.Transactional {
+		return f.db, nil
+	}
+	if f.tx == nil {
+		// The transaction outlives the context of a single element.
+		tx, err := f.db.BeginTx(context.Background(), nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to begin transaction: %v", f.Table)
+		}
+		f.tx = tx
+	}
+	return f.tx, nil
+}
+
+func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool) error {
+	if err := f.write(ctx, iter, nil); err != nil {
+		if rerr := f.rollback(); rerr != nil {
+			log.Errorf(ctx, "%v", rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// writeWithErrorsFn writes rows like writeFn, but outputs the rows that fail
+// to be written as ErrorRecords, instead of failing.
+type writeWithErrorsFn struct {
+	writeFn
+
+	enc beam.ElementEncoder
+}
+
+func (f *writeWithErrorsFn) Setup() error {
+	f.enc = beam.NewElementEncoder(f.Type.T)
+	return f.writeFn.Setup()
+}
+
+// StartBundle and FinishBundle wrap those of writeFn only to declare the
+// ErrorRecord emitter, since DoFn validation requires bundle methods to have
+// the same emitters as ProcessElement.
+func (f *writeWithErrorsFn) StartBundle(ctx context.Context, _ func(beam.ErrorRecord)) error {
+	return f.writeFn.StartBundle(ctx)
+}
+
+func (f *writeWithErrorsFn) FinishBundle(ctx context.Context, _ func(beam.ErrorRecord)) error {
+	return f.writeFn.FinishBundle(ctx)
+}
+
+func (f *writeWithErrorsFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool, emit func(beam.ErrorRecord)) error {
+	return f.write(ctx, iter, func(val any, rowErr error) error {
+		var buf bytes.Buffer
+		if err := f.enc.Encode(val, &buf); err != nil {
+			return errors.Wrapf(err, "failed to encode rejected row %T", val)
+		}
+		emit(beam.ErrorRecord{Element: buf.Bytes(), Error: rowErr.Error(), Transform: f.Driver + ".Write"})
+		return nil
+	})
+}
+
+// write writes the rows in batches. If rejected is set, the rows that fail to
+// be written are passed to it, instead of failing the write.
+func (f *writeFn) write(ctx context.Context, iter func(*beam.X) bool, rejected func(val any, err error) error) error {
+	db := f.db
 	projection := "*"
 	if len(f.Columns) > 0 {
 		projection = strings.Join(f.Columns, ",")
@@ -174,6 +364,7 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 	if err != nil {
 		return errors.Wrapf(err, "failed to query: %v", f.Table)
 	}
+	defer rows.Close()
 	columns, err := rows.Columns()
 	if err != nil {
 		return errors.Wrapf(err, "failed to discover column: %v", f.Table)
@@ -183,10 +374,28 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 	if err != nil {
 		return errors.WithContext(err, "creating row mapper")
 	}
-	writer, err := newWriter(f.Driver, f.BatchSize, f.Table, columns)
+	keys := f.KeyColumns
+	if len(keys) == 0 && f.Mode != InsertMode {
+		if keys, err = keyColumns(columns, f.Type.T); err != nil {
+			return errors.WithContext(err, "finding key columns")
+		}
+	}
+	writer, err := newWriter(f.Driver, f.BatchSize, f.Table, columns, f.Mode, keys)
+	if err != nil {
+		return err
+	}
+	ex, err := f.execer()
 	if err != nil {
 		return err
 	}
+	// batch holds the values of the rows buffered in the writer.
+	var batch []any
+	if rejected != nil {
+		writer.ping = db.PingContext
+		writer.rejected = func(row int, err error) error {
+			return rejected(batch[row], err)
+		}
+	}
 	var val beam.X
 	for iter(&val) {
 		var row []any
@@ -207,12 +416,16 @@ func (f *writeFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X)
 		if err = writer.add(row); err != nil {
 			return err
 		}
-		if err := writer.writeBatchIfNeeded(ctx, db); err != nil {
+		batch = append(batch, val)
+		if err := writer.writeBatchIfNeeded(ctx, ex); err != nil {
 			return err
 		}
+		if writer.rowCount == 0 {
+			batch = batch[:0]
+		}
 	}
 
-	if err := writer.writeIfNeeded(ctx, db); err != nil {
+	if err := writer.writeIfNeeded(ctx, ex); err != nil {
 		return err
 	}
 
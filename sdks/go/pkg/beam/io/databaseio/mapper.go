@@ -131,3 +131,25 @@ func newWriterRowMapper(columns []string, recordType reflect.Type) (rowMapper, e
 	}
 	return mapper, nil
 }
+
+// keyColumns returns the columns mapped to fields of the record type that are
+// tagged `key:"true"`, which identify rows for upserts.
+func keyColumns(columns []string, recordType reflect.Type) ([]string, error) {
+	if recordType.Kind() == reflect.Ptr {
+		recordType = recordType.Elem()
+	}
+	if recordType.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	mappedFieldIndex, err := mapFields(columns, recordType)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for i, fieldIndex := range mappedFieldIndex {
+		if recordType.Field(fieldIndex).Tag.Get("key") == "true" {
+			keys = append(keys, columns[i])
+		}
+	}
+	return keys, nil
+}
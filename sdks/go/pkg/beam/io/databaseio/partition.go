@@ -0,0 +1,274 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*columnRangeFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*partitionFn)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*columnRange)(nil)).Elem())
+}
+
+// timeLayouts are the layouts tried to parse timestamps that a driver returns as text.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ReadPartitioned reads all rows from the given table in parallel, like Read.
+// The rows are split into the given number of partitions, by equal ranges of
+// values of the given numeric or timestamp column, which are read separately
+// and may be split further dynamically by the runner. Rows with a NULL value
+// in the column are not read.
+func ReadPartitioned(s beam.Scope, driver, dsn, table, column string, partitions int, t reflect.Type) beam.PCollection {
+	s = s.Scope(driver + ".ReadPartitioned")
+	if partitions < 1 {
+		panic(fmt.Sprintf("databaseio.ReadPartitioned: partitions must be at least 1, got %v", partitions))
+	}
+
+	imp := beam.Impulse(s)
+	ranges := beam.ParDo(s, &columnRangeFn{Driver: driver, Dsn: dsn, Table: table, Column: column, Partitions: int64(partitions)}, imp)
+	return beam.ParDo(s, &partitionFn{Driver: driver, Dsn: dsn, Table: table, Column: column, Type: beam.EncodedType{T: t}}, ranges, beam.TypeDefinition{Var: beam.XType, T: t})
+}
+
+// columnRange is the inclusive range of values of the partition column, split
+// evenly into partitions that are claimed by index. Timestamps are represented
+// in microseconds since the Unix epoch.
+type columnRange struct {
+	Min        int64
+	Max        int64
+	Timestamp  bool
+	Partitions int64
+}
+
+// bounds returns the half open range [lower, upper) of values of the i-th
+// partition. The upper bound of the last partition is nil if Max is the
+// largest int64, as no value is above it. Offsets from Min are computed in
+// uint64, so ranges spanning most of the int64 values don't overflow.
+func (r columnRange) bounds(i int64) (lower, upper any) {
+	span := uint64(r.Max) - uint64(r.Min)
+	// The width only wraps to 0 for a single partition spanning all int64
+	// values, whose bounds don't depend on it.
+	width := span/uint64(r.Partitions) + 1
+	lo, ok := r.position(i, width, span)
+	if !ok {
+		// Rounding the width up left no values for this partition.
+		return r.value(r.Max), r.value(r.Max)
+	}
+	if hi, ok := r.position(i+1, width, span); ok && i < r.Partitions-1 {
+		return r.value(lo), r.value(hi)
+	}
+	if r.Max == math.MaxInt64 {
+		return r.value(lo), nil
+	}
+	return r.value(lo), r.value(r.Max + 1)
+}
+
+// position returns the first value of the i-th partition of the given width,
+// and false if it is past Max.
+func (r columnRange) position(i int64, width, span uint64) (int64, bool) {
+	carry, off := bits.Mul64(uint64(i), width)
+	if carry != 0 || off > span {
+		return 0, false
+	}
+	return int64(uint64(r.Min) + off), true
+}
+
+// value returns the column value at the given position.
+func (r columnRange) value(pos int64) any {
+	if r.Timestamp {
+		return time.UnixMicro(pos).UTC()
+	}
+	return pos
+}
+
+// newColumnRange returns the range between the given minimum and maximum
+// values of a column, split into at most the given number of partitions.
+func newColumnRange(min, max any, partitions int64) (columnRange, error) {
+	lo, loTs, err := columnPosition(min)
+	if err != nil {
+		return columnRange{}, err
+	}
+	hi, hiTs, err := columnPosition(max)
+	if err != nil {
+		return columnRange{}, err
+	}
+	if loTs != hiTs {
+		return columnRange{}, errors.Errorf("inconsistent partition column bounds: %v, %v", min, max)
+	}
+	if span := uint64(hi) - uint64(lo); span < uint64(partitions-1) {
+		partitions = int64(span) + 1
+	}
+	return columnRange{Min: lo, Max: hi, Timestamp: loTs, Partitions: partitions}, nil
+}
+
+// columnPosition converts a numeric or timestamp column value into an integer
+// position, rounding numbers down, and reports whether it was a timestamp.
+func columnPosition(v any) (int64, bool, error) {
+	switch v := v.(type) {
+	case int64:
+		return v, false, nil
+	case int32:
+		return int64(v), false, nil
+	case int:
+		return int64(v), false, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, false, errors.Errorf("partition column value %v is out of the int64 range", v)
+		}
+		return int64(v), false, nil
+	case float64:
+		// -MinInt64 is the smallest float64 above the int64 range.
+		if f := math.Floor(v); f >= math.MinInt64 && f < -math.MinInt64 {
+			return int64(f), false, nil
+		}
+		return 0, false, errors.Errorf("partition column value %v is out of the int64 range", v)
+	case float32:
+		return columnPosition(float64(v))
+	case time.Time:
+		return v.UnixMicro(), true, nil
+	case []byte:
+		return columnPosition(string(v))
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, false, nil
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return columnPosition(f)
+		}
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return columnPosition(t)
+			}
+		}
+	}
+	return 0, false, errors.Errorf("partition column value %v of type %T is neither numeric nor a timestamp", v, v)
+}
+
+// columnRangeFn emits the range of values of the partition column.
+type columnRangeFn struct {
+	Driver     string `json:"driver"`
+	Dsn        string `json:"dsn"`
+	Table      string `json:"table"`
+	Column     string `json:"column"`
+	Partitions int64  `json:"partitions"`
+}
+
+func (f *columnRangeFn) ProcessElement(ctx context.Context, _ []byte, emit func(columnRange)) error {
+	db, err := openDB(f.Driver, f.Dsn)
+	if err != nil {
+		return err
+	}
+	defer releaseDB(f.Driver, f.Dsn)
+
+	query := fmt.Sprintf("SELECT MIN(%v), MAX(%v) FROM %v", f.Column, f.Column, f.Table)
+	var min, max any
+	if err := db.QueryRowContext(ctx, query).Scan(&min, &max); err != nil {
+		return errors.Wrapf(err, "failed to query the range of %v", f.Column)
+	}
+	if min == nil || max == nil {
+		// The table has no rows to read.
+		return nil
+	}
+	r, err := newColumnRange(min, max, f.Partitions)
+	if err != nil {
+		return err
+	}
+	emit(r)
+	return nil
+}
+
+// partitionFn is a splittable DoFn reading the rows of the partitions of a
+// columnRange, whose indices are its restriction.
+type partitionFn struct {
+	Driver string           `json:"driver"`
+	Dsn    string           `json:"dsn"`
+	Table  string           `json:"table"`
+	Column string           `json:"column"`
+	Type   beam.EncodedType `json:"type"`
+	db     *sql.DB
+}
+
+func (f *partitionFn) Setup() error {
+	var err error
+	f.db, err = openDB(f.Driver, f.Dsn)
+	return err
+}
+
+func (f *partitionFn) Teardown() error {
+	return releaseDB(f.Driver, f.Dsn)
+}
+
+func (f *partitionFn) CreateInitialRestriction(r columnRange) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: r.Partitions}
+}
+
+func (f *partitionFn) SplitRestriction(_ columnRange, rest offsetrange.Restriction) []offsetrange.Restriction {
+	return rest.SizedSplits(1)
+}
+
+func (f *partitionFn) RestrictionSize(_ columnRange, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+func (f *partitionFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+}
+
+func (f *partitionFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, r columnRange, emit func(beam.X)) error {
+	query := fmt.Sprintf("SELECT * FROM %v WHERE %v >= %v AND %v < %v",
+		f.Table, f.Column, placeholder(f.Driver, 1), f.Column, placeholder(f.Driver, 2))
+	unbounded := fmt.Sprintf("SELECT * FROM %v WHERE %v >= %v", f.Table, f.Column, placeholder(f.Driver, 1))
+	for i := rt.GetRestriction().(offsetrange.Restriction).Start; rt.TryClaim(i); i++ {
+		lower, upper := r.bounds(i)
+		q, args := query, []any{lower, upper}
+		if upper == nil {
+			q, args = unbounded, []any{lower}
+		}
+		if err := readRows(ctx, f.db, f.Type.T, q, args, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placeholder returns the n-th query argument placeholder of the driver.
+func placeholder(driver string, n int) string {
+	switch driver {
+	case "postgres", "pgx":
+		return fmt.Sprintf("$%d", n)
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,232 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	_ "modernc.org/sqlite"
+)
+
+type Event struct {
+	ID   int
+	Name string
+	At   string
+}
+
+// openTestDB opens a new in-memory SQLite database with an event table of
+// the given number of rows, and returns its data source name.
+func openTestDB(t *testing.T, rows int) (string, []any) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%v?mode=memory&cache=shared", t.Name())
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Test infra failure: Failed to open database with error %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE event (id INTEGER, name TEXT, at DATETIME)"); err != nil {
+		t.Fatalf("Test infra failure: Failed to create table with error %v", err)
+	}
+	var want []any
+	for i := 0; i < rows; i++ {
+		at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Hour)
+		e := Event{ID: i, Name: fmt.Sprintf("event%v", i), At: at.Format(time.RFC3339Nano)}
+		if _, err := db.Exec("INSERT INTO event (id, name, at) VALUES (?, ?, ?)", e.ID, e.Name, at); err != nil {
+			t.Fatalf("Test infra failure: Failed to populate table with error %v", err)
+		}
+		want = append(want, e)
+	}
+	return dsn, want
+}
+
+func TestReadPartitioned(t *testing.T) {
+	dsn, want := openTestDB(t, 50)
+
+	p, s := beam.NewPipelineWithRoot()
+	elements := ReadPartitioned(s, "sqlite", dsn, "event", "id", 4, reflect.TypeOf(Event{}))
+	passert.Equals(s, elements, want...)
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestReadPartitioned_Timestamp(t *testing.T) {
+	dsn, want := openTestDB(t, 30)
+
+	p, s := beam.NewPipelineWithRoot()
+	elements := ReadPartitioned(s, "sqlite", dsn, "event", "at", 7, reflect.TypeOf(Event{}))
+	passert.Equals(s, elements, want...)
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestReadPartitioned_Empty(t *testing.T) {
+	dsn, _ := openTestDB(t, 0)
+
+	p, s := beam.NewPipelineWithRoot()
+	elements := ReadPartitioned(s, "sqlite", dsn, "event", "id", 4, reflect.TypeOf(Event{}))
+	passert.Empty(s, elements)
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestColumnRange_bounds(t *testing.T) {
+	r, err := newColumnRange(int64(10), int64(19), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][2]any
+	for i := int64(0); i < r.Partitions; i++ {
+		lower, upper := r.bounds(i)
+		got = append(got, [2]any{lower, upper})
+	}
+	want := [][2]any{{int64(10), int64(14)}, {int64(14), int64(18)}, {int64(18), int64(20)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("bounds() = %v, want %v", got, want)
+	}
+
+	// Fewer distinct values than partitions.
+	r, err = newColumnRange(int64(1), int64(2), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Partitions != 2 {
+		t.Errorf("newColumnRange(1, 2, 8).Partitions = %v, want 2", r.Partitions)
+	}
+}
+
+func TestColumnRange_boundsExtremes(t *testing.T) {
+	tests := []struct {
+		min, max   int64
+		partitions int64
+		want       [][2]any
+	}{
+		{
+			min: math.MinInt64, max: math.MaxInt64, partitions: 1,
+			want: [][2]any{{int64(math.MinInt64), nil}},
+		}, {
+			min: math.MinInt64, max: math.MaxInt64, partitions: 2,
+			want: [][2]any{{int64(math.MinInt64), int64(0)}, {int64(0), nil}},
+		}, {
+			min: math.MaxInt64 - 9, max: math.MaxInt64, partitions: 3,
+			want: [][2]any{
+				{int64(math.MaxInt64 - 9), int64(math.MaxInt64 - 5)},
+				{int64(math.MaxInt64 - 5), int64(math.MaxInt64 - 1)},
+				{int64(math.MaxInt64 - 1), nil},
+			},
+		}, {
+			min: math.MinInt64, max: math.MinInt64 + 9, partitions: 3,
+			want: [][2]any{
+				{int64(math.MinInt64), int64(math.MinInt64 + 4)},
+				{int64(math.MinInt64 + 4), int64(math.MinInt64 + 8)},
+				{int64(math.MinInt64 + 8), int64(math.MinInt64 + 10)},
+			},
+		}, {
+			// Rounding the width up leaves the last partitions empty.
+			min: math.MaxInt64 - 4, max: math.MaxInt64, partitions: 4,
+			want: [][2]any{
+				{int64(math.MaxInt64 - 4), int64(math.MaxInt64 - 2)},
+				{int64(math.MaxInt64 - 2), int64(math.MaxInt64)},
+				{int64(math.MaxInt64), nil},
+				{int64(math.MaxInt64), int64(math.MaxInt64)},
+			},
+		},
+	}
+	for _, test := range tests {
+		r, err := newColumnRange(test.min, test.max, test.partitions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got [][2]any
+		for i := int64(0); i < r.Partitions; i++ {
+			lower, upper := r.bounds(i)
+			got = append(got, [2]any{lower, upper})
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("newColumnRange(%v, %v, %v).bounds() = %v, want %v", test.min, test.max, test.partitions, got, test.want)
+		}
+	}
+}
+
+func TestColumnPosition(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in            any
+		want          int64
+		wantTimestamp bool
+		wantErr       bool
+	}{
+		{in: int64(5), want: 5},
+		{in: 2.5, want: 2},
+		{in: -2.5, want: -3},
+		{in: []byte("42"), want: 42},
+		{in: ts, want: ts.UnixMicro(), wantTimestamp: true},
+		{in: "2024-01-01 00:00:00+00:00", want: ts.UnixMicro(), wantTimestamp: true},
+		{in: uint64(math.MaxInt64), want: math.MaxInt64},
+		{in: uint64(math.MaxInt64) + 1, wantErr: true},
+		{in: float64(math.MinInt64), want: math.MinInt64},
+		{in: 1e19, wantErr: true},
+		{in: "18446744073709551615", wantErr: true},
+		{in: "not a position", wantErr: true},
+		{in: true, wantErr: true},
+	}
+	for _, test := range tests {
+		got, gotTimestamp, err := columnPosition(test.in)
+		if (err != nil) != test.wantErr {
+			t.Errorf("columnPosition(%v) error = %v, wantErr %v", test.in, err, test.wantErr)
+			continue
+		}
+		if got != test.want || gotTimestamp != test.wantTimestamp {
+			t.Errorf("columnPosition(%v) = %v, %v, want %v, %v", test.in, got, gotTimestamp, test.want, test.wantTimestamp)
+		}
+	}
+}
+
+func TestOpenDB_SharesPool(t *testing.T) {
+	dsn, _ := openTestDB(t, 0)
+
+	a, err := openDB("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := openDB("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Error("openDB() returned different pools for the same database")
+	}
+	if err := releaseDB("sqlite", dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Ping(); err != nil {
+		t.Errorf("pool closed while still in use: %v", err)
+	}
+	if err := releaseDB("sqlite", dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Ping(); err == nil {
+		t.Error("pool still open after its last release")
+	}
+}
@@ -0,0 +1,77 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"database/sql"
+	"sync"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
+)
+
+type poolKey struct {
+	driver, dsn string
+}
+
+type pooledDB struct {
+	db   *sql.DB
+	refs int
+}
+
+// pools holds the database connection pools of a worker, shared between the
+// DoFn instances that use the same database.
+var pools = struct {
+	mu sync.Mutex
+	m  map[poolKey]*pooledDB
+}{m: map[poolKey]*pooledDB{}}
+
+// openDB returns the shared connection pool to the given database, opening it
+// if needed. Each call must be matched with a call to releaseDB.
+func openDB(driver, dsn string) (*sql.DB, error) {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+
+	key := poolKey{driver: driver, dsn: dsn}
+	if p, ok := pools.m[key]; ok {
+		p.refs++
+		return p.db, nil
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open database: %v", driver)
+	}
+	pools.m[key] = &pooledDB{db: db, refs: 1}
+	return db, nil
+}
+
+// releaseDB releases a connection pool returned by openDB, closing it once
+// it's no longer used.
+func releaseDB(driver, dsn string) error {
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+
+	key := poolKey{driver: driver, dsn: dsn}
+	p, ok := pools.m[key]
+	if !ok {
+		return nil
+	}
+	p.refs--
+	if p.refs > 0 {
+		return nil
+	}
+	delete(pools.m, key)
+	return p.db.Close()
+}
@@ -0,0 +1,177 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package databaseio
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+)
+
+type Account struct {
+	ID      int `key:"true"`
+	Balance int
+}
+
+// openAccountDB opens a new in-memory SQLite database with an account table
+// holding the given accounts, and returns it with its data source name.
+func openAccountDB(t *testing.T, accounts ...Account) (*sql.DB, string) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%v?mode=memory&cache=shared", t.Name())
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Test infra failure: Failed to open database with error %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE account (id INTEGER PRIMARY KEY, balance INTEGER CHECK (balance >= 0))"); err != nil {
+		t.Fatalf("Test infra failure: Failed to create table with error %v", err)
+	}
+	for _, a := range accounts {
+		if _, err := db.Exec("INSERT INTO account (id, balance) VALUES (?, ?)", a.ID, a.Balance); err != nil {
+			t.Fatalf("Test infra failure: Failed to populate table with error %v", err)
+		}
+	}
+	return db, dsn
+}
+
+func queryAccounts(t *testing.T, db *sql.DB) []Account {
+	t.Helper()
+	rows, err := db.Query("SELECT id, balance FROM account ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var accounts []Account
+	for rows.Next() {
+		var a Account
+		if err := rows.Scan(&a.ID, &a.Balance); err != nil {
+			t.Fatal(err)
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts
+}
+
+func TestWrite_Modes(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []WriteOptionFn
+		want []Account
+	}{
+		{
+			name: "upsert",
+			opts: []WriteOptionFn{WriteUpsert()},
+			want: []Account{{ID: 1, Balance: 100}, {ID: 2, Balance: 200}, {ID: 3, Balance: 300}},
+		},
+		{
+			name: "upsert with key columns",
+			opts: []WriteOptionFn{WriteUpsert("id"), WriteTransactional()},
+			want: []Account{{ID: 1, Balance: 100}, {ID: 2, Balance: 200}, {ID: 3, Balance: 300}},
+		},
+		{
+			name: "insert ignore",
+			opts: []WriteOptionFn{WriteInsertIgnore()},
+			want: []Account{{ID: 1, Balance: 10}, {ID: 2, Balance: 20}, {ID: 3, Balance: 300}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, dsn := openAccountDB(t, Account{ID: 1, Balance: 10}, Account{ID: 2, Balance: 20})
+
+			p, s := beam.NewPipelineWithRoot()
+			col := beam.Create(s, Account{ID: 1, Balance: 100}, Account{ID: 2, Balance: 200}, Account{ID: 3, Balance: 300})
+			Write(s, "sqlite", dsn, "account", nil, col, test.opts...)
+			ptest.RunAndValidate(t, p)
+
+			if d := cmp.Diff(test.want, queryAccounts(t, db)); d != "" {
+				t.Errorf("accounts mismatch (-want, +got):\n%v", d)
+			}
+		})
+	}
+}
+
+func TestWrite_TransactionalRollsBack(t *testing.T) {
+	db, dsn := openAccountDB(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	// The negative balance violates the check constraint.
+	col := beam.Create(s, Account{ID: 1, Balance: 100}, Account{ID: 2, Balance: -1}, Account{ID: 3, Balance: 300})
+	WriteWithBatchSize(s, 1, "sqlite", dsn, "account", nil, col, WriteTransactional())
+	if err := ptest.Run(p); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+
+	if got := queryAccounts(t, db); len(got) != 0 {
+		t.Errorf("accounts = %v, want none written", got)
+	}
+}
+
+func TestWrite_ErrorHandler(t *testing.T) {
+	db, dsn := openAccountDB(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	// The negative balance violates the check constraint.
+	col := beam.Create(s, Account{ID: 1, Balance: 100}, Account{ID: 2, Balance: -1}, Account{ID: 3, Balance: 300})
+	eh := beam.NewErrorHandler()
+	Write(s, "sqlite", dsn, "account", nil, col, WriteErrorHandler(eh))
+	passert.Count(s, eh.Errors(s), "errors", 1)
+	ptest.RunAndValidate(t, p)
+
+	want := []Account{{ID: 1, Balance: 100}, {ID: 3, Balance: 300}}
+	if d := cmp.Diff(want, queryAccounts(t, db)); d != "" {
+		t.Errorf("accounts mismatch (-want, +got):\n%v", d)
+	}
+}
+
+func TestWrite_ErrorHandlerMissingTableFails(t *testing.T) {
+	_, dsn := openAccountDB(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s, Account{ID: 1, Balance: 100})
+	eh := beam.NewErrorHandler()
+	Write(s, "sqlite", dsn, "missing", nil, col, WriteErrorHandler(eh))
+	if err := ptest.Run(p); err == nil {
+		t.Fatal("expected the write to a missing table to fail, rather than output errors")
+	}
+}
+
+func TestWrite_UpsertWithoutKeysFails(t *testing.T) {
+	_, dsn := openAccountDB(t)
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s, Event{ID: 1})
+	Write(s, "sqlite", dsn, "account", []string{"id"}, col, WriteUpsert())
+	if err := ptest.Run(p); err == nil {
+		t.Fatal("expected the upsert to fail without key columns")
+	}
+}
+
+func TestKeyColumns(t *testing.T) {
+	got, err := keyColumns([]string{"balance", "id"}, reflect.TypeOf(&Account{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff([]string{"id"}, got); d != "" {
+		t.Errorf("keyColumns() mismatch (-want, +got):\n%v", d)
+	}
+}
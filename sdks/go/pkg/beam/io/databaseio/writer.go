@@ -33,15 +33,42 @@ type Writer interface {
 	SaveData() (map[string]any, error)
 }
 
+// WriteMode determines how a write handles rows that conflict with existing
+// rows of the table, on a primary key or unique constraint.
+type WriteMode int
+
+const (
+	// InsertMode writes rows with plain INSERT statements, which fail on conflicts.
+	InsertMode WriteMode = iota
+	// UpsertMode replaces conflicting rows, with INSERT ... ON CONFLICT DO UPDATE
+	// on Postgres and SQLite, or REPLACE on MySQL.
+	UpsertMode
+	// InsertIgnoreMode skips conflicting rows, with INSERT ... ON CONFLICT DO NOTHING
+	// on Postgres and SQLite, or INSERT IGNORE on MySQL.
+	InsertIgnoreMode
+)
+
+// execer executes statements on a database or within a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 type writer struct {
 	batchSize              int
 	table                  string
 	sqlTemplate            string
+	sqlSuffix              string
+	checkAffected          bool
 	valueTemplateGenerator *valueTemplateGenerator
 	binding                []any
 	columnCount            int
 	rowCount               int
 	totalCount             int
+	// rejected, if set, is passed the index in the batch of each row that
+	// fails to be written, when a batch fails and its rows are retried
+	// individually. Failures are only retried if ping reaches the database.
+	rejected func(row int, err error) error
+	ping     func(ctx context.Context) error
 }
 
 func (w *writer) add(row []any) error {
@@ -54,19 +81,48 @@ func (w *writer) add(row []any) error {
 	return nil
 }
 
-func (w *writer) write(ctx context.Context, db *sql.DB) error {
+func (w *writer) write(ctx context.Context, db execer) error {
+	binding, rowCount := w.binding, w.rowCount
+	err := w.exec(ctx, db)
+	if err == nil || w.rejected == nil || rowCount == 0 {
+		return err
+	}
+	if perr := w.ping(ctx); perr != nil {
+		return err
+	}
+	// Retry the rows individually, so that only the failing rows are rejected.
+	for i := 0; i < rowCount; i++ {
+		w.binding = binding[i*w.columnCount : (i+1)*w.columnCount]
+		w.rowCount = 1
+		if err := w.exec(ctx, db); err != nil {
+			if perr := w.ping(ctx); perr != nil {
+				return err
+			}
+			if err := w.rejected(i, err); err != nil {
+				return err
+			}
+		}
+	}
+	w.binding = []any{}
+	w.rowCount = 0
+	return nil
+}
+
+// exec writes the buffered rows in a single statement.
+func (w *writer) exec(ctx context.Context, db execer) error {
 	values := w.valueTemplateGenerator.generate(w.rowCount, w.columnCount)
 	if len(values) == 0 {
 		log.Info(ctx, "No value(s) to be written....")
 		return nil
 	}
-	SQL := w.sqlTemplate + values
+	SQL := w.sqlTemplate + values + w.sqlSuffix
 	resultSet, err := db.ExecContext(ctx, SQL, w.binding...)
 	if err != nil {
 		return err
 	}
 	affected, _ := resultSet.RowsAffected()
-	if int(affected) != w.rowCount {
+	// Upserts and ignored rows make the number of affected rows driver specific.
+	if w.checkAffected && int(affected) != w.rowCount {
 		return errors.Errorf("expected to write: %v, but written: %v", w.rowCount, affected)
 	}
 	w.binding = []any{}
@@ -74,34 +130,83 @@ func (w *writer) write(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
-func (w *writer) writeBatchIfNeeded(ctx context.Context, db *sql.DB) error {
+func (w *writer) writeBatchIfNeeded(ctx context.Context, db execer) error {
 	if w.rowCount >= w.batchSize {
 		return w.write(ctx, db)
 	}
 	return nil
 }
 
-func (w *writer) writeIfNeeded(ctx context.Context, db *sql.DB) error {
+func (w *writer) writeIfNeeded(ctx context.Context, db execer) error {
 	if w.rowCount >= 0 {
 		return w.write(ctx, db)
 	}
 	return nil
 }
 
-func newWriter(driver string, batchSize int, table string, columns []string) (*writer, error) {
+// newWriter creates a writer that handles conflicts on the given key columns
+// according to the mode.
+func newWriter(driver string, batchSize int, table string, columns []string, mode WriteMode, keyColumns []string) (*writer, error) {
 	if len(columns) == 0 {
 		return nil, errors.New("columns were empty")
 	}
+	insert, suffix, err := conflictClauses(driver, mode, columns, keyColumns)
+	if err != nil {
+		return nil, err
+	}
 	return &writer{
 		batchSize:              batchSize,
 		columnCount:            len(columns),
 		table:                  table,
 		binding:                make([]any, 0),
-		sqlTemplate:            fmt.Sprintf("INSERT INTO %v(%v) VALUES", table, strings.Join(columns, ",")),
+		sqlTemplate:            fmt.Sprintf("%v %v(%v) VALUES", insert, table, strings.Join(columns, ",")),
+		sqlSuffix:              suffix,
+		checkAffected:          mode == InsertMode,
 		valueTemplateGenerator: &valueTemplateGenerator{driver},
 	}, nil
 }
 
+// conflictClauses returns the statement that starts an INSERT for the driver and mode,
+// and the clause following its values.
+func conflictClauses(driver string, mode WriteMode, columns, keyColumns []string) (insert, suffix string, err error) {
+	if mode == InsertMode {
+		return "INSERT INTO", "", nil
+	}
+	switch driver {
+	case "mysql":
+		if mode == UpsertMode {
+			return "REPLACE INTO", "", nil
+		}
+		return "INSERT IGNORE INTO", "", nil
+	case "postgres", "pgx", "sqlite", "sqlite3":
+		if mode == InsertIgnoreMode {
+			if len(keyColumns) == 0 {
+				return "INSERT INTO", " ON CONFLICT DO NOTHING", nil
+			}
+			return "INSERT INTO", fmt.Sprintf(" ON CONFLICT (%v) DO NOTHING", strings.Join(keyColumns, ",")), nil
+		}
+		if len(keyColumns) == 0 {
+			return "", "", errors.New("upsert requires key columns")
+		}
+		isKey := make(map[string]bool)
+		for _, key := range keyColumns {
+			isKey[strings.ToLower(key)] = true
+		}
+		var updates []string
+		for _, column := range columns {
+			if !isKey[strings.ToLower(column)] {
+				updates = append(updates, fmt.Sprintf("%v = excluded.%v", column, column))
+			}
+		}
+		if len(updates) == 0 {
+			return "INSERT INTO", fmt.Sprintf(" ON CONFLICT (%v) DO NOTHING", strings.Join(keyColumns, ",")), nil
+		}
+		return "INSERT INTO", fmt.Sprintf(" ON CONFLICT (%v) DO UPDATE SET %v", strings.Join(keyColumns, ","), strings.Join(updates, ", ")), nil
+	default:
+		return "", "", errors.Errorf("write mode %v is not supported for driver: %v", mode, driver)
+	}
+}
+
 type valueTemplateGenerator struct {
 	driver string
 }
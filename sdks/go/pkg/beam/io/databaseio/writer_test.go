@@ -71,3 +71,47 @@ func TestValueTemplateGenerator_generate(t *testing.T) {
 		})
 	}
 }
+
+func TestConflictClauses(t *testing.T) {
+	columns := []string{"id", "name", "balance"}
+	tests := []struct {
+		driver     string
+		mode       WriteMode
+		keyColumns []string
+		insert     string
+		suffix     string
+		wantErr    bool
+	}{
+		{driver: "postgres", mode: InsertMode, insert: "INSERT INTO"},
+		{driver: "mysql", mode: UpsertMode, insert: "REPLACE INTO"},
+		{driver: "mysql", mode: InsertIgnoreMode, insert: "INSERT IGNORE INTO"},
+		{driver: "pgx", mode: InsertIgnoreMode, insert: "INSERT INTO", suffix: " ON CONFLICT DO NOTHING"},
+		{driver: "sqlite", mode: InsertIgnoreMode, keyColumns: []string{"id"}, insert: "INSERT INTO", suffix: " ON CONFLICT (id) DO NOTHING"},
+		{
+			driver:     "postgres",
+			mode:       UpsertMode,
+			keyColumns: []string{"id", "name"},
+			insert:     "INSERT INTO",
+			suffix:     " ON CONFLICT (id,name) DO UPDATE SET balance = excluded.balance",
+		},
+		{
+			driver:     "postgres",
+			mode:       UpsertMode,
+			keyColumns: []string{"ID", "Name", "Balance"},
+			insert:     "INSERT INTO",
+			suffix:     " ON CONFLICT (ID,Name,Balance) DO NOTHING",
+		},
+		{driver: "postgres", mode: UpsertMode, wantErr: true},
+		{driver: "ramsql", mode: InsertIgnoreMode, keyColumns: []string{"id"}, wantErr: true},
+	}
+	for _, test := range tests {
+		insert, suffix, err := conflictClauses(test.driver, test.mode, columns, test.keyColumns)
+		if (err != nil) != test.wantErr {
+			t.Errorf("conflictClauses(%v, %v, %v) error = %v, wantErr %v", test.driver, test.mode, test.keyColumns, err, test.wantErr)
+			continue
+		}
+		if insert != test.insert || suffix != test.suffix {
+			t.Errorf("conflictClauses(%v, %v, %v) = %q, %q, want %q, %q", test.driver, test.mode, test.keyColumns, insert, suffix, test.insert, test.suffix)
+		}
+	}
+}
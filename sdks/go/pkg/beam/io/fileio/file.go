@@ -113,6 +113,25 @@ func newDecompressionReader(
 	}
 }
 
+// newCompressionWriter returns an io.WriteCloser that compresses data written to writer, based
+// on the specified compression. Closing the returned writer also closes writer. If the compression
+// is compressionAuto, a non-nil error is returned.
+func newCompressionWriter(
+	writer io.WriteCloser,
+	compression compressionType,
+) (io.WriteCloser, error) {
+	switch compression {
+	case compressionAuto:
+		return nil, errors.New(
+			"compression must be resolved into a concrete type before obtaining a writer",
+		)
+	case compressionGzip:
+		return newGzipWriter(writer), nil
+	default:
+		return writer, nil
+	}
+}
+
 // Read reads the entire file into memory and returns the contents.
 func (f ReadableFile) Read(ctx context.Context) (data []byte, err error) {
 	rc, err := f.Open(ctx)
@@ -159,6 +159,60 @@ func Test_newDecompressionReader(t *testing.T) {
 	}
 }
 
+func Test_newCompressionWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		comp    compressionType
+		wantErr bool
+	}{
+		{
+			name: "Writer for uncompressed file",
+			comp: compressionUncompressed,
+		},
+		{
+			name: "Writer for gzip compressed file",
+			comp: compressionGzip,
+		},
+		{
+			name:    "Error - writer for auto compression not supported",
+			comp:    compressionAuto,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			wc := createFile(t, path)
+
+			cw, err := newCompressionWriter(wc, tt.comp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newCompressionWriter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				wc.Close()
+				return
+			}
+
+			if _, err := cw.Write([]byte("test1")); err != nil {
+				t.Fatalf("Write() error = %v, want nil", err)
+			}
+			if err := cw.Close(); err != nil {
+				t.Fatalf("Close() error = %v, want nil", err)
+			}
+
+			dr, err := newDecompressionReader(openFile(t, path), tt.comp)
+			if err != nil {
+				t.Fatalf("newDecompressionReader() error = %v, want nil", err)
+			}
+			defer dr.Close()
+
+			if err := iotest.TestReader(dr, []byte("test1")); err != nil {
+				t.Errorf("TestReader() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
 func TestReadableFile_Read(t *testing.T) {
 	dir := t.TempDir()
 	write(t, filepath.Join(dir, "file1.txt"), []byte("test1"))
@@ -58,3 +58,35 @@ func (r *gzipReader) Close() (err error) {
 
 	return r.zr.Close()
 }
+
+// gzipWriter is a wrapper around a gzip.Writer that also closes the underlying io.WriteCloser.
+type gzipWriter struct {
+	wc io.WriteCloser
+	zw *gzip.Writer
+}
+
+// newGzipWriter creates a new gzipWriter from an io.WriteCloser.
+func newGzipWriter(wc io.WriteCloser) *gzipWriter {
+	return &gzipWriter{wc: wc, zw: gzip.NewWriter(wc)}
+}
+
+// Write compresses and writes to the gzip writer.
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	return w.zw.Write(p)
+}
+
+// Close flushes and closes the gzip writer and closes the underlying io.WriteCloser.
+func (w *gzipWriter) Close() (err error) {
+	defer func() {
+		wcErr := w.wc.Close()
+		if err != nil {
+			if wcErr != nil {
+				log.Errorf(context.Background(), "error closing writer: %v", wcErr)
+			}
+			return
+		}
+		err = wcErr
+	}()
+
+	return w.zw.Close()
+}
@@ -13,7 +13,7 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package fileio provides transforms for matching and reading files.
+// Package fileio provides transforms for matching, reading and writing files.
 package fileio
 
 import (
@@ -142,23 +142,18 @@ func (fn *matchFn) ProcessElement(
 	}
 	defer fs.Close()
 
-	files, err := fs.List(ctx, glob)
+	metadata, err := listMetadata(ctx, fs, glob)
 	if err != nil {
 		return err
 	}
 
-	if len(files) == 0 {
+	if len(metadata) == 0 {
 		if !allowEmptyMatch(glob, fn.EmptyTreatment) {
 			return fmt.Errorf("no files matching pattern %q", glob)
 		}
 		return nil
 	}
 
-	metadata, err := metadataFromFiles(ctx, fs, files)
-	if err != nil {
-		return err
-	}
-
 	for _, md := range metadata {
 		emit(md)
 	}
@@ -177,6 +172,48 @@ func allowEmptyMatch(glob string, treatment emptyTreatment) bool {
 	}
 }
 
+// listMetadata returns the metadata of the files matching the glob pattern. It uses a single
+// listing if the filesystem implements filesystem.MetadataLister, and otherwise gets the size and
+// last modified time of each listed file.
+func listMetadata(
+	ctx context.Context,
+	fs filesystem.Interface,
+	glob string,
+) ([]FileMetadata, error) {
+	if lister, ok := fs.(filesystem.MetadataLister); ok {
+		infos, err := lister.ListMetadata(ctx, glob)
+		if err != nil {
+			return nil, err
+		}
+		return metadataFromInfos(infos), nil
+	}
+
+	files, err := fs.List(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadataFromFiles(ctx, fs, files)
+}
+
+func metadataFromInfos(infos []filesystem.FileInfo) []FileMetadata {
+	if len(infos) == 0 {
+		return nil
+	}
+
+	metadata := make([]FileMetadata, len(infos))
+
+	for i, info := range infos {
+		metadata[i] = FileMetadata{
+			Path:         info.Path,
+			Size:         info.Size,
+			LastModified: info.LastModified,
+		}
+	}
+
+	return metadata
+}
+
 func metadataFromFiles(
 	ctx context.Context,
 	fs filesystem.Interface,
@@ -303,6 +340,10 @@ func MatchApplyWindow() MatchContOptionFn {
 //   - DuplicateSkip: skip emitting matches that have already been observed. Defaults to true
 //   - ApplyWindow: assign each element to an individual window with a fixed size equivalent to the
 //     interval. Defaults to false, i.e. all elements will reside in the global window
+//
+// Each poll lists the files with a single listing when the filesystem implements
+// filesystem.MetadataLister, rather than requesting the size and last modified time of every
+// matching file.
 func MatchContinuously(
 	s beam.Scope,
 	glob string,
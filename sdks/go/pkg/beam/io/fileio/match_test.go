@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
@@ -306,3 +307,83 @@ func Test_metadataFromFiles(t *testing.T) {
 		})
 	}
 }
+
+// listOnlyFS hides the optional interfaces of a filesystem other than LastModifiedGetter.
+type listOnlyFS struct {
+	filesystem.Interface
+	filesystem.LastModifiedGetter
+}
+
+func Test_listMetadata(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, tf := range testFiles {
+		write(t, filepath.Join(dir, tf.filename), tf.data)
+	}
+
+	fp1 := filepath.Join(dir, "file1.txt")
+	fp2 := filepath.Join(dir, "file2.txt")
+
+	ctx := context.Background()
+	fs := local.New(ctx)
+
+	tests := []struct {
+		name string
+		fs   filesystem.Interface
+		glob string
+		want []FileMetadata
+	}{
+		{
+			name: "Metadata from MetadataLister",
+			fs:   fs,
+			glob: filepath.Join(dir, "*.txt"),
+			want: []FileMetadata{
+				{
+					Path:         fp1,
+					Size:         5,
+					LastModified: modTime(t, fp1),
+				},
+				{
+					Path:         fp2,
+					Size:         0,
+					LastModified: modTime(t, fp2),
+				},
+			},
+		},
+		{
+			name: "Metadata from List",
+			fs:   listOnlyFS{fs, fs.(filesystem.LastModifiedGetter)},
+			glob: filepath.Join(dir, "*.txt"),
+			want: []FileMetadata{
+				{
+					Path:         fp1,
+					Size:         5,
+					LastModified: modTime(t, fp1),
+				},
+				{
+					Path:         fp2,
+					Size:         0,
+					LastModified: modTime(t, fp2),
+				},
+			},
+		},
+		{
+			name: "Nil when no files match",
+			fs:   fs,
+			glob: filepath.Join(dir, "*.json"),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listMetadata(ctx, tt.fs, tt.glob)
+			if err != nil {
+				t.Fatalf("listMetadata() error = %v, want nil", err)
+			}
+
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("listMetadata() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
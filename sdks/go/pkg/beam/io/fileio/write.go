@@ -0,0 +1,531 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/funcx"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/util/reflectx"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/google/uuid"
+)
+
+func init() {
+	register.DoFn1x2[beam.X, int, beam.X](&assignShardFn{})
+	register.DoFn6x1[context.Context, beam.PaneInfo, beam.Window, int, func(*beam.X) bool, func(string), error](
+		&writeShardFn{},
+	)
+	register.DoFn1x2[beam.X, string, beam.X](&keyByDestinationFn{})
+	register.DoFn4x1[context.Context, string, func(*beam.X) bool, func(string), error](
+		&writeDestinationFn{},
+	)
+	register.DoFn4x0[context.Context, int, func(*string) bool, func(string)](&removeTempDirsFn{})
+	register.Iter1[beam.X]()
+	register.Iter1[string]()
+	register.Emitter1[string]()
+	beam.RegisterType(reflect.TypeOf((*defaultNaming)(nil)).Elem())
+}
+
+// Sink encodes elements into a single file. WriteFiles uses a new Sink for each file
+// it writes, so a Sink may keep state about the file being written, such as a buffered
+// writer or a header.
+//
+// Sinks are serialized with the WriteFiles transform, so they must be JSON serializable,
+// and their types registered with beam.RegisterType. Only the exported fields of a Sink
+// are retained.
+type Sink interface {
+	// Open prepares the Sink to write elements to w.
+	Open(ctx context.Context, w io.Writer) error
+	// Write encodes a single element to the file.
+	Write(ctx context.Context, elm any) error
+	// Flush writes any buffered data, and footers to the file. The file is closed
+	// by WriteFiles after Flush returns.
+	Flush(ctx context.Context) error
+}
+
+// FileNaming determines the name of each file written by WriteFiles, relative to
+// the output directory. Names must be unique for each window, pane and shard, as
+// files with the same name overwrite each other.
+//
+// Like Sinks, FileNamings must be JSON serializable, and their types registered with
+// beam.RegisterType.
+type FileNaming interface {
+	Filename(w beam.Window, pane beam.PaneInfo, shard, numShards int) string
+}
+
+// DefaultNaming returns a FileNaming that names files with the given prefix and suffix,
+// along with the window, pane, and shard of the file. For example, the second of three
+// shards of the first pane of a window is named:
+//
+//	<prefix>-2017-01-01T00:00:00.000Z-2017-01-02T00:00:00.000Z-pane-0-00001-of-00003<suffix>
+//
+// The window is omitted for the global window, and the pane is omitted when it is the
+// only firing for the window.
+func DefaultNaming(prefix, suffix string) FileNaming {
+	return defaultNaming{Prefix: prefix, Suffix: suffix}
+}
+
+type defaultNaming struct {
+	Prefix string `json:"prefix"`
+	Suffix string `json:"suffix"`
+}
+
+const windowTimeFormat = "2006-01-02T15:04:05.000Z"
+
+func (n defaultNaming) Filename(w beam.Window, pane beam.PaneInfo, shard, numShards int) string {
+	var b strings.Builder
+	b.WriteString(n.Prefix)
+	if iw, ok := w.(window.IntervalWindow); ok {
+		fmt.Fprintf(&b, "-%v-%v", formatWindowTime(iw.Start), formatWindowTime(iw.End))
+	}
+	if !pane.IsFirst || !pane.IsLast {
+		fmt.Fprintf(&b, "-pane-%d", pane.Index)
+		if pane.IsLast {
+			b.WriteString("-last")
+		}
+	}
+	fmt.Fprintf(&b, "-%05d-of-%05d", shard, numShards)
+	b.WriteString(n.Suffix)
+	return b.String()
+}
+
+func formatWindowTime(t mtime.Time) string {
+	return t.ToTime().UTC().Format(windowTimeFormat)
+}
+
+var destinationSig = &funcx.Signature{Args: []reflect.Type{beam.XType}, Return: []reflect.Type{reflectx.String}} // X -> string
+
+type writeOption struct {
+	NumShards   int
+	Naming      FileNaming
+	Compression compressionType
+}
+
+// WriteOptionFn is a function that can be passed to WriteFiles to configure options for
+// writing files.
+type WriteOptionFn func(*writeOption)
+
+// WriteNumShards specifies the number of files written for each window and pane.
+// Elements are spread evenly across the shards, which are written in parallel.
+func WriteNumShards(n int) WriteOptionFn {
+	return func(o *writeOption) {
+		o.NumShards = n
+	}
+}
+
+// WriteNaming specifies how output files are named.
+func WriteNaming(naming FileNaming) WriteOptionFn {
+	return func(o *writeOption) {
+		o.Naming = naming
+	}
+}
+
+// WriteAutoCompression specifies that the compression type of files should be determined by
+// their file extension.
+func WriteAutoCompression() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionAuto
+	}
+}
+
+// WriteGzip specifies that files should be compressed using gzip.
+func WriteGzip() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionGzip
+	}
+}
+
+// WriteUncompressed specifies that files should not be compressed.
+func WriteUncompressed() WriteOptionFn {
+	return func(o *writeOption) {
+		o.Compression = compressionUncompressed
+	}
+}
+
+// WriteFiles writes the elements of col to files in the directory dir, using the Sink
+// to encode the elements, and returns a PCollection<string> of the paths of the written files.
+//
+// Elements are grouped by window and trigger firing, so WriteFiles writes the files of each
+// pane as it fires, and may be used with unbounded PCollections. Each pane is spread across
+// a number of shards, with a file for each non-empty shard. Files are first written to a
+// temporary directory in dir, and only renamed to their final path once complete, so readers
+// never observe partially written files. The temporary directory is removed once all the
+// files of a pane are written, unless files of other panes are still being written to it.
+//
+// WriteFiles accepts a variadic number of WriteOptionFn that can be used to configure the
+// number of shards, the naming of the files, and their compression. By default, each pane is
+// written to a single uncompressed shard, and files are named with DefaultNaming("output", "").
+func WriteFiles(s beam.Scope, dir string, sink Sink, col beam.PCollection, opts ...WriteOptionFn) beam.PCollection {
+	s = s.Scope("fileio.WriteFiles")
+
+	filesystem.ValidateScheme(dir)
+
+	option := newWriteOption(opts)
+	if option.NumShards < 1 {
+		panic(fmt.Sprintf("fileio.WriteFiles: number of shards must be positive, got %v", option.NumShards))
+	}
+
+	keyed := beam.ParDo(s, &assignShardFn{NumShards: option.NumShards}, col)
+	grouped := beam.GroupByKey(s, keyed)
+	tempID := uuid.New().String()
+	written := beam.ParDo(s, &writeShardFn{
+		Dir:         dir,
+		NumShards:   option.NumShards,
+		Sink:        encodeValue(sink),
+		Naming:      encodeValue(option.Naming),
+		Compression: option.Compression,
+		TempID:      tempID,
+	}, grouped)
+	return finalizeWrites(s, tempID, written)
+}
+
+// WriteDynamic writes the elements of col to the files given by destFn, using the Sink to
+// encode the elements, and returns a PCollection<string> of the paths of the written files.
+// The destination function must be of the form: A -> string, where A is the type of the
+// elements of col. For example:
+//
+//	func tenantPath(e Event) string {
+//		return fmt.Sprintf("gs://bucket/%v/%v.json", e.Tenant, e.Date)
+//	}
+//
+//	// Destination functions must be registered with Beam, and must not be closures.
+//	func init() { register.Function1x1(tenantPath) }
+//
+//	fileio.WriteDynamic(s, tenantPath, sink, events)
+//
+// All the elements of a window and pane with the same destination are written to a
+// single file, which is written to a temporary directory next to it and renamed once
+// complete, as with WriteFiles. Files with the same path overwrite each other, so destinations should
+// include the window for windowed PCollections.
+//
+// WriteDynamic accepts a variadic number of WriteOptionFn that can be used to configure the
+// compression of the files. Shard and naming options are ignored, since the destination
+// determines the file name. By default, files are uncompressed.
+func WriteDynamic(s beam.Scope, destFn any, sink Sink, col beam.PCollection, opts ...WriteOptionFn) beam.PCollection {
+	s = s.Scope("fileio.WriteDynamic")
+
+	funcx.MustSatisfy(destFn, funcx.Replace(destinationSig, beam.XType, col.Type().Type()))
+	option := newWriteOption(opts)
+
+	keyed := beam.ParDo(s, &keyByDestinationFn{Destination: beam.EncodedFunc{Fn: reflectx.MakeFunc(destFn)}}, col)
+	grouped := beam.GroupByKey(s, keyed)
+	tempID := uuid.New().String()
+	written := beam.ParDo(s, &writeDestinationFn{
+		Sink:        encodeValue(sink),
+		Compression: option.Compression,
+		TempID:      tempID,
+	}, grouped)
+	return finalizeWrites(s, tempID, written)
+}
+
+// finalizeWrites removes the temporary directories of the written files once all the
+// files of a pane are written, and returns the written files.
+func finalizeWrites(s beam.Scope, tempID string, written beam.PCollection) beam.PCollection {
+	grouped := beam.GroupByKey(s, beam.AddFixedKey(s, written))
+	return beam.ParDo(s, &removeTempDirsFn{TempID: tempID}, grouped)
+}
+
+func newWriteOption(opts []WriteOptionFn) *writeOption {
+	option := &writeOption{
+		NumShards:   1,
+		Naming:      DefaultNaming("output", ""),
+		Compression: compressionUncompressed,
+	}
+	for _, opt := range opts {
+		opt(option)
+	}
+	return option
+}
+
+// assignShardFn keys elements by their shard, assigning shards round robin from a
+// random shard for each bundle.
+type assignShardFn struct {
+	NumShards int `json:"numShards"`
+
+	next int
+}
+
+func (fn *assignShardFn) StartBundle() {
+	fn.next = rand.Intn(fn.NumShards)
+}
+
+func (fn *assignShardFn) ProcessElement(elm beam.X) (int, beam.X) {
+	shard := fn.next
+	fn.next = (fn.next + 1) % fn.NumShards
+	return shard, elm
+}
+
+// writeShardFn writes the elements of a single shard of a pane to a file.
+type writeShardFn struct {
+	Dir         string          `json:"dir"`
+	NumShards   int             `json:"numShards"`
+	Sink        encodedValue    `json:"sink"`
+	Naming      encodedValue    `json:"naming"`
+	Compression compressionType `json:"compression"`
+	TempID      string          `json:"tempID"`
+
+	naming FileNaming
+}
+
+func (fn *writeShardFn) Setup() error {
+	naming, err := fn.Naming.decode()
+	if err != nil {
+		return fmt.Errorf("error decoding file naming: %v", err)
+	}
+	fn.naming = naming.(FileNaming)
+	return nil
+}
+
+func (fn *writeShardFn) ProcessElement(
+	ctx context.Context,
+	pane beam.PaneInfo,
+	w beam.Window,
+	shard int,
+	iter func(*beam.X) bool,
+	emit func(string),
+) error {
+	filename := joinPath(fn.Dir, fn.naming.Filename(w, pane, shard, fn.NumShards))
+	if err := commitFile(ctx, filename, fn.TempID, fn.Sink, fn.Compression, iter); err != nil {
+		return err
+	}
+	emit(filename)
+	return nil
+}
+
+// keyByDestinationFn keys elements by the path of the file they are written to.
+type keyByDestinationFn struct {
+	Destination beam.EncodedFunc `json:"destination"`
+
+	fn reflectx.Func1x1
+}
+
+func (fn *keyByDestinationFn) Setup() {
+	fn.fn = reflectx.ToFunc1x1(fn.Destination.Fn)
+}
+
+func (fn *keyByDestinationFn) ProcessElement(elm beam.X) (string, beam.X) {
+	return fn.fn.Call1x1(elm).(string), elm
+}
+
+// writeDestinationFn writes the elements of a single destination of a pane to a file.
+type writeDestinationFn struct {
+	Sink        encodedValue    `json:"sink"`
+	Compression compressionType `json:"compression"`
+	TempID      string          `json:"tempID"`
+}
+
+func (fn *writeDestinationFn) ProcessElement(
+	ctx context.Context,
+	filename string,
+	iter func(*beam.X) bool,
+	emit func(string),
+) error {
+	if err := commitFile(ctx, filename, fn.TempID, fn.Sink, fn.Compression, iter); err != nil {
+		return err
+	}
+	emit(filename)
+	return nil
+}
+
+// removeTempDirsFn removes the temporary directories of all the files written in a pane,
+// and emits the written files.
+type removeTempDirsFn struct {
+	TempID string `json:"tempID"`
+}
+
+func (fn *removeTempDirsFn) ProcessElement(ctx context.Context, _ int, iter func(*string) bool, emit func(string)) {
+	dirs := make(map[string]bool)
+	var filename string
+	for iter(&filename) {
+		dirs[tempDir(filename, fn.TempID)] = true
+		emit(filename)
+	}
+	for dir := range dirs {
+		fs, err := filesystem.New(ctx, dir)
+		if err != nil {
+			log.Warnf(ctx, "error removing temporary directory %q: %v", dir, err)
+			continue
+		}
+		// Removing the directory fails if files of other panes are still being written
+		// to it, or if the file system has no directories, both of which are expected.
+		if rm, ok := fs.(filesystem.Remover); ok {
+			if err := rm.Remove(ctx, dir); err != nil {
+				log.Debugf(ctx, "temporary directory %q not removed: %v", dir, err)
+			}
+		}
+		fs.Close()
+	}
+}
+
+// tempDir returns the temporary directory that filename is written to before it's renamed,
+// which is next to filename.
+func tempDir(filename, tempID string) string {
+	return joinPath(parentPath(filename), ".temp-beam-"+tempID)
+}
+
+// commitFile writes the elements to a temporary file in the temporary directory for tempID,
+// with a new copy of the encoded sink, and renames it to filename once complete.
+func commitFile(
+	ctx context.Context,
+	filename, tempID string,
+	encodedSink encodedValue,
+	compression compressionType,
+	iter func(*beam.X) bool,
+) error {
+	v, err := encodedSink.decode()
+	if err != nil {
+		return fmt.Errorf("error decoding sink: %v", err)
+	}
+	sink := v.(Sink)
+
+	if compression == compressionAuto {
+		compression = compressionFromExt(filename)
+	}
+	// Each attempt writes to a distinct temporary file, so retried or duplicate
+	// attempts don't interfere with each other.
+	tmpname := joinPath(tempDir(filename, tempID), uuid.New().String())
+
+	fs, err := filesystem.New(ctx, filename)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	log.Infof(ctx, "Writing to %v", filename)
+
+	if err := writeFile(ctx, fs, tmpname, sink, compression, iter); err != nil {
+		removeTemp(ctx, fs, tmpname)
+		return err
+	}
+	if err := filesystem.Rename(ctx, fs, tmpname, filename); err != nil {
+		removeTemp(ctx, fs, tmpname)
+		return fmt.Errorf("error renaming %q to %q: %v", tmpname, filename, err)
+	}
+	return nil
+}
+
+// writeFile writes all the elements to the named file with the sink.
+func writeFile(
+	ctx context.Context,
+	fs filesystem.Interface,
+	filename string,
+	sink Sink,
+	compression compressionType,
+	iter func(*beam.X) bool,
+) (err error) {
+	wc, err := fs.OpenWrite(ctx, filename)
+	if err != nil {
+		return err
+	}
+	fd, err := newCompressionWriter(wc, compression)
+	if err != nil {
+		wc.Close()
+		return err
+	}
+
+	defer func() {
+		closeErr := fd.Close()
+		if err != nil {
+			if closeErr != nil {
+				log.Errorf(ctx, "error closing writer: %v", closeErr)
+			}
+			return
+		}
+		err = closeErr
+	}()
+
+	if err := sink.Open(ctx, fd); err != nil {
+		return err
+	}
+	var elm beam.X
+	for iter(&elm) {
+		if err := sink.Write(ctx, elm); err != nil {
+			return err
+		}
+	}
+	return sink.Flush(ctx)
+}
+
+// removeTemp removes a temporary file after a failed write, if the file system supports it.
+func removeTemp(ctx context.Context, fs filesystem.Interface, filename string) {
+	rm, ok := fs.(filesystem.Remover)
+	if !ok {
+		return
+	}
+	if err := rm.Remove(ctx, filename); err != nil {
+		log.Warnf(ctx, "error removing temporary file %q: %v", filename, err)
+	}
+}
+
+// parentPath returns the directory of a file path, which may include a scheme,
+// including the trailing separator.
+func parentPath(path string) string {
+	if i := strings.LastIndexAny(path, "/\\"); i >= 0 {
+		return path[:i+1]
+	}
+	return ""
+}
+
+// joinPath joins a file name to a directory path, which may include a scheme.
+func joinPath(dir, name string) string {
+	if dir == "" || strings.HasSuffix(dir, "/") || strings.HasSuffix(dir, "\\") {
+		return dir + name
+	}
+	return dir + "/" + name
+}
+
+// encodedValue is a JSON serializable representation of a value of a registered type,
+// such as a Sink or FileNaming. Each decode returns a new copy of the value.
+type encodedValue struct {
+	Type beam.EncodedType `json:"type"`
+	Data []byte           `json:"data"`
+}
+
+func encodeValue(v any) encodedValue {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("unable to encode %T as JSON: %v", v, err))
+	}
+	return encodedValue{Type: beam.EncodedType{T: reflect.TypeOf(v)}, Data: data}
+}
+
+func (e encodedValue) decode() (any, error) {
+	t := e.Type.T
+	if t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem())
+		if err := json.Unmarshal(e.Data, v.Interface()); err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(e.Data, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
@@ -0,0 +1,282 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileio
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func init() {
+	beam.RegisterType(reflect.TypeOf((*lineSink)(nil)).Elem())
+	register.Function1x2[string, beam.EventTime, string](timestampLine)
+	register.Function1x1[string, bool](isThreeShardName)
+	register.Function1x1[string, string](destinationOfLine)
+}
+
+// lineSink writes string elements as lines, after an optional header.
+type lineSink struct {
+	Header string
+
+	w *bufio.Writer
+}
+
+func (s *lineSink) Open(_ context.Context, w io.Writer) error {
+	s.w = bufio.NewWriter(w)
+	if s.Header != "" {
+		_, err := s.w.WriteString(s.Header + "\n")
+		return err
+	}
+	return nil
+}
+
+func (s *lineSink) Write(_ context.Context, elm any) error {
+	_, err := s.w.WriteString(elm.(string) + "\n")
+	return err
+}
+
+func (s *lineSink) Flush(_ context.Context) error {
+	return s.w.Flush()
+}
+
+// lineTimes are the timestamps of the lines in TestWriteFiles_Windowed.
+var lineTimes = map[string]int64{"a": 0, "b": 30000, "c": 90000}
+
+func timestampLine(line string) (beam.EventTime, string) {
+	return mtime.FromMilliseconds(lineTimes[line]), line
+}
+
+func isThreeShardName(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), "output-0000") && strings.HasSuffix(path, "-of-00003")
+}
+
+// readLines reads the lines of all the files in dir, and returns them sorted.
+func readLines(t *testing.T, dir string) (files []string, lines []string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		files = append(files, e.Name())
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")...)
+	}
+	sort.Strings(lines)
+	return files, lines
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	in := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.CreateList(s, in)
+	written := WriteFiles(s, dir, &lineSink{}, col, WriteNumShards(3))
+	passert.True(s, written, isThreeShardName)
+	ptest.RunAndValidate(t, p)
+
+	files, lines := readLines(t, dir)
+	if len(files) == 0 || len(files) > 3 {
+		t.Errorf("WriteFiles wrote %v files, want between 1 and 3: %v", len(files), files)
+	}
+	if d := cmp.Diff(in, lines); d != "" {
+		t.Errorf("WriteFiles lines mismatch (-want, +got):\n%v", d)
+	}
+}
+
+func TestWriteFiles_Windowed(t *testing.T) {
+	dir := t.TempDir()
+
+	p, s := beam.NewPipelineWithRoot()
+	stamped := beam.ParDo(s, timestampLine, beam.Create(s, "a", "b", "c"))
+	windowed := beam.WindowInto(s, window.NewFixedWindows(time.Minute), stamped)
+	WriteFiles(s, dir, &lineSink{Header: "header"}, windowed, WriteNaming(DefaultNaming("part", ".txt")))
+	ptest.RunAndValidate(t, p)
+
+	files, got := readLines(t, dir)
+	wantFiles := []string{
+		"part-1970-01-01T00:00:00.000Z-1970-01-01T00:01:00.000Z-00000-of-00001.txt",
+		"part-1970-01-01T00:01:00.000Z-1970-01-01T00:02:00.000Z-00000-of-00001.txt",
+	}
+	if d := cmp.Diff(wantFiles, files); d != "" {
+		t.Errorf("WriteFiles files mismatch (-want, +got):\n%v", d)
+	}
+	if d := cmp.Diff([]string{"a", "b", "c", "header", "header"}, got); d != "" {
+		t.Errorf("WriteFiles lines mismatch (-want, +got):\n%v", d)
+	}
+}
+
+// writeDir is the output directory of TestWriteDynamic, for destinationOfLine.
+var writeDir string
+
+func destinationOfLine(line string) string {
+	return filepath.Join(writeDir, line[:1]+".txt.gz")
+}
+
+func TestWriteDynamic(t *testing.T) {
+	writeDir = t.TempDir()
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Create(s, "a1", "b1", "a2", "c1", "a3")
+	written := WriteDynamic(s, destinationOfLine, &lineSink{}, col, WriteAutoCompression())
+	passert.Equals(s, written,
+		filepath.Join(writeDir, "a.txt.gz"),
+		filepath.Join(writeDir, "b.txt.gz"),
+		filepath.Join(writeDir, "c.txt.gz"),
+	)
+	ptest.RunAndValidate(t, p)
+
+	// Only the written files remain, without the temporary directory.
+	entries, err := os.ReadDir(writeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(entries), 3; got != want {
+		t.Errorf("WriteDynamic left %v entries in %v, want %v: %v", got, writeDir, want, entries)
+	}
+
+	want := map[string][]string{
+		"a.txt.gz": {"a1", "a2", "a3"},
+		"b.txt.gz": {"b1"},
+		"c.txt.gz": {"c1"},
+	}
+	got := map[string][]string{}
+	for name := range want {
+		data, err := ReadableFile{Metadata: FileMetadata{Path: filepath.Join(writeDir, name)}}.ReadString(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+		sort.Strings(lines)
+		got[name] = lines
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("WriteDynamic files mismatch (-want, +got):\n%v", d)
+	}
+}
+
+func TestDefaultNaming(t *testing.T) {
+	iw := window.IntervalWindow{Start: 0, End: mtime.FromMilliseconds(3600000)}
+	tests := []struct {
+		name string
+		w    beam.Window
+		pane beam.PaneInfo
+		want string
+	}{
+		{
+			name: "global window, only pane",
+			w:    window.GlobalWindow{},
+			pane: typex.NoFiringPane(),
+			want: "out-00001-of-00003.txt",
+		},
+		{
+			name: "interval window, only pane",
+			w:    iw,
+			pane: typex.NoFiringPane(),
+			want: "out-1970-01-01T00:00:00.000Z-1970-01-01T01:00:00.000Z-00001-of-00003.txt",
+		},
+		{
+			name: "interval window, first pane",
+			w:    iw,
+			pane: typex.PaneInfo{Timing: typex.PaneEarly, IsFirst: true, Index: 0},
+			want: "out-1970-01-01T00:00:00.000Z-1970-01-01T01:00:00.000Z-pane-0-00001-of-00003.txt",
+		},
+		{
+			name: "interval window, last pane",
+			w:    iw,
+			pane: typex.PaneInfo{Timing: typex.PaneOnTime, IsLast: true, Index: 2},
+			want: "out-1970-01-01T00:00:00.000Z-1970-01-01T01:00:00.000Z-pane-2-last-00001-of-00003.txt",
+		},
+	}
+	naming := DefaultNaming("out", ".txt")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := naming.Filename(tt.w, tt.pane, 1, 3); got != tt.want {
+				t.Errorf("Filename() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_encodedValue(t *testing.T) {
+	want := &lineSink{Header: "h"}
+	v, err := encodeValue(want).decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := v.(*lineSink)
+	if !ok {
+		t.Fatalf("decode() returned %T, want *lineSink", v)
+	}
+	if got == want || got.Header != want.Header {
+		t.Errorf("decode() = %+v, want a copy of %+v", got, want)
+	}
+}
+
+func Test_parentPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"dir/file", "dir/"},
+		{"/file", "/"},
+		{"gs://bucket/dir/file", "gs://bucket/dir/"},
+		{"file", ""},
+	}
+	for _, tt := range tests {
+		if got := parentPath(tt.path); got != tt.want {
+			t.Errorf("parentPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_joinPath(t *testing.T) {
+	tests := []struct {
+		dir, name, want string
+	}{
+		{"dir", "file", "dir/file"},
+		{"dir/", "file", "dir/file"},
+		{"gs://bucket", "file", "gs://bucket/file"},
+		{"", "file", "file"},
+	}
+	for _, tt := range tests {
+		if got := joinPath(tt.dir, tt.name); got != tt.want {
+			t.Errorf("joinPath(%q, %q) = %v, want %v", tt.dir, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package azblob contains an Azure Blob Storage implementation of the Beam file system.
+//
+// Files are addressed as 'azblob://container/blob'. The storage account is configured from the
+// environment: AZURE_STORAGE_CONNECTION_STRING takes precedence, which also allows using local
+// emulators such as Azurite. Otherwise AZURE_STORAGE_ACCOUNT names the account, which is accessed
+// with the key in AZURE_STORAGE_KEY if set, and with the default Azure credential chain otherwise.
+package azblob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/util/fsx"
+)
+
+const (
+	connectionStringEnv = "AZURE_STORAGE_CONNECTION_STRING"
+	accountEnv          = "AZURE_STORAGE_ACCOUNT"
+	keyEnv              = "AZURE_STORAGE_KEY"
+)
+
+// copyPollInterval is the interval at which the status of pending copies is checked.
+var copyPollInterval = 500 * time.Millisecond
+
+func init() {
+	filesystem.Register("azblob", New)
+}
+
+type fs struct {
+	client *azblob.Client
+}
+
+// New creates a new Azure Blob Storage filesystem for the storage account configured in the
+// environment.
+func New(_ context.Context) filesystem.Interface {
+	client, err := newClient()
+	if err != nil {
+		panic(fmt.Sprintf("error creating Azure Blob Storage client: %v", err))
+	}
+	return &fs{client: client}
+}
+
+func newClient() (*azblob.Client, error) {
+	if connectionString := os.Getenv(connectionStringEnv); connectionString != "" {
+		return azblob.NewClientFromConnectionString(connectionString, nil)
+	}
+
+	account := os.Getenv(accountEnv)
+	if account == "" {
+		return nil, fmt.Errorf("either %s or %s must be set", connectionStringEnv, accountEnv)
+	}
+	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+
+	if key := os.Getenv(keyEnv); key != "" {
+		cred, err := azblob.NewSharedKeyCredential(account, key)
+		if err != nil {
+			return nil, err
+		}
+		return azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, err
+	}
+	return azblob.NewClient(serviceURL, cred, nil)
+}
+
+// Close closes the filesystem.
+func (f *fs) Close() error {
+	return nil
+}
+
+// List returns a slice of the files in the filesystem that match the glob pattern.
+func (f *fs) List(ctx context.Context, glob string) ([]string, error) {
+	container, blobs, err := f.listBlobs(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blobs) == 0 {
+		return nil, nil
+	}
+
+	uris := make([]string, len(blobs))
+	for i, item := range blobs {
+		uris[i] = makeURI(container, valueOrZero(item.Name))
+	}
+
+	return uris, nil
+}
+
+// ListMetadata returns a slice of the files in the filesystem that match the glob pattern, along
+// with their sizes and last modified times.
+func (f *fs) ListMetadata(ctx context.Context, glob string) ([]filesystem.FileInfo, error) {
+	container, blobs, err := f.listBlobs(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blobs) == 0 {
+		return nil, nil
+	}
+
+	infos := make([]filesystem.FileInfo, len(blobs))
+	for i, item := range blobs {
+		infos[i] = filesystem.FileInfo{Path: makeURI(container, valueOrZero(item.Name))}
+		if props := item.Properties; props != nil {
+			infos[i].Size = valueOrZero(props.ContentLength)
+			infos[i].LastModified = valueOrZero(props.LastModified)
+		}
+	}
+
+	return infos, nil
+}
+
+// listBlobs returns the container and the blobs in it whose names match the glob pattern.
+func (f *fs) listBlobs(ctx context.Context, glob string) (string, []*container.BlobItem, error) {
+	containerName, pattern, err := parseURI(glob)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing Azure Blob Storage uri %s: %v", glob, err)
+	}
+
+	options := &azblob.ListBlobsFlatOptions{
+		Prefix: to.Ptr(fsx.GetPrefix(pattern)),
+	}
+	pager := f.client.NewListBlobsFlatPager(containerName, options)
+
+	var blobs []*container.BlobItem
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return "", nil, fmt.Errorf("error listing blobs: %v", err)
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			match, err := filepath.Match(pattern, valueOrZero(item.Name))
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid blob pattern: %s", pattern)
+			}
+
+			if match {
+				blobs = append(blobs, item)
+			}
+		}
+	}
+
+	return containerName, blobs, nil
+}
+
+// OpenRead returns a new io.ReadCloser to read contents from the file. The caller must call Close
+// on the returned io.ReadCloser when done reading.
+func (f *fs) OpenRead(ctx context.Context, filename string) (io.ReadCloser, error) {
+	containerName, blobName, err := parseURI(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Azure Blob Storage uri %s: %v", filename, err)
+	}
+
+	resp, err := f.client.DownloadStream(ctx, containerName, blobName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading blob %s: %w", filename, notExist(err))
+	}
+
+	return resp.Body, nil
+}
+
+// OpenWrite returns a new io.WriteCloser to write contents to the file. The caller must call Close
+// on the returned io.WriteCloser when done writing.
+func (f *fs) OpenWrite(ctx context.Context, filename string) (io.WriteCloser, error) {
+	containerName, blobName, err := parseURI(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Azure Blob Storage uri %s: %v", filename, err)
+	}
+
+	return newWriter(ctx, f.client, containerName, blobName), nil
+}
+
+// Size returns the size of the file.
+func (f *fs) Size(ctx context.Context, filename string) (int64, error) {
+	props, err := f.properties(ctx, filename)
+	if err != nil {
+		return -1, err
+	}
+
+	if props.ContentLength == nil {
+		return -1, fmt.Errorf("content length for blob %s was nil", filename)
+	}
+
+	return *props.ContentLength, nil
+}
+
+// LastModified returns the time at which the file was last modified.
+func (f *fs) LastModified(ctx context.Context, filename string) (time.Time, error) {
+	props, err := f.properties(ctx, filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return valueOrZero(props.LastModified), nil
+}
+
+func (f *fs) properties(ctx context.Context, filename string) (blob.GetPropertiesResponse, error) {
+	client, err := f.blobClient(filename)
+	if err != nil {
+		return blob.GetPropertiesResponse{}, err
+	}
+
+	props, err := client.GetProperties(ctx, nil)
+	if err != nil {
+		return blob.GetPropertiesResponse{}, fmt.Errorf("error getting properties of blob %s: %w", filename, notExist(err))
+	}
+
+	return props, nil
+}
+
+// Remove removes the file from the filesystem.
+func (f *fs) Remove(ctx context.Context, filename string) error {
+	containerName, blobName, err := parseURI(filename)
+	if err != nil {
+		return fmt.Errorf("error parsing Azure Blob Storage uri %s: %v", filename, err)
+	}
+
+	if _, err := f.client.DeleteBlob(ctx, containerName, blobName, nil); err != nil {
+		return fmt.Errorf("error deleting blob %s: %w", filename, notExist(err))
+	}
+
+	return nil
+}
+
+// Copy copies the file from the old path to the new path. Copies are performed by the service,
+// and Copy waits for them to complete.
+func (f *fs) Copy(ctx context.Context, oldpath, newpath string) error {
+	source, err := f.blobClient(oldpath)
+	if err != nil {
+		return err
+	}
+	dest, err := f.blobClient(newpath)
+	if err != nil {
+		return err
+	}
+
+	resp, err := dest.StartCopyFromURL(ctx, source.URL(), nil)
+	if err != nil {
+		return fmt.Errorf("error copying blob %s: %w", oldpath, notExist(err))
+	}
+
+	status := valueOrZero(resp.CopyStatus)
+	for status == blob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(copyPollInterval):
+		}
+
+		props, err := dest.GetProperties(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("error getting copy status of blob %s: %v", newpath, err)
+		}
+		status = valueOrZero(props.CopyStatus)
+	}
+
+	if status != "" && status != blob.CopyStatusTypeSuccess {
+		return fmt.Errorf("error copying blob %s: copy status %v", oldpath, status)
+	}
+
+	return nil
+}
+
+// Rename renames the file from the old path to the new path, by copying it and removing the old
+// file.
+func (f *fs) Rename(ctx context.Context, oldpath, newpath string) error {
+	if err := f.Copy(ctx, oldpath, newpath); err != nil {
+		return err
+	}
+
+	return f.Remove(ctx, oldpath)
+}
+
+func (f *fs) blobClient(filename string) (*blob.Client, error) {
+	containerName, blobName, err := parseURI(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Azure Blob Storage uri %s: %v", filename, err)
+	}
+
+	return f.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobName), nil
+}
+
+// notExist wraps errors for missing blobs and containers so that they match os.ErrNotExist.
+func notExist(err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return errors.Join(os.ErrNotExist, err)
+	}
+	return err
+}
+
+// Compile time check for interface implementations.
+var (
+	_ filesystem.LastModifiedGetter = (*fs)(nil)
+	_ filesystem.Remover            = (*fs)(nil)
+	_ filesystem.Copier             = (*fs)(nil)
+	_ filesystem.Renamer            = (*fs)(nil)
+	_ filesystem.MetadataLister     = (*fs)(nil)
+)
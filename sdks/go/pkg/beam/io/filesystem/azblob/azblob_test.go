@@ -0,0 +1,54 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+)
+
+// azuriteConnectionString is the well known connection string of the Azurite emulator.
+const azuriteConnectionString = "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;" +
+	"AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==;" +
+	"BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;"
+
+func TestAzBlob_FilesystemNew(t *testing.T) {
+	t.Setenv(connectionStringEnv, azuriteConnectionString)
+
+	ctx := context.Background()
+	path := "azblob://container/"
+	c, err := filesystem.New(ctx, path)
+	if err != nil {
+		t.Errorf("filesystem.New(ctx, %q) = %v, want nil", path, err)
+	}
+	if _, ok := c.(*fs); !ok {
+		t.Errorf("filesystem.New(ctx, %q) type = %T, want *azblob.fs", path, c)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("c.Close() = %v, want nil", err)
+	}
+}
+
+func Test_newClient_MissingAccount(t *testing.T) {
+	t.Setenv(connectionStringEnv, "")
+	t.Setenv(accountEnv, "")
+
+	if _, err := newClient(); err == nil {
+		t.Error("newClient() error = nil, want error")
+	}
+}
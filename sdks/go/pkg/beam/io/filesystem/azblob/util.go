@@ -0,0 +1,54 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parseURI deconstructs the Azure Blob Storage uri in the format 'azblob://container/blob' to
+// (container, blob). The uri isn't parsed as a URL, so that blob patterns may contain '?'
+// wildcards.
+func parseURI(uri string) (string, string, error) {
+	rest, ok := strings.CutPrefix(uri, "azblob://")
+	if !ok {
+		return "", "", errors.New("scheme must be 'azblob'")
+	}
+
+	container, blob, _ := strings.Cut(rest, "/")
+	if container == "" {
+		return "", "", errors.New("container must not be empty")
+	}
+
+	return container, blob, nil
+}
+
+// makeURI constructs an Azure Blob Storage uri from the container and blob to the format
+// 'azblob://container/blob'
+func makeURI(container string, blob string) string {
+	return fmt.Sprintf("azblob://%s/%s", container, blob)
+}
+
+// valueOrZero returns the value p points to, or the zero value if p is nil.
+func valueOrZero[T any](p *T) T {
+	if p == nil {
+		var zero T
+		return zero
+	}
+	return *p
+}
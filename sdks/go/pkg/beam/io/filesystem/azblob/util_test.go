@@ -0,0 +1,82 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import "testing"
+
+func Test_parseURI(t *testing.T) {
+	tests := []struct {
+		name          string
+		uri           string
+		wantContainer string
+		wantBlob      string
+		wantErr       bool
+	}{
+		{
+			name:          "Valid uri with non-empty blob",
+			uri:           "azblob://container/path/to/blob",
+			wantContainer: "container",
+			wantBlob:      "path/to/blob",
+		},
+		{
+			name:          "Valid uri with wildcards",
+			uri:           "azblob://container/path/file-?.txt",
+			wantContainer: "container",
+			wantBlob:      "path/file-?.txt",
+		},
+		{
+			name:          "Valid uri with empty blob",
+			uri:           "azblob://container",
+			wantContainer: "container",
+		},
+		{
+			name:    "Invalid uri: missing scheme",
+			uri:     "container/path/to/blob",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid uri: wrong scheme",
+			uri:     "s3://container/path/to/blob",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid uri: missing container",
+			uri:     "azblob://",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotContainer, gotBlob, err := parseURI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseURI() err = %v, want %v", err, tt.wantErr)
+			}
+			if gotContainer != tt.wantContainer {
+				t.Errorf("parseURI() container = %v, want %v", gotContainer, tt.wantContainer)
+			}
+			if gotBlob != tt.wantBlob {
+				t.Errorf("parseURI() blob = %v, want %v", gotBlob, tt.wantBlob)
+			}
+		})
+	}
+}
+
+func Test_makeURI(t *testing.T) {
+	if got, want := makeURI("container", "path/blob"), "azblob://container/path/blob"; got != want {
+		t.Errorf("makeURI() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblob
+
+import (
+	"context"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+)
+
+type writer struct {
+	ctx       context.Context
+	client    *azblob.Client
+	container string
+	blob      string
+	done      chan struct{}
+	isOpened  bool
+	pw        *io.PipeWriter
+	err       error
+}
+
+// newWriter returns a writer that creates and writes to a block blob. If a blob with the same
+// name already exists in the container, it will be overwritten. The caller must call Close on the
+// writer when done writing for the blob to become available.
+func newWriter(
+	ctx context.Context,
+	client *azblob.Client,
+	container string,
+	blob string,
+) *writer {
+	return &writer{
+		ctx:       ctx,
+		client:    client,
+		container: container,
+		blob:      blob,
+		done:      make(chan struct{}),
+	}
+}
+
+// Write writes data to a pipe.
+func (w *writer) Write(p []byte) (int, error) {
+	if !w.isOpened {
+		w.open()
+	}
+
+	return w.pw.Write(p)
+}
+
+// Close completes the write operation.
+func (w *writer) Close() error {
+	if !w.isOpened {
+		w.open()
+	}
+
+	if err := w.pw.Close(); err != nil {
+		return err
+	}
+
+	<-w.done
+	return w.err
+}
+
+// open creates a pipe for uploading to the blob.
+func (w *writer) open() {
+	pr, pw := io.Pipe()
+	w.pw = pw
+
+	go func() {
+		defer close(w.done)
+
+		if _, err := w.client.UploadStream(w.ctx, w.container, w.blob, pr, nil); err != nil {
+			w.err = err
+			pr.CloseWithError(err)
+		}
+	}()
+
+	w.isOpened = true
+}
@@ -18,7 +18,8 @@
 //
 // Registered file systems at minimum implement the Interface abstraction, and
 // can then optionally implement Remover, Renamer, and Copier to support
-// rename operations. Filesystems are only expected to handle their own IO, and
+// rename operations, and MetadataLister to support listing files along with
+// their metadata. Filesystems are only expected to handle their own IO, and
 // not cross file system IO. Should cross file system IO be required, additional
 // utility methods should be added to this package to support them.
 package filesystem
@@ -42,6 +43,8 @@ var wellKnownSchemeImportPaths = map[string]string{
 	"default": "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local",
 	"gs":      "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/gcs",
 	"s3":      "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/s3",
+	"azblob":  "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/azblob",
+	"webhdfs": "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/webhdfs",
 }
 
 // Register registers a file system backend under the given scheme.  For
@@ -115,6 +118,22 @@ type Renamer interface {
 	Rename(ctx context.Context, oldpath, newpath string) error
 }
 
+// FileInfo describes a file returned by a MetadataLister.
+type FileInfo struct {
+	Path         string
+	Size         int64
+	LastModified time.Time
+}
+
+// MetadataLister is an interface for expanding a pattern to the matching files
+// along with their sizes and last modified times, in a single listing rather
+// than a request per file.
+type MetadataLister interface {
+	// ListMetadata expands a pattern to a list of files.
+	// Returns nil if there are no matching files.
+	ListMetadata(ctx context.Context, glob string) ([]FileInfo, error)
+}
+
 func getScheme(path string) string {
 	if index := strings.Index(path, "://"); index > 0 {
 		return path[:index]
@@ -102,12 +102,46 @@ func (f *fs) Close() error {
 }
 
 func (f *fs) List(ctx context.Context, glob string) ([]string, error) {
-	bucket, object, err := gcsx.ParseObject(glob)
+	bucket, objs, err := f.listObjects(ctx, glob)
 	if err != nil {
 		return nil, err
 	}
 
-	var candidates []string
+	var ret []string
+	for _, obj := range objs {
+		ret = append(ret, fmt.Sprintf("gs://%v/%v", bucket, obj.Name))
+	}
+	return ret, nil
+}
+
+// ListMetadata returns the files matching the pattern along with their sizes
+// and last modified times, from the attributes of a single object listing.
+func (f *fs) ListMetadata(ctx context.Context, glob string) ([]filesystem.FileInfo, error) {
+	bucket, objs, err := f.listObjects(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []filesystem.FileInfo
+	for _, obj := range objs {
+		ret = append(ret, filesystem.FileInfo{
+			Path:         fmt.Sprintf("gs://%v/%v", bucket, obj.Name),
+			Size:         obj.Size,
+			LastModified: obj.Updated,
+		})
+	}
+	return ret, nil
+}
+
+// listObjects returns the bucket and the attributes of the objects matching
+// the pattern.
+func (f *fs) listObjects(ctx context.Context, glob string) (string, []*storage.ObjectAttrs, error) {
+	bucket, object, err := gcsx.ParseObject(glob)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var candidates []*storage.ObjectAttrs
 
 	// We handle globs by list all candidates and matching them here.
 	// For now, we assume * is the first matching character to make a
@@ -122,23 +156,18 @@ func (f *fs) List(ctx context.Context, glob string) ([]string, error) {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 
 		match, err := filepath.Match(object, obj.Name)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		if match {
-			candidates = append(candidates, obj.Name)
+			candidates = append(candidates, obj)
 		}
 	}
-
-	var ret []string
-	for _, obj := range candidates {
-		ret = append(ret, fmt.Sprintf("gs://%v/%v", bucket, obj))
-	}
-	return ret, nil
+	return bucket, candidates, nil
 }
 
 func (f *fs) OpenRead(ctx context.Context, filename string) (io.ReadCloser, error) {
@@ -250,4 +279,5 @@ var (
 	_ filesystem.Remover            = ((*fs)(nil))
 	_ filesystem.Copier             = ((*fs)(nil))
 	_ filesystem.Renamer            = ((*fs)(nil))
+	_ filesystem.MetadataLister     = ((*fs)(nil))
 )
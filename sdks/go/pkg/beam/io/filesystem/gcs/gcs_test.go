@@ -26,6 +26,7 @@ import (
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/fsouza/fake-gcs-server/fakestorage"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestGCS_FilesystemNew(t *testing.T) {
@@ -181,6 +182,43 @@ func TestGCS_lastModified(t *testing.T) {
 	}
 }
 
+func TestGCS_listMetadata(t *testing.T) {
+	ctx := context.Background()
+	server := createFakeGCSServer(t)
+	gcsFS := &fs{client: server.Client()}
+
+	dirPath := "gs://beamgogcsfilesystemtest"
+	files := map[string]string{"foo.txt": "foo", "foobar.txt": "foobar", "baz.txt": "baz"}
+
+	t1 := time.Now()
+	for name, content := range files {
+		filePath := dirPath + "/" + name
+		if err := filesystem.Write(ctx, gcsFS, filePath, []byte(content)); err != nil {
+			t.Fatalf("filesystem.Write(ctx, %q) error = %v, want nil", filePath, err)
+		}
+	}
+	t2 := time.Now()
+
+	glob := dirPath + "/foo*"
+	got, err := gcsFS.ListMetadata(ctx, glob)
+	if err != nil {
+		t.Fatalf("ListMetadata(%q) error = %v, want nil", glob, err)
+	}
+
+	want := []filesystem.FileInfo{
+		{Path: dirPath + "/foo.txt", Size: 3},
+		{Path: dirPath + "/foobar.txt", Size: 6},
+	}
+	if d := cmp.Diff(want, got, cmpopts.IgnoreFields(filesystem.FileInfo{}, "LastModified")); d != "" {
+		t.Errorf("ListMetadata(%q) mismatch (-want +got):\n%s", glob, d)
+	}
+	for _, info := range got {
+		if info.LastModified.Before(t1) || info.LastModified.After(t2) {
+			t.Errorf("ListMetadata(%q) LastModified of %q = %v, want in range [%v, %v]", glob, info.Path, info.LastModified, t1, t2)
+		}
+	}
+}
+
 func TestGCS_rename(t *testing.T) {
 	ctx := context.Background()
 	dirPath := "gs://beamgogcsfilesystemtest"
@@ -45,6 +45,28 @@ func (f *fs) List(_ context.Context, glob string) ([]string, error) {
 	return filepath.Glob(glob)
 }
 
+// ListMetadata returns the files matching the pattern along with their sizes
+// and last modified times.
+func (f *fs) ListMetadata(_ context.Context, glob string) ([]filesystem.FileInfo, error) {
+	files, err := filepath.Glob(glob)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	ret := make([]filesystem.FileInfo, 0, len(files))
+	for _, filename := range files {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, filesystem.FileInfo{
+			Path:         filename,
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+	}
+	return ret, nil
+}
+
 func (f *fs) OpenRead(_ context.Context, filename string) (io.ReadCloser, error) {
 	return os.Open(filename)
 }
@@ -110,4 +132,5 @@ var (
 	_ filesystem.Copier             = ((*fs)(nil))
 	_ filesystem.Remover            = ((*fs)(nil))
 	_ filesystem.Renamer            = ((*fs)(nil))
+	_ filesystem.MetadataLister     = ((*fs)(nil))
 )
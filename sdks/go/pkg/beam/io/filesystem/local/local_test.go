@@ -26,6 +26,7 @@ import (
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestLocal_FilesystemNew(t *testing.T) {
@@ -154,6 +155,48 @@ func TestLocal_listNoMatches(t *testing.T) {
 	}
 }
 
+func TestLocal_listMetadata(t *testing.T) {
+	ctx := context.Background()
+	localFS := &fs{}
+
+	dir := t.TempDir()
+	files := map[string]string{"foo.txt": "foo", "foobar.txt": "foobar", "baz.txt": "baz"}
+
+	// Account for time skew between system and go runtime.
+	t1 := time.Now().Truncate(time.Second).Add(-time.Second)
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		if err := filesystem.Write(ctx, localFS, filePath, []byte(content)); err != nil {
+			t.Fatalf("filesystem.Write(ctx, %q) error = %v, want nil", filePath, err)
+		}
+	}
+	t2 := time.Now().Truncate(time.Second).Add(2 * time.Second)
+
+	glob := filepath.Join(dir, "foo*")
+	got, err := localFS.ListMetadata(ctx, glob)
+	if err != nil {
+		t.Fatalf("ListMetadata(%q) error = %v, want nil", glob, err)
+	}
+
+	want := []filesystem.FileInfo{
+		{Path: filepath.Join(dir, "foo.txt"), Size: 3},
+		{Path: filepath.Join(dir, "foobar.txt"), Size: 6},
+	}
+	if d := cmp.Diff(want, got, cmpopts.IgnoreFields(filesystem.FileInfo{}, "LastModified")); d != "" {
+		t.Errorf("ListMetadata(%q) mismatch (-want +got):\n%s", glob, d)
+	}
+	for _, info := range got {
+		if info.LastModified.Before(t1) || info.LastModified.After(t2) {
+			t.Errorf("ListMetadata(%q) LastModified of %q = %v, want in range [%v, %v]", glob, info.Path, info.LastModified, t1, t2)
+		}
+	}
+
+	glob = filepath.Join(dir, "bar*")
+	if got, err := localFS.ListMetadata(ctx, glob); err != nil || got != nil {
+		t.Errorf("ListMetadata(%q) = %v, %v, want nil, nil", glob, got, err)
+	}
+}
+
 func TestLocal_lastModified(t *testing.T) {
 	ctx := context.Background()
 	localFS := &fs{}
@@ -70,6 +70,33 @@ func (f *fs) List(_ context.Context, glob string) ([]string, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	return f.list(glob)
+}
+
+// ListMetadata returns the files matching the pattern along with their sizes
+// and last modified times.
+func (f *fs) ListMetadata(_ context.Context, glob string) ([]filesystem.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys, err := f.list(glob)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	ret := make([]filesystem.FileInfo, len(keys))
+	for i, k := range keys {
+		v := f.m[k]
+		ret[i] = filesystem.FileInfo{
+			Path:         k,
+			Size:         int64(len(v.Data)),
+			LastModified: v.LastModified,
+		}
+	}
+	return ret, nil
+}
+
+// list returns the sorted keys matching the pattern. The lock must be held.
+func (f *fs) list(glob string) ([]string, error) {
 	// As with other functions, the memfs:// prefix is optional.
 	globNoScheme := strings.TrimPrefix(glob, "memfs://")
 
@@ -161,6 +188,7 @@ var (
 	_ filesystem.Remover            = ((*fs)(nil))
 	_ filesystem.Renamer            = ((*fs)(nil))
 	_ filesystem.Copier             = ((*fs)(nil))
+	_ filesystem.MetadataLister     = ((*fs)(nil))
 )
 
 // write is a helper function for writing to the global store.
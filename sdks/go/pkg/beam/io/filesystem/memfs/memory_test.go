@@ -23,6 +23,7 @@ import (
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 // TestReadWrite tests that read and write from the memory filesystem
@@ -118,6 +119,45 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListMetadata(t *testing.T) {
+	ctx := context.Background()
+	fs := &fs{m: make(map[string]file)}
+
+	t1 := time.Now()
+	names := []string{"fizzbuzz", "foo", "foobar", "baz", "bazfoo"}
+	for _, name := range names {
+		file := []byte(name)
+		if err := filesystem.Write(ctx, fs, name, file); err != nil {
+			t.Fatalf("Write(%q) error = %v", name, err)
+		}
+	}
+	t2 := time.Now()
+
+	glob := "memfs://foo*"
+	got, err := fs.ListMetadata(ctx, glob)
+	if err != nil {
+		t.Fatalf("error ListMetadata(%q) = %v", glob, err)
+	}
+
+	want := []filesystem.FileInfo{
+		{Path: "memfs://foo", Size: 3},
+		{Path: "memfs://foobar", Size: 6},
+	}
+	if d := cmp.Diff(want, got, cmpopts.IgnoreFields(filesystem.FileInfo{}, "LastModified")); d != "" {
+		t.Errorf("ListMetadata(%q) mismatch (-want +got):\n%s", glob, d)
+	}
+	for _, info := range got {
+		if info.LastModified.Before(t1) || info.LastModified.After(t2) {
+			t.Errorf("ListMetadata(%q) LastModified of %q = %v, want in range [%v, %v]", glob, info.Path, info.LastModified, t1, t2)
+		}
+	}
+
+	glob = "memfs://bar*"
+	if got, err := fs.ListMetadata(ctx, glob); err != nil || got != nil {
+		t.Errorf("ListMetadata(%q) = %v, %v, want nil, nil", glob, got, err)
+	}
+}
+
 func TestListTable(t *testing.T) {
 	ctx := context.Background()
 	for _, tt := range []struct {
@@ -28,6 +28,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 func init() {
@@ -61,29 +62,58 @@ func (f *fs) List(ctx context.Context, glob string) ([]string, error) {
 		return nil, fmt.Errorf("error parsing S3 uri: %v", err)
 	}
 
-	keys, err := f.listObjectKeys(ctx, bucket, keyPattern)
+	objects, err := f.listObjects(ctx, bucket, keyPattern)
 	if err != nil {
 		return nil, fmt.Errorf("error listing object keys: %v", err)
 	}
 
-	if len(keys) == 0 {
+	if len(objects) == 0 {
 		return nil, nil
 	}
 
-	uris := make([]string, len(keys))
-	for i, key := range keys {
-		uris[i] = makeURI(bucket, key)
+	uris := make([]string, len(objects))
+	for i, object := range objects {
+		uris[i] = makeURI(bucket, aws.ToString(object.Key))
 	}
 
 	return uris, nil
 }
 
-// listObjectKeys returns a slice of the keys in the bucket that match the key pattern.
-func (f *fs) listObjectKeys(
+// ListMetadata returns a slice of the files in the filesystem that match the glob pattern, along
+// with their sizes and last modified times.
+func (f *fs) ListMetadata(ctx context.Context, glob string) ([]filesystem.FileInfo, error) {
+	bucket, keyPattern, err := parseURI(glob)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing S3 uri: %v", err)
+	}
+
+	objects, err := f.listObjects(ctx, bucket, keyPattern)
+	if err != nil {
+		return nil, fmt.Errorf("error listing objects: %v", err)
+	}
+
+	if len(objects) == 0 {
+		return nil, nil
+	}
+
+	infos := make([]filesystem.FileInfo, len(objects))
+	for i, object := range objects {
+		infos[i] = filesystem.FileInfo{
+			Path:         makeURI(bucket, aws.ToString(object.Key)),
+			Size:         aws.ToInt64(object.Size),
+			LastModified: aws.ToTime(object.LastModified),
+		}
+	}
+
+	return infos, nil
+}
+
+// listObjects returns a slice of the objects in the bucket whose keys match the key pattern.
+func (f *fs) listObjects(
 	ctx context.Context,
 	bucket string,
 	keyPattern string,
-) ([]string, error) {
+) ([]types.Object, error) {
 	prefix := fsx.GetPrefix(keyPattern)
 	params := &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucket),
@@ -91,7 +121,7 @@ func (f *fs) listObjectKeys(
 	}
 	paginator := s3.NewListObjectsV2Paginator(f.client, params)
 
-	var objects []string
+	var objects []types.Object
 	for paginator.HasMorePages() {
 		output, err := paginator.NextPage(ctx)
 		if err != nil {
@@ -106,7 +136,7 @@ func (f *fs) listObjectKeys(
 			}
 
 			if match {
-				objects = append(objects, key)
+				objects = append(objects, object)
 			}
 		}
 	}
@@ -235,4 +265,5 @@ var (
 	_ filesystem.LastModifiedGetter = (*fs)(nil)
 	_ filesystem.Remover            = (*fs)(nil)
 	_ filesystem.Copier             = (*fs)(nil)
+	_ filesystem.MetadataLister     = (*fs)(nil)
 )
@@ -24,6 +24,7 @@ import (
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestS3_FilesystemNew(t *testing.T) {
@@ -108,6 +109,75 @@ func Test_fs_List(t *testing.T) {
 	}
 }
 
+func Test_fs_ListMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		glob    string
+		want    []filesystem.FileInfo
+		wantErr bool
+	}{
+		{
+			name: "List matches with wildcard",
+			glob: "s3://bucket/*.txt",
+			want: []filesystem.FileInfo{
+				{Path: "s3://bucket/file-1.txt", Size: 7},
+				{Path: "s3://bucket/file-2.txt", Size: 7},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "List no matches",
+			glob:    "s3://bucket/*.json",
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name:    "Error: invalid S3 uri",
+			glob:    "bucket/without/scheme",
+			wantErr: true,
+		},
+		{
+			name:    "Error: bucket does not exist",
+			glob:    "s3://non-existing-bucket/file-1.txt",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := newServer(t)
+			client := newClient(ctx, t, server.URL)
+
+			bucket := "bucket"
+			keys := []string{"file-1.txt", "file-2.txt", "file-3.csv"}
+			content := []byte("content")
+			createBucket(ctx, t, client, bucket)
+
+			// Account for a timestamp resolution of one second.
+			t1 := time.Now().Truncate(time.Second)
+			for _, key := range keys {
+				createObject(ctx, t, client, bucket, key, content)
+			}
+			t2 := time.Now()
+
+			fileSystem := &fs{client: client}
+			got, err := fileSystem.ListMetadata(ctx, tt.glob)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			opt := cmpopts.IgnoreFields(filesystem.FileInfo{}, "LastModified")
+			if !cmp.Equal(got, tt.want, opt) {
+				t.Errorf("ListMetadata() got = %v, want %v", got, tt.want)
+			}
+			for _, info := range got {
+				if info.LastModified.Before(t1) || info.LastModified.After(t2) {
+					t.Errorf("ListMetadata() LastModified of %v = %v, want in range [%v, %v]", info.Path, info.LastModified, t1, t2)
+				}
+			}
+		})
+	}
+}
+
 func Test_fs_OpenRead(t *testing.T) {
 	tests := []struct {
 		name     string
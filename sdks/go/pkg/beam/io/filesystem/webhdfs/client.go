@@ -0,0 +1,165 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	apiPrefix = "/webhdfs/v1"
+
+	fileType      = "FILE"
+	directoryType = "DIRECTORY"
+)
+
+// fileStatus is the status of a file or directory returned by the GETFILESTATUS and LISTSTATUS
+// operations.
+type fileStatus struct {
+	// PathSuffix is the name of the entry in its directory, and empty for GETFILESTATUS.
+	PathSuffix string `json:"pathSuffix"`
+	Type       string `json:"type"`
+	Length     int64  `json:"length"`
+	// ModificationTime is in milliseconds since the epoch.
+	ModificationTime int64 `json:"modificationTime"`
+}
+
+func (s fileStatus) modTime() time.Time {
+	return time.UnixMilli(s.ModificationTime)
+}
+
+// remoteError is the error returned by WebHDFS operations that fail.
+type remoteError struct {
+	StatusCode int
+	Exception  string `json:"exception"`
+	Message    string `json:"message"`
+}
+
+func (e *remoteError) Error() string {
+	if e.Exception == "" {
+		return fmt.Sprintf("webhdfs request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("%s: %s", e.Exception, e.Message)
+}
+
+// Is reports whether missing files match os.ErrNotExist.
+func (e *remoteError) Is(target error) bool {
+	return target == os.ErrNotExist && (e.Exception == "FileNotFoundException" || e.StatusCode == http.StatusNotFound)
+}
+
+// url returns the URL of an operation on the path of the namenode.
+func (f *fs) url(host, p, op string, params url.Values) string {
+	query := url.Values{"op": {op}}
+	for k, v := range params {
+		query[k] = v
+	}
+	if f.user != "" {
+		query.Set("user.name", f.user)
+	}
+
+	u := url.URL{
+		Scheme:   "http",
+		Host:     host,
+		Path:     apiPrefix + p,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// do sends a request, returning a remoteError for responses with an error status. Redirects are
+// returned as is if the client doesn't follow them. The caller must close the body of the
+// response.
+func (f *fs) do(ctx context.Context, client *http.Client, method, u string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		var remote struct {
+			RemoteException remoteError `json:"RemoteException"`
+		}
+		// Errors that aren't reported as remote exceptions only have a status.
+		_ = json.NewDecoder(resp.Body).Decode(&remote)
+		remote.RemoteException.StatusCode = resp.StatusCode
+		return nil, &remote.RemoteException
+	}
+
+	return resp, nil
+}
+
+// doJSON sends a request to the namenode and decodes the JSON response into v.
+func (f *fs) doJSON(ctx context.Context, method, u string, v any) error {
+	resp, err := f.do(ctx, f.client, method, u, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("error decoding webhdfs response: %v", err)
+	}
+	return nil
+}
+
+// doBoolean sends a request for an operation returning a boolean result.
+func (f *fs) doBoolean(ctx context.Context, method, u string) (bool, error) {
+	var result struct {
+		Boolean bool `json:"boolean"`
+	}
+	if err := f.doJSON(ctx, method, u, &result); err != nil {
+		return false, err
+	}
+	return result.Boolean, nil
+}
+
+func (f *fs) getFileStatus(ctx context.Context, host, p string) (fileStatus, error) {
+	var result struct {
+		FileStatus fileStatus `json:"FileStatus"`
+	}
+	if err := f.doJSON(ctx, http.MethodGet, f.url(host, p, "GETFILESTATUS", nil), &result); err != nil {
+		return fileStatus{}, err
+	}
+	return result.FileStatus, nil
+}
+
+func (f *fs) listStatus(ctx context.Context, host, dir string) ([]fileStatus, error) {
+	var result struct {
+		FileStatuses struct {
+			FileStatus []fileStatus `json:"FileStatus"`
+		} `json:"FileStatuses"`
+	}
+	if err := f.doJSON(ctx, http.MethodGet, f.url(host, dir, "LISTSTATUS", nil), &result); err != nil {
+		return nil, err
+	}
+	return result.FileStatuses.FileStatus, nil
+}
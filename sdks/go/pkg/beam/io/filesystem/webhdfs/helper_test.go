@@ -0,0 +1,217 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const datanodePrefix = "/datanode"
+
+// fakeHDFS is a local HTTP stand-in for the WebHDFS REST API of a namenode and a datanode,
+// storing files in memory.
+type fakeHDFS struct {
+	url   string
+	mu    sync.Mutex
+	files map[string]fakeFile
+	dirs  map[string]bool
+	// users are the user.name parameters of the received requests.
+	users []string
+}
+
+type fakeFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+func newServer(t *testing.T) *fakeHDFS {
+	t.Helper()
+	h := &fakeHDFS{files: make(map[string]fakeFile), dirs: map[string]bool{"/": true}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiPrefix+"/", h.namenode)
+	mux.HandleFunc(datanodePrefix+"/", h.datanode)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	h.url = server.URL
+	return h
+}
+
+// host returns the host of the stand-in, for WebHDFS uris.
+func (h *fakeHDFS) host() string {
+	return strings.TrimPrefix(h.url, "http://")
+}
+
+// createFile stores a file, creating its parent directories.
+func (h *fakeHDFS) createFile(p string, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.createFileLocked(p, data)
+}
+
+func (h *fakeHDFS) createFileLocked(p string, data []byte) {
+	h.files[p] = fakeFile{data: data, modTime: time.Now()}
+	for dir := path.Dir(p); !h.dirs[dir]; dir = path.Dir(dir) {
+		h.dirs[dir] = true
+	}
+}
+
+func (h *fakeHDFS) file(p string) (fakeFile, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, ok := h.files[p]
+	return f, ok
+}
+
+func (h *fakeHDFS) namenode(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	p := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	query := r.URL.Query()
+	h.users = append(h.users, query.Get("user.name"))
+
+	switch op := query.Get("op"); op {
+	case "GETFILESTATUS":
+		status, ok := h.status(p)
+		if !ok {
+			notFound(w, p)
+			return
+		}
+		writeJSON(w, map[string]any{"FileStatus": status})
+	case "LISTSTATUS":
+		if !h.dirs[p] {
+			notFound(w, p)
+			return
+		}
+		statuses := []fileStatus{}
+		for _, child := range h.children(p) {
+			status, _ := h.status(child)
+			status.PathSuffix = path.Base(child)
+			statuses = append(statuses, status)
+		}
+		writeJSON(w, map[string]any{"FileStatuses": map[string]any{"FileStatus": statuses}})
+	case "OPEN":
+		if _, ok := h.files[p]; !ok {
+			notFound(w, p)
+			return
+		}
+		http.Redirect(w, r, h.url+datanodePrefix+p, http.StatusTemporaryRedirect)
+	case "CREATE":
+		http.Redirect(w, r, h.url+datanodePrefix+p, http.StatusTemporaryRedirect)
+	case "DELETE":
+		_, ok := h.files[p]
+		delete(h.files, p)
+		writeJSON(w, map[string]bool{"boolean": ok})
+	case "MKDIRS":
+		for dir := p; !h.dirs[dir]; dir = path.Dir(dir) {
+			h.dirs[dir] = true
+		}
+		writeJSON(w, map[string]bool{"boolean": true})
+	case "RENAME":
+		// As HDFS, fail renames to existing files and missing directories.
+		dest := query.Get("destination")
+		f, ok := h.files[p]
+		if _, exists := h.files[dest]; !ok || exists || !h.dirs[path.Dir(dest)] {
+			writeJSON(w, map[string]bool{"boolean": false})
+			return
+		}
+		h.files[dest] = f
+		delete(h.files, p)
+		writeJSON(w, map[string]bool{"boolean": true})
+	default:
+		http.Error(w, fmt.Sprintf("unsupported operation %q", op), http.StatusBadRequest)
+	}
+}
+
+func (h *fakeHDFS) datanode(w http.ResponseWriter, r *http.Request) {
+	p := strings.TrimPrefix(r.URL.Path, datanodePrefix)
+
+	switch r.Method {
+	case http.MethodGet:
+		f, ok := h.file(p)
+		if !ok {
+			notFound(w, p)
+			return
+		}
+		w.Write(f.data)
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.createFile(p, data)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *fakeHDFS) status(p string) (fileStatus, bool) {
+	if f, ok := h.files[p]; ok {
+		return fileStatus{Type: fileType, Length: int64(len(f.data)), ModificationTime: f.modTime.UnixMilli()}, true
+	}
+	if h.dirs[p] {
+		return fileStatus{Type: directoryType}, true
+	}
+	return fileStatus{}, false
+}
+
+// children returns the sorted paths of the files and directories in the directory.
+func (h *fakeHDFS) children(dir string) []string {
+	var children []string
+	for p := range h.files {
+		if path.Dir(p) == dir {
+			children = append(children, p)
+		}
+	}
+	for p := range h.dirs {
+		if p != dir && path.Dir(p) == dir {
+			children = append(children, p)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+func notFound(w http.ResponseWriter, p string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]any{
+		"RemoteException": map[string]string{
+			"exception":     "FileNotFoundException",
+			"javaClassName": "java.io.FileNotFoundException",
+			"message":       "File does not exist: " + p,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,43 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parseURI deconstructs the WebHDFS uri in the format 'webhdfs://host:port/path' to
+// (host:port, /path). The uri isn't parsed as a URL, so that the path may contain '?' wildcards.
+func parseURI(uri string) (string, string, error) {
+	rest, ok := strings.CutPrefix(uri, "webhdfs://")
+	if !ok {
+		return "", "", errors.New("scheme must be 'webhdfs'")
+	}
+
+	host, p, _ := strings.Cut(rest, "/")
+	if host == "" {
+		return "", "", errors.New("host must not be empty")
+	}
+
+	return host, "/" + p, nil
+}
+
+// makeURI constructs a WebHDFS uri from the host and path to the format 'webhdfs://host:port/path'
+func makeURI(host string, path string) string {
+	return fmt.Sprintf("webhdfs://%s%s", host, path)
+}
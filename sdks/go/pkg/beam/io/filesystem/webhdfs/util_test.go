@@ -0,0 +1,70 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfs
+
+import (
+	"testing"
+)
+
+func Test_parseURI(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		wantHost string
+		wantPath string
+		wantErr  bool
+	}{
+		{
+			name:     "URI with path",
+			uri:      "webhdfs://namenode:9870/path/to/file.txt",
+			wantHost: "namenode:9870",
+			wantPath: "/path/to/file.txt",
+		},
+		{
+			name:     "URI without path",
+			uri:      "webhdfs://namenode:9870",
+			wantHost: "namenode:9870",
+			wantPath: "/",
+		},
+		{
+			name:    "Error: invalid scheme",
+			uri:     "hdfs://namenode:9870/path",
+			wantErr: true,
+		},
+		{
+			name:    "Error: missing host",
+			uri:     "webhdfs:///path",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, path, err := parseURI(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseURI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if host != tt.wantHost || path != tt.wantPath {
+				t.Errorf("parseURI() = %v, %v, want %v, %v", host, path, tt.wantHost, tt.wantPath)
+			}
+		})
+	}
+}
+
+func Test_makeURI(t *testing.T) {
+	if got, want := makeURI("namenode:9870", "/path/file.txt"), "webhdfs://namenode:9870/path/file.txt"; got != want {
+		t.Errorf("makeURI() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,368 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhdfs contains a WebHDFS implementation of the Beam file system, for HDFS and HDFS
+// compatible storage systems exposing the WebHDFS REST API.
+//
+// Files are addressed as 'webhdfs://namenode:port/path', where the port is the HTTP port of the
+// namenode. Requests are made as the user named by the HADOOP_USER_NAME environment variable, if
+// set.
+package webhdfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+)
+
+const userEnv = "HADOOP_USER_NAME"
+
+func init() {
+	filesystem.Register("webhdfs", New)
+}
+
+type fs struct {
+	// client follows the redirects of the namenode to datanodes.
+	client *http.Client
+	// noRedirectClient returns redirects, for requests with bodies that are sent to the datanode
+	// only once its location is known.
+	noRedirectClient *http.Client
+	user             string
+}
+
+// New creates a new WebHDFS filesystem.
+func New(_ context.Context) filesystem.Interface {
+	return newFS(http.DefaultTransport, os.Getenv(userEnv))
+}
+
+func newFS(transport http.RoundTripper, user string) *fs {
+	return &fs{
+		client: &http.Client{Transport: transport},
+		noRedirectClient: &http.Client{
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		user: user,
+	}
+}
+
+// Close closes the filesystem.
+func (f *fs) Close() error {
+	return nil
+}
+
+// List returns a slice of the files in the filesystem that match the glob pattern.
+func (f *fs) List(ctx context.Context, glob string) ([]string, error) {
+	host, files, err := f.glob(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	uris := make([]string, len(files))
+	for i, file := range files {
+		uris[i] = makeURI(host, file.path)
+	}
+
+	return uris, nil
+}
+
+// ListMetadata returns a slice of the files in the filesystem that match the glob pattern, along
+// with their sizes and last modified times.
+func (f *fs) ListMetadata(ctx context.Context, glob string) ([]filesystem.FileInfo, error) {
+	host, files, err := f.glob(ctx, glob)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	infos := make([]filesystem.FileInfo, len(files))
+	for i, file := range files {
+		infos[i] = filesystem.FileInfo{
+			Path:         makeURI(host, file.path),
+			Size:         file.status.Length,
+			LastModified: file.status.modTime(),
+		}
+	}
+
+	return infos, nil
+}
+
+type globMatch struct {
+	path   string
+	status fileStatus
+}
+
+// glob returns the host and the files matching the glob pattern. Directories are listed one level
+// at a time, starting at the deepest directory without wildcards, and only the directories
+// matching each level of the pattern are descended into.
+func (f *fs) glob(ctx context.Context, glob string) (string, []globMatch, error) {
+	host, pattern, err := parseURI(glob)
+	if err != nil {
+		return "", nil, fmt.Errorf("error parsing WebHDFS uri %s: %v", glob, err)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	dirs := []string{"/"}
+	var matches []globMatch
+
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		if !hasMeta(segment) {
+			if !last {
+				for j, dir := range dirs {
+					dirs[j] = path.Join(dir, segment)
+				}
+				continue
+			}
+			for _, dir := range dirs {
+				p := path.Join(dir, segment)
+				status, err := f.getFileStatus(ctx, host, p)
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				if err != nil {
+					return "", nil, err
+				}
+				if status.Type == fileType {
+					matches = append(matches, globMatch{path: p, status: status})
+				}
+			}
+			break
+		}
+
+		var next []string
+		for _, dir := range dirs {
+			statuses, err := f.listStatus(ctx, host, dir)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return "", nil, err
+			}
+
+			for _, status := range statuses {
+				match, err := filepath.Match(segment, status.PathSuffix)
+				if err != nil {
+					return "", nil, fmt.Errorf("invalid path pattern: %s", pattern)
+				}
+				if !match {
+					continue
+				}
+
+				p := path.Join(dir, status.PathSuffix)
+				switch {
+				case last && status.Type == fileType:
+					matches = append(matches, globMatch{path: p, status: status})
+				case !last && status.Type == directoryType:
+					next = append(next, p)
+				}
+			}
+		}
+		dirs = next
+	}
+
+	return host, matches, nil
+}
+
+func hasMeta(segment string) bool {
+	return strings.ContainsAny(segment, `*?[\`)
+}
+
+// OpenRead returns a new io.ReadCloser to read contents from the file. The caller must call Close
+// on the returned io.ReadCloser when done reading.
+func (f *fs) OpenRead(ctx context.Context, filename string) (io.ReadCloser, error) {
+	host, p, err := parseURI(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing WebHDFS uri %s: %v", filename, err)
+	}
+
+	resp, err := f.do(ctx, f.client, http.MethodGet, f.url(host, p, "OPEN", nil), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %w", filename, err)
+	}
+
+	return resp.Body, nil
+}
+
+// OpenWrite returns a new io.WriteCloser to write contents to the file. The caller must call Close
+// on the returned io.WriteCloser when done writing.
+func (f *fs) OpenWrite(ctx context.Context, filename string) (io.WriteCloser, error) {
+	host, p, err := parseURI(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing WebHDFS uri %s: %v", filename, err)
+	}
+
+	// The namenode redirects the creation of files to the datanode that the data is sent to.
+	params := url.Values{"overwrite": {"true"}}
+	resp, err := f.do(ctx, f.noRedirectClient, http.MethodPut, f.url(host, p, "CREATE", params), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating file %s: %w", filename, err)
+	}
+	resp.Body.Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		return nil, fmt.Errorf("error creating file %s: no datanode location: %v", filename, err)
+	}
+
+	return newWriter(ctx, f, location.String()), nil
+}
+
+// Size returns the size of the file.
+func (f *fs) Size(ctx context.Context, filename string) (int64, error) {
+	host, p, err := parseURI(filename)
+	if err != nil {
+		return -1, fmt.Errorf("error parsing WebHDFS uri %s: %w", filename, err)
+	}
+
+	status, err := f.getFileStatus(ctx, host, p)
+	if err != nil {
+		return -1, fmt.Errorf("error getting status of file %s: %w", filename, err)
+	}
+
+	return status.Length, nil
+}
+
+// LastModified returns the time at which the file was last modified.
+func (f *fs) LastModified(ctx context.Context, filename string) (time.Time, error) {
+	host, p, err := parseURI(filename)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing WebHDFS uri %s: %v", filename, err)
+	}
+
+	status, err := f.getFileStatus(ctx, host, p)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error getting status of file %s: %w", filename, err)
+	}
+
+	return status.modTime(), nil
+}
+
+// Remove removes the file from the filesystem.
+func (f *fs) Remove(ctx context.Context, filename string) error {
+	host, p, err := parseURI(filename)
+	if err != nil {
+		return fmt.Errorf("error parsing WebHDFS uri %s: %v", filename, err)
+	}
+
+	ok, err := f.doBoolean(ctx, http.MethodDelete, f.url(host, p, "DELETE", nil))
+	if err != nil {
+		return fmt.Errorf("error deleting file %s: %w", filename, err)
+	}
+	if !ok {
+		return fmt.Errorf("error deleting file %s: %w", filename, os.ErrNotExist)
+	}
+
+	return nil
+}
+
+// Copy copies the file from the old path to the new path. WebHDFS has no copy operation, so the
+// contents are read and written through the client.
+func (f *fs) Copy(ctx context.Context, oldpath, newpath string) error {
+	r, err := f.OpenRead(ctx, oldpath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := f.OpenWrite(ctx, newpath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return fmt.Errorf("error copying file %s: %v", oldpath, err)
+	}
+
+	return w.Close()
+}
+
+// Rename renames the file from the old path to the new path, replacing any existing file at the
+// new path.
+func (f *fs) Rename(ctx context.Context, oldpath, newpath string) error {
+	host, oldp, err := parseURI(oldpath)
+	if err != nil {
+		return fmt.Errorf("error parsing WebHDFS source uri %s: %v", oldpath, err)
+	}
+	newHost, newp, err := parseURI(newpath)
+	if err != nil {
+		return fmt.Errorf("error parsing WebHDFS destination uri %s: %v", newpath, err)
+	}
+	if newHost != host {
+		return fmt.Errorf("error renaming file %s: destination %s is on another namenode", oldpath, newpath)
+	}
+
+	params := url.Values{"destination": {newp}}
+	renameURL := f.url(host, oldp, "RENAME", params)
+
+	ok, err := f.doBoolean(ctx, http.MethodPut, renameURL)
+	if err != nil {
+		return fmt.Errorf("error renaming file %s: %w", oldpath, err)
+	}
+	if ok {
+		return nil
+	}
+
+	// HDFS neither replaces existing files nor creates missing parent directories when renaming.
+	if _, err := f.getFileStatus(ctx, host, oldp); err != nil {
+		return fmt.Errorf("error renaming file %s: %w", oldpath, err)
+	}
+	if err := f.Remove(ctx, newpath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if _, err := f.doBoolean(ctx, http.MethodPut, f.url(host, path.Dir(newp), "MKDIRS", nil)); err != nil {
+		return fmt.Errorf("error creating parent directory of %s: %w", newpath, err)
+	}
+
+	ok, err = f.doBoolean(ctx, http.MethodPut, renameURL)
+	if err != nil {
+		return fmt.Errorf("error renaming file %s: %w", oldpath, err)
+	}
+	if !ok {
+		return fmt.Errorf("error renaming file %s to %s", oldpath, newpath)
+	}
+
+	return nil
+}
+
+// Compile time check for interface implementations.
+var (
+	_ filesystem.LastModifiedGetter = (*fs)(nil)
+	_ filesystem.Remover            = (*fs)(nil)
+	_ filesystem.Copier             = (*fs)(nil)
+	_ filesystem.Renamer            = (*fs)(nil)
+	_ filesystem.MetadataLister     = (*fs)(nil)
+)
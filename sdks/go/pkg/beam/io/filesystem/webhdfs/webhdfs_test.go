@@ -0,0 +1,347 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestWebHDFS_FilesystemNew(t *testing.T) {
+	ctx := context.Background()
+	path := "webhdfs://namenode:9870/tmp/"
+	c, err := filesystem.New(ctx, path)
+	if err != nil {
+		t.Errorf("filesystem.New(ctx, %q) = %v, want nil", path, err)
+	}
+	if _, ok := c.(*fs); !ok {
+		t.Errorf("filesystem.New(ctx, %q) type = %T, want *webhdfs.fs", path, c)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("c.Close() = %v, want nil", err)
+	}
+}
+
+// newTestFS returns a stand-in server with files, and a filesystem to access it.
+func newTestFS(t *testing.T, files ...string) (*fakeHDFS, *fs) {
+	t.Helper()
+	server := newServer(t)
+	for _, p := range files {
+		server.createFile(p, []byte("content"))
+	}
+	return server, newFS(http.DefaultTransport, "beam")
+}
+
+func Test_fs_List(t *testing.T) {
+	files := []string{"/data/file-1.txt", "/data/file-2.txt", "/data/file-3.csv", "/data/sub/file-4.txt", "/other/file-5.txt"}
+	tests := []struct {
+		name    string
+		glob    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "List match with full path",
+			glob: "/data/file-1.txt",
+			want: []string{"/data/file-1.txt"},
+		},
+		{
+			name: "List matches with wildcard",
+			glob: "/data/*.txt",
+			want: []string{"/data/file-1.txt", "/data/file-2.txt"},
+		},
+		{
+			name: "List matches with wildcard directories",
+			glob: "/*/*.txt",
+			want: []string{"/data/file-1.txt", "/data/file-2.txt", "/other/file-5.txt"},
+		},
+		{
+			name: "List matches in subdirectory",
+			glob: "/data/s?b/*",
+			want: []string{"/data/sub/file-4.txt"},
+		},
+		{
+			name: "List no matches",
+			glob: "/data/*.json",
+			want: nil,
+		},
+		{
+			name: "List no matches in missing directory",
+			glob: "/missing/*.txt",
+			want: nil,
+		},
+		{
+			name: "List does not match directories",
+			glob: "/data/sub",
+			want: nil,
+		},
+		{
+			name:    "Error: invalid glob pattern",
+			glob:    "/data/*file-[].txt",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server, fileSystem := newTestFS(t, files...)
+
+			got, err := fileSystem.List(ctx, makeURI(server.host(), tt.glob))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("List() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var want []string
+			for _, p := range tt.want {
+				want = append(want, makeURI(server.host(), p))
+			}
+			if !cmp.Equal(got, want) {
+				t.Errorf("List() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_fs_List_InvalidURI(t *testing.T) {
+	_, fileSystem := newTestFS(t)
+	if _, err := fileSystem.List(context.Background(), "namenode/without/scheme"); err == nil {
+		t.Error("List() error = nil, want error")
+	}
+}
+
+func Test_fs_ListMetadata(t *testing.T) {
+	ctx := context.Background()
+
+	// Account for the millisecond resolution of modification times.
+	t1 := time.Now().Truncate(time.Millisecond)
+	server, fileSystem := newTestFS(t, "/data/file-1.txt", "/data/file-2.csv")
+	server.createFile("/data/file-3.txt", []byte("more content"))
+	t2 := time.Now()
+
+	glob := makeURI(server.host(), "/data/*.txt")
+	got, err := fileSystem.ListMetadata(ctx, glob)
+	if err != nil {
+		t.Fatalf("ListMetadata(%q) error = %v, want nil", glob, err)
+	}
+
+	want := []filesystem.FileInfo{
+		{Path: makeURI(server.host(), "/data/file-1.txt"), Size: 7},
+		{Path: makeURI(server.host(), "/data/file-3.txt"), Size: 12},
+	}
+	if d := cmp.Diff(want, got, cmpopts.IgnoreFields(filesystem.FileInfo{}, "LastModified")); d != "" {
+		t.Errorf("ListMetadata(%q) mismatch (-want +got):\n%s", glob, d)
+	}
+	for _, info := range got {
+		if info.LastModified.Before(t1) || info.LastModified.After(t2) {
+			t.Errorf("ListMetadata(%q) LastModified of %v = %v, want in range [%v, %v]", glob, info.Path, info.LastModified, t1, t2)
+		}
+	}
+}
+
+func Test_fs_OpenRead(t *testing.T) {
+	ctx := context.Background()
+	server, fileSystem := newTestFS(t, "/data/file.txt")
+
+	filename := makeURI(server.host(), "/data/file.txt")
+	r, err := fileSystem.OpenRead(ctx, filename)
+	if err != nil {
+		t.Fatalf("OpenRead(%q) error = %v, want nil", filename, err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v, want nil", err)
+	}
+	if want := "content"; string(got) != want {
+		t.Errorf("OpenRead(%q) content = %q, want %q", filename, got, want)
+	}
+
+	missing := makeURI(server.host(), "/data/missing.txt")
+	if _, err := fileSystem.OpenRead(ctx, missing); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenRead(%q) error = %v, want %v", missing, err, os.ErrNotExist)
+	}
+}
+
+func Test_fs_OpenWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{
+			name:    "Write content",
+			content: []byte("content"),
+		},
+		{
+			name:    "Write empty file",
+			content: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server, fileSystem := newTestFS(t, "/data/file.txt")
+
+			// Overwrites existing files, and creates missing directories.
+			for _, p := range []string{"/data/file.txt", "/new/dir/file.txt"} {
+				filename := makeURI(server.host(), p)
+				if err := filesystem.Write(ctx, fileSystem, filename, tt.content); err != nil {
+					t.Fatalf("Write(%q) error = %v, want nil", filename, err)
+				}
+
+				f, ok := server.file(p)
+				if !ok {
+					t.Fatalf("file %v does not exist after Write", p)
+				}
+				if !cmp.Equal(f.data, tt.content, cmpopts.EquateEmpty()) {
+					t.Errorf("file %v content = %q, want %q", p, f.data, tt.content)
+				}
+			}
+
+			for _, user := range server.users {
+				if user != "beam" {
+					t.Errorf("user.name = %q, want %q", user, "beam")
+				}
+			}
+		})
+	}
+}
+
+func Test_fs_SizeAndLastModified(t *testing.T) {
+	ctx := context.Background()
+
+	t1 := time.Now().Truncate(time.Millisecond)
+	server, fileSystem := newTestFS(t, "/data/file.txt")
+	t2 := time.Now()
+
+	filename := makeURI(server.host(), "/data/file.txt")
+	size, err := fileSystem.Size(ctx, filename)
+	if err != nil || size != 7 {
+		t.Errorf("Size(%q) = %v, %v, want 7, nil", filename, size, err)
+	}
+
+	mtime, err := fileSystem.LastModified(ctx, filename)
+	if err != nil {
+		t.Errorf("LastModified(%q) error = %v, want nil", filename, err)
+	}
+	if mtime.Before(t1) || mtime.After(t2) {
+		t.Errorf("LastModified(%q) = %v, want in range [%v, %v]", filename, mtime, t1, t2)
+	}
+
+	missing := makeURI(server.host(), "/data/missing.txt")
+	if _, err := fileSystem.Size(ctx, missing); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(%q) error = %v, want %v", missing, err, os.ErrNotExist)
+	}
+	if _, err := fileSystem.LastModified(ctx, missing); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LastModified(%q) error = %v, want %v", missing, err, os.ErrNotExist)
+	}
+}
+
+func Test_fs_Remove(t *testing.T) {
+	ctx := context.Background()
+	server, fileSystem := newTestFS(t, "/data/file.txt")
+
+	filename := makeURI(server.host(), "/data/file.txt")
+	if err := fileSystem.Remove(ctx, filename); err != nil {
+		t.Fatalf("Remove(%q) error = %v, want nil", filename, err)
+	}
+	if _, ok := server.file("/data/file.txt"); ok {
+		t.Errorf("file %v exists after Remove", filename)
+	}
+
+	if err := fileSystem.Remove(ctx, filename); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Remove(%q) error = %v, want %v", filename, err, os.ErrNotExist)
+	}
+}
+
+func Test_fs_Copy(t *testing.T) {
+	ctx := context.Background()
+	server, fileSystem := newTestFS(t, "/data/file.txt")
+
+	oldpath := makeURI(server.host(), "/data/file.txt")
+	newpath := makeURI(server.host(), "/copy/file.txt")
+	if err := fileSystem.Copy(ctx, oldpath, newpath); err != nil {
+		t.Fatalf("Copy(%q, %q) error = %v, want nil", oldpath, newpath, err)
+	}
+
+	for _, p := range []string{"/data/file.txt", "/copy/file.txt"} {
+		if f, ok := server.file(p); !ok || string(f.data) != "content" {
+			t.Errorf("file %v = %q, %v, want %q, true", p, f.data, ok, "content")
+		}
+	}
+}
+
+func Test_fs_Rename(t *testing.T) {
+	tests := []struct {
+		name    string
+		newpath string
+	}{
+		{
+			name:    "Rename in directory",
+			newpath: "/data/renamed.txt",
+		},
+		{
+			name:    "Rename to missing directory",
+			newpath: "/new/dir/renamed.txt",
+		},
+		{
+			name:    "Rename to existing file",
+			newpath: "/data/existing.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			server, fileSystem := newTestFS(t, "/data/existing.txt")
+			server.createFile("/data/file.txt", []byte("renamed"))
+
+			oldpath := makeURI(server.host(), "/data/file.txt")
+			newpath := makeURI(server.host(), tt.newpath)
+			if err := fileSystem.Rename(ctx, oldpath, newpath); err != nil {
+				t.Fatalf("Rename(%q, %q) error = %v, want nil", oldpath, newpath, err)
+			}
+
+			if _, ok := server.file("/data/file.txt"); ok {
+				t.Errorf("file %v exists after Rename", oldpath)
+			}
+			if f, ok := server.file(tt.newpath); !ok || string(f.data) != "renamed" {
+				t.Errorf("file %v = %q, %v, want %q, true", newpath, f.data, ok, "renamed")
+			}
+		})
+	}
+}
+
+func Test_fs_Rename_MissingSource(t *testing.T) {
+	ctx := context.Background()
+	server, fileSystem := newTestFS(t, "/data/existing.txt")
+
+	oldpath := makeURI(server.host(), "/data/missing.txt")
+	newpath := makeURI(server.host(), "/data/existing.txt")
+	if err := fileSystem.Rename(ctx, oldpath, newpath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Rename(%q, %q) error = %v, want %v", oldpath, newpath, err, os.ErrNotExist)
+	}
+	if _, ok := server.file("/data/existing.txt"); !ok {
+		t.Errorf("file %v removed by failed Rename", newpath)
+	}
+}
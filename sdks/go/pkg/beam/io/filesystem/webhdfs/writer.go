@@ -0,0 +1,87 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhdfs
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+type writer struct {
+	ctx      context.Context
+	fs       *fs
+	location string
+	done     chan struct{}
+	isOpened bool
+	pw       *io.PipeWriter
+	err      error
+}
+
+// newWriter returns a writer that sends the data of a created file to the datanode location the
+// namenode redirected the creation to. The caller must call Close on the writer when done writing
+// for the file to become available.
+func newWriter(ctx context.Context, fs *fs, location string) *writer {
+	return &writer{
+		ctx:      ctx,
+		fs:       fs,
+		location: location,
+		done:     make(chan struct{}),
+	}
+}
+
+// Write writes data to a pipe.
+func (w *writer) Write(p []byte) (int, error) {
+	if !w.isOpened {
+		w.open()
+	}
+
+	return w.pw.Write(p)
+}
+
+// Close completes the write operation.
+func (w *writer) Close() error {
+	if !w.isOpened {
+		w.open()
+	}
+
+	if err := w.pw.Close(); err != nil {
+		return err
+	}
+
+	<-w.done
+	return w.err
+}
+
+// open creates a pipe for streaming the data to the datanode.
+func (w *writer) open() {
+	pr, pw := io.Pipe()
+	w.pw = pw
+
+	go func() {
+		defer close(w.done)
+
+		resp, err := w.fs.do(w.ctx, w.fs.noRedirectClient, http.MethodPut, w.location, pr)
+		if err != nil {
+			w.err = err
+			pr.CloseWithError(err)
+			return
+		}
+		resp.Body.Close()
+	}()
+
+	w.isOpened = true
+}
@@ -16,8 +16,11 @@
 package mongodbio
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"reflect"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
@@ -32,6 +35,9 @@ import (
 const (
 	defaultWriteBatchSize = 1000
 	defaultWriteOrdered   = true
+
+	// writeShards is the number of groups the documents are written in, with an ErrorHandler.
+	writeShards = 32
 )
 
 func init() {
@@ -47,6 +53,15 @@ func init() {
 		&writeFn{},
 	)
 	register.Emitter1[primitive.ObjectID]()
+
+	register.DoFn3x1[beam.X, beam.Y, func(int, []byte), error](&shardDocumentFn{})
+	register.Emitter2[int, []byte]()
+
+	register.DoFn5x1[context.Context, int, func(*[]byte) bool, func(beam.X), func(beam.ErrorRecord), error](
+		&writeDocumentsFn{},
+	)
+	register.Iter1[[]byte]()
+	register.Emitter1[beam.ErrorRecord]()
 }
 
 // Write writes a PCollection<T> of a type T to MongoDB. T must be a struct with exported fields
@@ -66,6 +81,10 @@ func init() {
 // fields:
 //   - BatchSize: the number of documents to write in a single batch. Defaults to 1000
 //   - Ordered: whether to execute the writes in order. Defaults to true
+//   - ErrorHandler: receives the documents that fail to be written, instead of failing the
+//     pipeline. The documents are then grouped into batches before they're written, their
+//     writes are unordered, and only the ids of the documents written are returned. Failures
+//     to reach MongoDB still fail the pipeline. Defaults to nil
 func Write(
 	s beam.Scope,
 	uri string,
@@ -91,19 +110,35 @@ func Write(
 	idIndex := structx.FieldIndexByTag(t, bsonTag, "_id")
 
 	var keyed beam.PCollection
+	idType := reflect.TypeOf(primitive.ObjectID{})
 
 	if idIndex == -1 {
 		pre := beam.ParDo(s, createIDFn, col)
 		keyed = beam.Reshuffle(s, pre)
 	} else {
+		idType = t.Field(idIndex).Type
 		keyed = beam.ParDo(
 			s,
 			newExtractIDFn(idIndex),
 			col,
-			beam.TypeDefinition{Var: beam.XType, T: t.Field(idIndex).Type},
+			beam.TypeDefinition{Var: beam.XType, T: idType},
 		)
 	}
 
+	if option.ErrorHandler != nil {
+		docs := beam.ParDo(s, &shardDocumentFn{Shards: writeShards}, keyed)
+		grouped := beam.GroupByKey(s, docs)
+		ids, errs := beam.ParDo2(
+			s,
+			newWriteDocumentsFn(uri, database, collection, option, t, idType),
+			grouped,
+			beam.TypeDefinition{Var: beam.XType, T: idType},
+		)
+		option.ErrorHandler.Add(errs)
+
+		return ids
+	}
+
 	return beam.ParDo(
 		s,
 		newWriteFn(uri, database, collection, option),
@@ -202,3 +237,173 @@ func (fn *writeFn) flush(ctx context.Context) error {
 
 	return nil
 }
+
+// shardDocumentFn marshals each document with its id, and assigns it a random shard, so the
+// documents can be grouped into batches.
+type shardDocumentFn struct {
+	Shards int
+}
+
+func (fn *shardDocumentFn) ProcessElement(key beam.X, value beam.Y, emit func(int, []byte)) error {
+	raw, err := bson.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error marshaling document: %w", err)
+	}
+
+	var elems bson.D
+	if err := bson.Unmarshal(raw, &elems); err != nil {
+		return fmt.Errorf("error unmarshaling document: %w", err)
+	}
+
+	doc := bson.D{{Key: "_id", Value: key}}
+	for _, elem := range elems {
+		if elem.Key != "_id" {
+			doc = append(doc, elem)
+		}
+	}
+
+	raw, err = bson.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("error marshaling document: %w", err)
+	}
+
+	emit(rand.Intn(fn.Shards), raw)
+
+	return nil
+}
+
+// writeDocumentsFn writes the grouped documents in unordered batches, and outputs the ids of
+// the documents written, and the documents that fail to be written as ErrorRecords.
+type writeDocumentsFn struct {
+	mongoDBFn
+	BatchSize int64
+	Type      beam.EncodedType
+	IDType    beam.EncodedType
+	enc       beam.ElementEncoder
+}
+
+func newWriteDocumentsFn(
+	uri string,
+	database string,
+	collection string,
+	option *WriteOption,
+	t reflect.Type,
+	idType reflect.Type,
+) *writeDocumentsFn {
+	return &writeDocumentsFn{
+		mongoDBFn: mongoDBFn{
+			URI:        uri,
+			Database:   database,
+			Collection: collection,
+		},
+		BatchSize: option.BatchSize,
+		Type:      beam.EncodedType{T: t},
+		IDType:    beam.EncodedType{T: idType},
+	}
+}
+
+func (fn *writeDocumentsFn) Setup(ctx context.Context) error {
+	fn.enc = beam.NewElementEncoder(fn.Type.T)
+
+	return fn.mongoDBFn.Setup(ctx)
+}
+
+func (fn *writeDocumentsFn) ProcessElement(
+	ctx context.Context,
+	_ int,
+	iter func(*[]byte) bool,
+	emitID func(beam.X),
+	emitErr func(beam.ErrorRecord),
+) error {
+	var docs []bson.Raw
+
+	var doc []byte
+	for iter(&doc) {
+		docs = append(docs, doc)
+
+		if len(docs) >= int(fn.BatchSize) {
+			if err := fn.write(ctx, docs, emitID, emitErr); err != nil {
+				return err
+			}
+
+			docs = nil
+		}
+	}
+
+	if len(docs) > 0 {
+		return fn.write(ctx, docs, emitID, emitErr)
+	}
+
+	return nil
+}
+
+func (fn *writeDocumentsFn) write(
+	ctx context.Context,
+	docs []bson.Raw,
+	emitID func(beam.X),
+	emitErr func(beam.ErrorRecord),
+) error {
+	models := make([]mongo.WriteModel, len(docs))
+	for i, doc := range docs {
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": doc.Lookup("_id")}).
+			SetUpsert(true).
+			SetReplacement(doc)
+	}
+
+	failed := make(map[int]error)
+
+	opts := options.BulkWrite().SetOrdered(false)
+	if _, err := fn.collection.BulkWrite(ctx, models, opts); err != nil {
+		// Only the errors of individual documents are output, other errors fail the write.
+		var bwe mongo.BulkWriteException
+		if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+			return fmt.Errorf("error bulk writing to MongoDB: %w", err)
+		}
+
+		for _, we := range bwe.WriteErrors {
+			failed[we.Index] = we
+		}
+	}
+
+	for i, doc := range docs {
+		if err, ok := failed[i]; ok {
+			rec, recErr := fn.errorRecord(doc, err)
+			if recErr != nil {
+				return recErr
+			}
+
+			emitErr(rec)
+
+			continue
+		}
+
+		id := reflect.New(fn.IDType.T)
+		if err := doc.Lookup("_id").Unmarshal(id.Interface()); err != nil {
+			return fmt.Errorf("error unmarshaling document id: %w", err)
+		}
+
+		emitID(id.Elem().Interface())
+	}
+
+	return nil
+}
+
+// errorRecord returns an ErrorRecord of the document, encoded as its type.
+func (fn *writeDocumentsFn) errorRecord(doc bson.Raw, err error) (beam.ErrorRecord, error) {
+	value := reflect.New(fn.Type.T)
+	if err := bson.Unmarshal(doc, value.Interface()); err != nil {
+		return beam.ErrorRecord{}, fmt.Errorf("error unmarshaling document: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := fn.enc.Encode(value.Elem().Interface(), &buf); err != nil {
+		return beam.ErrorRecord{}, fmt.Errorf("error encoding document: %w", err)
+	}
+
+	return beam.ErrorRecord{
+		Element:   buf.Bytes(),
+		Error:     err.Error(),
+		Transform: "mongodbio.Write",
+	}, nil
+}
@@ -17,12 +17,15 @@ package mongodbio
 
 import (
 	"errors"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 )
 
 // WriteOption represents options for writing to MongoDB.
 type WriteOption struct {
-	BatchSize int64
-	Ordered   bool
+	BatchSize    int64
+	Ordered      bool
+	ErrorHandler *beam.ErrorHandler
 }
 
 // WriteOptionFn is a function that configures a WriteOption.
@@ -48,3 +51,17 @@ func WithWriteOrdered(ordered bool) WriteOptionFn {
 		return nil
 	}
 }
+
+// WithWriteErrorHandler configures the WriteOption to output the documents that fail to be
+// written to the provided ErrorHandler, instead of failing the pipeline. The documents are then
+// grouped into batches before they're written, and their writes are unordered.
+func WithWriteErrorHandler(eh *beam.ErrorHandler) WriteOptionFn {
+	return func(o *WriteOption) error {
+		if eh == nil {
+			return errors.New("error handler must not be nil")
+		}
+
+		o.ErrorHandler = eh
+		return nil
+	}
+}
@@ -17,6 +17,8 @@ package mongodbio
 
 import (
 	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 )
 
 func TestWithWriteBatchSize(t *testing.T) {
@@ -81,3 +83,39 @@ func TestWithWriteOrdered(t *testing.T) {
 		})
 	}
 }
+
+func TestWithWriteErrorHandler(t *testing.T) {
+	eh := beam.NewErrorHandler()
+
+	tests := []struct {
+		name         string
+		errorHandler *beam.ErrorHandler
+		want         *beam.ErrorHandler
+		wantErr      bool
+	}{
+		{
+			name:         "Set error handler",
+			errorHandler: eh,
+			want:         eh,
+			wantErr:      false,
+		},
+		{
+			name:         "Error - error handler must not be nil",
+			errorHandler: nil,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var option WriteOption
+
+			if err := WithWriteErrorHandler(tt.errorHandler)(&option); (err != nil) != tt.wantErr {
+				t.Fatalf("WithWriteErrorHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if option.ErrorHandler != tt.want {
+				t.Errorf("option.ErrorHandler = %v, want %v", option.ErrorHandler, tt.want)
+			}
+		})
+	}
+}
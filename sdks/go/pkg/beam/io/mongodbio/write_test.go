@@ -20,6 +20,7 @@ import (
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func Test_createIDFn(t *testing.T) {
@@ -52,3 +53,60 @@ func Test_createIDFn(t *testing.T) {
 		})
 	}
 }
+
+func Test_shardDocumentFn_ProcessElement(t *testing.T) {
+	type doc struct {
+		Field1 int32 `bson:"field1"`
+	}
+
+	type docWithID struct {
+		ID     string `bson:"_id"`
+		Field1 int32  `bson:"field1"`
+	}
+
+	tests := []struct {
+		name  string
+		key   beam.X
+		value beam.Y
+		want  bson.D
+	}{
+		{
+			name:  "Add the id to the document",
+			key:   "id1",
+			value: doc{Field1: 1},
+			want:  bson.D{{Key: "_id", Value: "id1"}, {Key: "field1", Value: int32(1)}},
+		},
+		{
+			name:  "Keep the id of the document",
+			key:   "id2",
+			value: docWithID{ID: "id2", Field1: 2},
+			want:  bson.D{{Key: "_id", Value: "id2"}, {Key: "field1", Value: int32(2)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := &shardDocumentFn{Shards: 4}
+
+			var gotShard int
+			var gotRaw []byte
+			if err := fn.ProcessElement(tt.key, tt.value, func(shard int, raw []byte) {
+				gotShard, gotRaw = shard, raw
+			}); err != nil {
+				t.Fatalf("ProcessElement() error = %v", err)
+			}
+
+			if gotShard < 0 || gotShard >= fn.Shards {
+				t.Errorf("ProcessElement() shard = %v, want in [0, %v)", gotShard, fn.Shards)
+			}
+
+			var got bson.D
+			if err := bson.Unmarshal(gotRaw, &got); err != nil {
+				t.Fatalf("bson.Unmarshal() error = %v", err)
+			}
+
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("ProcessElement() document = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
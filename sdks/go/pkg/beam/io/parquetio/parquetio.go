@@ -18,25 +18,71 @@ package parquetio
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"path"
 	"reflect"
+	"strings"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
-	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/parquet"
 	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
 	"github.com/xitongsys/parquet-go/writer"
 )
 
 func init() {
 	register.Emitter1[string]()
 
-	register.DoFn3x1[context.Context, fileio.ReadableFile, func(beam.X), error](&parquetReadFn{})
+	register.DoFn3x1[context.Context, fileio.ReadableFile, func(parquetFile), error](&rowGroupsFn{})
+	register.Emitter1[parquetFile]()
+	register.DoFn4x1[context.Context, *sdf.LockRTracker, parquetFile, func(beam.X), error](&parquetReadFn{})
 	register.Emitter1[beam.X]()
 
-	register.DoFn3x1[context.Context, int, func(*beam.X) bool, error](&parquetWriteFn{})
-	register.Iter1[beam.X]()
+	beam.RegisterType(reflect.TypeOf((*parquetSink)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*fixedNaming)(nil)).Elem())
+}
+
+type readOption struct {
+	Columns    []string
+	Predicates []predicateOption
+}
+
+type predicateOption struct {
+	Column string
+	Op     Op
+	Value  any
+}
+
+// ReadOptionFn is a function that can be passed to Read to configure options for
+// reading parquet files.
+type ReadOptionFn func(*readOption)
+
+// ReadColumns projects the read onto the given columns, named by their dotted paths
+// in the file, so that only their column chunks are read. The other fields of the
+// elements are left zero.
+func ReadColumns(columns ...string) ReadOptionFn {
+	return func(o *readOption) {
+		o.Columns = append(o.Columns, columns...)
+	}
+}
+
+// ReadPredicate filters the read to the rows whose value of the given column compares
+// to value with the operator. Row groups whose column statistics show that none of
+// their rows match are skipped entirely. Rows with a null value never match.
+// Multiple predicates must all match.
+//
+// The column, named by its dotted path in the file, must be a scalar numeric, string
+// or boolean column, and value a Go value of a comparable type.
+func ReadPredicate(column string, op Op, value any) ReadOptionFn {
+	return func(o *readOption) {
+		o.Predicates = append(o.Predicates, predicateOption{Column: column, Op: op, Value: value})
+	}
 }
 
 // Read reads a set of files and returns lines as a PCollection<elem>
@@ -52,49 +98,293 @@ func init() {
 //	  Day     int32   `parquet:"name=day, type=INT32, convertedtype=DATE"`
 //	  Ignored int32   //without parquet tag and won't write
 //	}
-func Read(s beam.Scope, glob string, t reflect.Type) beam.PCollection {
+//
+// Files are split into their row groups, which are read in parallel. Read accepts
+// a variadic number of ReadOptionFn that can be used to project the read onto some
+// columns, and to filter rows with predicates. For example:
+//
+//	parquetio.Read(s, glob, reflect.TypeOf(Student{}),
+//		parquetio.ReadColumns("name", "age"),
+//		parquetio.ReadPredicate("age", parquetio.GtEq, 21))
+func Read(s beam.Scope, glob string, t reflect.Type, opts ...ReadOptionFn) beam.PCollection {
 	s = s.Scope("parquetio.Read")
 	filesystem.ValidateScheme(glob)
-	return read(s, t, beam.Create(s, glob))
+	return read(s, t, beam.Create(s, glob), opts...)
 }
 
-func read(s beam.Scope, t reflect.Type, col beam.PCollection) beam.PCollection {
+func read(s beam.Scope, t reflect.Type, col beam.PCollection, opts ...ReadOptionFn) beam.PCollection {
+	option := &readOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
+	columns, predicates, err := newProjection(t, option)
+	if err != nil {
+		panic(fmt.Sprintf("parquetio.Read: %v", err))
+	}
+
 	matches := fileio.MatchAll(s, col, fileio.MatchEmptyAllow())
 	files := fileio.ReadMatches(s, matches, fileio.ReadUncompressed())
+	groups := beam.ParDo(s, &rowGroupsFn{Predicates: predicates}, files)
 	return beam.ParDo(s,
-		&parquetReadFn{Type: beam.EncodedType{T: t}},
-		files,
+		&parquetReadFn{Type: beam.EncodedType{T: t}, Columns: columns, Predicates: predicates},
+		groups,
 		beam.TypeDefinition{Var: beam.XType, T: t},
 	)
 }
 
-type parquetReadFn struct {
-	Type beam.EncodedType
+// newProjection returns the internal paths of the columns to read, including
+// the columns of the predicates, and the predicates of the read options.
+func newProjection(t reflect.Type, option *readOption) ([]string, []predicate, error) {
+	sh, err := schema.NewSchemaHandlerFromStruct(reflect.New(t).Interface())
+	if err != nil {
+		return nil, nil, err
+	}
+	var predicates []predicate
+	for _, p := range option.Predicates {
+		pred, err := newPredicate(sh, t, p.Column, p.Op, p.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		predicates = append(predicates, pred)
+	}
+	if len(option.Columns) == 0 {
+		return nil, predicates, nil
+	}
+	var columns []string
+	seen := make(map[string]bool)
+	add := func(column string) error {
+		inPath, err := columnInPath(sh, column)
+		if err != nil {
+			return err
+		}
+		if !seen[inPath] {
+			seen[inPath] = true
+			columns = append(columns, inPath)
+		}
+		return nil
+	}
+	for _, c := range option.Columns {
+		if err := add(c); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, p := range predicates {
+		if err := add(p.Column); err != nil {
+			return nil, nil, err
+		}
+	}
+	return columns, predicates, nil
+}
+
+// parquetFile is a file with the indices of the row groups to read from it.
+type parquetFile struct {
+	File      fileio.ReadableFile
+	RowGroups []int64
+}
+
+// rowGroupsFn reads the footer of a file, and emits the row groups that may
+// match the predicates.
+type rowGroupsFn struct {
+	Predicates []predicate
 }
 
-func (a *parquetReadFn) ProcessElement(ctx context.Context, file fileio.ReadableFile, emit func(beam.X)) error {
-	data, err := file.Read(ctx)
+func (f *rowGroupsFn) ProcessElement(ctx context.Context, file fileio.ReadableFile, emit func(parquetFile)) error {
+	fs, err := filesystem.New(ctx, file.Metadata.Path)
 	if err != nil {
 		return err
 	}
+	defer fs.Close()
+	src := newSourceFile(ctx, fs, file.Metadata.Path, file.Metadata.Size)
+	defer src.Close()
+
+	pr := &reader.ParquetReader{PFile: src}
+	if err := pr.ReadFooter(); err != nil {
+		return fmt.Errorf("error reading parquet footer of %v: %v", file.Metadata.Path, err)
+	}
+	var groups []int64
+	for i, rg := range pr.Footer.GetRowGroups() {
+		if rg.GetNumRows() > 0 && f.mayMatch(rg) {
+			groups = append(groups, int64(i))
+		}
+	}
+	if len(groups) > 0 {
+		emit(parquetFile{File: file, RowGroups: groups})
+	}
+	return nil
+}
+
+func (f *rowGroupsFn) mayMatch(rg *parquet.RowGroup) bool {
+	for _, p := range f.Predicates {
+		if !p.mayMatchRowGroup(rg) {
+			return false
+		}
+	}
+	return true
+}
+
+// parquetReadFn is a splittable DoFn reading the row groups of a parquetFile,
+// whose positions in its RowGroups are the restriction.
+type parquetReadFn struct {
+	Type beam.EncodedType
+	// Columns are the internal paths of the columns to read, or all if empty.
+	Columns    []string
+	Predicates []predicate
+}
+
+func (a *parquetReadFn) CreateInitialRestriction(file parquetFile) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: int64(len(file.RowGroups))}
+}
+
+func (a *parquetReadFn) SplitRestriction(_ parquetFile, rest offsetrange.Restriction) []offsetrange.Restriction {
+	return rest.SizedSplits(1)
+}
+
+func (a *parquetReadFn) RestrictionSize(_ parquetFile, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+func (a *parquetReadFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+}
 
-	bufferReader := buffer.NewBufferFileFromBytes(data)
-	parquetReader, err := reader.NewParquetReader(bufferReader, reflect.New(a.Type.T).Interface(), 4)
+func (a *parquetReadFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker, file parquetFile, emit func(beam.X)) error {
+	path := file.File.Metadata.Path
+	fs, err := filesystem.New(ctx, path)
 	if err != nil {
 		return err
 	}
+	defer fs.Close()
+	src := newSourceFile(ctx, fs, path, file.File.Metadata.Size)
+	defer src.Close()
 
-	vals, err := parquetReader.ReadByNumber(int(parquetReader.GetNumRows()))
+	parquetReader, err := reader.NewParquetReader(src, reflect.New(a.Type.T).Interface(), 4)
 	if err != nil {
 		return err
 	}
-	for _, v := range vals {
-		emit(v)
+	defer parquetReader.ReadStop()
+	a.project(parquetReader)
+
+	rowGroups := parquetReader.Footer.GetRowGroups()
+	for i := rt.GetRestriction().(offsetrange.Restriction).Start; rt.TryClaim(i); i++ {
+		rg := file.RowGroups[i]
+		if err := seekRowGroup(parquetReader, rg); err != nil {
+			return fmt.Errorf("error seeking row group %v of %v: %v", rg, path, err)
+		}
+		vals, err := parquetReader.ReadByNumber(int(rowGroups[rg].GetNumRows()))
+		if err != nil {
+			return err
+		}
+		for _, v := range vals {
+			if a.matches(v) {
+				emit(v)
+			}
+		}
+	}
+	return nil
+}
+
+// project drops the column buffers of the reader that aren't projected.
+func (a *parquetReadFn) project(pr *reader.ParquetReader) {
+	if len(a.Columns) == 0 {
+		return
+	}
+	keep := make(map[string]bool)
+	for _, c := range a.Columns {
+		keep[c] = true
 	}
+	for path, cb := range pr.ColumnBuffers {
+		if !keep[path] {
+			cb.PFile.Close()
+			delete(pr.ColumnBuffers, path)
+		}
+	}
+}
+
+func (a *parquetReadFn) matches(v any) bool {
+	if len(a.Predicates) == 0 {
+		return true
+	}
+	record := reflect.ValueOf(v)
+	for _, p := range a.Predicates {
+		if !p.matchesRecord(record) {
+			return false
+		}
+	}
+	return true
+}
 
+// seekRowGroup positions the column buffers of the reader at the start of
+// the given row group.
+func seekRowGroup(pr *reader.ParquetReader, rg int64) error {
+	for _, cb := range pr.ColumnBuffers {
+		cb.RowGroupIndex = rg
+		cb.DataTable = nil
+		cb.DataTableNumRows = -1
+		if err := cb.NextRowGroup(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Codec is the compression codec of the pages of a parquet file.
+type Codec string
+
+const (
+	// Uncompressed writes uncompressed pages.
+	Uncompressed Codec = "uncompressed"
+	// Snappy compresses pages with snappy. It's the default codec.
+	Snappy Codec = "snappy"
+	// Gzip compresses pages with gzip.
+	Gzip Codec = "gzip"
+	// Zstd compresses pages with zstandard.
+	Zstd Codec = "zstd"
+	// LZ4 compresses pages with lz4.
+	LZ4 Codec = "lz4"
+)
+
+var codecs = map[Codec]parquet.CompressionCodec{
+	Uncompressed: parquet.CompressionCodec_UNCOMPRESSED,
+	Snappy:       parquet.CompressionCodec_SNAPPY,
+	Gzip:         parquet.CompressionCodec_GZIP,
+	Zstd:         parquet.CompressionCodec_ZSTD,
+	LZ4:          parquet.CompressionCodec_LZ4,
+}
+
+type writeOption struct {
+	NumShards    int
+	Codec        Codec
+	RowGroupSize int64
+}
+
+// WriteOptionFn is a function that can be passed to Write to configure options for
+// writing parquet files.
+type WriteOptionFn func(*writeOption)
+
+// WriteNumShards specifies the number of files written, in parallel. Shards are
+// named after the file name, with the shard inserted before its extension, such
+// as "students-00001-of-00003.parquet".
+func WriteNumShards(n int) WriteOptionFn {
+	return func(o *writeOption) {
+		o.NumShards = n
+	}
+}
+
+// WriteCodec specifies the compression codec of the pages of the files.
+func WriteCodec(codec Codec) WriteOptionFn {
+	return func(o *writeOption) {
+		o.Codec = codec
+	}
+}
+
+// WriteRowGroupSize specifies the approximate size in bytes of the row groups of the
+// files, which are the unit of parallelism when reading them. It defaults to 128MB.
+func WriteRowGroupSize(size int64) WriteOptionFn {
+	return func(o *writeOption) {
+		o.RowGroupSize = size
+	}
+}
+
 // Write writes a PCollection<parquetStruct> to .parquet file.
 // Write expects elements of a struct type with parquet tags
 // For example:
@@ -108,43 +398,84 @@ func (a *parquetReadFn) ProcessElement(ctx context.Context, file fileio.Readable
 //	  Day     int32   `parquet:"name=day, type=INT32, convertedtype=DATE"`
 //	  Ignored int32   //without parquet tag and won't write
 //	}
-func Write(s beam.Scope, filename string, col beam.PCollection) {
+//
+// Write accepts a variadic number of WriteOptionFn that can be used to configure the
+// number of shards, the compression codec and the row group size. By default, all
+// elements are written to a single snappy compressed file.
+func Write(s beam.Scope, filename string, col beam.PCollection, opts ...WriteOptionFn) {
 	t := col.Type().Type()
 	s = s.Scope("parquetio.Write")
 	filesystem.ValidateScheme(filename)
-	pre := beam.AddFixedKey(s, col)
-	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &parquetWriteFn{Filename: filename, Type: beam.EncodedType{T: t}}, post)
-}
 
-type parquetWriteFn struct {
-	Type     beam.EncodedType
-	Filename string `json:"filename"`
-}
+	option := &writeOption{NumShards: 1, Codec: Snappy, RowGroupSize: 128 * 1024 * 1024}
+	for _, opt := range opts {
+		opt(option)
+	}
+	if _, ok := codecs[option.Codec]; !ok {
+		panic(fmt.Sprintf("parquetio.Write: unsupported codec %q", option.Codec))
+	}
+	if option.RowGroupSize <= 0 {
+		panic(fmt.Sprintf("parquetio.Write: row group size must be positive, got %v", option.RowGroupSize))
+	}
 
-func (a *parquetWriteFn) ProcessElement(ctx context.Context, _ int, iter func(*beam.X) bool) error {
-	fs, err := filesystem.New(ctx, a.Filename)
-	if err != nil {
-		return err
+	dir, name := splitPath(filename)
+	naming := fileio.FileNaming(fixedNaming{Name: name})
+	if option.NumShards != 1 {
+		ext := path.Ext(name)
+		naming = fileio.DefaultNaming(strings.TrimSuffix(name, ext), ext)
 	}
-	defer fs.Close()
+	sink := &parquetSink{Type: beam.EncodedType{T: t}, Codec: option.Codec, RowGroupSize: option.RowGroupSize}
+	fileio.WriteFiles(s, dir, sink, col, fileio.WriteNumShards(option.NumShards), fileio.WriteNaming(naming))
+}
 
-	fd, err := fs.OpenWrite(ctx, a.Filename)
-	if err != nil {
-		return err
+// splitPath splits a file path into its directory and file name. The directory of a
+// path without one is the current directory.
+func splitPath(filename string) (dir, name string) {
+	i := strings.LastIndexAny(filename, "/\\")
+	if i < 0 {
+		return ".", filename
 	}
+	return filename[:i+1], filename[i+1:]
+}
+
+// fixedNaming is a fileio.FileNaming that always returns the same file name.
+type fixedNaming struct {
+	Name string `json:"name"`
+}
+
+func (n fixedNaming) Filename(_ beam.Window, _ beam.PaneInfo, _, _ int) string {
+	return n.Name
+}
+
+// parquetSink is a fileio.Sink that writes elements of a struct type with parquet tags.
+type parquetSink struct {
+	Type         beam.EncodedType `json:"type"`
+	Codec        Codec            `json:"codec"`
+	RowGroupSize int64            `json:"rowGroupSize"`
+
+	pw *writer.ParquetWriter
+}
 
-	defer fd.Close()
-	pw, err := writer.NewParquetWriterFromWriter(fd, reflect.New(a.Type.T).Interface(), 4)
+func (a *parquetSink) Open(_ context.Context, w io.Writer) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, reflect.New(a.Type.T).Interface(), 4)
 	if err != nil {
 		return err
 	}
-
-	var val beam.X
-	for iter(&val) {
-		if err := pw.Write(val); err != nil {
-			return err
-		}
+	pw.CompressionType = codecs[a.Codec]
+	pw.RowGroupSize = a.RowGroupSize
+	// Rows are buffered up to a page per column and goroutine before the row
+	// group size is checked, so small row groups need small pages.
+	if size := a.RowGroupSize / (pw.NP * pw.SchemaHandler.GetColumnNum()); size < pw.PageSize {
+		pw.PageSize = max(size, 1)
 	}
-	return pw.WriteStop()
+	a.pw = pw
+	return nil
+}
+
+func (a *parquetSink) Write(_ context.Context, elm any) error {
+	return a.pw.Write(elm)
+}
+
+func (a *parquetSink) Flush(_ context.Context) error {
+	return a.pw.WriteStop()
 }
@@ -16,19 +16,30 @@
 package parquetio
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/fileio"
 	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/local"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/memfs"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
 	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/parquet"
 	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
 )
 
+func init() {
+	beam.RegisterType(reflect.TypeOf((*Score)(nil)).Elem())
+}
+
 func TestMain(m *testing.M) {
 	ptest.Main(m)
 }
@@ -124,3 +135,194 @@ func TestWrite(t *testing.T) {
 		t.Fatalf("students differs from studentList. got %+v, expected %+v", students, studentList)
 	}
 }
+
+type Score struct {
+	Name  string  `parquet:"name=name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Score int64   `parquet:"name=score, type=INT64"`
+	Bonus *int32  `parquet:"name=bonus, type=INT32, repetitiontype=OPTIONAL"`
+	Ratio float64 `parquet:"name=ratio, type=DOUBLE"`
+}
+
+func newScores(n int) []any {
+	var scores []any
+	for i := 0; i < n; i++ {
+		s := Score{Name: fmt.Sprintf("name%03d", i), Score: int64(i), Ratio: float64(i) / 2}
+		if i%2 == 0 {
+			bonus := int32(i)
+			s.Bonus = &bonus
+		}
+		scores = append(scores, s)
+	}
+	return scores
+}
+
+// writeScores writes the scores to a parquet file with small row groups, and
+// returns its path and number of row groups.
+func writeScores(t *testing.T, scores []any) (string, int) {
+	t.Helper()
+	parquetFile := filepath.Join(t.TempDir(), "scores.parquet")
+	p, s, col := ptest.CreateList(scores)
+	Write(s, parquetFile, col, WriteRowGroupSize(1024))
+	ptest.RunAndValidate(t, p)
+
+	pf, err := local.NewLocalFileReader(parquetFile)
+	if err != nil {
+		t.Fatalf("Failed to read file %v. err: %v", parquetFile, err)
+	}
+	defer pf.Close()
+	pr, err := reader.NewParquetReader(pf, new(Score), 1)
+	if err != nil {
+		t.Fatalf("Failed to create parquet reader %v. err: %v", parquetFile, err)
+	}
+	defer pr.ReadStop()
+	return parquetFile, len(pr.Footer.GetRowGroups())
+}
+
+func TestRead_RowGroups(t *testing.T) {
+	scores := newScores(2000)
+	parquetFile, rowGroups := writeScores(t, scores)
+	if rowGroups < 2 {
+		t.Fatalf("Test infra failure: wrote %v row groups, want several", rowGroups)
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+	read := Read(s, parquetFile, reflect.TypeOf(Score{}))
+	passert.Equals(s, read, scores...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_ColumnsAndPredicates(t *testing.T) {
+	parquetFile, _ := writeScores(t, newScores(2000))
+
+	p, s := beam.NewPipelineWithRoot()
+	read := Read(s, parquetFile, reflect.TypeOf(Score{}),
+		ReadColumns("name"),
+		ReadPredicate("score", GtEq, 1990),
+		ReadPredicate("bonus", NotEq, 1994),
+	)
+	var want []any
+	for i := 1990; i < 2000; i += 2 {
+		if i == 1994 {
+			continue
+		}
+		bonus := int32(i)
+		// The predicate columns are read along with the projected columns.
+		want = append(want, Score{Name: fmt.Sprintf("name%03d", i), Score: int64(i), Bonus: &bonus})
+	}
+	passert.Equals(s, read, want...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRead_InvalidOptionsPanic(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  ReadOptionFn
+	}{
+		{name: "unknown column", opt: ReadColumns("unknown")},
+		{name: "unknown predicate column", opt: ReadPredicate("unknown", Eq, 1)},
+		{name: "incomparable value", opt: ReadPredicate("score", Eq, "1")},
+		{name: "unknown operator", opt: ReadPredicate("score", "~", 1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatal("expected panic")
+				}
+			}()
+			_, s := beam.NewPipelineWithRoot()
+			Read(s, "scores.parquet", reflect.TypeOf(Score{}), test.opt)
+		})
+	}
+}
+
+func TestWrite_ShardsAndCodec(t *testing.T) {
+	scores := newScores(100)
+	p, s, col := ptest.CreateList(scores)
+	Write(s, "memfs://parquetio/scores.parquet", col, WriteNumShards(3), WriteCodec(Gzip))
+	ptest.RunAndValidate(t, p)
+
+	// memfs files aren't seekable, so they are also read through.
+	p, s = beam.NewPipelineWithRoot()
+	read := Read(s, "memfs://parquetio/scores-*-of-00003.parquet", reflect.TypeOf(Score{}))
+	passert.Equals(s, read, scores...)
+	ptest.RunAndValidate(t, p)
+}
+
+func TestRowGroupsFn_SkipsRowGroups(t *testing.T) {
+	path, rowGroups := writeScores(t, newScores(2000))
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := fileio.ReadableFile{Metadata: fileio.FileMetadata{Path: path, Size: info.Size()}}
+	sh, err := schema.NewSchemaHandlerFromStruct(new(Score))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pred, err := newPredicate(sh, reflect.TypeOf(Score{}), "score", Lt, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []parquetFile
+	fn := &rowGroupsFn{Predicates: []predicate{pred}}
+	if err := fn.ProcessElement(context.Background(), file, func(f parquetFile) { got = append(got, f) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0].RowGroups) != 1 || got[0].RowGroups[0] != 0 {
+		t.Errorf("rowGroupsFn emitted %+v, want only the first of %v row groups", got, rowGroups)
+	}
+}
+
+func TestPredicate_mayMatch(t *testing.T) {
+	min, max := value{Kind: intKind, Int: 10}, value{Kind: intKind, Int: 20}
+	tests := []struct {
+		op    Op
+		value value
+		want  bool
+	}{
+		{Eq, value{Kind: intKind, Int: 5}, false},
+		{Eq, value{Kind: intKind, Int: 15}, true},
+		{Eq, value{Kind: floatKind, Float: 20.5}, false},
+		{NotEq, value{Kind: intKind, Int: 15}, true},
+		{Lt, value{Kind: intKind, Int: 10}, false},
+		{LtEq, value{Kind: intKind, Int: 10}, true},
+		{Gt, value{Kind: intKind, Int: 20}, false},
+		{GtEq, value{Kind: intKind, Int: 20}, true},
+		{Eq, value{Kind: stringKind, Str: "15"}, true},
+	}
+	for _, test := range tests {
+		p := predicate{Op: test.op, Value: test.value}
+		if got := p.mayMatch(min, max); got != test.want {
+			t.Errorf("predicate(%v %+v).mayMatch(10, 20) = %v, want %v", test.op, test.value, got, test.want)
+		}
+	}
+	if p := (predicate{Op: NotEq, Value: min}); p.mayMatch(min, min) {
+		t.Errorf("predicate(!= 10).mayMatch(10, 10) = true, want false")
+	}
+}
+
+func TestDecodeStat(t *testing.T) {
+	tests := []struct {
+		typ  parquet.Type
+		in   []byte
+		want value
+	}{
+		{parquet.Type_INT32, []byte{0xff, 0xff, 0xff, 0xff}, value{Kind: intKind, Int: -1}},
+		{parquet.Type_INT64, []byte{2, 0, 0, 0, 0, 0, 0, 0}, value{Kind: intKind, Int: 2}},
+		{parquet.Type_FLOAT, []byte{0, 0, 0xc0, 0x3f}, value{Kind: floatKind, Float: 1.5}},
+		{parquet.Type_DOUBLE, []byte{0, 0, 0, 0, 0, 0, 0xf8, 0x3f}, value{Kind: floatKind, Float: 1.5}},
+		{parquet.Type_BYTE_ARRAY, []byte("abc"), value{Kind: stringKind, Str: "abc"}},
+		{parquet.Type_BOOLEAN, []byte{1}, value{Kind: boolKind, Bool: true}},
+	}
+	for _, test := range tests {
+		got, ok := decodeStat(test.typ, test.in)
+		if !ok || got != test.want {
+			t.Errorf("decodeStat(%v, %v) = %+v, %v, want %+v", test.typ, test.in, got, ok, test.want)
+		}
+	}
+	if _, ok := decodeStat(parquet.Type_INT32, []byte{1}); ok {
+		t.Errorf("decodeStat(INT32, [1]) succeeded, want failure")
+	}
+}
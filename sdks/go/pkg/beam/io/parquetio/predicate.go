@@ -0,0 +1,351 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquetio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/schema"
+)
+
+// Op is a comparison operator of a predicate.
+type Op string
+
+// Comparison operators of predicates.
+const (
+	Eq    Op = "="
+	NotEq Op = "!="
+	Lt    Op = "<"
+	LtEq  Op = "<="
+	Gt    Op = ">"
+	GtEq  Op = ">="
+)
+
+type valueKind int
+
+const (
+	intKind valueKind = iota
+	floatKind
+	stringKind
+	boolKind
+)
+
+// value is a comparable column value, normalized from Go values and
+// statistics of the physical types of columns.
+type value struct {
+	Kind  valueKind
+	Int   int64
+	Float float64
+	Str   string
+	Bool  bool
+}
+
+// newValue normalizes a Go value, dereferencing pointers. It returns false
+// for nil pointers and values of unsupported types.
+func newValue(v reflect.Value) (value, bool) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return value{}, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value{Kind: intKind, Int: v.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value{Kind: intKind, Int: int64(v.Uint())}, true
+	case reflect.Float32, reflect.Float64:
+		return value{Kind: floatKind, Float: v.Float()}, true
+	case reflect.String:
+		return value{Kind: stringKind, Str: v.String()}, true
+	case reflect.Bool:
+		return value{Kind: boolKind, Bool: v.Bool()}, true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return value{Kind: stringKind, Str: string(v.Bytes())}, true
+		}
+	}
+	return value{}, false
+}
+
+// kindOfType returns the kind of values of a physical parquet type.
+func kindOfType(t parquet.Type) (valueKind, bool) {
+	switch t {
+	case parquet.Type_INT32, parquet.Type_INT64:
+		return intKind, true
+	case parquet.Type_FLOAT, parquet.Type_DOUBLE:
+		return floatKind, true
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		return stringKind, true
+	case parquet.Type_BOOLEAN:
+		return boolKind, true
+	}
+	return 0, false
+}
+
+// decodeStat decodes a plain encoded minimum or maximum statistic of a column
+// of the physical type.
+func decodeStat(t parquet.Type, b []byte) (value, bool) {
+	switch t {
+	case parquet.Type_INT32:
+		if len(b) == 4 {
+			return value{Kind: intKind, Int: int64(int32(binary.LittleEndian.Uint32(b)))}, true
+		}
+	case parquet.Type_INT64:
+		if len(b) == 8 {
+			return value{Kind: intKind, Int: int64(binary.LittleEndian.Uint64(b))}, true
+		}
+	case parquet.Type_FLOAT:
+		if len(b) == 4 {
+			return value{Kind: floatKind, Float: float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))}, true
+		}
+	case parquet.Type_DOUBLE:
+		if len(b) == 8 {
+			return value{Kind: floatKind, Float: math.Float64frombits(binary.LittleEndian.Uint64(b))}, true
+		}
+	case parquet.Type_BYTE_ARRAY, parquet.Type_FIXED_LEN_BYTE_ARRAY:
+		return value{Kind: stringKind, Str: string(b)}, true
+	case parquet.Type_BOOLEAN:
+		if len(b) == 1 {
+			return value{Kind: boolKind, Bool: b[0] != 0}, true
+		}
+	}
+	return value{}, false
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater than b,
+// comparing integers and floats as floats. Values of other different kinds
+// aren't comparable.
+func compare(a, b value) (int, bool) {
+	if a.Kind != b.Kind {
+		if a.Kind == intKind && b.Kind == floatKind {
+			return compare(value{Kind: floatKind, Float: float64(a.Int)}, b)
+		}
+		if a.Kind == floatKind && b.Kind == intKind {
+			return compare(a, value{Kind: floatKind, Float: float64(b.Int)})
+		}
+		return 0, false
+	}
+	var lt, gt bool
+	switch a.Kind {
+	case intKind:
+		lt, gt = a.Int < b.Int, a.Int > b.Int
+	case floatKind:
+		if math.IsNaN(a.Float) || math.IsNaN(b.Float) {
+			return 0, false
+		}
+		lt, gt = a.Float < b.Float, a.Float > b.Float
+	case stringKind:
+		lt, gt = a.Str < b.Str, a.Str > b.Str
+	case boolKind:
+		lt, gt = !a.Bool && b.Bool, a.Bool && !b.Bool
+	}
+	switch {
+	case lt:
+		return -1, true
+	case gt:
+		return 1, true
+	}
+	return 0, true
+}
+
+// predicate compares the values of a column to a constant.
+type predicate struct {
+	// Column is the dotted path of the column in the file.
+	Column string
+	// Fields are the names of the fields holding the column in the Go type.
+	Fields []string
+	Op     Op
+	Value  value
+}
+
+// newPredicate returns a predicate on a column of the schema of the given
+// struct type, checking that the column holds values comparable to v.
+func newPredicate(sh *schema.SchemaHandler, t reflect.Type, column string, op Op, v any) (predicate, error) {
+	switch op {
+	case Eq, NotEq, Lt, LtEq, Gt, GtEq:
+	default:
+		return predicate{}, fmt.Errorf("unknown operator %q", op)
+	}
+	val, ok := newValue(reflect.ValueOf(v))
+	if !ok {
+		return predicate{}, fmt.Errorf("unsupported predicate value %v of type %T", v, v)
+	}
+	inPath, err := columnInPath(sh, column)
+	if err != nil {
+		return predicate{}, err
+	}
+	elem := sh.SchemaElements[sh.MapIndex[inPath]]
+	kind, ok := kindOfType(elem.GetType())
+	if !ok {
+		return predicate{}, fmt.Errorf("column %v of type %v does not support predicates", column, elem.GetType())
+	}
+	if _, ok := compare(value{Kind: kind}, val); !ok {
+		return predicate{}, fmt.Errorf("column %v of type %v is not comparable to %v of type %T", column, elem.GetType(), v, v)
+	}
+	fields := common.StrToPath(inPath)[1:]
+	for _, f := range fields {
+		t = derefType(t)
+		if t.Kind() != reflect.Struct {
+			return predicate{}, fmt.Errorf("column %v is not a field of %v", column, t)
+		}
+		sf, ok := t.FieldByName(f)
+		if !ok {
+			return predicate{}, fmt.Errorf("column %v is not a field of %v", column, t)
+		}
+		t = sf.Type
+	}
+	if _, ok := newValue(reflect.New(derefType(t)).Elem()); !ok {
+		return predicate{}, fmt.Errorf("column %v is not a scalar field", column)
+	}
+	return predicate{Column: column, Fields: fields, Op: op, Value: val}, nil
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// columnInPath returns the internal path of the given dotted path of a
+// leaf column of the schema.
+func columnInPath(sh *schema.SchemaHandler, column string) (string, error) {
+	exPath := append([]string{sh.GetRootExName()}, strings.Split(column, ".")...)
+	inPath, ok := sh.ExPathToInPath[common.PathToStr(exPath)]
+	if !ok {
+		return "", fmt.Errorf("unknown column %v", column)
+	}
+	if sh.SchemaElements[sh.MapIndex[inPath]].GetNumChildren() > 0 {
+		return "", fmt.Errorf("column %v is not a leaf column", column)
+	}
+	return inPath, nil
+}
+
+// matches reports whether a value satisfies the predicate.
+func (p predicate) matches(v value) bool {
+	c, ok := compare(v, p.Value)
+	if !ok {
+		return false
+	}
+	switch p.Op {
+	case Eq:
+		return c == 0
+	case NotEq:
+		return c != 0
+	case Lt:
+		return c < 0
+	case LtEq:
+		return c <= 0
+	case Gt:
+		return c > 0
+	case GtEq:
+		return c >= 0
+	}
+	return false
+}
+
+// matchesRecord reports whether the field of the record holding the column
+// satisfies the predicate. Null values don't satisfy any predicate.
+func (p predicate) matchesRecord(record reflect.Value) bool {
+	v := record
+	for _, f := range p.Fields {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return false
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(f)
+	}
+	val, ok := newValue(v)
+	return ok && p.matches(val)
+}
+
+// mayMatch reports whether any value between the minimum and maximum of a
+// column chunk may satisfy the predicate, so that row groups that don't are
+// skipped.
+func (p predicate) mayMatch(min, max value) bool {
+	cmin, ok := compare(min, p.Value)
+	if !ok {
+		return true
+	}
+	cmax, ok := compare(max, p.Value)
+	if !ok {
+		return true
+	}
+	switch p.Op {
+	case Eq:
+		return cmin <= 0 && cmax >= 0
+	case NotEq:
+		return cmin != 0 || cmax != 0
+	case Lt:
+		return cmin < 0
+	case LtEq:
+		return cmin <= 0
+	case Gt:
+		return cmax > 0
+	case GtEq:
+		return cmax >= 0
+	}
+	return true
+}
+
+// mayMatchRowGroup reports whether the rows of the row group may satisfy the
+// predicate, according to the statistics of its column chunk.
+func (p predicate) mayMatchRowGroup(rg *parquet.RowGroup) bool {
+	for _, chunk := range rg.GetColumns() {
+		md := chunk.GetMetaData()
+		if md == nil || strings.Join(md.GetPathInSchema(), ".") != p.Column {
+			continue
+		}
+		stats := md.GetStatistics()
+		if stats == nil {
+			return true
+		}
+		if md.GetNumValues() > 0 && stats.IsSetNullCount() && stats.GetNullCount() == md.GetNumValues() {
+			// Null values don't satisfy any predicate.
+			return false
+		}
+		minb, maxb := stats.GetMinValue(), stats.GetMaxValue()
+		if !stats.IsSetMinValue() || !stats.IsSetMaxValue() {
+			if md.GetType() == parquet.Type_BYTE_ARRAY || md.GetType() == parquet.Type_FIXED_LEN_BYTE_ARRAY {
+				// The deprecated statistics of byte arrays are signed.
+				return true
+			}
+			minb, maxb = stats.GetMin(), stats.GetMax()
+			if minb == nil || maxb == nil {
+				return true
+			}
+		}
+		min, ok := decodeStat(md.GetType(), minb)
+		if !ok {
+			return true
+		}
+		max, ok := decodeStat(md.GetType(), maxb)
+		if !ok {
+			return true
+		}
+		return p.mayMatch(min, max)
+	}
+	return true
+}
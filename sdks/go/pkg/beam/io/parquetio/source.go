@@ -0,0 +1,133 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parquetio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// maxSkip is the largest distance a non seekable file is read ahead to reach
+// a position, rather than reopening it.
+const maxSkip = 1 << 20
+
+// sourceFile is a read only source.ParquetFile of a file of a Beam file
+// system, so that only the footer and the column chunks that are read are
+// fetched. Files whose readers don't implement io.Seeker are reopened to seek
+// backwards, and read through to seek forwards.
+type sourceFile struct {
+	ctx  context.Context
+	fs   filesystem.Interface
+	path string
+	size int64
+
+	rc    io.ReadCloser
+	pos   int64 // position of the next read
+	rcPos int64 // position of rc
+}
+
+func newSourceFile(ctx context.Context, fs filesystem.Interface, path string, size int64) *sourceFile {
+	return &sourceFile{ctx: ctx, fs: fs, path: path, size: size}
+}
+
+func (f *sourceFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, fmt.Errorf("invalid whence %v", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position %v seeking %v", offset, f.path)
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+func (f *sourceFile) Read(p []byte) (int, error) {
+	if err := f.sync(); err != nil {
+		return 0, err
+	}
+	n, err := f.rc.Read(p)
+	f.pos += int64(n)
+	f.rcPos = f.pos
+	return n, err
+}
+
+// sync moves the underlying reader to the position of the next read.
+func (f *sourceFile) sync() error {
+	if f.rc != nil && f.rcPos == f.pos {
+		return nil
+	}
+	if f.rc == nil || f.pos < f.rcPos || f.pos-f.rcPos > maxSkip {
+		if _, ok := f.rc.(io.Seeker); !ok {
+			if f.rc != nil {
+				f.rc.Close()
+			}
+			rc, err := f.fs.OpenRead(f.ctx, f.path)
+			if err != nil {
+				return err
+			}
+			f.rc, f.rcPos = rc, 0
+		}
+	}
+	if s, ok := f.rc.(io.Seeker); ok {
+		if _, err := s.Seek(f.pos, io.SeekStart); err != nil {
+			return err
+		}
+		f.rcPos = f.pos
+		return nil
+	}
+	if _, err := io.CopyN(io.Discard, f.rc, f.pos-f.rcPos); err != nil {
+		return err
+	}
+	f.rcPos = f.pos
+	return nil
+}
+
+func (f *sourceFile) Write(_ []byte) (int, error) {
+	return 0, errors.New("parquet source files are read only")
+}
+
+func (f *sourceFile) Close() error {
+	if f.rc == nil {
+		return nil
+	}
+	err := f.rc.Close()
+	f.rc = nil
+	return err
+}
+
+// Open opens the file again, for reading a column independently. Column
+// chunks in other files aren't supported.
+func (f *sourceFile) Open(name string) (source.ParquetFile, error) {
+	if name != "" && name != f.path {
+		return nil, fmt.Errorf("column chunks in other files are not supported: %v", name)
+	}
+	return newSourceFile(f.ctx, f.fs, f.path, f.size), nil
+}
+
+func (f *sourceFile) Create(_ string) (source.ParquetFile, error) {
+	return nil, errors.New("parquet source files are read only")
+}
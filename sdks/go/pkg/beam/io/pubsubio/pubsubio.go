@@ -15,7 +15,8 @@
 
 // Package pubsubio provides access to Pub/Sub on Dataflow streaming.
 //
-// This implementation only functions on the Dataflow runner.
+// Read and Write only function on the Dataflow runner. ReadSdf is a native
+// implementation of Read, which functions on any portable runner.
 //
 // See https://cloud.google.com/dataflow/docs/concepts/streaming-with-cloud-pubsub
 // for details on using Pub/Sub with Dataflow.
@@ -0,0 +1,463 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsubio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	vkit "cloud.google.com/go/pubsub/apiv1"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/util/pubsubx"
+	"github.com/google/uuid"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	register.DoFn3x1[context.Context, []byte, func(string), error](&createSubscriptionFn{})
+	register.DoFn6x2[
+		context.Context, *watermarkEstimator, beam.BundleFinalization, *sdf.LockRTracker, string,
+		func(beam.EventTime, *pb.PubsubMessage), sdf.ProcessContinuation, error,
+	](&readFn{})
+	register.Function2x0(messageDataFn)
+	register.Emitter1[string]()
+	register.Emitter2[beam.EventTime, *pb.PubsubMessage]()
+}
+
+const (
+	pullBatchSize   = 1000
+	pullTimeout     = 5 * time.Second
+	ackTimeout      = 10 * time.Minute
+	dedupeWindow    = 10 * time.Minute
+	assumedLag      = 1 * time.Second
+	resumeDelay     = 1 * time.Second
+	watermarkWindow = 1 * time.Minute
+	redeliveryDelay = 10 * time.Second
+)
+
+// ReadSdf reads PubSubMessages from the given pubsub topic or subscription,
+// like Read. Unlike Read, it is implemented natively as a splittable DoFn,
+// so it works on any portable runner.
+//
+// Messages are acknowledged once the runner has durably committed the bundle
+// that read them. The event time of each message is its publish time, or the
+// value of TimestampAttribute if set, which must be either milliseconds since
+// the Unix epoch or an RFC 3339 timestamp. A message whose TimestampAttribute
+// can't be parsed fails the bundle, and is made available for redelivery.
+//
+// If IDAttribute is set, messages with the ID of a message committed in the
+// last 10 minutes are dropped as duplicates. Duplicates of a message that
+// hasn't been committed yet are left to be redelivered, and only acknowledged
+// once the original has been committed.
+//
+// The watermark is the oldest event time of the messages received in the last
+// minute. If no TimestampAttribute is set, it advances with the wall clock
+// while the subscription is idle, as publish times do. Otherwise, it's held
+// while the subscription is idle.
+//
+// If a Topic is given, a new subscription to it is created for the pipeline,
+// which is not deleted afterwards.
+func ReadSdf(s beam.Scope, project string, opts ReadOptions) beam.PCollection {
+	s = s.Scope("pubsubio.ReadSdf")
+
+	if (opts.Topic == "" && opts.Subscription == "") || (opts.Topic != "" && opts.Subscription != "") {
+		panic("Exactly one of Topic or Subscription must be set in ReadOptions")
+	}
+	return readSdf(s, project, opts, &readFn{
+		IDAttribute:        opts.IDAttribute,
+		TimestampAttribute: opts.TimestampAttribute,
+	})
+}
+
+// readSdf reads from the given topic or subscription with the given readFn.
+func readSdf(s beam.Scope, project string, opts ReadOptions, fn *readFn) beam.PCollection {
+	imp := beam.Impulse(s)
+	var sub beam.PCollection
+	if opts.Topic != "" {
+		sub = beam.ParDo(s, &createSubscriptionFn{Project: project, Topic: opts.Topic}, imp)
+	} else {
+		sub = beam.Create(s, pubsubx.MakeQualifiedSubscriptionName(project, opts.Subscription))
+	}
+	msgs := beam.ParDo(s, fn, sub)
+
+	if opts.WithAttributes {
+		return msgs
+	}
+	return beam.ParDo(s, messageDataFn, msgs)
+}
+
+func messageDataFn(msg *pb.PubsubMessage, emit func([]byte)) {
+	emit(msg.GetData())
+}
+
+// createSubscriptionFn creates a new subscription to a topic, and emits its
+// qualified name.
+type createSubscriptionFn struct {
+	Project string
+	Topic   string
+}
+
+func (fn *createSubscriptionFn) ProcessElement(ctx context.Context, _ []byte, emit func(string)) error {
+	client, err := pubsub.NewClient(ctx, fn.Project)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	id := fmt.Sprintf("%v.beam.%v", fn.Topic, uuid.New())
+	sub, err := pubsubx.EnsureSubscription(ctx, client, fn.Topic, id)
+	if err != nil {
+		return err
+	}
+	emit(pubsubx.MakeQualifiedSubscriptionName(fn.Project, sub.ID()))
+	return nil
+}
+
+// readFn is a splittable DoFn that pulls messages from a subscription.
+//
+// The restriction counts the messages emitted. It is never split, other than
+// to checkpoint, so each subscription is read by a single bundle at a time.
+type readFn struct {
+	IDAttribute        string
+	TimestampAttribute string
+	// MaxMessages bounds the number of messages read, which is otherwise
+	// unbounded, if positive. Only set by tests.
+	MaxMessages int64
+
+	client     *vkit.SubscriberClient
+	ids        *dedupeCache // ids holds the IDs of committed messages.
+	inFlight   *dedupeCache // inFlight holds the IDs of emitted, uncommitted messages.
+	eventTimes *eventTimes
+}
+
+func (fn *readFn) Setup(ctx context.Context) error {
+	client, err := pubsubx.NewSubscriberClient(ctx)
+	if err != nil {
+		return err
+	}
+	fn.client = client
+	fn.ids = newDedupeCache(dedupeWindow)
+	fn.inFlight = newDedupeCache(ackTimeout)
+	fn.eventTimes = &eventTimes{window: watermarkWindow}
+	return nil
+}
+
+func (fn *readFn) Teardown() error {
+	if fn.client == nil {
+		return nil
+	}
+	return fn.client.Close()
+}
+
+func (fn *readFn) CreateInitialRestriction(_ string) offsetrange.Restriction {
+	end := int64(math.MaxInt64)
+	if fn.MaxMessages > 0 {
+		end = fn.MaxMessages
+	}
+	return offsetrange.Restriction{Start: 0, End: end}
+}
+
+func (fn *readFn) SplitRestriction(_ string, rest offsetrange.Restriction) []offsetrange.Restriction {
+	return []offsetrange.Restriction{rest}
+}
+
+func (fn *readFn) RestrictionSize(_ string, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+func (fn *readFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+}
+
+func (fn *readFn) TruncateRestriction(rt *sdf.LockRTracker, _ string) offsetrange.Restriction {
+	start := rt.GetRestriction().(offsetrange.Restriction).Start
+	return offsetrange.Restriction{Start: start, End: start}
+}
+
+func (fn *readFn) InitialWatermarkEstimatorState(et beam.EventTime, _ offsetrange.Restriction, _ string) int64 {
+	return et.Milliseconds()
+}
+
+func (fn *readFn) CreateWatermarkEstimator(ms int64) *watermarkEstimator {
+	return &watermarkEstimator{state: ms}
+}
+
+func (fn *readFn) WatermarkEstimatorState(we *watermarkEstimator) int64 {
+	return we.state
+}
+
+func (fn *readFn) ProcessElement(
+	ctx context.Context,
+	we *watermarkEstimator,
+	bf beam.BundleFinalization,
+	rt *sdf.LockRTracker,
+	sub string,
+	emit func(beam.EventTime, *pb.PubsubMessage),
+) (sdf.ProcessContinuation, error) {
+	rest := rt.GetRestriction().(offsetrange.Restriction)
+	pos := rest.Start
+	if pos >= rest.End {
+		rt.TryClaim(rest.End)
+		return sdf.StopProcessing(), nil
+	}
+
+	pullCtx, cancel := context.WithTimeout(ctx, pullTimeout)
+	resp, err := fn.client.Pull(pullCtx, &pb.PullRequest{
+		Subscription: sub,
+		MaxMessages:  pullBatchSize,
+	})
+	cancel()
+	if err != nil && status.Code(err) != codes.DeadlineExceeded {
+		return sdf.StopProcessing(), fmt.Errorf("error pulling from %v: %w", sub, err)
+	}
+	received := resp.GetReceivedMessages()
+
+	var ackIDs, emittedIDs, unclaimed, redeliver []string
+	var procErr error
+	done := false
+	for _, rm := range received {
+		if done {
+			unclaimed = append(unclaimed, rm.GetAckId())
+			continue
+		}
+		msg := rm.GetMessage()
+		id := msg.GetAttributes()[fn.IDAttribute]
+		dedupe := fn.IDAttribute != "" && id != ""
+		if dedupe && fn.ids.seen(id) {
+			ackIDs = append(ackIDs, rm.GetAckId())
+			continue
+		}
+		if dedupe && fn.inFlight.seen(id) {
+			// The original may yet fail to commit, so the duplicate can only
+			// be dropped once it has.
+			redeliver = append(redeliver, rm.GetAckId())
+			continue
+		}
+		et, err := messageTimestamp(msg, fn.TimestampAttribute)
+		if err != nil {
+			procErr = err
+			done = true
+			unclaimed = append(unclaimed, rm.GetAckId())
+			continue
+		}
+		fn.eventTimes.add(time.Now(), et)
+		if !rt.TryClaim(pos) {
+			done = true
+			unclaimed = append(unclaimed, rm.GetAckId())
+			continue
+		}
+		pos++
+		if dedupe {
+			fn.inFlight.add(id)
+			emittedIDs = append(emittedIDs, id)
+		}
+		emit(et, msg)
+		ackIDs = append(ackIDs, rm.GetAckId())
+	}
+
+	// Make messages that weren't read available for redelivery immediately,
+	// and duplicates of uncommitted messages once those have likely committed.
+	// The messages read are kept from redelivery for as long as the bundle's
+	// finalization may take to acknowledge them.
+	fn.modifyAckDeadline(ctx, sub, unclaimed, 0)
+	fn.modifyAckDeadline(ctx, sub, redeliver, redeliveryDelay)
+	fn.modifyAckDeadline(ctx, sub, ackIDs, ackTimeout)
+	if len(ackIDs) > 0 {
+		client, ids, inFlight := fn.client, fn.ids, fn.inFlight
+		bf.RegisterCallback(ackTimeout, func() error {
+			for _, id := range emittedIDs {
+				ids.add(id)
+				inFlight.remove(id)
+			}
+			return client.Acknowledge(context.Background(), &pb.AcknowledgeRequest{
+				Subscription: sub,
+				AckIds:       ackIDs,
+			})
+		})
+	}
+	fn.observeWatermark(we)
+	if procErr != nil {
+		return sdf.StopProcessing(), procErr
+	}
+	if done {
+		return sdf.StopProcessing(), nil
+	}
+	if len(received) == 0 {
+		return sdf.ResumeProcessingIn(resumeDelay), nil
+	}
+	// Checkpoint after every batch, so the bundle can commit and its messages
+	// be acknowledged promptly.
+	return sdf.ResumeProcessingIn(0), nil
+}
+
+// modifyAckDeadline makes the given messages available for redelivery after
+// the given delay, unless they're acknowledged first.
+func (fn *readFn) modifyAckDeadline(ctx context.Context, sub string, ackIDs []string, delay time.Duration) {
+	if len(ackIDs) == 0 {
+		return
+	}
+	if err := fn.client.ModifyAckDeadline(ctx, &pb.ModifyAckDeadlineRequest{
+		Subscription:       sub,
+		AckIds:             ackIDs,
+		AckDeadlineSeconds: int32(delay / time.Second),
+	}); err != nil {
+		log.Warnf(ctx, "pubsubio.ReadSdf: unable to modify ack deadlines of messages from %v: %v", sub, err)
+	}
+}
+
+// observeWatermark advances the watermark to the oldest event time of the
+// recently received messages. Without any, publish times are assumed to
+// follow the wall clock, while event times from the TimestampAttribute may not.
+func (fn *readFn) observeWatermark(we *watermarkEstimator) {
+	now := time.Now()
+	if et, ok := fn.eventTimes.min(now); ok {
+		we.ObserveTimestamp(et.ToTime())
+	} else if fn.TimestampAttribute == "" {
+		we.ObserveTimestamp(now.Add(-assumedLag))
+	}
+}
+
+// messageTimestamp returns the event time of a message, which is the value of
+// the given attribute, if set, or otherwise its publish time.
+func messageTimestamp(msg *pb.PubsubMessage, attr string) (beam.EventTime, error) {
+	if attr != "" {
+		if v, ok := msg.GetAttributes()[attr]; ok {
+			if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return mtime.FromMilliseconds(ms), nil
+			}
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return 0, fmt.Errorf("pubsubio.ReadSdf: invalid timestamp attribute %v=%q on message %v", attr, v, msg.GetMessageId())
+			}
+			return mtime.FromTime(t), nil
+		}
+	}
+	return mtime.FromTime(msg.GetPublishTime().AsTime()), nil
+}
+
+// watermarkEstimator holds the watermark, in milliseconds since the epoch,
+// which only ever advances.
+type watermarkEstimator struct {
+	state int64
+}
+
+func (e *watermarkEstimator) CurrentWatermark() time.Time {
+	return time.UnixMilli(e.state)
+}
+
+func (e *watermarkEstimator) ObserveTimestamp(t time.Time) {
+	ms := t.UnixMilli()
+	if ms > e.state {
+		e.state = ms
+	}
+}
+
+// eventTimes tracks the oldest event time of the messages received within a
+// window of wall clock time, in buckets of a second.
+type eventTimes struct {
+	window  time.Duration
+	buckets []eventTimeBucket
+}
+
+type eventTimeBucket struct {
+	received time.Time
+	oldest   mtime.Time
+}
+
+// add records the event time of a message received now.
+func (e *eventTimes) add(now time.Time, et mtime.Time) {
+	at := now.Truncate(time.Second)
+	if n := len(e.buckets); n > 0 && e.buckets[n-1].received.Equal(at) {
+		e.buckets[n-1].oldest = mtime.Min(e.buckets[n-1].oldest, et)
+		return
+	}
+	e.buckets = append(e.buckets, eventTimeBucket{received: at, oldest: et})
+}
+
+// min returns the oldest event time of the messages received within the
+// window, or false if there were none.
+func (e *eventTimes) min(now time.Time) (mtime.Time, bool) {
+	i := 0
+	for i < len(e.buckets) && now.Sub(e.buckets[i].received) >= e.window {
+		i++
+	}
+	e.buckets = e.buckets[i:]
+	if len(e.buckets) == 0 {
+		return 0, false
+	}
+	oldest := mtime.MaxTimestamp
+	for _, b := range e.buckets {
+		oldest = mtime.Min(oldest, b.oldest)
+	}
+	return oldest, true
+}
+
+// dedupeCache remembers message IDs for a limited time. It's safe for
+// concurrent use, as bundle finalization may run alongside processing.
+type dedupeCache struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	ids  map[string]time.Time
+	now  func() time.Time
+	next time.Time // next is when expired IDs are next removed.
+}
+
+func newDedupeCache(ttl time.Duration) *dedupeCache {
+	return &dedupeCache{ttl: ttl, ids: map[string]time.Time{}, now: time.Now}
+}
+
+// seen reports whether the ID was added within the last ttl.
+func (c *dedupeCache) seen(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.ids[id]
+	return ok && c.now().Sub(t) < c.ttl
+}
+
+// add records the ID as seen now.
+func (c *dedupeCache) add(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if !now.Before(c.next) {
+		for k, t := range c.ids {
+			if now.Sub(t) >= c.ttl {
+				delete(c.ids, k)
+			}
+		}
+		c.next = now.Add(c.ttl)
+	}
+	c.ids[id] = now
+}
+
+// remove forgets the ID.
+func (c *dedupeCache) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids, id)
+}
@@ -0,0 +1,267 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pubsubio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	pb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+// initTestServer starts a fake Pub/Sub server, with a topic and a
+// subscription to it, and points clients at it.
+func initTestServer(t *testing.T, topic, sub string) (*pstest.Server, *pubsub.Client, *pubsub.Topic) {
+	t.Helper()
+	srv := pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+	t.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr)
+
+	ctx := context.Background()
+	client, err := pubsub.NewClient(ctx, "project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	top, err := client.CreateTopic(ctx, topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(top.Stop)
+	if _, err := client.CreateSubscription(ctx, sub, pubsub.SubscriptionConfig{Topic: top}); err != nil {
+		t.Fatal(err)
+	}
+	return srv, client, top
+}
+
+// readMax is ReadSdf, but reads at most the given number of messages.
+func readMax(s beam.Scope, opts ReadOptions, n int64) beam.PCollection {
+	s = s.Scope("pubsubio.ReadSdf")
+	return readSdf(s, "project", opts, &readFn{
+		IDAttribute:        opts.IDAttribute,
+		TimestampAttribute: opts.TimestampAttribute,
+		MaxMessages:        n,
+	})
+}
+
+func publish(t *testing.T, top *pubsub.Topic, msgs ...*pubsub.Message) {
+	t.Helper()
+	for _, msg := range msgs {
+		if _, err := top.Publish(context.Background(), msg).Get(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadSdf(t *testing.T) {
+	srv, _, top := initTestServer(t, "topic", "sub")
+	publish(t, top,
+		&pubsub.Message{Data: []byte("a"), Attributes: map[string]string{"id": "1"}},
+		&pubsub.Message{Data: []byte("b"), Attributes: map[string]string{"id": "2"}},
+		&pubsub.Message{Data: []byte("a"), Attributes: map[string]string{"id": "1"}},
+		&pubsub.Message{Data: []byte("c")},
+	)
+
+	p, s := beam.NewPipelineWithRoot()
+	msgs := readMax(s, ReadOptions{
+		Subscription: "sub",
+		IDAttribute:  "id",
+	}, 3)
+	passert.Equals(s, msgs, []byte("a"), []byte("b"), []byte("c"))
+	ptest.RunAndValidate(t, p)
+
+	// Either copy of the duplicate may be read first. The other is only
+	// acknowledged if it's redelivered after the first was committed.
+	acked, extended := map[string]bool{}, map[string]bool{}
+	for _, m := range srv.Messages() {
+		if m.Acks > 0 {
+			acked[string(m.Data)] = true
+		}
+		for _, ma := range m.Modacks {
+			if ma.AckDeadline == int32(ackTimeout/time.Second) {
+				extended[string(m.Data)] = true
+			}
+		}
+	}
+	for _, data := range []string{"a", "b", "c"} {
+		if !acked[data] {
+			t.Errorf("message %q wasn't acknowledged", data)
+		}
+		// Messages awaiting their bundle's finalization must not be redelivered.
+		if !extended[data] {
+			t.Errorf("ack deadline of message %q wasn't extended until finalization", data)
+		}
+	}
+}
+
+func TestReadSdf_InvalidTimestamp(t *testing.T) {
+	srv, _, top := initTestServer(t, "topic", "sub")
+	publish(t, top,
+		&pubsub.Message{Data: []byte("a"), Attributes: map[string]string{"ts": "1000"}},
+		&pubsub.Message{Data: []byte("b"), Attributes: map[string]string{"ts": "yesterday"}},
+	)
+
+	p, s := beam.NewPipelineWithRoot()
+	readMax(s, ReadOptions{
+		Subscription:       "sub",
+		TimestampAttribute: "ts",
+	}, 2)
+	if err := ptest.Run(p); err == nil {
+		t.Fatal("pipeline succeeded, want error for the invalid timestamp attribute")
+	}
+	for _, m := range srv.Messages() {
+		if m.Acks != 0 {
+			t.Errorf("message %q was acknowledged, but its bundle failed", m.Data)
+		}
+	}
+}
+
+func TestReadSdf_Topic(t *testing.T) {
+	_, client, top := initTestServer(t, "topic", "sub")
+
+	p, s := beam.NewPipelineWithRoot()
+	msgs := readMax(s, ReadOptions{
+		Topic:          "topic",
+		WithAttributes: true,
+	}, 1)
+	passert.Count(s, msgs, "messages", 1)
+
+	// Publish once the pipeline's subscription exists.
+	go func() {
+		for {
+			it := client.Subscriptions(context.Background())
+			for sub, err := it.Next(); err == nil; sub, err = it.Next() {
+				if sub.ID() != "sub" {
+					top.Publish(context.Background(), &pubsub.Message{Data: []byte("a")})
+					return
+				}
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+	ptest.RunAndValidate(t, p)
+}
+
+func TestReadSdf_BothTopicAndSubscriptionPanics(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("expected panic when both topic and subscription are set")
+		}
+	}()
+	_, s := beam.NewPipelineWithRoot()
+	ReadSdf(s, "test-project", ReadOptions{Topic: "topic", Subscription: "sub"})
+}
+
+func TestMessageTimestamp(t *testing.T) {
+	publish := time.UnixMilli(5000)
+	tests := []struct {
+		name    string
+		attrs   map[string]string
+		attr    string
+		want    beam.EventTime
+		wantErr bool
+	}{
+		{name: "publish time", want: mtime.FromMilliseconds(5000)},
+		{name: "missing attribute", attr: "ts", want: mtime.FromMilliseconds(5000)},
+		{name: "millis attribute", attrs: map[string]string{"ts": "1234"}, attr: "ts", want: mtime.FromMilliseconds(1234)},
+		{name: "RFC 3339 attribute", attrs: map[string]string{"ts": "1970-01-01T00:00:02.5Z"}, attr: "ts", want: mtime.FromMilliseconds(2500)},
+		{name: "invalid attribute", attrs: map[string]string{"ts": "yesterday"}, attr: "ts", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &pb.PubsubMessage{Attributes: tt.attrs, PublishTime: timestamppb.New(publish)}
+			got, err := messageTimestamp(msg, tt.attr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("messageTimestamp() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("messageTimestamp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupeCache(t *testing.T) {
+	now := time.UnixMilli(0)
+	c := newDedupeCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.add("a")
+	if !c.seen("a") {
+		t.Error("seen(a) = false after add, want true")
+	}
+	if c.seen("b") {
+		t.Error("seen(b) = true, want false")
+	}
+	now = now.Add(time.Minute)
+	if c.seen("a") {
+		t.Error("seen(a) = true after ttl, want false")
+	}
+	c.add("b")
+	if _, ok := c.ids["a"]; ok {
+		t.Error("expired ID a wasn't removed")
+	}
+	c.remove("b")
+	if c.seen("b") {
+		t.Error("seen(b) = true after remove, want false")
+	}
+}
+
+func TestEventTimes(t *testing.T) {
+	now := time.UnixMilli(0)
+	e := &eventTimes{window: time.Minute}
+	if _, ok := e.min(now); ok {
+		t.Error("min() ok = true without any event times, want false")
+	}
+
+	e.add(now, mtime.FromMilliseconds(300))
+	e.add(now.Add(time.Second), mtime.FromMilliseconds(200))
+	e.add(now.Add(2*time.Second), mtime.FromMilliseconds(500))
+	if got, ok := e.min(now.Add(2 * time.Second)); !ok || got != mtime.FromMilliseconds(200) {
+		t.Errorf("min() = %v, %v, want 200, true", got, ok)
+	}
+	// The event times received over a minute ago no longer hold back the watermark.
+	if got, ok := e.min(now.Add(time.Minute + 1500*time.Millisecond)); !ok || got != mtime.FromMilliseconds(500) {
+		t.Errorf("min() after a minute = %v, %v, want 500, true", got, ok)
+	}
+	if _, ok := e.min(now.Add(2 * time.Minute)); ok {
+		t.Error("min() ok = true after the window, want false")
+	}
+}
+
+func TestWatermarkEstimator(t *testing.T) {
+	we := &watermarkEstimator{state: 100}
+	we.ObserveTimestamp(time.UnixMilli(50))
+	if got, want := we.CurrentWatermark(), time.UnixMilli(100); !got.Equal(want) {
+		t.Errorf("CurrentWatermark() = %v, want %v", got, want)
+	}
+	we.ObserveTimestamp(time.UnixMilli(200))
+	if got, want := we.CurrentWatermark(), time.UnixMilli(200); !got.Equal(want) {
+		t.Errorf("CurrentWatermark() = %v, want %v", got, want)
+	}
+}
@@ -41,9 +41,8 @@ func init() {
 	register.DoFn4x1[context.Context, *sdf.LockRTracker, fileio.ReadableFile, func(string, string), error](&readWNameFn{})
 	register.Emitter2[string, string]()
 
-	beam.RegisterType(reflect.TypeOf((*writeFileFn)(nil)).Elem())
-	register.DoFn3x1[context.Context, int, func(*string) bool, error](&writeFileFn{})
-	register.Iter1[string]()
+	beam.RegisterType(reflect.TypeOf((*textSink)(nil)).Elem())
+	beam.RegisterType(reflect.TypeOf((*fixedNaming)(nil)).Elem())
 }
 
 type readOption struct {
@@ -302,60 +301,121 @@ func (fn *readWNameFn) ProcessElement(ctx context.Context, rt *sdf.LockRTracker,
 	return fn.process(ctx, rt, file, &kvEmitter{Key: file.Metadata.Path, Emit: emit})
 }
 
-// TODO(herohde) 7/12/2017: extend Write to write to a series of files
-// as well as allow sharding.
+type writeOption struct {
+	FileOpts []fileio.WriteOptionFn
+}
+
+// WriteOptionFn is a function that can be passed to Write or WriteDynamic to configure options
+// for writing files.
+type WriteOptionFn func(*writeOption)
+
+// WriteAutoCompression specifies that the compression type of files should be determined by
+// their file extension.
+func WriteAutoCompression() WriteOptionFn {
+	return func(o *writeOption) {
+		o.FileOpts = append(o.FileOpts, fileio.WriteAutoCompression())
+	}
+}
+
+// WriteGzip specifies that files should be compressed using gzip.
+func WriteGzip() WriteOptionFn {
+	return func(o *writeOption) {
+		o.FileOpts = append(o.FileOpts, fileio.WriteGzip())
+	}
+}
+
+// WriteUncompressed specifies that files should not be compressed.
+func WriteUncompressed() WriteOptionFn {
+	return func(o *writeOption) {
+		o.FileOpts = append(o.FileOpts, fileio.WriteUncompressed())
+	}
+}
 
 // Write writes a PCollection<string> to a file as separate lines. The
 // writer add a newline after each element.
-func Write(s beam.Scope, filename string, col beam.PCollection) {
+// Write accepts a variadic number of WriteOptionFn that can be used to configure the compression
+// type of the file. By default, the file is uncompressed.
+func Write(s beam.Scope, filename string, col beam.PCollection, opts ...WriteOptionFn) {
 	s = s.Scope("textio.Write")
 
 	filesystem.ValidateScheme(filename)
 
-	// NOTE(BEAM-3579): We may never call Teardown for non-local runners and
-	// FinishBundle doesn't have the right granularity. We therefore
-	// perform a GBK with a fixed key to get all values in a single invocation.
-
-	// TODO(BEAM-3860) 3/15/2018: use side input instead of GBK.
+	option := &writeOption{}
+	for _, opt := range opts {
+		opt(option)
+	}
 
-	pre := beam.AddFixedKey(s, col)
-	post := beam.GroupByKey(s, pre)
-	beam.ParDo0(s, &writeFileFn{Filename: filename}, post)
+	// All lines are written by a single shard, which is named after the file.
+	dir, name := splitPath(filename)
+	fileOpts := append(option.FileOpts, fileio.WriteNaming(fixedNaming{Name: name}))
+	fileio.WriteFiles(s, dir, &textSink{}, col, fileOpts...)
 }
 
-type writeFileFn struct {
-	Filename string `json:"filename"`
-}
+// WriteDynamic writes a PCollection<string> to the files given by destFn, as separate
+// lines, and returns a PCollection<string> of the paths of the written files. The
+// destination function must be of the form: string -> string. For example:
+//
+//	func tenantPath(line string) string {
+//		tenant, _, _ := strings.Cut(line, ",")
+//		return fmt.Sprintf("gs://bucket/logs/%v.txt", tenant)
+//	}
+//
+//	// Destination functions must be registered with Beam, and must not be closures.
+//	func init() { register.Function1x1(tenantPath) }
+//
+//	textio.WriteDynamic(s, tenantPath, lines)
+//
+// WriteDynamic accepts a variadic number of WriteOptionFn that can be used to configure the
+// compression type of the files. By default, files are uncompressed.
+func WriteDynamic(s beam.Scope, destFn any, col beam.PCollection, opts ...WriteOptionFn) beam.PCollection {
+	s = s.Scope("textio.WriteDynamic")
 
-func (w *writeFileFn) ProcessElement(ctx context.Context, _ int, lines func(*string) bool) error {
-	fs, err := filesystem.New(ctx, w.Filename)
-	if err != nil {
-		return err
+	option := &writeOption{}
+	for _, opt := range opts {
+		opt(option)
 	}
-	defer fs.Close()
 
-	fd, err := fs.OpenWrite(ctx, w.Filename)
-	if err != nil {
-		return err
+	return fileio.WriteDynamic(s, destFn, &textSink{}, col, option.FileOpts...)
+}
+
+// splitPath splits a file path into its directory and file name. The directory of a
+// path without one is the current directory.
+func splitPath(filename string) (dir, name string) {
+	i := strings.LastIndexAny(filename, "/\\")
+	if i < 0 {
+		return ".", filename
 	}
-	buf := bufio.NewWriterSize(fd, 1<<20) // use 1MB buffer
+	return filename[:i+1], filename[i+1:]
+}
 
-	log.Infof(ctx, "Writing to %v", w.Filename)
+// fixedNaming is a fileio.FileNaming that always returns the same file name.
+type fixedNaming struct {
+	Name string `json:"name"`
+}
 
-	var line string
-	for lines(&line) {
-		if _, err := buf.WriteString(line); err != nil {
-			return err
-		}
-		if _, err := buf.Write([]byte{'\n'}); err != nil {
-			return err
-		}
-	}
+func (n fixedNaming) Filename(_ beam.Window, _ beam.PaneInfo, _, _ int) string {
+	return n.Name
+}
+
+// textSink is a fileio.Sink that writes string elements as lines.
+type textSink struct {
+	buf *bufio.Writer
+}
 
-	if err := buf.Flush(); err != nil {
+func (t *textSink) Open(_ context.Context, w io.Writer) error {
+	t.buf = bufio.NewWriterSize(w, 1<<20) // use 1MB buffer
+	return nil
+}
+
+func (t *textSink) Write(_ context.Context, elm any) error {
+	if _, err := t.buf.WriteString(elm.(string)); err != nil {
 		return err
 	}
-	return fd.Close()
+	return t.buf.WriteByte('\n')
+}
+
+func (t *textSink) Flush(_ context.Context) error {
+	return t.buf.Flush()
 }
 
 // Immediate reads a local file at pipeline construction-time and embeds the
@@ -35,6 +35,7 @@ func TestMain(m *testing.M) {
 
 func init() {
 	register.Function2x1(toKV)
+	register.Function1x1(destinationOfLine)
 }
 
 const testDir = "../../../../data"
@@ -122,6 +123,47 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestWriteGzip(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "text.gz")
+	p, s := beam.NewPipelineWithRoot()
+	lines := Read(s, testFilePath)
+	Write(s, out, lines, WriteGzip())
+
+	ptest.RunAndValidate(t, p)
+
+	p, s = beam.NewPipelineWithRoot()
+	got := Read(s, out, ReadGzip())
+	passert.Equals(s, got, Read(s, testFilePath))
+	ptest.RunAndValidate(t, p)
+}
+
+// writeDir is the output directory of TestWriteDynamic, for destinationOfLine.
+var writeDir string
+
+func destinationOfLine(line string) string {
+	return filepath.Join(writeDir, line+".txt")
+}
+
+func TestWriteDynamic(t *testing.T) {
+	writeDir = t.TempDir()
+	p, s := beam.NewPipelineWithRoot()
+	lines := Read(s, testGzFilePath)
+	written := WriteDynamic(s, destinationOfLine, lines)
+	passert.Equals(s, written, filepath.Join(writeDir, "hello.txt"), filepath.Join(writeDir, "go.txt"))
+
+	ptest.RunAndValidate(t, p)
+
+	for _, name := range []string{"hello", "go"} {
+		contents, err := os.ReadFile(filepath.Join(writeDir, name+".txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(contents), name+"\n"; got != want {
+			t.Errorf("WriteDynamic() wrote the wrong contents. Got: %v Want: %v", got, want)
+		}
+	}
+}
+
 func TestImmediate(t *testing.T) {
 	f, err := os.CreateTemp("", "test2.txt")
 	if err != nil {
@@ -92,3 +92,47 @@ func (c Gauge) Set(ctx context.Context, v int64) {
 func NewGauge(namespace, name string) Gauge {
 	return Gauge{metrics.NewGauge(namespace, name)}
 }
+
+// StringSet is a metric that records the set of unique strings added to it,
+// such as the names of the files or tables accessed by a DoFn.
+//
+// StringSets are safe to use in multiple bundles simultaneously, but
+// not generally threadsafe. Your DoFn needs to manage the thread
+// safety of Beam metrics for any additional concurrency it uses.
+type StringSet struct {
+	*metrics.StringSet
+}
+
+// Add adds a string to this set. The context must be
+// provided by the framework, or the value will not be recorded.
+func (c StringSet) Add(ctx context.Context, v string) {
+	c.StringSet.Add(ctx, v)
+}
+
+// NewStringSet returns the StringSet with the given namespace and name.
+func NewStringSet(namespace, name string) StringSet {
+	return StringSet{metrics.NewStringSet(namespace, name)}
+}
+
+// BoundedTrie is a metric that records a bounded set of paths of segments,
+// such as the lineage of the resources accessed by a DoFn. When more paths
+// are added than the trie retains, paths are truncated to their common
+// prefixes.
+//
+// BoundedTries are safe to use in multiple bundles simultaneously, but
+// not generally threadsafe. Your DoFn needs to manage the thread
+// safety of Beam metrics for any additional concurrency it uses.
+type BoundedTrie struct {
+	*metrics.BoundedTrie
+}
+
+// Add adds a path of segments to this trie. The context must be
+// provided by the framework, or the value will not be recorded.
+func (c BoundedTrie) Add(ctx context.Context, segments ...string) {
+	c.BoundedTrie.Add(ctx, segments...)
+}
+
+// NewBoundedTrie returns the BoundedTrie with the given namespace and name.
+func NewBoundedTrie(namespace, name string) BoundedTrie {
+	return BoundedTrie{metrics.NewBoundedTrie(namespace, name)}
+}
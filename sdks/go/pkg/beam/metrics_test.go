@@ -112,3 +112,13 @@ func ExampleGauge_Set() {
 	g := beam.NewGauge("example", "progress")
 	g.Set(ctx, 42)
 }
+
+func ExampleStringSet_Add() {
+	s := beam.NewStringSet("example", "tables")
+	s.Add(ctx, "project.dataset.table")
+}
+
+func ExampleBoundedTrie_Add() {
+	b := beam.NewBoundedTrie("example", "lineage")
+	b.Add(ctx, "gcs", "my-bucket", "path/to/file.txt")
+}
@@ -37,6 +37,7 @@ func addParDoCtx(err error, s Scope) error {
 // for multiple reasons, notably that the dofn is not valid or cannot be bound
 // -- due to type mismatch, say -- to the incoming PCollections.
 func TryParDo(s Scope, dofn any, col PCollection, opts ...Option) ([]PCollection, error) {
+	errorHandler, opts := parseErrorHandler(opts)
 	side, typedefs, err := validate(s, col, opts)
 	if err != nil {
 		return nil, addParDoCtx(err, s)
@@ -122,6 +123,16 @@ func TryParDo(s Scope, dofn any, col PCollection, opts ...Option) ([]PCollection
 		c.SetCoder(NewCoder(c.Type()))
 		ret = append(ret, c)
 	}
+
+	if errorHandler != nil {
+		n, err := graph.AddErrorOutput(s.real, edge)
+		if err != nil {
+			return nil, addParDoCtx(err, s)
+		}
+		c := PCollection{n}
+		c.SetCoder(NewCoder(c.Type()))
+		errorHandler.errors = append(errorHandler.errors, c)
+	}
 	return ret, nil
 }
 
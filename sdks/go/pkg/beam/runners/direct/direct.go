@@ -261,6 +261,13 @@ func (b *builder) makeLink(id linkID) (exec.Node, error) {
 			Out:     out,
 			PID:     path.Base(edge.DoFn.Name()),
 		}
+		if graph.HasErrorOutput(edge.DoFn, edge.Output) {
+			c := edge.Input[0].From.Coder
+			if typex.IsCoGBK(edge.Input[0].Type) {
+				c = c.Components[0]
+			}
+			pardo.ErrorHandler = &exec.ErrorHandler{Out: out[len(out)-1], Coder: c, Transform: edge.Name()}
+		}
 		u = pardo
 		if edge.DoFn.IsSplittable() {
 			u = &exec.SdfFallback{PDo: pardo}
@@ -0,0 +1,718 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"golang.org/x/exp/maps"
+)
+
+// Checkpointing
+//
+// When Config.CheckpointDir is set, the ElementManager writes a snapshot of its
+// execution state to that directory after a bundle is committed, once the
+// checkpoint interval has passed or enough bundles have been committed. Bundle
+// commits are blocked while the state is captured and encoded, but not while the
+// snapshot is written to disk. A snapshot contains the watermarks, pending
+// elements, timers, watermark holds, side inputs, and user and trigger state of
+// every stage.
+//
+// Bundles that are in progress when the snapshot is taken are recorded with their
+// elements, and are re-run when the job is restored, since their results had not
+// been committed. Bundles committed after the last snapshot are re-run too. This
+// retains at least once processing semantics for the job.
+//
+// Restoring requires the same pipeline to be submitted, so that the stages line up
+// with those in the snapshot. Pipelines with TestStreams are not checkpointed, as
+// their processing time is synthetic.
+
+// snapshotFile is the name of the snapshot file in the checkpoint directory.
+const snapshotFile = "elementmanager.snapshot"
+
+// snapshotVersion is incremented on incompatible changes to the snapshot format.
+const snapshotVersion = 1
+
+type emSnapshot struct {
+	Version int
+	Stages  map[string]*stageSnapshot
+}
+
+type stageSnapshot struct {
+	Input, Output, EstimatedOutput mtime.Time
+	Upstream                       map[string]mtime.Time // upstream watermarks by input PCollection.
+
+	Pending    []elementSnapshot
+	ByKey      []keySnapshot
+	InProgress []bundleSnapshot
+	Sides      []sideSnapshot
+	State      []stateSnapshot
+	Holds      map[mtime.Time]int
+
+	ProcessingTimers []fireSnapshot
+	KeysToExpire     []expirySnapshot
+}
+
+type windowKind uint8
+
+const (
+	globalWindowKind windowKind = iota
+	intervalWindowKind
+	customWindowKind
+)
+
+type windowSnapshot struct {
+	Kind       windowKind
+	Start, End mtime.Time
+	Custom     []byte
+}
+
+type elementSnapshot struct {
+	Window                 windowSnapshot
+	Timestamp, Hold        mtime.Time
+	Pane                   typex.PaneInfo
+	Transform, Family, Tag string
+	Sequence               int
+	Timer                  bool
+	Elm, Key               []byte
+}
+
+type timerSnapshot struct {
+	Family, Tag  string
+	Window       windowSnapshot
+	Firing, Hold mtime.Time
+}
+
+type keySnapshot struct {
+	Key      string
+	Elements []elementSnapshot
+	Timers   []timerSnapshot
+}
+
+type bundleSnapshot struct {
+	ID           string
+	Elements     []elementSnapshot
+	MinTimestamp mtime.Time
+	Keys         []string
+	Holds        map[mtime.Time]int
+	ExpiryWindow *windowSnapshot
+}
+
+type sideSnapshot struct {
+	Transform, Local string
+	Window           windowSnapshot
+	Data             [][]byte
+	Panes            []sidePaneSnapshot // Set for triggered side inputs.
+}
+
+type sidePaneSnapshot struct {
+	Key  string
+	Pane typex.PaneInfo
+	Data [][]byte
+}
+
+type stateSnapshot struct {
+	Transform, Local string
+	Window           windowSnapshot
+	Key              string
+
+	Bag      [][]byte
+	Multimap map[string][][]byte
+	Pane     typex.PaneInfo
+	Triggers []triggerStateSnapshot
+}
+
+type fireSnapshot struct {
+	Firing mtime.Time
+	Timer  elementSnapshot
+}
+
+type expirySnapshot struct {
+	Window windowSnapshot
+	Keys   []string
+}
+
+type triggerExtraKind uint8
+
+const (
+	noTriggerExtra triggerExtraKind = iota
+	countTriggerExtra
+	flagTriggerExtra
+	processingTimeTriggerExtra
+)
+
+type triggerStateSnapshot struct {
+	Index    int // Index of the trigger in the pre-order traversal of the stage's trigger.
+	Finished bool
+
+	ExtraKind  triggerExtraKind
+	Count      int
+	Flag       bool
+	FiringTime mtime.Time
+}
+
+func snapshotWindow(w typex.Window) windowSnapshot {
+	switch w := w.(type) {
+	case window.GlobalWindow:
+		return windowSnapshot{Kind: globalWindowKind}
+	case window.IntervalWindow:
+		return windowSnapshot{Kind: intervalWindowKind, Start: w.Start, End: w.End}
+	case customWindow:
+		return windowSnapshot{Kind: customWindowKind, End: w.End, Custom: w.Custom}
+	default:
+		panic(fmt.Sprintf("prism error: unable to snapshot window of type %T", w))
+	}
+}
+
+func (w windowSnapshot) window() typex.Window {
+	switch w.Kind {
+	case intervalWindowKind:
+		return window.IntervalWindow{Start: w.Start, End: w.End}
+	case customWindowKind:
+		return customWindow{End: w.End, Custom: w.Custom}
+	default:
+		return window.GlobalWindow{}
+	}
+}
+
+func snapshotElement(e element) elementSnapshot {
+	return elementSnapshot{
+		Window:    snapshotWindow(e.window),
+		Timestamp: e.timestamp,
+		Hold:      e.holdTimestamp,
+		Pane:      e.pane,
+		Transform: e.transform,
+		Family:    e.family,
+		Tag:       e.tag,
+		Sequence:  e.sequence,
+		Timer:     e.IsTimer(),
+		Elm:       e.data(),
+		Key:       e.keyBytes,
+	}
+}
+
+func (e elementSnapshot) element() element {
+	elm := e.Elm
+	if !e.Timer && elm == nil {
+		// Data elements must not be nil, as that indicates a timer.
+		elm = []byte{}
+	}
+	return element{
+		window:        e.Window.window(),
+		timestamp:     e.Timestamp,
+		holdTimestamp: e.Hold,
+		pane:          e.Pane,
+		transform:     e.Transform,
+		family:        e.Family,
+		tag:           e.Tag,
+		sequence:      e.Sequence,
+		elmBytes:      elm,
+		keyBytes:      e.Key,
+	}
+}
+
+func snapshotElements(es []element) []elementSnapshot {
+	ret := make([]elementSnapshot, 0, len(es))
+	for _, e := range es {
+		ret = append(ret, snapshotElement(e))
+	}
+	return ret
+}
+
+// allTriggers returns the triggers in the trigger tree rooted at t, in pre-order.
+// The position of a trigger in the list identifies its state in snapshots.
+func allTriggers(t Trigger) []Trigger {
+	if t == nil {
+		return nil
+	}
+	ret := []Trigger{t}
+	switch t := t.(type) {
+	case *TriggerAfterAll:
+		for _, sub := range t.SubTriggers {
+			ret = append(ret, allTriggers(sub)...)
+		}
+	case *TriggerAfterAny:
+		for _, sub := range t.SubTriggers {
+			ret = append(ret, allTriggers(sub)...)
+		}
+	case *TriggerAfterEach:
+		for _, sub := range t.SubTriggers {
+			ret = append(ret, allTriggers(sub)...)
+		}
+	case *TriggerAfterEndOfWindow:
+		ret = append(ret, allTriggers(t.Early)...)
+		ret = append(ret, allTriggers(t.Late)...)
+	case *TriggerOrFinally:
+		ret = append(ret, allTriggers(t.Main)...)
+		ret = append(ret, allTriggers(t.Finally)...)
+	case *TriggerRepeatedly:
+		ret = append(ret, allTriggers(t.Repeated)...)
+	}
+	return ret
+}
+
+func snapshotTriggerState(index int, ts triggerState) triggerStateSnapshot {
+	tss := triggerStateSnapshot{Index: index, Finished: ts.finished}
+	switch extra := ts.extra.(type) {
+	case nil:
+	case int:
+		tss.ExtraKind, tss.Count = countTriggerExtra, extra
+	case bool:
+		tss.ExtraKind, tss.Flag = flagTriggerExtra, extra
+	case afterProcessingTimeState:
+		tss.ExtraKind, tss.FiringTime, tss.Flag = processingTimeTriggerExtra, extra.firingTime, extra.reached
+	default:
+		panic(fmt.Sprintf("prism error: unable to snapshot trigger state of type %T", extra))
+	}
+	return tss
+}
+
+func (tss triggerStateSnapshot) triggerState() triggerState {
+	ts := triggerState{finished: tss.Finished}
+	switch tss.ExtraKind {
+	case countTriggerExtra:
+		ts.extra = tss.Count
+	case flagTriggerExtra:
+		ts.extra = tss.Flag
+	case processingTimeTriggerExtra:
+		ts.extra = afterProcessingTimeState{firingTime: tss.FiringTime, reached: tss.Flag}
+	}
+	return ts
+}
+
+// snapshot captures the state of the stage. Must be called with the stage lock held.
+func (ss *stageState) snapshot() *stageSnapshot {
+	snap := &stageSnapshot{
+		Input:           ss.input,
+		Output:          ss.output,
+		EstimatedOutput: ss.estimatedOutput,
+		Upstream:        map[string]mtime.Time{},
+		Pending:         snapshotElements(ss.pending),
+		Holds:           map[mtime.Time]int{},
+	}
+	ss.upstreamWatermarks.Range(func(k, v any) bool {
+		snap.Upstream[k.(string)] = v.(mtime.Time)
+		return true
+	})
+	for k, dnt := range ss.pendingByKeys {
+		ks := keySnapshot{Key: k, Elements: snapshotElements(dnt.elements)}
+		for tk, tt := range dnt.timers {
+			ks.Timers = append(ks.Timers, timerSnapshot{
+				Family: tk.family,
+				Tag:    tk.tag,
+				Window: snapshotWindow(tk.window),
+				Firing: tt.firing,
+				Hold:   tt.hold,
+			})
+		}
+		snap.ByKey = append(snap.ByKey, ks)
+	}
+	for id, es := range ss.inprogress {
+		bs := bundleSnapshot{
+			ID:           id,
+			Elements:     snapshotElements(es.es),
+			MinTimestamp: es.minTimestamp,
+			Keys:         maps.Keys(ss.inprogressKeysByBundle[id]),
+			Holds:        ss.inprogressHoldsByBundle[id],
+		}
+		if w, ok := ss.expiryWindowsByBundles[id]; ok {
+			ws := snapshotWindow(w)
+			bs.ExpiryWindow = &ws
+		}
+		snap.InProgress = append(snap.InProgress, bs)
+	}
+	for link, wins := range ss.sideInputs {
+		for w, data := range wins {
+			side := sideSnapshot{Transform: link.Transform, Local: link.Local, Window: snapshotWindow(w), Data: data}
+			for k, p := range ss.sidePanes[link][w] {
+				side.Panes = append(side.Panes, sidePaneSnapshot{Key: k, Pane: p.pane, Data: p.elms})
+			}
+			snap.Sides = append(snap.Sides, side)
+		}
+	}
+	triggers := allTriggers(ss.strat.Trigger)
+	for link, wins := range ss.spilledState {
+		for w, keys := range wins {
+			for k, ref := range keys {
+				sd := decodeUserState(ref.mustRead())
+				snap.State = append(snap.State, stateSnapshot{
+					Transform: link.Transform,
+					Local:     link.Local,
+					Window:    snapshotWindow(w),
+					Key:       k,
+					Bag:       sd.Bag,
+					Multimap:  sd.Multimap,
+				})
+			}
+		}
+	}
+	for link, wins := range ss.state {
+		for w, keys := range wins {
+			for k, sd := range keys {
+				st := stateSnapshot{
+					Transform: link.Transform,
+					Local:     link.Local,
+					Window:    snapshotWindow(w),
+					Key:       k,
+					Bag:       sd.Bag,
+					Multimap:  sd.Multimap,
+					Pane:      sd.Pane,
+				}
+				for i, t := range triggers {
+					if ts, ok := sd.Trigger[t]; ok {
+						st.Triggers = append(st.Triggers, snapshotTriggerState(i, ts))
+					}
+				}
+				snap.State = append(snap.State, st)
+			}
+		}
+	}
+	for h, c := range ss.watermarkHolds.counts {
+		snap.Holds[h] = c
+	}
+	for _, timers := range ss.processingTimeTimers.nextFiring {
+		for _, fe := range timers {
+			snap.ProcessingTimers = append(snap.ProcessingTimers, fireSnapshot{Firing: fe.firing, Timer: snapshotElement(fe.timer)})
+		}
+	}
+	for w, keys := range ss.keysToExpireByWindow {
+		snap.KeysToExpire = append(snap.KeysToExpire, expirySnapshot{Window: snapshotWindow(w), Keys: maps.Keys(keys)})
+	}
+	return snap
+}
+
+// restore replaces the state of the stage with the snapshot, and returns the number
+// of pending elements the snapshot represents, and the in progress bundles that
+// need to be re-run. Must be called with the stage lock held.
+func (ss *stageState) restore(snap *stageSnapshot) (int, []RunBundle) {
+	var count int
+	ss.input, ss.output, ss.estimatedOutput = snap.Input, snap.Output, snap.EstimatedOutput
+	for pcol, w := range snap.Upstream {
+		ss.upstreamWatermarks.Store(pcol, w)
+	}
+
+	ss.pending = nil
+	for _, e := range snap.Pending {
+		ss.pending = append(ss.pending, e.element())
+	}
+	heap.Init(&ss.pending)
+	count += len(ss.pending)
+
+	if len(snap.ByKey) > 0 {
+		ss.pendingByKeys = map[string]*dataAndTimers{}
+	}
+	for _, ks := range snap.ByKey {
+		dnt := &dataAndTimers{timers: map[timerKey]timerTimes{}}
+		for _, e := range ks.Elements {
+			elm := e.element()
+			if elm.IsData() {
+				// Stale timer elements aren't counted, only the latest set timer is.
+				count++
+			}
+			dnt.elements = append(dnt.elements, elm)
+		}
+		heap.Init(&dnt.elements)
+		for _, ts := range ks.Timers {
+			dnt.timers[timerKey{family: ts.Family, tag: ts.Tag, window: ts.Window.window()}] = timerTimes{firing: ts.Firing, hold: ts.Hold}
+		}
+		count += len(dnt.timers)
+		ss.pendingByKeys[ks.Key] = dnt
+	}
+	if ss.paneHoldTimes != nil {
+		ss.paneHolds = map[string]map[typex.Window]mtime.Time{}
+		ss.paneHoldTimes = newHoldTracker()
+		for k := range ss.pendingByKeys {
+			ss.resetPaneHoldsLocked(k)
+		}
+	}
+
+	var bundles []RunBundle
+	for _, bs := range snap.InProgress {
+		if ss.inprogress == nil {
+			ss.inprogress = map[string]elements{}
+			ss.inprogressKeysByBundle = map[string]set[string]{}
+			ss.inprogressHoldsByBundle = map[string]map[mtime.Time]int{}
+		}
+		// Restored bundles are renamed to avoid colliding with new bundle IDs.
+		id := "restored-" + bs.ID
+		es := elements{minTimestamp: bs.MinTimestamp}
+		for _, e := range bs.Elements {
+			es.es = append(es.es, e.element())
+		}
+		ss.inprogress[id] = es
+		count += len(es.es)
+		keys := set[string]{}
+		for _, k := range bs.Keys {
+			keys.insert(k)
+		}
+		ss.inprogressKeysByBundle[id] = keys
+		if ss.inprogressKeys == nil {
+			ss.inprogressKeys = set[string]{}
+		}
+		ss.inprogressKeys.merge(keys)
+		holds := bs.Holds
+		if holds == nil {
+			holds = map[mtime.Time]int{}
+		}
+		ss.inprogressHoldsByBundle[id] = holds
+		if bs.ExpiryWindow != nil {
+			w := bs.ExpiryWindow.window()
+			ss.expiryWindowsByBundles[id] = w
+			ss.inProgressExpiredWindows[w] += 1
+		}
+		bundles = append(bundles, RunBundle{StageID: ss.ID, BundleID: id, Watermark: ss.input})
+	}
+
+	ss.sideInputs = map[LinkID]map[typex.Window][][]byte{}
+	for _, side := range snap.Sides {
+		link := LinkID{Transform: side.Transform, Local: side.Local}
+		wins, ok := ss.sideInputs[link]
+		if !ok {
+			wins = map[typex.Window][][]byte{}
+			ss.sideInputs[link] = wins
+		}
+		w := side.Window.window()
+		wins[w] = side.Data
+		if len(side.Panes) > 0 {
+			if ss.sidePanes == nil {
+				ss.sidePanes = map[LinkID]map[typex.Window]map[string]*sidePaneData{}
+			}
+			panes, ok := ss.sidePanes[link]
+			if !ok {
+				panes = map[typex.Window]map[string]*sidePaneData{}
+				ss.sidePanes[link] = panes
+			}
+			byKey := map[string]*sidePaneData{}
+			for _, p := range side.Panes {
+				byKey[p.Key] = &sidePaneData{pane: p.Pane, elms: p.Data}
+			}
+			panes[w] = byKey
+		}
+	}
+
+	triggers := allTriggers(ss.strat.Trigger)
+	ss.state = map[LinkID]map[typex.Window]map[string]StateData{}
+	ss.spilledState = nil
+	ss.activeWindows = nil
+	for _, st := range snap.State {
+		link := LinkID{Transform: st.Transform, Local: st.Local}
+		wins, ok := ss.state[link]
+		if !ok {
+			wins = map[typex.Window]map[string]StateData{}
+			ss.state[link] = wins
+		}
+		w := st.Window.window()
+		keys, ok := wins[w]
+		if !ok {
+			keys = map[string]StateData{}
+			wins[w] = keys
+		}
+		sd := StateData{Bag: st.Bag, Multimap: st.Multimap, Pane: st.Pane}
+		for _, tss := range st.Triggers {
+			if sd.Trigger == nil {
+				sd.Trigger = map[Trigger]triggerState{}
+			}
+			sd.Trigger[triggers[tss.Index]] = tss.triggerState()
+		}
+		keys[st.Key] = sd
+	}
+
+	ss.watermarkHolds = newHoldTracker()
+	for h, c := range snap.Holds {
+		ss.watermarkHolds.Add(h, c)
+	}
+
+	ss.processingTimeTimers = newTimerHandler()
+	for _, fs := range snap.ProcessingTimers {
+		timer := fs.Timer.element()
+		// The holds for timers are restored above.
+		ss.processingTimeTimers.Persist(fs.Firing, timer, map[mtime.Time]int{})
+		if timer.family != processingTimeTriggerFamily {
+			// Synthetic trigger timers aren't counted as pending.
+			count++
+		}
+	}
+
+	for _, exp := range snap.KeysToExpire {
+		keys := set[string]{}
+		for _, k := range exp.Keys {
+			keys.insert(k)
+		}
+		ss.keysToExpireByWindow[exp.Window.window()] = keys
+	}
+	return count, bundles
+}
+
+// maybeCheckpoint writes a snapshot if the checkpoint interval has passed, or enough
+// bundles have been committed since the last one. Bundles committed while another
+// bundle's snapshot is being written don't wait for it, and are included in a later one.
+func (em *ElementManager) maybeCheckpoint() error {
+	if em.config.CheckpointDir == "" || em.testStreamHandler != nil {
+		return nil
+	}
+	n := em.bundlesSinceCheckpoint.Add(1)
+	interval, bundles := em.config.CheckpointInterval, int64(em.config.CheckpointBundles)
+	due := interval <= 0 && bundles <= 0 ||
+		interval > 0 && time.Since(time.Unix(0, em.lastCheckpoint.Load())) >= interval ||
+		bundles > 0 && n >= bundles
+	if !due || !em.checkpointMu.TryLock() {
+		return nil
+	}
+	defer em.checkpointMu.Unlock()
+	return em.checkpoint()
+}
+
+// Checkpoint writes a snapshot of the element manager to the configured checkpoint
+// directory. The snapshot is written to a temporary file first and renamed, so an
+// existing snapshot is only replaced by a complete one.
+func (em *ElementManager) Checkpoint() error {
+	if em.config.CheckpointDir == "" || em.testStreamHandler != nil {
+		return nil
+	}
+	em.checkpointMu.Lock()
+	defer em.checkpointMu.Unlock()
+	return em.checkpoint()
+}
+
+// checkpoint must be called with checkpointMu held.
+func (em *ElementManager) checkpoint() error {
+	data, err := em.encodeSnapshot()
+	if err != nil {
+		return err
+	}
+	// Files are written without blocking bundle commits, since checkpointMu keeps
+	// snapshots in order.
+	if err := os.MkdirAll(em.config.CheckpointDir, 0o755); err != nil {
+		return fmt.Errorf("unable to create checkpoint directory: %w", err)
+	}
+	f, err := os.CreateTemp(em.config.CheckpointDir, snapshotFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot file: %w", err)
+	}
+	defer os.Remove(f.Name()) // Cleans up on failure, and is a no-op after a successful rename.
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to write snapshot: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("unable to sync snapshot file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close snapshot file: %w", err)
+	}
+	if err := os.Rename(f.Name(), filepath.Join(em.config.CheckpointDir, snapshotFile)); err != nil {
+		return fmt.Errorf("unable to replace snapshot file: %w", err)
+	}
+	return nil
+}
+
+// encodeSnapshot captures and encodes the execution state, blocking bundle commits
+// only while doing so.
+func (em *ElementManager) encodeSnapshot() ([]byte, error) {
+	em.commitMu.Lock()
+	defer em.commitMu.Unlock()
+	em.refreshCond.L.Lock()
+	defer em.refreshCond.L.Unlock()
+
+	snap := emSnapshot{
+		Version: snapshotVersion,
+		Stages:  map[string]*stageSnapshot{},
+	}
+	for id, ss := range em.stages {
+		ss.mu.Lock()
+		snap.Stages[id] = ss.snapshot()
+		ss.mu.Unlock()
+	}
+	// The snapshot shares maps and slices with the live state, so it's encoded
+	// before bundles may modify them.
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&snap); err != nil {
+		return nil, fmt.Errorf("unable to encode snapshot: %w", err)
+	}
+	em.bundlesSinceCheckpoint.Store(0)
+	em.lastCheckpoint.Store(time.Now().UnixNano())
+	return buf.Bytes(), nil
+}
+
+// Restore replaces the execution state of the element manager with the snapshot in
+// the given directory. It must be called after all stages are added, and before
+// calling Bundles. Impulses must not be added for a restored job, since their
+// output is already accounted for in the snapshot.
+func (em *ElementManager) Restore(dir string) error {
+	if em.testStreamHandler != nil {
+		return fmt.Errorf("restoring pipelines with a TestStream is unsupported")
+	}
+	f, err := os.Open(filepath.Join(dir, snapshotFile))
+	if err != nil {
+		return fmt.Errorf("unable to open snapshot: %w", err)
+	}
+	defer f.Close()
+	var snap emSnapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("unable to decode snapshot %v: %w", f.Name(), err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %v, want %v", snap.Version, snapshotVersion)
+	}
+	var missing []string
+	for id := range em.stages {
+		if _, ok := snap.Stages[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	for id := range snap.Stages {
+		if _, ok := em.stages[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("snapshot doesn't match the pipeline, stages %v are not in both", missing)
+	}
+
+	em.refreshCond.L.Lock()
+	defer em.refreshCond.L.Unlock()
+	var count int
+	for id, ss := range em.stages {
+		ss.mu.Lock()
+		c, bundles := ss.restore(snap.Stages[id])
+		for _, fs := range snap.Stages[id].ProcessingTimers {
+			em.processTimeEvents.Schedule(fs.Firing, id)
+		}
+		ss.mu.Unlock()
+		count += c
+		for _, rb := range bundles {
+			em.inprogressBundles.insert(rb.BundleID)
+			em.injectedBundles = append(em.injectedBundles, rb)
+		}
+		em.changedStages.insert(id)
+	}
+	em.addPending(count)
+	slog.Info("restored from snapshot", slog.String("dir", dir), slog.Int("pending", count))
+	return nil
+}
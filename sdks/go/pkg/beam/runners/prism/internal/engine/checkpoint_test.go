@@ -0,0 +1,234 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSnapshotWindow(t *testing.T) {
+	tests := []typex.Window{
+		window.GlobalWindow{},
+		window.IntervalWindow{Start: 10, End: 20},
+		customWindow{End: 30, Custom: []byte{1, 2, 3}},
+	}
+	for _, w := range tests {
+		if got := snapshotWindow(w).window(); !cmp.Equal(got, w) {
+			t.Errorf("snapshotWindow(%v).window() = %v, want %v", w, got, w)
+		}
+	}
+}
+
+func TestStageState_SnapshotRestore(t *testing.T) {
+	ss := makeStageState("stage", []string{"input"}, []string{"output"}, nil)
+	ss.kind = &statefulStageKind{}
+	ss.pendingByKeys = map[string]*dataAndTimers{}
+	ss.watermarkHolds = newHoldTracker()
+
+	key := []byte{1, 'a'}
+	ss.AddPending(nil, []element{
+		{window: window.GlobalWindow{}, timestamp: 5, pane: typex.NoFiringPane(), elmBytes: []byte{1}, keyBytes: key},
+		{window: window.GlobalWindow{}, timestamp: 7, pane: typex.NoFiringPane(), elmBytes: []byte{2}, keyBytes: key},
+	})
+	ss.watermarkHolds.Add(5, 2)
+	ss.state = map[LinkID]map[typex.Window]map[string]StateData{
+		{Transform: "stage", Local: "bag"}: {
+			window.GlobalWindow{}: {
+				string(key): {Bag: [][]byte{{3}, {4}}},
+			},
+		},
+	}
+
+	snap := ss.snapshot()
+
+	rs := makeStageState("stage", []string{"input"}, []string{"output"}, nil)
+	rs.kind = &statefulStageKind{}
+	rs.pendingByKeys = map[string]*dataAndTimers{}
+	rs.watermarkHolds = newHoldTracker()
+	count, bundles := rs.restore(snap)
+
+	if got, want := count, 2; got != want {
+		t.Errorf("restore() pending count = %v, want %v", got, want)
+	}
+	if got, want := len(bundles), 0; got != want {
+		t.Errorf("restore() bundles = %v, want %v", got, want)
+	}
+	if got, want := len(rs.pendingByKeys[string(key)].elements), 2; got != want {
+		t.Errorf("restored pending elements for key = %v, want %v", got, want)
+	}
+	if got, want := rs.watermarkHolds.counts[5], 2; got != want {
+		t.Errorf("restored hold count at 5 = %v, want %v", got, want)
+	}
+	if d := cmp.Diff(ss.state, rs.state); d != "" {
+		t.Errorf("restored state diff (-want, +got):\n%v", d)
+	}
+}
+
+func TestElementManager_CheckpointRestore(t *testing.T) {
+	info := PColInfo{
+		GlobalID: "generic_info",
+		WDec:     exec.MakeWindowDecoder(coder.NewGlobalWindow()),
+		WEnc:     exec.MakeWindowEncoder(coder.NewGlobalWindow()),
+		EDec: func(r io.Reader) []byte {
+			b, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("error decoding \"generic_info\" data:%v", err)
+			}
+			return b
+		},
+	}
+	es := elements{
+		es: []element{{
+			window:    window.GlobalWindow{},
+			timestamp: mtime.MinTimestamp,
+			pane:      typex.NoFiringPane(),
+			elmBytes:  []byte{3, 65, 66, 67}, // "ABC"
+		}},
+		minTimestamp: mtime.MinTimestamp,
+	}
+	outputCoders := map[string]PColInfo{
+		"output": info,
+	}
+	newEM := func(dir string) *ElementManager {
+		em := NewElementManager(Config{CheckpointDir: dir})
+		em.AddStage("impulse", nil, []string{"input"}, nil)
+		em.AddStage("dofn1", []string{"input"}, []string{"output"}, nil)
+		em.AddStage("dofn2", []string{"output"}, nil, nil)
+		return em
+	}
+	bundleIDs := func(prefix string) func() string {
+		var i int
+		return func() string {
+			defer func() { i++ }()
+			return fmt.Sprintf("%v%v", prefix, i)
+		}
+	}
+
+	dir := t.TempDir()
+
+	// Run the first stage, which snapshots the data pending for the second stage.
+	ctx, cancelFn := context.WithCancelCause(context.Background())
+	defer cancelFn(nil)
+	em := newEM(dir)
+	em.Impulse("impulse")
+	ch := em.Bundles(ctx, cancelFn, bundleIDs("first"))
+	rb, ok := <-ch
+	if !ok {
+		t.Fatal("Bundles channel unexpectedly closed")
+	}
+	if got, want := rb.StageID, "dofn1"; got != want {
+		t.Fatalf("stage to execute = %v, want %v", got, want)
+	}
+	td := TentativeData{}
+	for _, d := range es.ToData(info) {
+		td.WriteData("output", d)
+	}
+	em.PersistBundle(rb, outputCoders, td, info, Residuals{})
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); err != nil {
+		t.Fatalf("snapshot not written: %v", err)
+	}
+	// Abandon the first manager, as though the process had died.
+	cancelFn(nil)
+
+	// Restore into a fresh manager, which should only need to run the second stage.
+	ctx2, cancelFn2 := context.WithCancelCause(context.Background())
+	defer cancelFn2(nil)
+	restored := newEM(t.TempDir())
+	if err := restored.Restore(dir); err != nil {
+		t.Fatalf("Restore(%v) = %v", dir, err)
+	}
+	ch = restored.Bundles(ctx2, cancelFn2, bundleIDs("second"))
+	rb, ok = <-ch
+	if !ok {
+		t.Fatal("restored Bundles channel unexpectedly closed")
+	}
+	if got, want := rb.StageID, "dofn2"; got != want {
+		t.Fatalf("restored stage to execute = %v, want %v", got, want)
+	}
+	data := restored.InputForBundle(rb, info)
+	if got, want := len(data), 1; got != want {
+		t.Fatalf("restored data len = %v, want %v", got, want)
+	}
+	if !cmp.Equal([]byte{127, 223, 59, 100, 90, 28, 172, 9, 0, 0, 0, 1, 15, 3, 65, 66, 67}, data[0]) {
+		t.Errorf("unexpected restored data, got %v", data[0])
+	}
+	restored.PersistBundle(rb, outputCoders, TentativeData{}, info, Residuals{})
+	if rb, ok := <-ch; ok {
+		t.Errorf("restored Bundles channel expected to be closed, got %v", rb)
+	}
+}
+
+func TestElementManager_RestoreMismatch(t *testing.T) {
+	dir := t.TempDir()
+	em := NewElementManager(Config{CheckpointDir: dir})
+	em.AddStage("impulse", nil, []string{"input"}, nil)
+	em.AddStage("dofn1", []string{"input"}, nil, nil)
+	if err := em.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint() = %v", err)
+	}
+
+	other := NewElementManager(Config{})
+	other.AddStage("impulse", nil, []string{"input"}, nil)
+	other.AddStage("dofnOther", []string{"input"}, nil, nil)
+	if err := other.Restore(dir); err == nil {
+		t.Errorf("Restore(%v) with different stages succeeded, want error", dir)
+	}
+}
+
+func TestElementManager_maybeCheckpoint(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   []bool // Whether a snapshot is written after each bundle.
+	}{
+		{name: "everyBundle", want: []bool{true, true, true}},
+		{name: "bundles", config: Config{CheckpointBundles: 2}, want: []bool{false, true, false, true}},
+		{name: "interval", config: Config{CheckpointInterval: time.Hour}, want: []bool{true, false, false}},
+		{name: "intervalOrBundles", config: Config{CheckpointInterval: time.Hour, CheckpointBundles: 2}, want: []bool{true, false, true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.CheckpointDir = t.TempDir()
+			em := NewElementManager(test.config)
+			em.AddStage("impulse", nil, []string{"input"}, nil)
+			em.AddStage("dofn1", []string{"input"}, nil, nil)
+			snapshot := filepath.Join(test.config.CheckpointDir, snapshotFile)
+			for i, want := range test.want {
+				os.Remove(snapshot)
+				if err := em.maybeCheckpoint(); err != nil {
+					t.Fatalf("maybeCheckpoint() = %v", err)
+				}
+				_, err := os.Stat(snapshot)
+				if got := err == nil; got != want {
+					t.Errorf("bundle %d: snapshot written = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
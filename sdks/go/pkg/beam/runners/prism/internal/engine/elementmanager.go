@@ -22,10 +22,12 @@ import (
 	"container/heap"
 	"context"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"log/slog"
 	"runtime/debug"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -56,12 +58,13 @@ type element struct {
 	// If a timer element has sequence set to -1, it means it is being cleared.
 	sequence int
 
-	elmBytes []byte // When nil, indicates this is a timer.
+	elmBytes []byte    // When nil, indicates this is a timer, unless the bytes have been spilled.
+	spilled  *spillRef // When set, elmBytes have been spilled to disk.
 	keyBytes []byte
 }
 
 func (e *element) IsTimer() bool {
-	return e.elmBytes == nil
+	return e.elmBytes == nil && e.spilled == nil
 }
 
 func (e *element) IsData() bool {
@@ -116,7 +119,7 @@ func (es elements) ToData(info PColInfo) [][]byte {
 	for _, e := range es.es {
 		var buf bytes.Buffer
 		exec.EncodeWindowedValueHeader(info.WEnc, []typex.Window{e.window}, e.timestamp, e.pane, &buf)
-		buf.Write(e.elmBytes)
+		buf.Write(e.data())
 		ret = append(ret, buf.Bytes())
 	}
 	return ret
@@ -159,10 +162,34 @@ func (h *elementHeap) Pop() any {
 
 type Config struct {
 	// MaxBundleSize caps the number of elements permitted in a bundle.
+	// Stateful stages stop adding keys to a bundle once it has at least this many elements.
 	// 0 or less means this is ignored.
 	MaxBundleSize int
+	// MaxBundlesPerStage caps the number of concurrent bundles for a stateful stage,
+	// by partitioning its pending keys into that many disjoint key groups. Each key
+	// group is processed by at most one bundle at a time.
+	// 0 or less means keys aren't partitioned, and bundles take all available keys.
+	MaxBundlesPerStage int
 	// Whether to use real-time clock as processing time
 	EnableRTC bool
+	// CheckpointDir is the directory snapshots of the execution state are written
+	// to when bundles are committed. Empty means snapshots are not written.
+	CheckpointDir string
+	// CheckpointInterval is the time between snapshots. A snapshot is written after
+	// the first bundle committed once the interval has passed since the last one.
+	CheckpointInterval time.Duration
+	// CheckpointBundles is the number of committed bundles after which a snapshot
+	// is written, if the interval hasn't passed first.
+	// If both CheckpointInterval and CheckpointBundles are 0 or less, a snapshot is
+	// written after every bundle.
+	CheckpointBundles int
+	// MemoryBudget is the approximate number of bytes of pending elements and user state
+	// to hold in memory. Above the budget, they're spilled to files in SpillDir.
+	// 0 or less means data is never spilled.
+	MemoryBudget int64
+	// SpillDir is the directory spilled data is written to. Empty means the default
+	// directory for temporary files.
+	SpillDir string
 }
 
 // ElementManager handles elements, watermarks, and related errata to determine
@@ -206,8 +233,24 @@ type ElementManager struct {
 	livePending     atomic.Int64   // An accessible live pending count. DEBUG USE ONLY
 	pendingElements sync.WaitGroup // pendingElements counts all unprocessed elements in a job. Jobs with no pending elements terminate successfully.
 
+	// commitMu is held for reading while bundle results are committed, and for writing while
+	// a snapshot is taken, so snapshots never observe a partially committed bundle.
+	commitMu sync.RWMutex
+
+	checkpointMu           sync.Mutex   // Serializes writing snapshots, so an older one never replaces a newer one.
+	lastCheckpoint         atomic.Int64 // Unix nanos of when the last snapshot was taken.
+	bundlesSinceCheckpoint atomic.Int64 // Bundles committed since the last snapshot was taken.
+
+	draining atomic.Bool // Whether the job is draining, which advances processing time to the end of time.
+
+	spill      *spillFile   // Holds spilled element and state bytes, when over the memory budget.
+	spillMu    sync.Mutex   // Serializes spilling.
+	addedBytes atomic.Int64 // Bytes added since the memory budget was last checked.
+
 	processTimeEvents *stageRefreshQueue // Manages sequence of stage updates when interfacing with processing time.
-	testStreamHandler *testStreamHandler // Optional test stream handler when a test stream is in the pipeline.
+	wakeTimer         *time.Timer        // Wakes the watermark evaluation loop for processing time events, when using the real time clock.
+	wakeAt            mtime.Time         // When wakeTimer is set to fire.
+	testStreamHandler *testStreamHandler // Optional test stream handler when test streams are in the pipeline.
 }
 
 func (em *ElementManager) addPending(v int) {
@@ -215,6 +258,42 @@ func (em *ElementManager) addPending(v int) {
 	em.pendingElements.Add(v)
 }
 
+// Drain begins draining the job. Processing time advances to the end of time, so processing
+// time timers and triggers fire without waiting. Stages processing splittable DoFns are
+// expected to truncate their restrictions while draining, after which the watermarks
+// advance to infinity, firing the remaining event time timers and windows.
+func (em *ElementManager) Drain() {
+	em.draining.Store(true)
+	em.refreshCond.L.Lock()
+	defer em.refreshCond.L.Unlock()
+	for id := range em.stages {
+		em.changedStages.insert(id)
+	}
+	em.refreshCond.Broadcast()
+}
+
+// Draining returns whether the job is draining.
+func (em *ElementManager) Draining() bool {
+	return em.draining.Load()
+}
+
+// BundleConcurrency reports the number of bundles in progress for a stage,
+// and the most bundles that have been in progress at once.
+type BundleConcurrency struct {
+	Active, Peak int
+}
+
+// StageBundleConcurrency returns the bundle concurrency for each stage, keyed by stage ID.
+func (em *ElementManager) StageBundleConcurrency() map[string]BundleConcurrency {
+	ret := make(map[string]BundleConcurrency, len(em.stages))
+	for id, ss := range em.stages {
+		ss.mu.Lock()
+		ret[id] = BundleConcurrency{Active: len(ss.inprogress), Peak: ss.peakInProgress}
+		ss.mu.Unlock()
+	}
+	return ret
+}
+
 // LinkID represents a fully qualified input or output.
 type LinkID struct {
 	Transform, Local, Global string
@@ -231,6 +310,7 @@ func NewElementManager(config Config) *ElementManager {
 		inprogressBundles: set[string]{},
 		refreshCond:       sync.Cond{L: &sync.Mutex{}},
 		processTimeEvents: newStageRefreshQueue(),
+		spill:             &spillFile{dir: config.SpillDir},
 	}
 }
 
@@ -269,7 +349,21 @@ func (em *ElementManager) StageAggregates(ID string, strat WinStrat) {
 	ss := em.stages[ID]
 	ss.kind = &aggregateStageKind{}
 	ss.strat = strat
+	ss.processingTimeTriggers = processingTimeTriggers(strat.Trigger)
 	ss.inprogressKeys = set[string]{}
+	ss.paneHolds = map[string]map[typex.Window]mtime.Time{}
+	ss.paneHoldTimes = newHoldTracker()
+}
+
+// StageTriggeredSideInputs marks the given side inputs of the stage as triggered.
+// Each trigger firing of a triggered side input is published as a new version
+// of the side input, instead of waiting for the end of the window.
+func (em *ElementManager) StageTriggeredSideInputs(ID string, sides []LinkID) {
+	ss := em.stages[ID]
+	ss.triggeredSides = set[LinkID]{}
+	for _, side := range sides {
+		ss.triggeredSides.insert(LinkID{Transform: side.Transform, Local: side.Local})
+	}
 }
 
 // StageStateful marks the given stage as stateful, which means elements are
@@ -279,6 +373,8 @@ func (em *ElementManager) StageStateful(ID string, stateTypeLen map[LinkID]func(
 	ss.kind = &statefulStageKind{}
 	ss.stateTypeLen = stateTypeLen
 	ss.inprogressKeys = set[string]{}
+	ss.keyGroups = em.config.MaxBundlesPerStage
+	ss.maxBundleSize = em.config.MaxBundleSize
 }
 
 // StageOnWindowExpiration marks the given stage as stateful, which means elements are
@@ -383,9 +479,13 @@ func (em *ElementManager) Bundles(ctx context.Context, upstreamCancelFn context.
 					return
 				default:
 				}
+				// Ensure we're woken for the next processing time event.
+				if t, ok := em.processTimeEvents.Peek(); ok {
+					em.wakeUpAt(t)
+				}
 				em.refreshCond.Wait() // until watermarks may have changed.
 
-				// Update if the processing time has advanced while we waited, and add refreshes here. (TODO waking on real time here for prod mode)
+				// Update if the processing time has advanced while we waited, and add refreshes here.
 				emNow = em.ProcessingTimeNow()
 				changedByProcessingTime = em.processTimeEvents.AdvanceTo(emNow)
 				em.changedStages.merge(changedByProcessingTime)
@@ -527,10 +627,10 @@ func (em *ElementManager) checkForQuiescence(advanced set[string]) error {
 		// It's not correct to move to the next event if no refreshes would occur.
 		if len(em.changedStages) > 0 {
 			return nil
-		} else if _, ok := nextEvent.(tsProcessingTimeEvent); ok {
+		} else if ev, ok := nextEvent.(tsProcessingTimeEvent); ok {
 			// It's impossible to fully control processing time SDK side handling for processing time
 			// Runner side, so we specialize refresh handling here to avoid spuriously getting stuck.
-			em.changedStages.insert(em.testStreamHandler.ID)
+			em.changedStages.insert(ev.stream.ID)
 			return nil
 		}
 		// If there are no changed stages due to a test stream event
@@ -549,8 +649,7 @@ func (em *ElementManager) checkForQuiescence(advanced set[string]) error {
 	var stageState []string
 	ids := maps.Keys(em.stages)
 	if em.testStreamHandler != nil {
-		stageState = append(stageState, fmt.Sprintf("TestStreamHandler: completed %v, curIndex %v of %v events: %+v, processingTime %v, %v, ptEvents %v \n",
-			em.testStreamHandler.completed, em.testStreamHandler.nextEventIndex, len(em.testStreamHandler.events), em.testStreamHandler.events, em.testStreamHandler.processingTime, mtime.FromTime(em.testStreamHandler.processingTime), em.processTimeEvents))
+		stageState = append(stageState, fmt.Sprintf("%v ptEvents %v \n", em.testStreamHandler, em.processTimeEvents))
 	} else {
 		stageState = append(stageState, fmt.Sprintf("ElementManager Now: %v processingTimeEvents: %v injectedBundles: %v\n", em.ProcessingTimeNow(), em.processTimeEvents.events, em.injectedBundles))
 	}
@@ -640,7 +739,7 @@ func (em *ElementManager) DataAndTimerInputForBundle(rb RunBundle, info PColInfo
 		default:
 			var buf bytes.Buffer
 			exec.EncodeWindowedValueHeader(info.WEnc, []typex.Window{e.window}, e.timestamp, e.pane, &buf)
-			buf.Write(e.elmBytes)
+			buf.Write(e.data())
 			cur.Bytes = append(cur.Bytes, buf.Bytes())
 		}
 	}
@@ -681,6 +780,7 @@ func (em *ElementManager) StateForBundle(rb RunBundle) TentativeData {
 		stateTypeLen: ss.stateTypeLen,
 	}
 	keys := ss.inprogressKeysByBundle[rb.BundleID]
+	ss.pageInStateLocked(keys)
 	// TODO(lostluck): Also track windows per bundle, to reduce copying.
 	if len(ss.state) > 0 {
 		ret.state = map[LinkID]map[typex.Window]map[string]StateData{}
@@ -788,9 +888,23 @@ func reElementResiduals(residuals []Residual, inputInfo PColInfo, rb RunBundle)
 //
 // PersistBundle takes in the stage ID, ID of the bundle associated with the pending
 // input elements, and the committed output elements.
+//
+// If checkpointing is configured, a snapshot is written after the bundle is committed
+// once the checkpoint interval or bundle count is reached.
 func (em *ElementManager) PersistBundle(rb RunBundle, col2Coders map[string]PColInfo, d TentativeData, inputInfo PColInfo, residuals Residuals) {
+	em.commitMu.RLock()
+	em.persistBundle(rb, col2Coders, d, inputInfo, residuals)
+	em.commitMu.RUnlock()
+	if err := em.maybeCheckpoint(); err != nil {
+		slog.Error("unable to checkpoint after bundle", "bundle", rb, "error", err)
+	}
+}
+
+func (em *ElementManager) persistBundle(rb RunBundle, col2Coders map[string]PColInfo, d TentativeData, inputInfo PColInfo, residuals Residuals) {
 	stage := em.stages[rb.StageID]
 	var seq int
+	var addedBytes int // Tracks bytes added to pending elements and state, for the memory budget.
+	sideRefreshes := set[string]{}
 	for output, data := range d.Raw {
 		info := col2Coders[output]
 		var newPending []element
@@ -844,11 +958,20 @@ func (em *ElementManager) PersistBundle(rb RunBundle, col2Coders map[string]PCol
 			count := consumer.AddPending(em, newPending)
 			em.addPending(count)
 		}
+		for _, e := range newPending {
+			addedBytes += len(e.elmBytes) * len(consumers)
+		}
 		for _, link := range sideConsumers {
 			consumer := em.stages[link.Global]
-			consumer.AddPendingSide(newPending, link.Transform, link.Local)
+			if consumer.AddPendingSide(newPending, link.Transform, link.Local) {
+				// The consumer may now be able to process with the new side input version.
+				sideRefreshes.insert(link.Global)
+			}
 		}
 	}
+	if len(sideRefreshes) > 0 {
+		em.markStagesAsChanged(sideRefreshes)
+	}
 
 	// Triage timers into their time domains for scheduling.
 	// EventTime timers are handled with normal elements,
@@ -928,10 +1051,12 @@ func (em *ElementManager) PersistBundle(rb RunBundle, col2Coders map[string]PCol
 			}
 			for key, data := range keyMap {
 				wlinkMap[key] = data
+				addedBytes += data.userBytes()
 			}
 		}
 	}
 	stage.mu.Unlock()
+	em.noteBytes(addedBytes)
 
 	em.markChangedAndClearBundle(stage.ID, rb.BundleID, ptRefreshes)
 }
@@ -952,6 +1077,7 @@ func (em *ElementManager) triageTimers(d TentativeData, inputInfo PColInfo, stag
 
 	var pendingEventTimers []element
 	var pendingProcessingTimers []fireElement
+	var clearedProcessingTimers []element
 	stageRefreshTimes := set[mtime.Time]{}
 	for tentativeKey, timers := range d.timers {
 		keyToTimers := map[timerKey]element{}
@@ -972,6 +1098,11 @@ func (em *ElementManager) triageTimers(d TentativeData, inputInfo PColInfo, stag
 			elm.family = tentativeKey.Family
 
 			if stage.processingTimeTimersFamilies[elm.family] {
+				if elm.sequence < 0 {
+					// Cleared timers have no firing time, and are removed from the store.
+					clearedProcessingTimers = append(clearedProcessingTimers, elm)
+					continue
+				}
 				// Conditionally rebase processing time or always rebase?
 				newTimerFire := rebaseProcessingTime(emNow, elm.timestamp)
 				elm.timestamp = elm.holdTimestamp // Processing Time always uses the hold timestamp as the resulting event time.
@@ -993,9 +1124,12 @@ func (em *ElementManager) triageTimers(d TentativeData, inputInfo PColInfo, stag
 		em.addPending(count)
 	}
 	changedHolds := map[mtime.Time]int{}
-	if len(pendingProcessingTimers) > 0 {
+	if len(pendingProcessingTimers)+len(clearedProcessingTimers) > 0 {
 		stage.mu.Lock()
 		var count int
+		for _, v := range clearedProcessingTimers {
+			count += stage.processingTimeTimers.Clear(v, changedHolds)
+		}
 		for _, v := range pendingProcessingTimers {
 			count += stage.processingTimeTimers.Persist(v.firing, v.timer, changedHolds)
 		}
@@ -1007,6 +1141,8 @@ func (em *ElementManager) triageTimers(d TentativeData, inputInfo PColInfo, stag
 
 // FailBundle clears the extant data allowing the execution to shut down.
 func (em *ElementManager) FailBundle(rb RunBundle) {
+	em.commitMu.RLock()
+	defer em.commitMu.RUnlock()
 	stage := em.stages[rb.StageID]
 	stage.mu.Lock()
 	completed := stage.inprogress[rb.BundleID]
@@ -1019,9 +1155,11 @@ func (em *ElementManager) FailBundle(rb RunBundle) {
 // ReturnResiduals is called after a successful split, so the remaining work
 // can be re-assigned to a new bundle.
 func (em *ElementManager) ReturnResiduals(rb RunBundle, firstRsIndex int, inputInfo PColInfo, residuals Residuals) {
+	em.commitMu.RLock()
+	defer em.commitMu.RUnlock()
 	stage := em.stages[rb.StageID]
 
-	stage.splitBundle(rb, firstRsIndex)
+	stage.splitBundle(em, rb, firstRsIndex)
 	unprocessedElements := reElementResiduals(residuals.Data, inputInfo, rb)
 	if len(unprocessedElements) > 0 {
 		slog.Debug("ReturnResiduals: unprocessed elements", "bundle", rb, "count", len(unprocessedElements))
@@ -1049,10 +1187,48 @@ func (em *ElementManager) markChangedAndClearBundle(stageID, bundID string, ptRe
 	em.changedStages.insert(stageID)
 	for t := range ptRefreshes {
 		em.processTimeEvents.Schedule(t, stageID)
+		em.wakeUpAt(t)
 	}
 	em.refreshCond.Broadcast()
 }
 
+// wakeUpAt ensures the watermark evaluation loop is woken up at the given
+// processing time when using the real time clock, so processing time events
+// are handled without needing other changes to occur.
+//
+// Must be called while holding em.refreshCond.L.
+func (em *ElementManager) wakeUpAt(t mtime.Time) {
+	if !em.config.EnableRTC {
+		// Synthetic processing time advances to the next event immediately.
+		return
+	}
+	now := mtime.Now()
+	if em.wakeTimer != nil && em.wakeAt > now && em.wakeAt <= t {
+		// An earlier wake up is already pending.
+		return
+	}
+	// Clamp the delay, as t may be far in the future, such as the end of the global window.
+	// Later events are rescheduled by the evaluation loop once it's woken.
+	d := maxWakeDelay
+	if t-now < mtime.Time(maxWakeDelay.Milliseconds()) {
+		d = time.Duration(max(t-now, 0)) * time.Millisecond
+	}
+	em.wakeAt = now + mtime.Time(d.Milliseconds())
+	if em.wakeTimer == nil {
+		em.wakeTimer = time.AfterFunc(d, func() {
+			em.refreshCond.L.Lock()
+			defer em.refreshCond.L.Unlock()
+			em.refreshCond.Broadcast()
+		})
+		return
+	}
+	em.wakeTimer.Reset(d)
+}
+
+// maxWakeDelay is the longest the watermark evaluation loop is left waiting on
+// a processing time event before being woken to reschedule.
+const maxWakeDelay = time.Hour
+
 // refreshWatermarks incrementally refreshes the watermarks of stages that have
 // been marked as changed, and returns the set of stages where the
 // the watermark may have advanced.
@@ -1115,8 +1291,9 @@ type stageState struct {
 
 	// Special handling bits
 	kind                         stageKind
-	strat                        WinStrat        // Windowing Strategy for aggregation fireings.
-	processingTimeTimersFamilies map[string]bool // Indicates which timer families use the processing time domain.
+	strat                        WinStrat                // Windowing Strategy for aggregation fireings.
+	processingTimeTriggers       []processingTimeTrigger // Processing time triggers within the windowing strategy, for aggregations.
+	processingTimeTimersFamilies map[string]bool         // Indicates which timer families use the processing time domain.
 
 	// onWindowExpiration management
 	onWindowExpiration       StaticTimerID                // The static ID of the OnWindowExpiration callback.
@@ -1130,15 +1307,24 @@ type stageState struct {
 	output             mtime.Time // Output watermark for the whole stage
 	estimatedOutput    mtime.Time // Estimated watermark output from DoFns
 
-	pending    elementHeap                          // pending input elements for this stage that are to be processesd
-	inprogress map[string]elements                  // inprogress elements by active bundles, keyed by bundle
-	sideInputs map[LinkID]map[typex.Window][][]byte // side input data for this stage, from {tid, inputID} -> window
+	pending        elementHeap                          // pending input elements for this stage that are to be processesd
+	inprogress     map[string]elements                  // inprogress elements by active bundles, keyed by bundle
+	peakInProgress int                                  // the most bundles that have been in progress at once.
+	sideInputs     map[LinkID]map[typex.Window][][]byte // side input data for this stage, from {tid, inputID} -> window
+
+	// Fields for triggered side inputs, which publish each firing as a new version of the side input.
+	triggeredSides set[LinkID]                                          // triggered side inputs for this stage, from {tid, inputID}.
+	sidePanes      map[LinkID]map[typex.Window]map[string]*sidePaneData // the published side input version, from {tid, inputID} -> window -> key
 
 	// Fields for stateful stages which need to be per key.
 	pendingByKeys          map[string]*dataAndTimers                        // pending input elements by Key, if stateful.
 	inprogressKeys         set[string]                                      // all keys that are assigned to bundles.
 	inprogressKeysByBundle map[string]set[string]                           // bundle to key assignments.
+	keyGroups              int                                              // number of disjoint key groups that may be processed concurrently, if greater than 0.
+	maxBundleSize          int                                              // the most elements to take for a bundle, if greater than 0.
 	state                  map[LinkID]map[typex.Window]map[string]StateData // state data for this stage, from {tid, stateID} -> window -> userKey
+	spilledState           map[LinkID]map[typex.Window]map[string]*spillRef // state data spilled to disk, paged back into state when a bundle needs it.
+	activeWindows          map[string]set[typex.Window]                     // windows with aggregation state by key, if windows are merging.
 	stateTypeLen           map[LinkID]func([]byte) int                      // map from state to a function that will produce the total length of a single value in bytes.
 	bundlesToInject        []RunBundle                                      // bundlesToInject are triggered bundles that will be injected by the watermark loop to avoid premature pipeline termination.
 
@@ -1149,6 +1335,10 @@ type stageState struct {
 	watermarkHolds          *holdTracker
 	inprogressHoldsByBundle map[string]map[mtime.Time]int // bundle to associated holds.
 
+	// Accounting for the output watermark holds of pending aggregation panes.
+	paneHolds     map[string]map[typex.Window]mtime.Time // the hold for each pending pane, by key and window.
+	paneHoldTimes *holdTracker                           // counts of the pane holds, to find the minimum hold.
+
 	processingTimeTimers *timerHandler
 }
 
@@ -1161,6 +1351,8 @@ type stageKind interface {
 	addPending(ss *stageState, em *ElementManager, newPending []element) int
 	// buildEventTimeBundle handles building bundles for the stage per it's kind.
 	buildEventTimeBundle(ss *stageState, watermark mtime.Time) (toProcess elementHeap, minTs mtime.Time, newKeys set[string], holdsInBundle map[mtime.Time]int, schedulable bool, pendingAdjustment int)
+	// buildProcessingTimeBundle handles building processing time bundles for the stage per it's kind.
+	buildProcessingTimeBundle(ss *stageState, em *ElementManager, emNow mtime.Time, upstreamPending bool) (toProcess elementHeap, minTs mtime.Time, newKeys set[string], holdsInBundle map[mtime.Time]int, schedulable bool)
 
 	// updatePane based on the stage state.
 	updatePane(ss *stageState, pane typex.PaneInfo, w typex.Window, keyBytes []byte) typex.PaneInfo
@@ -1267,23 +1459,45 @@ func (*aggregateStageKind) addPending(ss *stageState, em *ElementManager, newPen
 	if ss.pendingByKeys == nil {
 		ss.pendingByKeys = map[string]*dataAndTimers{}
 	}
+	// Processing time triggers need to know when elements arrived.
+	var emNow mtime.Time
+	ptRefreshes := set[mtime.Time]{}
+	if len(ss.processingTimeTriggers) > 0 {
+		em.refreshCond.L.Lock()
+		emNow = em.ProcessingTimeNow()
+		em.refreshCond.L.Unlock()
+	}
+	if ss.state == nil {
+		ss.state = make(map[LinkID]map[typex.Window]map[string]StateData)
+	}
+	// Merge before evaluating triggers, so they see the merged windows' state.
+	var mergedInto map[string]map[typex.Window]typex.Window
+	if ss.strat.MergeWindows != nil && len(newPending) > 0 {
+		mergedInto = ss.mergeWindowsLocked(newPending)
+	}
 	count := 0
 	for _, e := range newPending {
 		count++
 		if len(e.keyBytes) == 0 {
 			panic(fmt.Sprintf("zero length key: %v %v", ss.ID, ss.inputID))
 		}
+		// Check on triggers for this key.
+		// We use an empty linkID as the key into state for aggregations.
+		if w, ok := mergedInto[string(e.keyBytes)][e.window]; ok {
+			e.window = w
+		}
+		if ss.strat.OutputTime != OutputTimeEndOfWindow && e.timestamp < threshold {
+			// Late elements can't hold the watermark behind the output watermark,
+			// so they're output at the end of their window instead.
+			e.timestamp = e.window.MaxTimestamp()
+		}
 		dnt, ok := ss.pendingByKeys[string(e.keyBytes)]
 		if !ok {
 			dnt = &dataAndTimers{}
 			ss.pendingByKeys[string(e.keyBytes)] = dnt
 		}
 		heap.Push(&dnt.elements, e)
-		// Check on triggers for this key.
-		// We use an empty linkID as the key into state for aggregations.
-		if ss.state == nil {
-			ss.state = make(map[LinkID]map[typex.Window]map[string]StateData)
-		}
+		ss.addPaneHoldLocked(string(e.keyBytes), e)
 		lv, ok := ss.state[LinkID{}]
 		if !ok {
 			lv = make(map[typex.Window]map[string]StateData)
@@ -1299,6 +1513,7 @@ func (*aggregateStageKind) addPending(ss *stageState, em *ElementManager, newPen
 		ready := ss.strat.IsTriggerReady(triggerInput{
 			newElementCount:    1,
 			endOfWindowReached: endOfWindowReached,
+			emNow:              emNow,
 		}, &state)
 
 		if ready {
@@ -1306,15 +1521,58 @@ func (*aggregateStageKind) addPending(ss *stageState, em *ElementManager, newPen
 		}
 		// Store the state as triggers may have changed it.
 		ss.state[LinkID{}][e.window][string(e.keyBytes)] = state
+		ptRefreshes.merge(ss.scheduleProcessingTimeTriggers(e.keyBytes, e.window, &state))
 
 		// If we're ready, it's time to fire!
 		if ready {
 			count += ss.buildTriggeredBundle(em, e.keyBytes, e.window)
 		}
 	}
+	if len(ptRefreshes) > 0 {
+		em.refreshCond.L.Lock()
+		for t := range ptRefreshes {
+			em.processTimeEvents.Schedule(t, ss.ID)
+			em.wakeUpAt(t)
+		}
+		em.refreshCond.L.Unlock()
+	}
 	return count
 }
 
+// processingTimeTriggerFamily is the timer family used for the synthetic processing
+// time timers that schedule processing time trigger evaluations for a key and window.
+const processingTimeTriggerFamily = "prism:processing_time_trigger"
+
+// scheduleProcessingTimeTriggers persists synthetic processing time timers for the
+// processing time triggers waiting to reach their firing time for the given key
+// and window. Returns the processing times the stage needs to be refreshed at.
+//
+// Must be called with the stage lock held.
+func (ss *stageState) scheduleProcessingTimeTriggers(key []byte, win typex.Window, state *StateData) set[mtime.Time] {
+	refreshes := set[mtime.Time]{}
+	for i, t := range ss.processingTimeTriggers {
+		firing, ok := t.pendingFiringTime(state)
+		if !ok {
+			continue
+		}
+		// Processing time triggers don't hold the watermark, as the pending
+		// elements already do, so the hold changes are dropped.
+		ss.processingTimeTimers.Persist(firing, element{
+			window:        win,
+			timestamp:     win.MaxTimestamp(),
+			holdTimestamp: win.MaxTimestamp(),
+			pane:          typex.NoFiringPane(),
+			transform:     ss.ID,
+			family:        processingTimeTriggerFamily,
+			tag:           strconv.Itoa(i),
+			sequence:      1,
+			keyBytes:      key,
+		}, map[mtime.Time]int{})
+		refreshes.insert(firing)
+	}
+	return refreshes
+}
+
 func (*statefulStageKind) addPending(ss *stageState, em *ElementManager, newPending []element) int {
 	if ss.pendingByKeys == nil {
 		ss.pendingByKeys = map[string]*dataAndTimers{}
@@ -1428,12 +1686,42 @@ func computeNextWatermarkPane(pane typex.PaneInfo) typex.PaneInfo {
 // When in discarding mode, returns 0.
 // When in accumulating mode, returns the number of fired elements to maintain a correct pending count.
 func (ss *stageState) buildTriggeredBundle(em *ElementManager, key []byte, win typex.Window) int {
-	var toProcess []element
-	dnt := ss.pendingByKeys[string(key)]
-	var notYet []element
+	toProcess, accumulationDiff := ss.popTriggeredElements(key, win)
 
 	rb := RunBundle{StageID: ss.ID, BundleID: "agg-" + em.nextBundID(), Watermark: ss.input}
 
+	if ss.inprogressKeys == nil {
+		ss.inprogressKeys = set[string]{}
+	}
+	ss.makeInProgressBundle(
+		func() string { return rb.BundleID },
+		toProcess,
+		ss.input,
+		singleSet(string(key)),
+		nil,
+	)
+	ss.bundlesToInject = append(ss.bundlesToInject, rb)
+	// Bundle is marked in progress here to prevent a race condition.
+	em.refreshCond.L.Lock()
+	em.inprogressBundles.insert(rb.BundleID)
+	em.refreshCond.L.Unlock()
+	return accumulationDiff
+}
+
+// popTriggeredElements removes the pending elements for the given key and window
+// for a triggered firing. When accumulating, the elements remain pending for
+// subsequent firings, and the returned count adjusts the pending elements count
+// to include the duplicated elements.
+//
+// Must be called with the stage lock held.
+func (ss *stageState) popTriggeredElements(key []byte, win typex.Window) ([]element, int) {
+	var toProcess []element
+	dnt, ok := ss.pendingByKeys[string(key)]
+	if !ok {
+		return nil, 0
+	}
+	var notYet []element
+
 	// Look at all elements for this key, and only for this window.
 	for dnt.elements.Len() > 0 {
 		e := heap.Pop(&dnt.elements).(element)
@@ -1463,27 +1751,32 @@ func (ss *stageState) buildTriggeredBundle(em *ElementManager, key []byte, win t
 		// Ensure the heap invariants are maintained.
 		heap.Init(&dnt.elements)
 	}
+	ss.resetPaneHoldsLocked(string(key))
+	return toProcess, accumulationDiff
+}
 
-	if ss.inprogressKeys == nil {
-		ss.inprogressKeys = set[string]{}
-	}
-	ss.makeInProgressBundle(
-		func() string { return rb.BundleID },
-		toProcess,
-		ss.input,
-		singleSet(string(key)),
-		nil,
-	)
-	ss.bundlesToInject = append(ss.bundlesToInject, rb)
-	// Bundle is marked in progress here to prevent a race condition.
-	em.refreshCond.L.Lock()
-	em.inprogressBundles.insert(rb.BundleID)
-	em.refreshCond.L.Unlock()
-	return accumulationDiff
+// sidePaneData is the latest published pane of a triggered side input,
+// for a single key and window.
+type sidePaneData struct {
+	pane typex.PaneInfo
+	elms [][]byte
 }
 
 // AddPendingSide adds elements to be consumed as side inputs.
-func (ss *stageState) AddPendingSide(newPending []element, tID, inputID string) {
+// Returns true if the elements were published to a triggered side input,
+// and are immediately available to the stage.
+//
+// Triggered side inputs publish each pane as a new version of the side input for
+// its key and window, replacing the previous version. Elements from the same pane
+// as the published version are added to it, and elements from earlier panes are dropped.
+// As a result, side inputs from accumulating aggregations contain all elements
+// seen so far for the window, while those from discarding aggregations only
+// contain the elements of the latest firing of each key.
+//
+// Panes are tracked per key when the side input is a KV, since each key of an
+// aggregation fires independently. Otherwise, the key of the aggregation isn't
+// known, and panes are tracked per window.
+func (ss *stageState) AddPendingSide(newPending []element, tID, inputID string) bool {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 	if ss.sideInputs == nil {
@@ -1495,20 +1788,66 @@ func (ss *stageState) AddPendingSide(newPending []element, tID, inputID string)
 		in = map[typex.Window][][]byte{}
 		ss.sideInputs[key] = in
 	}
+	if !ss.triggeredSides.present(key) {
+		for _, e := range newPending {
+			in[e.window] = append(in[e.window], e.elmBytes)
+		}
+		return false
+	}
+	if ss.sidePanes == nil {
+		ss.sidePanes = map[LinkID]map[typex.Window]map[string]*sidePaneData{}
+	}
+	panes, ok := ss.sidePanes[key]
+	if !ok {
+		panes = map[typex.Window]map[string]*sidePaneData{}
+		ss.sidePanes[key] = panes
+	}
+	changed := set[typex.Window]{}
 	for _, e := range newPending {
-		in[e.window] = append(in[e.window], e.elmBytes)
+		byKey, ok := panes[e.window]
+		if !ok {
+			byKey = map[string]*sidePaneData{}
+			panes[e.window] = byKey
+		}
+		published, ok := byKey[string(e.keyBytes)]
+		switch {
+		case !ok || e.pane.Index > published.pane.Index:
+			// A new firing, so it replaces the published version.
+			byKey[string(e.keyBytes)] = &sidePaneData{pane: e.pane, elms: [][]byte{e.elmBytes}}
+		case e.pane.Index == published.pane.Index:
+			published.elms = append(published.elms, e.elmBytes)
+		default:
+			// Elements from an earlier firing are stale, and are dropped.
+			continue
+		}
+		changed.insert(e.window)
+	}
+	// Rebuild the published data for the changed windows, in key order.
+	for win := range changed {
+		byKey := panes[win]
+		keys := maps.Keys(byKey)
+		sort.Strings(keys)
+		var data [][]byte
+		for _, k := range keys {
+			data = append(data, byKey[k].elms...)
+		}
+		in[win] = data
 	}
+	return len(newPending) > 0
 }
 
 // GetSideData returns side input data for the provided transform+input pair, valid to the watermark.
+// The latest published versions of triggered side inputs are always valid.
 func (ss *stageState) GetSideData(tID, inputID string, watermark mtime.Time) map[typex.Window][][]byte {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
-	d := ss.sideInputs[LinkID{Transform: tID, Local: inputID}]
+	key := LinkID{Transform: tID, Local: inputID}
+	d := ss.sideInputs[key]
+	triggered := ss.triggeredSides.present(key)
 	ret := map[typex.Window][][]byte{}
 	for win, ds := range d {
-		if win.MaxTimestamp() <= watermark {
+		if triggered || win.MaxTimestamp() <= watermark {
 			ret[win] = ds
 		}
 	}
@@ -1610,11 +1949,33 @@ func (*statefulStageKind) buildEventTimeBundle(ss *stageState, watermark mtime.T
 	// timers might have held back the minimum pending watermark.
 	timerCleared := false
 
+	// When keys are partitioned into key groups, a bundle only takes keys from a single
+	// group that has no bundle in progress, so bundles for the other groups may run concurrently.
+	bundleGroup := -1
+	var busyGroups set[int]
+	if ss.keyGroups > 0 {
+		busyGroups = set[int]{}
+		for k := range ss.inprogressKeys {
+			busyGroups.insert(keyGroup(k, ss.keyGroups))
+		}
+	}
+
 keysPerBundle:
 	for k, dnt := range ss.pendingByKeys {
 		if ss.inprogressKeys.present(k) {
 			continue
 		}
+		if ss.keyGroups > 0 {
+			g := keyGroup(k, ss.keyGroups)
+			if busyGroups.present(g) {
+				continue
+			}
+			if bundleGroup < 0 {
+				bundleGroup = g
+			} else if g != bundleGroup {
+				continue
+			}
+		}
 		newKeys.insert(k)
 		// Track the min-timestamp for later watermark handling.
 		if dnt.elements[0].timestamp < minTs {
@@ -1675,14 +2036,37 @@ keysPerBundle:
 		if OneKeyPerBundle {
 			break keysPerBundle
 		}
+		if ss.maxBundleSize > 0 && len(toProcess) >= ss.maxBundleSize {
+			break keysPerBundle
+		}
 	}
 
 	// If we're out of data, and timers were not cleared then the watermark is accurate.
 	stillSchedulable := !(len(ss.pendingByKeys) == 0 && !timerCleared)
+	if ss.keyGroups > 0 {
+		// Keys in busy groups are rescheduled when their bundles complete, so only
+		// reschedule now if other groups can start bundles. The loop may have ended
+		// before reaching them, so check all remaining keys.
+		busyGroups.insert(bundleGroup)
+		stillSchedulable = timerCleared
+		for k := range ss.pendingByKeys {
+			if !busyGroups.present(keyGroup(k, ss.keyGroups)) {
+				stillSchedulable = true
+				break
+			}
+		}
+	}
 
 	return toProcess, minTs, newKeys, holdsInBundle, stillSchedulable, 0
 }
 
+// keyGroup returns the key group of the given key, out of the given number of groups.
+func keyGroup(key string, groups int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(groups))
+}
+
 // buildEventTimeBundle for aggregation stages, processes all elements that are within the watermark for completed windows.
 func (*aggregateStageKind) buildEventTimeBundle(ss *stageState, watermark mtime.Time) (toProcess elementHeap, _ mtime.Time, _ set[string], _ map[mtime.Time]int, schedulable bool, pendingAdjustment int) {
 	minTs := mtime.MaxTimestamp
@@ -1792,6 +2176,7 @@ keysPerBundle:
 		if dnt.elements.Len() == 0 {
 			delete(ss.pendingByKeys, k)
 		}
+		ss.resetPaneHoldsLocked(k)
 		if OneKeyPerBundle {
 			break keysPerBundle
 		}
@@ -1804,9 +2189,43 @@ keysPerBundle:
 }
 
 func (ss *stageState) startProcessingTimeBundle(em *ElementManager, emNow mtime.Time, genBundID func() string) (string, bool, bool) {
+	// Upstream stages are checked before taking this stage's lock, so stage locks aren't nested.
+	var upstreamPending bool
+	for _, t := range ss.processingTimeTriggers {
+		if _, ok := t.(*TriggerAfterSynchronizedProcessingTime); ok {
+			upstreamPending = em.upstreamPending(ss)
+			break
+		}
+	}
+
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
+	toProcess, minTs, newKeys, holdsInBundle, stillSchedulable := ss.kind.buildProcessingTimeBundle(ss, em, emNow, upstreamPending)
+
+	if len(toProcess) == 0 {
+		// If we have nothing
+		return "", false, stillSchedulable
+	}
+	bundID := ss.makeInProgressBundle(genBundID, toProcess, minTs, newKeys, holdsInBundle)
+	return bundID, true, stillSchedulable
+}
+
+// buildProcessingTimeBundle for ordinary stages processes all processing time timers that are ready.
+func (*ordinaryStageKind) buildProcessingTimeBundle(ss *stageState, em *ElementManager, emNow mtime.Time, upstreamPending bool) (toProcess elementHeap, minTs mtime.Time, newKeys set[string], holdsInBundle map[mtime.Time]int, schedulable bool) {
+	return ss.buildProcessingTimeTimerBundle(em, emNow)
+}
+
+// buildProcessingTimeBundle for stateful stages processes all processing time timers that are ready.
+func (*statefulStageKind) buildProcessingTimeBundle(ss *stageState, em *ElementManager, emNow mtime.Time, upstreamPending bool) (toProcess elementHeap, minTs mtime.Time, newKeys set[string], holdsInBundle map[mtime.Time]int, schedulable bool) {
+	return ss.buildProcessingTimeTimerBundle(em, emNow)
+}
+
+// buildProcessingTimeTimerBundle processes all processing time timers that are ready,
+// for keys that aren't already in progress.
+//
+// Must be called with the stage lock held.
+func (ss *stageState) buildProcessingTimeTimerBundle(em *ElementManager, emNow mtime.Time) (elementHeap, mtime.Time, set[string], map[mtime.Time]int, bool) {
 	// TODO: Determine if it's possible and a good idea to treat all EventTime processing as a MinTime
 	// Special Case for ProcessingTime handling.
 	// Eg. Always queue EventTime elements at minTime.
@@ -1814,7 +2233,7 @@ func (ss *stageState) startProcessingTimeBundle(em *ElementManager, emNow mtime.
 	//
 	// Potentially puts too much work on the scheduling thread though.
 
-	var toProcess []element
+	var toProcess elementHeap
 	minTs := mtime.MaxTimestamp
 	holdsInBundle := map[mtime.Time]int{}
 
@@ -1871,12 +2290,139 @@ func (ss *stageState) startProcessingTimeBundle(em *ElementManager, emNow mtime.
 	// Add a refresh if there are still processing time events to process.
 	stillSchedulable := (nextTime < emNow && nextTime != mtime.MaxTimestamp || len(notYet) > 0)
 
-	if len(toProcess) == 0 {
-		// If we have nothing
-		return "", false, stillSchedulable
+	return toProcess, minTs, newKeys, holdsInBundle, stillSchedulable
+}
+
+// buildProcessingTimeBundle for aggregation stages evaluates processing time triggers
+// for the keys and windows whose firing times have been reached, and processes the
+// elements of those that are ready to fire.
+func (*aggregateStageKind) buildProcessingTimeBundle(ss *stageState, em *ElementManager, emNow mtime.Time, upstreamPending bool) (toProcess elementHeap, minTs mtime.Time, newKeys set[string], holdsInBundle map[mtime.Time]int, schedulable bool) {
+	minTs = mtime.MaxTimestamp
+	newKeys = set[string]{}
+	holdsInBundle = map[mtime.Time]int{}
+
+	type keyWindow struct {
+		key string
+		win typex.Window
 	}
-	bundID := ss.makeInProgressBundle(genBundID, toProcess, minTs, newKeys, holdsInBundle)
-	return bundID, true, stillSchedulable
+	fired := set[keyWindow]{}
+	evaluated := set[keyWindow]{}
+	var notYet []fireElement
+	accumulatingPendingAdjustment := 0
+
+	nextTime := ss.processingTimeTimers.Peek()
+	for nextTime <= emNow {
+		elems := ss.processingTimeTimers.FireAt(nextTime)
+		for _, e := range elems {
+			// Check if we're already executing this key.
+			if ss.inprogressKeys.present(string(e.keyBytes)) {
+				notYet = append(notYet, fireElement{firing: nextTime, timer: e})
+				continue
+			}
+			kw := keyWindow{key: string(e.keyBytes), win: e.window}
+			if fired.present(kw) {
+				// Another processing time trigger already fired this key and window.
+				continue
+			}
+			state, ok := ss.state[LinkID{}][e.window][string(e.keyBytes)]
+			if !ok {
+				// The window has already been garbage collected.
+				continue
+			}
+			endOfWindowReached := e.window.MaxTimestamp() < ss.input
+			ready := ss.strat.IsTriggerReady(triggerInput{
+				endOfWindowReached: endOfWindowReached,
+				emNow:              emNow,
+				processingTimeTick: true,
+				upstreamPending:    upstreamPending,
+			}, &state)
+			if ready {
+				elms, accumulationDiff := ss.popTriggeredElements(e.keyBytes, e.window)
+				// Only produce a pane if there's something to fire.
+				if len(elms) > 0 {
+					fired.insert(kw)
+					state.Pane = computeNextTriggeredPane(state.Pane, endOfWindowReached)
+					newKeys.insert(kw.key)
+					for _, elm := range elms {
+						minTs = mtime.Min(minTs, elm.timestamp)
+					}
+					toProcess = append(toProcess, elms...)
+					accumulatingPendingAdjustment += accumulationDiff
+				}
+			}
+			// Store the state as triggers may have changed it.
+			ss.state[LinkID{}][e.window][string(e.keyBytes)] = state
+			evaluated.insert(kw)
+		}
+
+		nextTime = ss.processingTimeTimers.Peek()
+		if nextTime == mtime.MaxTimestamp {
+			// Escape the loop if there are no more events.
+			break
+		}
+	}
+
+	// Reschedule unfired triggers.
+	for _, v := range notYet {
+		ss.processingTimeTimers.Persist(v.firing, v.timer, map[mtime.Time]int{})
+		em.processTimeEvents.Schedule(v.firing, ss.ID)
+	}
+	// Schedule any processing time triggers that are still waiting for the evaluated keys and windows.
+	for kw := range evaluated {
+		state := ss.state[LinkID{}][kw.win][kw.key]
+		for t := range ss.scheduleProcessingTimeTriggers([]byte(kw.key), kw.win, &state) {
+			if t <= emNow {
+				// Synchronized processing time is being held back by upstream stages,
+				// which will refresh this stage as they make progress.
+				continue
+			}
+			em.processTimeEvents.Schedule(t, ss.ID)
+			em.wakeUpAt(t)
+		}
+	}
+	if accumulatingPendingAdjustment > 0 {
+		em.addPending(accumulatingPendingAdjustment)
+	}
+
+	// Add a refresh if there are still processing time events to process.
+	stillSchedulable := (nextTime < emNow && nextTime != mtime.MaxTimestamp || len(notYet) > 0)
+	return toProcess, minTs, newKeys, holdsInBundle, stillSchedulable
+}
+
+// upstreamPending returns whether any stage upstream of the given stage has
+// elements waiting to be processed, or bundles in progress.
+//
+// Elements buffered by upstream aggregations aren't considered, as they are
+// waiting on their own triggers rather than processing.
+//
+// Upstream stage locks are taken one at a time, so this must not be called
+// while holding a stage lock.
+func (em *ElementManager) upstreamPending(ss *stageState) bool {
+	visited := set[string]{}
+	toVisit := []*stageState{ss}
+	for len(toVisit) > 0 {
+		cur := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		cur.upstreamWatermarks.Range(func(k, _ any) bool {
+			pID, ok := em.pcolParents[k.(string)]
+			if !ok || visited.present(pID) {
+				return true
+			}
+			visited.insert(pID)
+			toVisit = append(toVisit, em.stages[pID])
+			return true
+		})
+		if cur == ss {
+			continue
+		}
+		cur.mu.Lock()
+		pending := len(cur.pending) > 0 || len(cur.inprogress) > 0
+		cur.mu.Unlock()
+		if pending {
+			return true
+		}
+	}
+	return false
 }
 
 // makeInProgressBundle is common code to store a set of elements as a bundle in progress.
@@ -1903,13 +2449,19 @@ func (ss *stageState) makeInProgressBundle(genBundID func() string, toProcess []
 	}
 	bundID := genBundID()
 	ss.inprogress[bundID] = es
+	ss.peakInProgress = max(ss.peakInProgress, len(ss.inprogress))
 	ss.inprogressKeysByBundle[bundID] = newKeys
 	ss.inprogressKeys.merge(newKeys)
 	ss.inprogressHoldsByBundle[bundID] = holdsInBundle
 	return bundID
 }
 
-func (ss *stageState) splitBundle(rb RunBundle, firstResidual int) {
+// splitBundle returns the unstarted elements of a split bundle to the pending
+// elements for the stage, by key if the stage is stateful.
+//
+// Aggregations have already fired the pane for the unstarted elements, so they're
+// moved to a new bundle instead of having their triggers evaluated again.
+func (ss *stageState) splitBundle(em *ElementManager, rb RunBundle, firstResidual int) {
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
 
@@ -1920,9 +2472,27 @@ func (ss *stageState) splitBundle(rb RunBundle, firstResidual int) {
 	res := es.es[firstResidual:]
 
 	es.es = prim
-	ss.pending = append(ss.pending, res...)
-	heap.Init(&ss.pending)
 	ss.inprogress[rb.BundleID] = es
+	if len(res) == 0 {
+		return
+	}
+
+	if _, ok := ss.kind.(*aggregateStageKind); ok {
+		// The keys remain assigned to the primary bundle. The elements are
+		// already counted as pending, as they remain in progress.
+		resRB := RunBundle{StageID: ss.ID, BundleID: "agg-" + em.nextBundID(), Watermark: rb.Watermark}
+		ss.makeInProgressBundle(func() string { return resRB.BundleID }, res, es.minTimestamp, set[string]{}, nil)
+		ss.bundlesToInject = append(ss.bundlesToInject, resRB)
+		em.refreshCond.L.Lock()
+		em.inprogressBundles.insert(resRB.BundleID)
+		em.refreshCond.L.Unlock()
+		return
+	}
+
+	// The returned elements are already counted as pending by the element manager,
+	// so only adjust for elements that weren't added, such as replaced timers.
+	count := ss.kind.addPending(ss, em, res)
+	em.addPending(count - len(res))
 }
 
 // minimumPendingTimestamp returns the minimum pending timestamp from all pending elements,
@@ -1941,7 +2511,10 @@ func (ss *stageState) minPendingTimestampLocked() mtime.Time {
 	if len(ss.pending) != 0 {
 		minPending = ss.pending[0].timestamp
 	}
-	if len(ss.pendingByKeys) != 0 {
+	if ss.paneHoldTimes != nil {
+		// Aggregations hold the watermark where their pending panes will be output.
+		minPending = mtime.Min(minPending, ss.paneHoldTimes.Min())
+	} else if len(ss.pendingByKeys) != 0 {
 		// TODO(lostluck): Can we figure out how to avoid checking every key on every watermark refresh?
 		for _, dnt := range ss.pendingByKeys {
 			minPending = mtime.Min(minPending, dnt.elements[0].timestamp)
@@ -2008,7 +2581,7 @@ func (ss *stageState) updateWatermarks(em *ElementManager) set[string] {
 	// that are before the new output watermark, if they aren't in progress
 	// of being expired.
 	// They'll never be read in again.
-	for _, wins := range ss.sideInputs {
+	for link, wins := range ss.sideInputs {
 		for win := range wins {
 			// Clear out anything we've already used.
 			if ss.strat.EarliestCompletion(win) < newOut {
@@ -2017,6 +2590,7 @@ func (ss *stageState) updateWatermarks(em *ElementManager) set[string] {
 					continue
 				}
 				delete(wins, win)
+				delete(ss.sidePanes[link], win)
 			}
 		}
 	}
@@ -2031,6 +2605,23 @@ func (ss *stageState) updateWatermarks(em *ElementManager) set[string] {
 			}
 		}
 	}
+	for _, wins := range ss.spilledState {
+		for win := range wins {
+			if ss.strat.EarliestCompletion(win) < newOut && ss.inProgressExpiredWindows[win] == 0 {
+				delete(wins, win)
+			}
+		}
+	}
+	for key, active := range ss.activeWindows {
+		for win := range active {
+			if ss.strat.EarliestCompletion(win) < newOut && ss.inProgressExpiredWindows[win] == 0 {
+				active.remove(win)
+			}
+		}
+		if len(active) == 0 {
+			delete(ss.activeWindows, key)
+		}
+	}
 	// If there are windows to expire, we don't update the output watermark yet.
 	if preventDownstreamUpdate {
 		return nil
@@ -2159,15 +2750,28 @@ func (ss *stageState) bundleReady(em *ElementManager, emNow mtime.Time) (mtime.T
 			panic(fmt.Sprintf("stage[%v] no parent for side input %v, with parent ID %v", ss.ID, side, pID))
 		}
 		ow := parent.OutputWatermark()
-		if upstreamW > ow {
+		if upstreamW > ow && !ss.hasTriggeredSideData(side) {
 			ready = false
 		}
 	}
 	return upstreamW, ready, ptimeEventsReady, injectedReady
 }
 
+// hasTriggeredSideData returns whether the given side input is triggered, and
+// has published a version that the stage may process with.
+//
+// Must be called with the stage lock held.
+func (ss *stageState) hasTriggeredSideData(side LinkID) bool {
+	key := LinkID{Transform: side.Transform, Local: side.Local}
+	return ss.triggeredSides.present(key) && len(ss.sidePanes[key]) > 0
+}
+
 // ProcessingTimeNow gives the current processing time for the runner.
 func (em *ElementManager) ProcessingTimeNow() (ret mtime.Time) {
+	// Draining jobs fire all processing time events immediately.
+	if em.draining.Load() {
+		return mtime.MaxTimestamp
+	}
 	if em.testStreamHandler != nil && !em.testStreamHandler.completed {
 		return em.testStreamHandler.Now()
 	}
@@ -2186,6 +2790,7 @@ func (em *ElementManager) ProcessingTimeNow() (ret mtime.Time) {
 
 // rebaseProcessingTime turns an absolute processing time to be relative to the provided local clock now.
 // Necessary to reasonably schedule ProcessingTime timers within a TestStream using pipeline.
+// The result is capped at the end of time, which is where processing time is when draining.
 func rebaseProcessingTime(localNow, scheduled mtime.Time) mtime.Time {
-	return localNow + (scheduled - mtime.Now())
+	return mtime.Min(localNow+(scheduled-mtime.Now()), mtime.MaxTimestamp)
 }
@@ -22,6 +22,7 @@ import (
 	"io"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
@@ -526,6 +527,124 @@ func TestElementManager(t *testing.T) {
 	})
 }
 
+func TestStageState_AddPendingSide(t *testing.T) {
+	side := LinkID{Transform: "dofn", Global: "sideCol", Local: "local"}
+	keyedElm := func(k string, v byte, paneIndex int64) element {
+		pane := typex.NoFiringPane()
+		pane.Index = paneIndex
+		pane.IsFirst = paneIndex == 0
+		pane.Timing = typex.PaneEarly
+		var keyBytes []byte
+		if k != "" {
+			keyBytes = []byte(k)
+		}
+		return element{
+			window:    window.GlobalWindow{},
+			timestamp: mtime.MinTimestamp,
+			pane:      pane,
+			elmBytes:  []byte{v},
+			keyBytes:  keyBytes,
+		}
+	}
+	elm := func(v byte, paneIndex int64) element {
+		return keyedElm("", v, paneIndex)
+	}
+	tests := []struct {
+		name      string
+		triggered bool
+		adds      [][]element
+		want      [][]byte
+	}{
+		{
+			name: "untriggered",
+			adds: [][]element{{elm(1, 0)}, {elm(2, 1)}},
+			want: nil, // Not ready until the end of the global window.
+		}, {
+			name:      "triggered_firstPane",
+			triggered: true,
+			adds:      [][]element{{elm(1, 0), elm(2, 0)}},
+			want:      [][]byte{{1}, {2}},
+		}, {
+			name:      "triggered_samePane",
+			triggered: true,
+			adds:      [][]element{{elm(1, 0)}, {elm(2, 0)}},
+			want:      [][]byte{{1}, {2}},
+		}, {
+			name:      "triggered_newPaneReplaces",
+			triggered: true,
+			adds:      [][]element{{elm(1, 0), elm(2, 0)}, {elm(3, 1)}},
+			want:      [][]byte{{3}},
+		}, {
+			name:      "triggered_stalePaneDropped",
+			triggered: true,
+			adds:      [][]element{{elm(3, 1)}, {elm(1, 0)}},
+			want:      [][]byte{{3}},
+		}, {
+			name:      "triggered_keysFireIndependently",
+			triggered: true,
+			adds:      [][]element{{keyedElm("b", 1, 0), keyedElm("a", 2, 0)}, {keyedElm("a", 3, 1)}, {keyedElm("b", 4, 0)}},
+			want:      [][]byte{{3}, {1}, {4}},
+		}, {
+			name:      "triggered_keyStalePaneDropped",
+			triggered: true,
+			adds:      [][]element{{keyedElm("a", 3, 2), keyedElm("b", 1, 0)}, {keyedElm("a", 2, 1)}},
+			want:      [][]byte{{3}, {1}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			em := NewElementManager(Config{})
+			em.AddStage("dofn", []string{"input"}, nil, []LinkID{side})
+			if test.triggered {
+				em.StageTriggeredSideInputs("dofn", []LinkID{side})
+			}
+			ss := em.stages["dofn"]
+			for _, add := range test.adds {
+				if got, want := ss.AddPendingSide(add, side.Transform, side.Local), test.triggered; got != want {
+					t.Errorf("AddPendingSide(%v) = %v, want %v", add, got, want)
+				}
+			}
+			got := em.GetSideData("dofn", side.Transform, side.Local, mtime.MinTimestamp)[window.GlobalWindow{}]
+			if d := cmp.Diff(test.want, got); d != "" {
+				t.Errorf("GetSideData() diff (-want, +got):\n%v", d)
+			}
+			if got, want := ss.hasTriggeredSideData(side), test.triggered; got != want {
+				t.Errorf("hasTriggeredSideData() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStageState_AddPendingSide_expiredWindowsPruned(t *testing.T) {
+	side := LinkID{Transform: "dofn", Global: "sideCol", Local: "local"}
+	em := NewElementManager(Config{})
+	em.AddStage("dofn", []string{"input"}, nil, []LinkID{side})
+	em.StageTriggeredSideInputs("dofn", []LinkID{side})
+	ss := em.stages["dofn"]
+
+	early, late := window.IntervalWindow{Start: 0, End: 10}, window.IntervalWindow{Start: 10, End: 20}
+	pane := typex.NoFiringPane()
+	pane.Timing = typex.PaneEarly
+	ss.AddPendingSide([]element{
+		{window: early, timestamp: 5, pane: pane, elmBytes: []byte{1}, keyBytes: []byte("a")},
+		{window: late, timestamp: 15, pane: pane, elmBytes: []byte{2}, keyBytes: []byte("a")},
+	}, side.Transform, side.Local)
+
+	ss.updateUpstreamWatermark("input", 15)
+	ss.updateWatermarks(em)
+
+	link := LinkID{Transform: side.Transform, Local: side.Local}
+	if _, ok := ss.sidePanes[link][early]; ok {
+		t.Errorf("sidePanes still tracks expired window %v", early)
+	}
+	if _, ok := ss.sidePanes[link][late]; !ok {
+		t.Errorf("sidePanes dropped unexpired window %v", late)
+	}
+	if got := em.GetSideData("dofn", side.Transform, side.Local, 15); len(got) != 1 {
+		t.Errorf("GetSideData() = %v, want only the data for window %v", got, late)
+	}
+}
+
 func TestElementManager_OnWindowExpiration(t *testing.T) {
 	t.Run("createOnWindowExpirationBundles", func(t *testing.T) {
 		// Unlike the other tests above, we synthesize the input configuration,
@@ -684,3 +803,300 @@ func TestElementManager_OnWindowExpiration(t *testing.T) {
 		validateSideBundles(t, singleSet("\u0004key5")) // still exist..
 	})
 }
+
+func TestElementManager_Drain(t *testing.T) {
+	em := NewElementManager(Config{})
+	em.AddStage("impulse", nil, []string{"input"}, nil)
+	em.AddStage("dofn", []string{"input"}, nil, nil)
+
+	if em.Draining() {
+		t.Fatal("Draining() = true before Drain(), want false")
+	}
+	em.processTimeEvents.Schedule(mtime.Now().Add(time.Hour), "dofn")
+
+	em.Drain()
+	if !em.Draining() {
+		t.Error("Draining() = false after Drain(), want true")
+	}
+	if got, want := em.ProcessingTimeNow(), mtime.MaxTimestamp; got != want {
+		t.Errorf("ProcessingTimeNow() = %v, want %v", got, want)
+	}
+	if got, want := em.processTimeEvents.AdvanceTo(em.ProcessingTimeNow()), singleSet("dofn"); !cmp.Equal(got, want) {
+		t.Errorf("AdvanceTo(ProcessingTimeNow()) = %v, want %v", got, want)
+	}
+	if got, want := len(em.changedStages), 2; got != want {
+		t.Errorf("len(changedStages) = %v, want %v", got, want)
+	}
+	if got, want := rebaseProcessingTime(em.ProcessingTimeNow(), mtime.Now().Add(time.Hour)), mtime.MaxTimestamp; got != want {
+		t.Errorf("rebaseProcessingTime(drained now, an hour from now) = %v, want %v", got, want)
+	}
+}
+
+func TestElementManager_wakeUpAt(t *testing.T) {
+	em := NewElementManager(Config{EnableRTC: true})
+	em.refreshCond.L.Lock()
+	defer em.refreshCond.L.Unlock()
+
+	// Far future times are clamped rather than overflowing the timer.
+	now := mtime.Now()
+	em.wakeUpAt(mtime.MaxTimestamp)
+	if em.wakeAt < now || em.wakeAt > now.Add(maxWakeDelay+time.Second) {
+		t.Errorf("wakeUpAt(MaxTimestamp) scheduled for %v, want within %v of %v", em.wakeAt, maxWakeDelay, now)
+	}
+	timer := em.wakeTimer
+
+	// Earlier times reuse and reset the timer.
+	soon := mtime.Now().Add(time.Minute)
+	em.wakeUpAt(soon)
+	if got, want := em.wakeAt, soon; got > want {
+		t.Errorf("wakeUpAt(%v) scheduled for %v, want at or before %v", soon, got, want)
+	}
+	if em.wakeTimer != timer {
+		t.Error("wakeUpAt created a new timer, want the existing timer reset")
+	}
+
+	// Later times don't displace an earlier pending wake up.
+	em.wakeUpAt(soon.Add(time.Minute))
+	if got, want := em.wakeAt, soon; got > want {
+		t.Errorf("wakeUpAt(later) scheduled for %v, want at or before %v", got, want)
+	}
+	em.wakeTimer.Stop()
+}
+
+func TestStageState_KeyGroups(t *testing.T) {
+	ss := makeStageState("stage", []string{"input"}, []string{"output"}, nil)
+	ss.kind = &statefulStageKind{}
+	ss.pendingByKeys = map[string]*dataAndTimers{}
+	ss.inprogressKeys = set[string]{}
+	ss.keyGroups = 2
+
+	var newPending []element
+	for i := 0; i < 10; i++ {
+		newPending = append(newPending, element{
+			window:    window.GlobalWindow{},
+			timestamp: mtime.Time(i),
+			pane:      typex.NoFiringPane(),
+			elmBytes:  []byte{byte(i)},
+			keyBytes:  []byte(fmt.Sprintf("key%d", i)),
+			sequence:  i,
+		})
+	}
+	ss.AddPending(nil, newPending)
+
+	var i int
+	genBundID := func() string {
+		defer func() { i++ }()
+		return fmt.Sprintf("bundle%d", i)
+	}
+	groups := set[int]{}
+	var bundles []string
+	for {
+		bundID, ok, reschedule, _ := ss.startEventTimeBundle(mtime.MaxTimestamp, genBundID)
+		if !ok {
+			if reschedule {
+				t.Errorf("startEventTimeBundle() rescheduled with all key groups busy")
+			}
+			break
+		}
+		bundles = append(bundles, bundID)
+		bundleGroups := set[int]{}
+		for k := range ss.inprogressKeysByBundle[bundID] {
+			bundleGroups.insert(keyGroup(k, ss.keyGroups))
+		}
+		if len(bundleGroups) != 1 {
+			t.Errorf("bundle %v has keys from key groups %v, want a single group", bundID, bundleGroups)
+		}
+		for g := range bundleGroups {
+			if groups.present(g) {
+				t.Errorf("bundle %v uses key group %v, which is already in progress", bundID, g)
+			}
+			groups.insert(g)
+		}
+		if len(bundles) > ss.keyGroups {
+			t.Fatalf("started %v bundles, want at most %v", len(bundles), ss.keyGroups)
+		}
+	}
+	if got, want := len(bundles), 2; got != want {
+		t.Errorf("started %v concurrent bundles, want %v", got, want)
+	}
+	if got, want := ss.peakInProgress, 2; got != want {
+		t.Errorf("peakInProgress = %v, want %v", got, want)
+	}
+	if got, want := len(ss.pendingByKeys), 0; got != want {
+		t.Errorf("keys still pending = %v, want %v", got, want)
+	}
+}
+
+func TestStageState_KeyGroups_MaxBundleSize(t *testing.T) {
+	ss := makeStageState("stage", []string{"input"}, []string{"output"}, nil)
+	ss.kind = &statefulStageKind{}
+	ss.pendingByKeys = map[string]*dataAndTimers{}
+	ss.inprogressKeys = set[string]{}
+	ss.keyGroups = 2
+	ss.maxBundleSize = 1
+
+	var newPending []element
+	for i := 0; i < 10; i++ {
+		newPending = append(newPending, element{
+			window:    window.GlobalWindow{},
+			timestamp: mtime.Time(i),
+			pane:      typex.NoFiringPane(),
+			elmBytes:  []byte{byte(i)},
+			keyBytes:  []byte(fmt.Sprintf("key%d", i)),
+			sequence:  i,
+		})
+	}
+	ss.AddPending(nil, newPending)
+
+	var i int
+	genBundID := func() string {
+		defer func() { i++ }()
+		return fmt.Sprintf("bundle%d", i)
+	}
+	// The first bundle is cut short by the bundle size, but the other key group may still start a bundle.
+	first, ok, reschedule, _ := ss.startEventTimeBundle(mtime.MaxTimestamp, genBundID)
+	if !ok {
+		t.Fatal("startEventTimeBundle() didn't start a bundle")
+	}
+	if got, want := len(ss.inprogress[first].es), 1; got != want {
+		t.Errorf("bundle %v has %v elements, want %v", first, got, want)
+	}
+	if !reschedule {
+		t.Error("startEventTimeBundle() didn't reschedule with an idle key group remaining")
+	}
+	second, ok, reschedule, _ := ss.startEventTimeBundle(mtime.MaxTimestamp, genBundID)
+	if !ok {
+		t.Fatal("startEventTimeBundle() didn't start a bundle for the idle key group")
+	}
+	for k1 := range ss.inprogressKeysByBundle[first] {
+		for k2 := range ss.inprogressKeysByBundle[second] {
+			if keyGroup(k1, ss.keyGroups) == keyGroup(k2, ss.keyGroups) {
+				t.Errorf("bundles %v and %v use the same key group", first, second)
+			}
+		}
+	}
+	if reschedule {
+		t.Error("startEventTimeBundle() rescheduled with all key groups busy")
+	}
+	if got, want := ss.peakInProgress, 2; got != want {
+		t.Errorf("peakInProgress = %v, want %v", got, want)
+	}
+}
+
+func TestStageState_SplitBundle_Aggregate(t *testing.T) {
+	var i int
+	genBundID := func() string {
+		defer func() { i++ }()
+		return fmt.Sprintf("bundle%d", i)
+	}
+	em := NewElementManager(Config{})
+	em.nextBundID = genBundID
+	em.AddStage("agg", []string{"input"}, nil, nil)
+	em.StageAggregates("agg", WinStrat{Trigger: &TriggerDefault{}})
+	ss := em.stages["agg"]
+
+	var newPending []element
+	for _, key := range []string{"a", "b", "c"} {
+		newPending = append(newPending, element{
+			window:    window.GlobalWindow{},
+			timestamp: 1,
+			pane:      typex.NoFiringPane(),
+			elmBytes:  []byte{1},
+			keyBytes:  []byte(key),
+		})
+	}
+	em.addPending(ss.AddPending(em, newPending))
+
+	bundID, ok, _, _ := ss.startEventTimeBundle(mtime.MaxTimestamp, genBundID)
+	if !ok {
+		t.Fatal("startEventTimeBundle() didn't start a bundle")
+	}
+	fired := ss.inprogress[bundID].es
+	if got, want := len(fired), 3; got != want {
+		t.Fatalf("bundle %v has %v elements, want %v", bundID, got, want)
+	}
+	pending := em.livePending.Load()
+
+	rb := RunBundle{StageID: "agg", BundleID: bundID, Watermark: mtime.MaxTimestamp}
+	ss.splitBundle(em, rb, 1)
+
+	if got, want := len(ss.inprogress[bundID].es), 1; got != want {
+		t.Errorf("primary bundle has %v elements, want %v", got, want)
+	}
+	if got, want := len(ss.bundlesToInject), 1; got != want {
+		t.Fatalf("bundlesToInject = %v, want %v", got, want)
+	}
+	resID := ss.bundlesToInject[0].BundleID
+	if !em.inprogressBundles.present(resID) {
+		t.Errorf("residual bundle %v isn't in progress", resID)
+	}
+	res := ss.inprogress[resID].es
+	if got, want := len(res), 2; got != want {
+		t.Fatalf("residual bundle has %v elements, want %v", got, want)
+	}
+	for j, e := range res {
+		if got, want := e.pane, fired[j+1].pane; got != want {
+			t.Errorf("residual %d pane = %+v, want %+v", j, got, want)
+		}
+	}
+	// The residuals have already fired, so they must not be pending again.
+	if got, want := len(ss.pendingByKeys), 0; got != want {
+		t.Errorf("keys pending after split = %v, want %v", got, want)
+	}
+	if got, want := em.livePending.Load(), pending; got != want {
+		t.Errorf("pending elements after split = %v, want %v", got, want)
+	}
+}
+
+func TestStageState_SplitBundle_StatefulTimers(t *testing.T) {
+	var i int
+	genBundID := func() string {
+		defer func() { i++ }()
+		return fmt.Sprintf("bundle%d", i)
+	}
+	em := NewElementManager(Config{})
+	em.AddStage("stage", []string{"input"}, nil, nil)
+	em.StageStateful("stage", nil)
+	ss := em.stages["stage"]
+
+	var timers []element
+	for _, key := range []string{"a", "b"} {
+		timers = append(timers, element{
+			window:        window.GlobalWindow{},
+			timestamp:     10,
+			holdTimestamp: 10,
+			pane:          typex.NoFiringPane(),
+			transform:     "transform",
+			family:        "family",
+			keyBytes:      []byte(key),
+		})
+	}
+	em.addPending(ss.AddPending(em, timers))
+
+	// runBundle starts a bundle, returning the elements after the first as residuals,
+	// and completes it.
+	runBundle := func(wantLen int) {
+		t.Helper()
+		bundID, ok, _, _ := ss.startEventTimeBundle(mtime.MaxTimestamp, genBundID)
+		if !ok {
+			t.Fatal("startEventTimeBundle() didn't start a bundle")
+		}
+		if got := len(ss.inprogress[bundID].es); got != wantLen {
+			t.Fatalf("bundle %v has %v elements, want %v", bundID, got, wantLen)
+		}
+		rb := RunBundle{StageID: "stage", BundleID: bundID, Watermark: mtime.MaxTimestamp}
+		em.ReturnResiduals(rb, 1, PColInfo{}, Residuals{})
+		em.PersistBundle(rb, nil, TentativeData{}, PColInfo{}, Residuals{})
+	}
+	runBundle(2)
+	if got, want := ss.watermarkHolds.counts, (map[mtime.Time]int{10: 1}); !cmp.Equal(got, want) {
+		t.Errorf("holds after split = %v, want %v", got, want)
+	}
+	runBundle(1)
+	if got := ss.watermarkHolds.counts; len(got) != 0 {
+		t.Errorf("holds after all timers fired = %v, want none", got)
+	}
+	if got, want := em.livePending.Load(), int64(0); got != want {
+		t.Errorf("pending elements after all timers fired = %v, want %v", got, want)
+	}
+}
@@ -234,6 +234,8 @@ func TestTriggers(t *testing.T) {
 		{pipeline: primitives.TriggerAfterEach},
 		{pipeline: primitives.TriggerAfterEndOfWindow},
 		{pipeline: primitives.TriggerRepeat},
+		{pipeline: primitives.TriggerAfterProcessingTimeRepeat},
+		{pipeline: primitives.TriggerAfterSynchronizedProcessingTime},
 	}
 
 	configs := []struct {
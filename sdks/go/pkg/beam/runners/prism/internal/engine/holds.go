@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
 )
 
 // mtimeHeap is a minHeap to find the earliest processing time event.
@@ -108,3 +109,51 @@ func (ht *holdTracker) Min() mtime.Time {
 	}
 	return minWatermarkHold
 }
+
+// Pane holds
+//
+// Aggregations hold back their output watermark until their panes have been output.
+// Each key and window with pending elements has a hold at the timestamp the pane
+// will be output at, determined by the stage's OutputTime. Since the hold depends only
+// on the pending elements, it's recomputed whenever elements are removed for a key.
+
+// addPaneHoldLocked includes the element in the hold for its key and window's pane.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) addPaneHoldLocked(key string, e element) {
+	holds, ok := ss.paneHolds[key]
+	if !ok {
+		holds = map[typex.Window]mtime.Time{}
+		ss.paneHolds[key] = holds
+	}
+	hold, ok := holds[e.window]
+	if !ok {
+		hold = mtime.MaxTimestamp
+	}
+	newHold := ss.strat.paneHold(e.window, hold, e.timestamp)
+	if ok {
+		if newHold == hold {
+			return
+		}
+		ss.paneHoldTimes.Drop(hold, 1)
+	}
+	holds[e.window] = newHold
+	ss.paneHoldTimes.Add(newHold, 1)
+}
+
+// resetPaneHoldsLocked recomputes the pane holds for the key from its pending elements.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) resetPaneHoldsLocked(key string) {
+	for _, hold := range ss.paneHolds[key] {
+		ss.paneHoldTimes.Drop(hold, 1)
+	}
+	delete(ss.paneHolds, key)
+	dnt, ok := ss.pendingByKeys[key]
+	if !ok {
+		return
+	}
+	for _, e := range dnt.elements {
+		ss.addPaneHoldLocked(key, e)
+	}
+}
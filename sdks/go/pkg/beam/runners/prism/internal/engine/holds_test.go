@@ -19,6 +19,8 @@ import (
 	"testing"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
 )
 
 func TestHoldTracker(t *testing.T) {
@@ -113,3 +115,71 @@ func TestHoldTracker(t *testing.T) {
 		})
 	}
 }
+
+func TestStageState_PaneHolds(t *testing.T) {
+	early := window.IntervalWindow{Start: 0, End: 100}
+	later := window.IntervalWindow{Start: 100, End: 200}
+	elm := func(key string, w typex.Window, ts mtime.Time) element {
+		return element{
+			window:    w,
+			timestamp: ts,
+			pane:      typex.NoFiringPane(),
+			elmBytes:  []byte{1},
+			keyBytes:  []byte(key),
+		}
+	}
+	tests := []struct {
+		outputTime OutputTime
+		// The minimum hold after adding elements, after firing key "a",
+		// and after a late element for key "a".
+		wantAdded, wantFired, wantLate mtime.Time
+	}{
+		{
+			outputTime: OutputTimeEndOfWindow,
+			wantAdded:  early.MaxTimestamp(),
+			wantFired:  later.MaxTimestamp(),
+			wantLate:   early.MaxTimestamp(),
+		}, {
+			outputTime: OutputTimeEarliestInPane,
+			wantAdded:  10,
+			wantFired:  150,
+			wantLate:   early.MaxTimestamp(),
+		}, {
+			outputTime: OutputTimeLatestInPane,
+			wantAdded:  30,
+			wantFired:  150,
+			wantLate:   early.MaxTimestamp(),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.outputTime.String(), func(t *testing.T) {
+			em := NewElementManager(Config{})
+			em.AddStage("agg", []string{"input"}, nil, nil)
+			em.StageAggregates("agg", WinStrat{OutputTime: test.outputTime, Trigger: &TriggerDefault{}})
+			ss := em.stages["agg"]
+			ss.mu.Lock()
+			defer ss.mu.Unlock()
+
+			ss.kind.addPending(ss, em, []element{
+				elm("a", early, 30),
+				elm("a", early, 10),
+				elm("b", later, 150),
+			})
+			if got, want := ss.minPendingTimestampLocked(), test.wantAdded; got != want {
+				t.Errorf("after adding elements, minPendingTimestamp() = %v, want %v", got, want)
+			}
+
+			ss.popTriggeredElements([]byte("a"), early)
+			if got, want := ss.minPendingTimestampLocked(), test.wantFired; got != want {
+				t.Errorf("after firing, minPendingTimestamp() = %v, want %v", got, want)
+			}
+
+			// Late elements don't hold the watermark before the end of their window.
+			ss.output = 50
+			ss.kind.addPending(ss, em, []element{elm("a", early, 20)})
+			if got, want := ss.minPendingTimestampLocked(), test.wantLate; got != want {
+				t.Errorf("after late element, minPendingTimestamp() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+)
+
+// Merging Windows
+//
+// Aggregations with merging windowing strategies merge each key's windows as
+// elements arrive, before triggers are evaluated. This way triggers like AfterCount
+// observe all the elements of the merged window, rather than those of the window
+// the element was originally assigned to.
+//
+// Which windows merge is decided by the strategy's WindowFn, so the engine delegates
+// merging to the stage's WindowMerger, which the runner implements with the SDK's
+// MergeWindows transform. This supports arbitrary merging WindowFns, such as custom
+// WindowFns from cross language transforms, not only session windows.
+// The new windows of each batch of pending elements are merged with the active
+// windows of their keys in a single request.
+//
+// When windows merge, the pending elements of the merged away windows are moved to
+// the new window, and their trigger states and panes are combined with mergeStateData.
+
+// WindowMerger merges the given windows of each key. For each key, the windows
+// that merged are returned, mapped to the windows they consumed. Keys and windows
+// that didn't merge may be omitted.
+type WindowMerger func(windows map[string][]typex.Window) (map[string]map[typex.Window][]typex.Window, error)
+
+// mergeWindowsLocked merges the windows of the new elements with the active windows
+// of their keys, and returns the windows the new elements' windows merged into,
+// by key. The state and pending elements of merged away windows are moved to the
+// windows they merged into.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) mergeWindowsLocked(newPending []element) map[string]map[typex.Window]typex.Window {
+	if ss.activeWindows == nil {
+		ss.indexActiveWindowsLocked()
+	}
+	toMerge := map[string]set[typex.Window]{}
+	for _, e := range newPending {
+		key := string(e.keyBytes)
+		wins, ok := toMerge[key]
+		if !ok {
+			wins = set[typex.Window]{}
+			for w := range ss.activeWindows[key] {
+				wins.insert(w)
+			}
+			toMerge[key] = wins
+		}
+		wins.insert(e.window)
+	}
+	req := make(map[string][]typex.Window, len(toMerge))
+	for key, wins := range toMerge {
+		for w := range wins {
+			req[key] = append(req[key], w)
+		}
+	}
+	merges, err := ss.strat.MergeWindows(req)
+	if err != nil {
+		panic(fmt.Sprintf("stage %v: unable to merge windows: %v", ss.ID, err))
+	}
+
+	mergedInto := map[string]map[typex.Window]typex.Window{}
+	for key, wins := range toMerge {
+		active, ok := ss.activeWindows[key]
+		if !ok {
+			active = set[typex.Window]{}
+			ss.activeWindows[key] = active
+		}
+		into := map[typex.Window]typex.Window{}
+		for merged, consumed := range merges[key] {
+			ss.moveMergedStateLocked(key, merged, consumed)
+			for _, w := range consumed {
+				active.remove(w)
+				into[w] = merged
+			}
+			active.insert(merged)
+		}
+		for w := range wins {
+			if _, ok := into[w]; !ok {
+				active.insert(w)
+			}
+		}
+		if len(into) == 0 {
+			continue
+		}
+		mergedInto[key] = into
+		if dnt, ok := ss.pendingByKeys[key]; ok {
+			for i, e := range dnt.elements {
+				if w, ok := into[e.window]; ok {
+					// Only the window changes, so the heap ordering is unaffected.
+					dnt.elements[i].window = w
+				}
+			}
+		}
+		ss.resetPaneHoldsLocked(key)
+	}
+	return mergedInto
+}
+
+// moveMergedStateLocked combines the aggregation state of the key in the consumed
+// windows into the state for the merged window.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) moveMergedStateLocked(key string, merged typex.Window, consumed []typex.Window) {
+	aggState := ss.state[LinkID{}]
+	if aggState == nil {
+		aggState = map[typex.Window]map[string]StateData{}
+		ss.state[LinkID{}] = aggState
+	}
+	var mergedState StateData
+	if wv, ok := aggState[merged]; ok {
+		mergedState = wv[key]
+	}
+	for _, w := range consumed {
+		if w == merged {
+			continue
+		}
+		if wv, ok := aggState[w]; ok {
+			if st, ok := wv[key]; ok {
+				mergeStateData(ss.strat.Trigger, &mergedState, st)
+				delete(wv, key)
+				if len(wv) == 0 {
+					delete(aggState, w)
+				}
+			}
+		}
+	}
+	wv, ok := aggState[merged]
+	if !ok {
+		wv = map[string]StateData{}
+		aggState[merged] = wv
+	}
+	wv[key] = mergedState
+}
+
+// indexActiveWindowsLocked builds the index of windows with aggregation state for each key.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) indexActiveWindowsLocked() {
+	ss.activeWindows = map[string]set[typex.Window]{}
+	for w, keys := range ss.state[LinkID{}] {
+		for k := range keys {
+			active, ok := ss.activeWindows[k]
+			if !ok {
+				active = set[typex.Window]{}
+				ss.activeWindows[k] = active
+			}
+			active.insert(w)
+		}
+	}
+}
+
+// mergeStateData combines the trigger state and pane of a merged away window
+// into the state for the merged window.
+//
+// A trigger is finished in the merged window if it finished in any of the
+// merged windows. Element counts are summed, processing time delays use the
+// earliest firing time, and the end of window is only reached if it was
+// reached by all merged windows, since the merged window ends no earlier.
+// The pane with the most firings is retained.
+func mergeStateData(t Trigger, into *StateData, from StateData) {
+	for _, tr := range allTriggers(t) {
+		fts, ok := from.Trigger[tr]
+		if !ok {
+			continue
+		}
+		its, ok := into.Trigger[tr]
+		if !ok {
+			into.getTriggerState(tr)
+			into.setTriggerState(tr, fts)
+			continue
+		}
+		into.setTriggerState(tr, triggerState{
+			finished: its.finished || fts.finished,
+			extra:    mergeTriggerExtra(its.extra, fts.extra),
+		})
+	}
+	if from.Pane.Index > into.Pane.Index {
+		into.Pane = from.Pane
+	}
+}
+
+// mergeTriggerExtra combines the extra state of a trigger from two windows.
+func mergeTriggerExtra(a, b any) any {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	switch av := a.(type) {
+	case int:
+		return av + b.(int)
+	case bool:
+		return av && b.(bool)
+	case afterProcessingTimeState:
+		bv := b.(afterProcessingTimeState)
+		return afterProcessingTimeState{
+			firingTime: mtime.Min(av.firingTime, bv.firingTime),
+			reached:    av.reached || bv.reached,
+		}
+	default:
+		panic(fmt.Sprintf("unable to merge trigger state of type %T", a))
+	}
+}
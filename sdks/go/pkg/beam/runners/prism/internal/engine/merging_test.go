@@ -0,0 +1,245 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/google/go-cmp/cmp"
+)
+
+// sessionMerger merges overlapping or adjacent interval windows, as session windows do.
+func sessionMerger(windows map[string][]typex.Window) (map[string]map[typex.Window][]typex.Window, error) {
+	merges := map[string]map[typex.Window][]typex.Window{}
+	for key, ws := range windows {
+		ordered := make([]window.IntervalWindow, 0, len(ws))
+		for _, w := range ws {
+			ordered = append(ordered, w.(window.IntervalWindow))
+		}
+		sort.Slice(ordered, func(i, j int) bool { return ordered[i].Start < ordered[j].Start })
+		for i := 0; i < len(ordered); {
+			cur := ordered[i]
+			consumed := []typex.Window{ordered[i]}
+			j := i + 1
+			for ; j < len(ordered) && ordered[j].Start <= cur.End; j++ {
+				cur.End = mtime.Max(cur.End, ordered[j].End)
+				consumed = append(consumed, ordered[j])
+			}
+			if len(consumed) > 1 {
+				if merges[key] == nil {
+					merges[key] = map[typex.Window][]typex.Window{}
+				}
+				merges[key][cur] = consumed
+			}
+			i = j
+		}
+	}
+	return merges, nil
+}
+
+// spanMerger merges all the windows of a key into the window spanning them,
+// regardless of overlap, to stand in for an arbitrary merging WindowFn.
+func spanMerger(windows map[string][]typex.Window) (map[string]map[typex.Window][]typex.Window, error) {
+	merges := map[string]map[typex.Window][]typex.Window{}
+	for key, ws := range windows {
+		if len(ws) < 2 {
+			continue
+		}
+		span := ws[0].(window.IntervalWindow)
+		for _, w := range ws[1:] {
+			iw := w.(window.IntervalWindow)
+			span = window.IntervalWindow{Start: mtime.Min(span.Start, iw.Start), End: mtime.Max(span.End, iw.End)}
+		}
+		merges[key] = map[typex.Window][]typex.Window{span: ws}
+	}
+	return merges, nil
+}
+
+func TestStageState_MergeWindows(t *testing.T) {
+	iw := func(start, end mtime.Time) window.IntervalWindow {
+		return window.IntervalWindow{Start: start, End: end}
+	}
+	elm := func(key string, w window.IntervalWindow) element {
+		return element{
+			window:    w,
+			timestamp: w.Start,
+			pane:      typex.NoFiringPane(),
+			elmBytes:  []byte{1},
+			keyBytes:  []byte(key),
+		}
+	}
+	tests := []struct {
+		name      string
+		merger    WindowMerger
+		adds      []element
+		wantFired []window.IntervalWindow // windows of the fired elements.
+		wantState map[string][]window.IntervalWindow
+	}{
+		{
+			name:      "disjoint",
+			adds:      []element{elm("a", iw(0, 10)), elm("a", iw(20, 30))},
+			wantState: map[string][]window.IntervalWindow{"a": {iw(0, 10), iw(20, 30)}},
+		}, {
+			name:      "overlapping",
+			adds:      []element{elm("a", iw(0, 10)), elm("a", iw(5, 15))},
+			wantFired: []window.IntervalWindow{iw(0, 15), iw(0, 15)},
+			wantState: map[string][]window.IntervalWindow{"a": {iw(0, 15)}},
+		}, {
+			name:      "adjacent",
+			adds:      []element{elm("a", iw(0, 10)), elm("a", iw(10, 20))},
+			wantFired: []window.IntervalWindow{iw(0, 20), iw(0, 20)},
+			wantState: map[string][]window.IntervalWindow{"a": {iw(0, 20)}},
+		}, {
+			name:      "bridging",
+			adds:      []element{elm("a", iw(0, 10)), elm("a", iw(20, 30)), elm("a", iw(8, 22))},
+			wantFired: []window.IntervalWindow{iw(0, 30), iw(0, 30), iw(0, 30)},
+			wantState: map[string][]window.IntervalWindow{"a": {iw(0, 30)}},
+		}, {
+			name:      "contained",
+			adds:      []element{elm("a", iw(0, 10)), elm("a", iw(2, 4))},
+			wantFired: []window.IntervalWindow{iw(0, 10), iw(0, 10)},
+			wantState: map[string][]window.IntervalWindow{"a": {iw(0, 10)}},
+		}, {
+			name: "perKey",
+			adds: []element{elm("a", iw(0, 10)), elm("b", iw(5, 15))},
+			wantState: map[string][]window.IntervalWindow{
+				"a": {iw(0, 10)},
+				"b": {iw(5, 15)},
+			},
+		}, {
+			name:      "nonSession",
+			merger:    spanMerger,
+			adds:      []element{elm("a", iw(0, 10)), elm("a", iw(50, 60)), elm("b", iw(20, 30))},
+			wantFired: []window.IntervalWindow{iw(0, 60), iw(0, 60)},
+			wantState: map[string][]window.IntervalWindow{
+				"a": {iw(0, 60)},
+				"b": {iw(20, 30)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			em := NewElementManager(Config{})
+			var bundles int
+			em.nextBundID = func() string {
+				bundles++
+				return fmt.Sprint(bundles)
+			}
+			em.AddStage("agg", []string{"input"}, nil, nil)
+			merger := test.merger
+			if merger == nil {
+				merger = sessionMerger
+			}
+			em.StageAggregates("agg", WinStrat{
+				MergeWindows: merger,
+				Trigger:      &TriggerElementCount{ElementCount: 2},
+			})
+			ss := em.stages["agg"]
+			ss.mu.Lock()
+			defer ss.mu.Unlock()
+			for _, e := range test.adds {
+				ss.kind.addPending(ss, em, []element{e})
+			}
+
+			var fired []window.IntervalWindow
+			for _, es := range ss.inprogress {
+				for _, e := range es.es {
+					fired = append(fired, e.window.(window.IntervalWindow))
+				}
+			}
+			if d := cmp.Diff(test.wantFired, fired); d != "" {
+				t.Errorf("fired windows diff (-want, +got):\n%v", d)
+			}
+
+			for key, wins := range test.wantState {
+				for _, w := range wins {
+					if _, ok := ss.state[LinkID{}][w][key]; !ok {
+						t.Errorf("missing state for key %q in window %v", key, w)
+					}
+				}
+				if got, want := len(ss.activeWindows[key]), len(wins); got != want {
+					t.Errorf("active windows for key %q = %v, want %v", key, ss.activeWindows[key], wins)
+				}
+			}
+			var stateCount int
+			for _, keys := range ss.state[LinkID{}] {
+				stateCount += len(keys)
+			}
+			var wantCount int
+			for _, wins := range test.wantState {
+				wantCount += len(wins)
+			}
+			if stateCount != wantCount {
+				t.Errorf("state has %v key windows, want %v: %v", stateCount, wantCount, ss.state[LinkID{}])
+			}
+		})
+	}
+}
+
+func TestMergeStateData(t *testing.T) {
+	count := &TriggerElementCount{ElementCount: 3}
+	eow := &TriggerAfterEndOfWindow{Early: count}
+
+	into := StateData{Pane: typex.PaneInfo{Index: 0}}
+	eow.onElement(triggerInput{newElementCount: 1}, &into)
+	into.setTriggerState(eow, triggerState{extra: true})
+	from := StateData{Pane: typex.PaneInfo{Index: 2}}
+	eow.onElement(triggerInput{newElementCount: 1}, &from)
+	from.setTriggerState(eow, triggerState{finished: true, extra: false})
+
+	mergeStateData(eow, &into, from)
+	if got, want := into.getTriggerState(count).extra, 2; got != want {
+		t.Errorf("merged element count = %v, want %v", got, want)
+	}
+	ts := into.getTriggerState(eow)
+	if got, want := ts.extra, false; got != want {
+		t.Errorf("merged end of window reached = %v, want %v", got, want)
+	}
+	if !ts.finished {
+		t.Errorf("merged trigger finished = false, want true")
+	}
+	if got, want := into.Pane.Index, int64(2); got != want {
+		t.Errorf("merged pane index = %v, want %v", got, want)
+	}
+}
+
+func TestMergeTriggerExtra(t *testing.T) {
+	tests := []struct {
+		a, b, want any
+	}{
+		{nil, nil, nil},
+		{nil, 2, 2},
+		{3, nil, 3},
+		{3, 4, 7},
+		{true, true, true},
+		{true, false, false},
+		{
+			afterProcessingTimeState{firingTime: 10},
+			afterProcessingTimeState{firingTime: 5, reached: true},
+			afterProcessingTimeState{firingTime: 5, reached: true},
+		},
+	}
+	for _, test := range tests {
+		if got := mergeTriggerExtra(test.a, test.b); got != test.want {
+			t.Errorf("mergeTriggerExtra(%v, %v) = %v, want %v", test.a, test.b, got, test.want)
+		}
+	}
+}
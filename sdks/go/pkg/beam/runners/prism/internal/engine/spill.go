@@ -0,0 +1,588 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Spilling
+//
+// When a memory budget is configured, the ElementManager keeps an estimate of the
+// bytes it holds for pending elements and user state. Once that estimate exceeds
+// the budget, the largest stages have the payloads of their pending elements, and
+// the user state of keys not in an active bundle, written to a local spill file.
+// Element payloads shared by several consuming stages are counted and spilled once.
+//
+// Spilled elements keep all their metadata in memory, so watermarks, holds and
+// bundle scheduling are unaffected. Only the encoded element bytes are paged back
+// in, when the bundle's input is produced by InputForBundle or DataAndTimerInputForBundle.
+// Spilled state is paged back in for a bundle's keys by StateForBundle.
+//
+// The spill file is written as a sequence of append only segments. Once a segment
+// reaches spillSegmentBytes, writes move to a new segment. Whenever the memory budget
+// is checked, segments no longer referenced by any stage are removed, so disk usage
+// follows the spilled data that's still needed. All segments are removed when the
+// ElementManager is closed.
+
+// minSpillBytes is the smallest element payload worth spilling, since smaller
+// payloads take more memory to reference than to keep.
+const minSpillBytes = 64
+
+// spillSegmentBytes is the size at which the spill file moves writes to a new segment.
+const spillSegmentBytes = 64 << 20
+
+// spillFile holds spilled element and state bytes in append only segments.
+type spillFile struct {
+	dir          string
+	segmentBytes int64 // Size at which to start a new segment. 0 means spillSegmentBytes.
+
+	mu       sync.RWMutex // Held for reading while reading from segments.
+	active   *spillSegment
+	segments set[*spillSegment]
+}
+
+// spillSegment is a single file of the spill file.
+type spillSegment struct {
+	f    *os.File // nil once the segment is removed.
+	size int64
+}
+
+// spillRef refers to a spilled range of bytes.
+type spillRef struct {
+	f      *spillFile
+	seg    *spillSegment
+	offset int64
+	length int
+}
+
+// write appends each buffer to the spill file, returning references to them in order.
+func (sf *spillFile) write(bufs [][]byte) ([]*spillRef, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	segmentBytes := sf.segmentBytes
+	if segmentBytes <= 0 {
+		segmentBytes = spillSegmentBytes
+	}
+	if sf.active == nil || sf.active.size >= segmentBytes {
+		f, err := os.CreateTemp(sf.dir, "prism-spill-*")
+		if err != nil {
+			return nil, fmt.Errorf("unable to create spill file: %w", err)
+		}
+		sf.active = &spillSegment{f: f}
+		if sf.segments == nil {
+			sf.segments = set[*spillSegment]{}
+		}
+		sf.segments.insert(sf.active)
+	}
+	seg := sf.active
+	var total int
+	for _, b := range bufs {
+		total += len(b)
+	}
+	all := make([]byte, 0, total)
+	refs := make([]*spillRef, 0, len(bufs))
+	for _, b := range bufs {
+		refs = append(refs, &spillRef{f: sf, seg: seg, offset: seg.size + int64(len(all)), length: len(b)})
+		all = append(all, b...)
+	}
+	if _, err := seg.f.WriteAt(all, seg.size); err != nil {
+		return nil, fmt.Errorf("unable to write to spill file %v: %w", seg.f.Name(), err)
+	}
+	seg.size += int64(len(all))
+	return refs, nil
+}
+
+// read returns the referenced bytes from the spill file.
+func (r *spillRef) read() ([]byte, error) {
+	// Hold the lock while reading, so the segment isn't closed out from under us.
+	r.f.mu.RLock()
+	defer r.f.mu.RUnlock()
+	if r.seg.f == nil {
+		return nil, fmt.Errorf("spill file closed")
+	}
+	b := make([]byte, r.length)
+	if _, err := r.seg.f.ReadAt(b, r.offset); err != nil {
+		return nil, fmt.Errorf("unable to read from spill file %v: %w", r.seg.f.Name(), err)
+	}
+	return b, nil
+}
+
+// mustRead returns the referenced bytes, and panics if they can't be read, as the
+// data is otherwise lost.
+func (r *spillRef) mustRead() []byte {
+	b, err := r.read()
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// compact removes the segments not in live, returning the bytes reclaimed.
+func (sf *spillFile) compact(live set[*spillSegment]) (int64, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	var reclaimed int64
+	var err error
+	for seg := range sf.segments {
+		if live.present(seg) {
+			continue
+		}
+		reclaimed += seg.size
+		if rerr := sf.removeLocked(seg); err == nil {
+			err = rerr
+		}
+	}
+	return reclaimed, err
+}
+
+// close closes and removes the spill file segments.
+func (sf *spillFile) close() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	var err error
+	for seg := range sf.segments {
+		if rerr := sf.removeLocked(seg); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// removeLocked closes and removes the segment.
+//
+// Must be called while holding sf.mu.
+func (sf *spillFile) removeLocked(seg *spillSegment) error {
+	sf.segments.remove(seg)
+	if sf.active == seg {
+		sf.active = nil
+	}
+	name := seg.f.Name()
+	err := seg.f.Close()
+	seg.f = nil
+	if rerr := os.Remove(name); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// payloadKey identifies an element payload buffer, which may be shared by the
+// pending elements of several consuming stages.
+type payloadKey struct {
+	p *byte
+	n int
+}
+
+// keyOf returns the key for the non-empty payload.
+func keyOf(b []byte) payloadKey {
+	return payloadKey{p: &b[0], n: len(b)}
+}
+
+// data returns the element's encoded bytes, reading them back from the spill file if necessary.
+func (e *element) data() []byte {
+	if e.spilled != nil {
+		return e.spilled.mustRead()
+	}
+	return e.elmBytes
+}
+
+// Close releases resources held by the ElementManager, such as spilled data.
+// It must only be called once no further bundles will be executed.
+func (em *ElementManager) Close() error {
+	return em.spill.close()
+}
+
+// noteBytes records bytes that were added to the ElementManager, and spills if
+// enough have been added that the memory budget may be exceeded.
+func (em *ElementManager) noteBytes(n int) {
+	budget := em.config.MemoryBudget
+	if budget <= 0 || n == 0 {
+		return
+	}
+	// Only check the budget periodically, since it requires scanning all stages.
+	if em.addedBytes.Add(int64(n)) < max(budget/8, 1) {
+		return
+	}
+	em.addedBytes.Store(0)
+	em.enforceMemoryBudget()
+}
+
+// enforceMemoryBudget removes spill file segments that are no longer referenced,
+// and spills data from the stages holding the most bytes, until the resident bytes
+// are at most half of the memory budget.
+func (em *ElementManager) enforceMemoryBudget() {
+	em.spillMu.Lock()
+	defer em.spillMu.Unlock()
+
+	budget := em.config.MemoryBudget
+	var total int64
+	resident := map[string]int64{}
+	seen := map[payloadKey]bool{}
+	live := set[*spillSegment]{}
+	for id, ss := range em.stages {
+		ss.mu.Lock()
+		n := ss.residentBytesLocked(seen)
+		ss.liveSegmentsLocked(live)
+		ss.mu.Unlock()
+		resident[id] = n
+		total += n
+	}
+	// New references are only made while spilling, so it's safe to compact here.
+	if reclaimed, err := em.spill.compact(live); err != nil {
+		slog.Warn("unable to remove unused spill file segments", "error", err)
+	} else if reclaimed > 0 {
+		slog.Debug("removed unused spill file segments", "bytes", reclaimed)
+	}
+	if total <= budget {
+		return
+	}
+	target := budget / 2
+	written := map[payloadKey]*spillRef{}
+	for total > target && len(resident) > 0 {
+		var largest string
+		for id, n := range resident {
+			if largest == "" || n > resident[largest] {
+				largest = id
+			}
+		}
+		delete(resident, largest)
+		ss := em.stages[largest]
+		ss.mu.Lock()
+		freed, err := ss.spillLocked(em.spill, written)
+		ss.mu.Unlock()
+		if err != nil {
+			slog.Error("unable to spill stage data, continuing in memory", "stage", largest, "error", err)
+			return
+		}
+		if freed > 0 {
+			slog.Debug("spilled stage data", "stage", largest, "bytes", freed)
+		}
+		total -= freed
+		// Other stages sharing the spilled payloads refer to the spilled copy too, so the bytes are released.
+		for id, other := range em.stages {
+			if id == largest {
+				continue
+			}
+			other.mu.Lock()
+			other.useSpilledLocked(written)
+			other.mu.Unlock()
+		}
+	}
+}
+
+// residentBytesLocked estimates the in memory bytes of pending element payloads
+// and user state for the stage. Payloads already in seen aren't counted, and
+// payloads counted are added to seen.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) residentBytesLocked(seen map[payloadKey]bool) int64 {
+	var n int64
+	count := func(e element) {
+		if len(e.elmBytes) == 0 {
+			return
+		}
+		k := keyOf(e.elmBytes)
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		n += int64(len(e.elmBytes))
+	}
+	for _, e := range ss.pending {
+		count(e)
+	}
+	for _, dnt := range ss.pendingByKeys {
+		for _, e := range dnt.elements {
+			count(e)
+		}
+	}
+	for link, wins := range ss.state {
+		if link == (LinkID{}) {
+			continue
+		}
+		for _, keys := range wins {
+			for _, sd := range keys {
+				n += int64(sd.userBytes())
+			}
+		}
+	}
+	return n
+}
+
+// liveSegmentsLocked adds the spill file segments referenced by the stage to live.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) liveSegmentsLocked(live set[*spillSegment]) {
+	for _, e := range ss.pending {
+		if e.spilled != nil {
+			live.insert(e.spilled.seg)
+		}
+	}
+	for _, dnt := range ss.pendingByKeys {
+		for _, e := range dnt.elements {
+			if e.spilled != nil {
+				live.insert(e.spilled.seg)
+			}
+		}
+	}
+	for _, es := range ss.inprogress {
+		for _, e := range es.es {
+			if e.spilled != nil {
+				live.insert(e.spilled.seg)
+			}
+		}
+	}
+	for _, swins := range ss.spilledState {
+		for _, skeys := range swins {
+			for _, ref := range skeys {
+				live.insert(ref.seg)
+			}
+		}
+	}
+}
+
+// spillLocked writes the stage's pending element payloads, and the user state for
+// keys that aren't in progress to the spill file, returning the bytes freed.
+// Payloads already in written refer to their existing spilled copy, and newly
+// spilled payloads are added to written.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) spillLocked(sf *spillFile, written map[payloadKey]*spillRef) (int64, error) {
+	var freed int64
+	spillElements := func(es elementHeap) error {
+		var idxs []int
+		var bufs [][]byte
+		for i, e := range es {
+			if len(e.elmBytes) < minSpillBytes {
+				continue
+			}
+			if ref, ok := written[keyOf(e.elmBytes)]; ok {
+				es[i].elmBytes = nil
+				es[i].spilled = ref
+				continue
+			}
+			idxs = append(idxs, i)
+			bufs = append(bufs, e.elmBytes)
+		}
+		if len(bufs) == 0 {
+			return nil
+		}
+		refs, err := sf.write(bufs)
+		if err != nil {
+			return err
+		}
+		for j, i := range idxs {
+			if k := keyOf(es[i].elmBytes); written[k] == nil {
+				written[k] = refs[j]
+				freed += int64(len(es[i].elmBytes))
+			}
+			es[i].elmBytes = nil
+			es[i].spilled = refs[j]
+		}
+		return nil
+	}
+	if err := spillElements(ss.pending); err != nil {
+		return freed, err
+	}
+	for _, dnt := range ss.pendingByKeys {
+		if err := spillElements(dnt.elements); err != nil {
+			return freed, err
+		}
+	}
+
+	for link, wins := range ss.state {
+		// Trigger and pane state is kept in memory, as it's needed to schedule bundles.
+		if link == (LinkID{}) {
+			continue
+		}
+		for w, keys := range wins {
+			var ks []string
+			var bufs [][]byte
+			for k, sd := range keys {
+				if ss.inprogressKeys.present(k) || sd.Trigger != nil {
+					continue
+				}
+				ks = append(ks, k)
+				bufs = append(bufs, encodeUserState(sd))
+			}
+			if len(bufs) == 0 {
+				continue
+			}
+			refs, err := sf.write(bufs)
+			if err != nil {
+				return freed, err
+			}
+			if ss.spilledState == nil {
+				ss.spilledState = map[LinkID]map[typex.Window]map[string]*spillRef{}
+			}
+			swins, ok := ss.spilledState[link]
+			if !ok {
+				swins = map[typex.Window]map[string]*spillRef{}
+				ss.spilledState[link] = swins
+			}
+			skeys, ok := swins[w]
+			if !ok {
+				skeys = map[string]*spillRef{}
+				swins[w] = skeys
+			}
+			for j, k := range ks {
+				freed += int64(keys[k].userBytes())
+				skeys[k] = refs[j]
+				delete(keys, k)
+			}
+		}
+	}
+	return freed, nil
+}
+
+// useSpilledLocked replaces the stage's pending element payloads that are in written
+// with their spilled copy.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) useSpilledLocked(written map[payloadKey]*spillRef) {
+	replace := func(es elementHeap) {
+		for i, e := range es {
+			if len(e.elmBytes) == 0 {
+				continue
+			}
+			if ref, ok := written[keyOf(e.elmBytes)]; ok {
+				es[i].elmBytes = nil
+				es[i].spilled = ref
+			}
+		}
+	}
+	replace(ss.pending)
+	for _, dnt := range ss.pendingByKeys {
+		replace(dnt.elements)
+	}
+}
+
+// pageInStateLocked reads back any spilled state for the given keys.
+//
+// Must be called while holding ss.mu.
+func (ss *stageState) pageInStateLocked(keys set[string]) {
+	for link, swins := range ss.spilledState {
+		for w, skeys := range swins {
+			for k := range keys {
+				ref, ok := skeys[k]
+				if !ok {
+					continue
+				}
+				wins, ok := ss.state[link]
+				if !ok {
+					wins = map[typex.Window]map[string]StateData{}
+					ss.state[link] = wins
+				}
+				kmap, ok := wins[w]
+				if !ok {
+					kmap = map[string]StateData{}
+					wins[w] = kmap
+				}
+				kmap[k] = decodeUserState(ref.mustRead())
+				delete(skeys, k)
+			}
+			if len(skeys) == 0 {
+				delete(swins, w)
+			}
+		}
+		if len(swins) == 0 {
+			delete(ss.spilledState, link)
+		}
+	}
+}
+
+// userBytes returns the number of bytes held by the user state.
+func (sd StateData) userBytes() int {
+	var n int
+	for _, b := range sd.Bag {
+		n += len(b)
+	}
+	for k, vs := range sd.Multimap {
+		n += len(k)
+		for _, v := range vs {
+			n += len(v)
+		}
+	}
+	return n
+}
+
+// encodeUserState encodes the Bag and Multimap state, which is all user state.
+func encodeUserState(sd StateData) []byte {
+	var b []byte
+	b = protowire.AppendVarint(b, uint64(len(sd.Bag)))
+	for _, v := range sd.Bag {
+		b = protowire.AppendBytes(b, v)
+	}
+	if sd.Multimap == nil {
+		return protowire.AppendVarint(b, 0)
+	}
+	b = protowire.AppendVarint(b, uint64(len(sd.Multimap))+1)
+	for k, vs := range sd.Multimap {
+		b = protowire.AppendString(b, k)
+		b = protowire.AppendVarint(b, uint64(len(vs)))
+		for _, v := range vs {
+			b = protowire.AppendBytes(b, v)
+		}
+	}
+	return b
+}
+
+// decodeUserState decodes state encoded by encodeUserState.
+func decodeUserState(b []byte) StateData {
+	var sd StateData
+	consumeVarint := func() int {
+		v, n := protowire.ConsumeVarint(b)
+		if n < 0 {
+			panic(fmt.Sprintf("corrupt spilled state: %v", protowire.ParseError(n)))
+		}
+		b = b[n:]
+		return int(v)
+	}
+	consumeBytes := func() []byte {
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			panic(fmt.Sprintf("corrupt spilled state: %v", protowire.ParseError(n)))
+		}
+		b = b[n:]
+		return v
+	}
+	if n := consumeVarint(); n > 0 {
+		sd.Bag = make([][]byte, 0, n)
+		for i := 0; i < n; i++ {
+			sd.Bag = append(sd.Bag, consumeBytes())
+		}
+	}
+	n := consumeVarint()
+	if n == 0 {
+		return sd
+	}
+	sd.Multimap = make(map[string][][]byte, n-1)
+	for i := 0; i < n-1; i++ {
+		k := string(consumeBytes())
+		vs := make([][]byte, 0, consumeVarint())
+		for j := 0; j < cap(vs); j++ {
+			vs = append(vs, consumeBytes())
+		}
+		sd.Multimap[k] = vs
+	}
+	return sd
+}
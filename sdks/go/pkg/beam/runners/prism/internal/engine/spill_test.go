@@ -0,0 +1,318 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package engine
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUserStateEncoding(t *testing.T) {
+	tests := []StateData{
+		{},
+		{Bag: [][]byte{{1}, {}, {2, 3}}},
+		{Multimap: map[string][][]byte{}},
+		{Multimap: map[string][][]byte{"a": {{1}}, "b": {{2}, {3, 4}}}},
+		{Bag: [][]byte{{5}}, Multimap: map[string][][]byte{"c": {{6}}}},
+	}
+	for _, want := range tests {
+		got := decodeUserState(encodeUserState(want))
+		if d := cmp.Diff(want, got); d != "" {
+			t.Errorf("decodeUserState(encodeUserState(%v)) diff (-want, +got):\n%v", want, d)
+		}
+		if (want.Multimap == nil) != (got.Multimap == nil) {
+			t.Errorf("decodeUserState(encodeUserState(%v)) Multimap = %v, want %v", want, got.Multimap, want.Multimap)
+		}
+	}
+}
+
+func TestStageState_SpillState(t *testing.T) {
+	sf := &spillFile{dir: t.TempDir()}
+	defer sf.close()
+
+	ss := makeStageState("stage", []string{"input"}, []string{"output"}, nil)
+	ss.kind = &statefulStageKind{}
+	ss.pendingByKeys = map[string]*dataAndTimers{}
+	ss.inprogressKeys = set[string]{}
+
+	cold, hot := "cold", "hot"
+	link := LinkID{Transform: "stage", Local: "bag"}
+	want := map[string]StateData{
+		cold: {Bag: [][]byte{bytes.Repeat([]byte{1}, 100)}},
+		hot:  {Bag: [][]byte{bytes.Repeat([]byte{2}, 100)}},
+	}
+	ss.state[link] = map[typex.Window]map[string]StateData{
+		window.GlobalWindow{}: {cold: want[cold], hot: want[hot]},
+	}
+	ss.inprogressKeys.insert(hot)
+
+	freed, err := ss.spillLocked(sf, map[payloadKey]*spillRef{})
+	if err != nil {
+		t.Fatalf("spillLocked() = %v", err)
+	}
+	if got, want := freed, int64(100); got != want {
+		t.Errorf("spillLocked() freed %v bytes, want %v", got, want)
+	}
+	if _, ok := ss.state[link][window.GlobalWindow{}][cold]; ok {
+		t.Errorf("state for key %q not spilled", cold)
+	}
+	if _, ok := ss.state[link][window.GlobalWindow{}][hot]; !ok {
+		t.Errorf("state for in progress key %q was spilled", hot)
+	}
+
+	ss.pageInStateLocked(singleSet(cold))
+	if d := cmp.Diff(want, ss.state[link][window.GlobalWindow{}]); d != "" {
+		t.Errorf("paged in state diff (-want, +got):\n%v", d)
+	}
+	if got := len(ss.spilledState); got != 0 {
+		t.Errorf("spilled state remaining after page in = %v, want 0", ss.spilledState)
+	}
+}
+
+func TestElementManager_Spill(t *testing.T) {
+	info := PColInfo{
+		GlobalID: "generic_info",
+		WDec:     exec.MakeWindowDecoder(coder.NewGlobalWindow()),
+		WEnc:     exec.MakeWindowEncoder(coder.NewGlobalWindow()),
+		EDec: func(r io.Reader) []byte {
+			b, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("error decoding \"generic_info\" data:%v", err)
+			}
+			return b
+		},
+	}
+	var es elements
+	for i := 0; i < 3; i++ {
+		es.es = append(es.es, element{
+			window:    window.GlobalWindow{},
+			timestamp: mtime.MinTimestamp,
+			pane:      typex.NoFiringPane(),
+			elmBytes:  bytes.Repeat([]byte{byte('A' + i)}, 100),
+		})
+	}
+	outputCoders := map[string]PColInfo{
+		"output": info,
+	}
+
+	dir := t.TempDir()
+	em := NewElementManager(Config{MemoryBudget: 64, SpillDir: dir})
+	em.AddStage("impulse", nil, []string{"input"}, nil)
+	em.AddStage("dofn1", []string{"input"}, []string{"output"}, nil)
+	em.AddStage("dofn2", []string{"output"}, nil, nil)
+	em.Impulse("impulse")
+
+	var i int
+	ctx, cancelFn := context.WithCancelCause(context.Background())
+	defer cancelFn(nil)
+	ch := em.Bundles(ctx, cancelFn, func() string {
+		defer func() { i++ }()
+		return fmt.Sprintf("%v", i)
+	})
+	rb, ok := <-ch
+	if !ok {
+		t.Fatal("Bundles channel unexpectedly closed")
+	}
+	td := TentativeData{}
+	for _, d := range es.ToData(info) {
+		td.WriteData("output", d)
+	}
+	em.PersistBundle(rb, outputCoders, td, info, Residuals{})
+
+	ss := em.stages["dofn2"]
+	ss.mu.Lock()
+	for _, e := range ss.pending {
+		if e.elmBytes != nil || e.spilled == nil {
+			t.Errorf("pending element not spilled: %v", e)
+		}
+		if e.IsTimer() {
+			t.Errorf("spilled element %v IsTimer() = true, want false", e)
+		}
+	}
+	ss.mu.Unlock()
+
+	rb, ok = <-ch
+	if !ok {
+		t.Fatal("Bundles channel unexpectedly closed")
+	}
+	if got, want := rb.StageID, "dofn2"; got != want {
+		t.Fatalf("stage to execute = %v, want %v", got, want)
+	}
+	if d := cmp.Diff(es.ToData(info), em.InputForBundle(rb, info)); d != "" {
+		t.Errorf("InputForBundle diff (-want, +got):\n%v", d)
+	}
+	em.PersistBundle(rb, outputCoders, TentativeData{}, info, Residuals{})
+	if rb, ok := <-ch; ok {
+		t.Errorf("Bundles channel expected to be closed, got %v", rb)
+	}
+
+	if err := em.Close(); err != nil {
+		t.Errorf("Close() = %v", err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir(%v) = %v", dir, err)
+	}
+	if len(files) != 0 {
+		t.Errorf("spill files remain after Close(): %v", files)
+	}
+}
+
+func TestSpillFile_Compact(t *testing.T) {
+	dir := t.TempDir()
+	sf := &spillFile{dir: dir, segmentBytes: 100}
+	defer sf.close()
+
+	files := func() int {
+		t.Helper()
+		fs, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("ReadDir(%v) = %v", dir, err)
+		}
+		return len(fs)
+	}
+	write := func(b []byte) *spillRef {
+		t.Helper()
+		refs, err := sf.write([][]byte{b})
+		if err != nil {
+			t.Fatalf("write() = %v", err)
+		}
+		return refs[0]
+	}
+
+	// Writes past the segment size move to a new segment.
+	first := write(bytes.Repeat([]byte{1}, 100))
+	second := write(bytes.Repeat([]byte{2}, 10))
+	third := write(bytes.Repeat([]byte{3}, 10))
+	if first.seg == second.seg {
+		t.Error("write() past the segment size used the same segment, want a new segment")
+	}
+	if second.seg != third.seg {
+		t.Error("write() under the segment size used a new segment, want the same segment")
+	}
+	if got, want := files(), 2; got != want {
+		t.Errorf("spill files = %v, want %v", got, want)
+	}
+
+	// Only referenced segments are kept.
+	reclaimed, err := sf.compact(singleSet(second.seg))
+	if err != nil {
+		t.Fatalf("compact() = %v", err)
+	}
+	if got, want := reclaimed, int64(100); got != want {
+		t.Errorf("compact() reclaimed %v bytes, want %v", got, want)
+	}
+	if got, want := files(), 1; got != want {
+		t.Errorf("spill files after compact() = %v, want %v", got, want)
+	}
+	if _, err := first.read(); err == nil {
+		t.Error("read() from a removed segment succeeded, want error")
+	}
+	if got, err := third.read(); err != nil || !cmp.Equal(got, bytes.Repeat([]byte{3}, 10)) {
+		t.Errorf("read() = %v, %v, want %v", got, err, bytes.Repeat([]byte{3}, 10))
+	}
+
+	// Once nothing is referenced, no segments remain, and new writes start a new segment.
+	if _, err := sf.compact(set[*spillSegment]{}); err != nil {
+		t.Fatalf("compact() = %v", err)
+	}
+	if got, want := files(), 0; got != want {
+		t.Errorf("spill files after compact() = %v, want %v", got, want)
+	}
+	if got, err := write([]byte{4}).read(); err != nil || !cmp.Equal(got, []byte{4}) {
+		t.Errorf("read() after compact() = %v, %v, want %v", got, err, []byte{4})
+	}
+}
+
+func TestSpillFile_ReadClose(t *testing.T) {
+	sf := &spillFile{dir: t.TempDir()}
+	refs, err := sf.write([][]byte{bytes.Repeat([]byte{1}, 100)})
+	if err != nil {
+		t.Fatalf("write() = %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			// Reads either succeed, or fail because the file is closed.
+			if b, err := refs[0].read(); err != nil && err.Error() != "spill file closed" {
+				t.Errorf("read() = %v, want success or closed", err)
+			} else if err == nil && len(b) != 100 {
+				t.Errorf("read() = %v bytes, want 100", len(b))
+			}
+		}
+	}()
+	if err := sf.close(); err != nil {
+		t.Errorf("close() = %v", err)
+	}
+	<-done
+}
+
+func TestElementManager_SpillSharedPayloads(t *testing.T) {
+	em := NewElementManager(Config{MemoryBudget: 100, SpillDir: t.TempDir()})
+	defer em.Close()
+	em.AddStage("impulse", nil, []string{"input"}, nil)
+	em.AddStage("dofn1", []string{"input"}, nil, nil)
+	em.AddStage("dofn2", []string{"input"}, nil, nil)
+
+	// Both consumers hold the same payloads, as they do for a shared input PCollection.
+	var es []element
+	for i := 0; i < 2; i++ {
+		es = append(es, element{
+			window:    window.GlobalWindow{},
+			timestamp: mtime.MinTimestamp,
+			pane:      typex.NoFiringPane(),
+			elmBytes:  bytes.Repeat([]byte{byte('A' + i)}, 100),
+		})
+	}
+	for _, id := range []string{"dofn1", "dofn2"} {
+		em.stages[id].AddPending(em, es)
+	}
+
+	seen := map[payloadKey]bool{}
+	var total int64
+	for _, id := range []string{"dofn1", "dofn2"} {
+		total += em.stages[id].residentBytesLocked(seen)
+	}
+	if got, want := total, int64(200); got != want {
+		t.Errorf("resident bytes = %v, want %v", got, want)
+	}
+
+	em.enforceMemoryBudget()
+	for _, id := range []string{"dofn1", "dofn2"} {
+		for _, e := range em.stages[id].pending {
+			if e.elmBytes != nil || e.spilled == nil {
+				t.Errorf("stage %v pending element not spilled: %v", id, e)
+			}
+		}
+	}
+	em.spill.mu.RLock()
+	defer em.spill.mu.RUnlock()
+	if got, want := em.spill.active.size, int64(200); got != want {
+		t.Errorf("spilled bytes = %v, want %v", got, want)
+	}
+}
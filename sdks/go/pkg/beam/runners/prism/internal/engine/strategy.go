@@ -47,6 +47,8 @@ import (
 type WinStrat struct {
 	AllowedLateness time.Duration // Used to extend duration
 	Accumulating    bool          // If true, elements remain pending until the last firing.
+	MergeWindows    WindowMerger  // If set, the windows of each key are merged with it, as with session windows.
+	OutputTime      OutputTime    // How aggregation output timestamps are determined, and so their watermark holds.
 
 	Trigger Trigger // Evaluated during execution.
 }
@@ -68,19 +70,67 @@ func (ws WinStrat) EarliestCompletion(w typex.Window) mtime.Time {
 	return w.MaxTimestamp().Add(ws.AllowedLateness)
 }
 
+// OutputTime determines the timestamp of aggregation outputs, from the timestamps
+// of the elements in the pane.
+type OutputTime int
+
+const (
+	// OutputTimeEndOfWindow outputs at the end of the window. The default.
+	OutputTimeEndOfWindow OutputTime = iota
+	// OutputTimeEarliestInPane outputs at the earliest element timestamp in the pane.
+	OutputTimeEarliestInPane
+	// OutputTimeLatestInPane outputs at the latest element timestamp in the pane.
+	OutputTimeLatestInPane
+)
+
+func (ot OutputTime) String() string {
+	switch ot {
+	case OutputTimeEndOfWindow:
+		return "EndOfWindow"
+	case OutputTimeEarliestInPane:
+		return "EarliestInPane"
+	case OutputTimeLatestInPane:
+		return "LatestInPane"
+	default:
+		return fmt.Sprintf("OutputTime(%d)", int(ot))
+	}
+}
+
+// paneHold returns the watermark hold for a pane in the given window, given the pane's
+// current hold, and the timestamp of an element added to the pane. The hold is where
+// the pane will be output, so the output watermark may not advance beyond it until
+// the pane is fired.
+func (ws WinStrat) paneHold(w typex.Window, hold, ts mtime.Time) mtime.Time {
+	switch ws.OutputTime {
+	case OutputTimeEarliestInPane:
+		return mtime.Min(hold, ts)
+	case OutputTimeLatestInPane:
+		if hold == mtime.MaxTimestamp {
+			// There's no hold for the pane yet.
+			return ts
+		}
+		return mtime.Max(hold, ts)
+	default:
+		return w.MaxTimestamp()
+	}
+}
+
 func (ws WinStrat) IsNeverTrigger() bool {
 	_, ok := ws.Trigger.(*TriggerNever)
 	return ok
 }
 
 func (ws WinStrat) String() string {
-	return fmt.Sprintf("WinStrat[AllowedLateness:%v Trigger:%v]", ws.AllowedLateness, ws.Trigger)
+	return fmt.Sprintf("WinStrat[AllowedLateness:%v OutputTime:%v Trigger:%v]", ws.AllowedLateness, ws.OutputTime, ws.Trigger)
 }
 
 // triggerInput represents a Key + window + stage's trigger conditions.
 type triggerInput struct {
-	newElementCount    int  // The number of new elements since the last check.
-	endOfWindowReached bool // Whether or not the end of the window has been reached.
+	newElementCount    int        // The number of new elements since the last check.
+	endOfWindowReached bool       // Whether or not the end of the window has been reached.
+	emNow              mtime.Time // The current processing time of the ElementManager.
+	processingTimeTick bool       // Whether this input is from a processing time event for the key + window, rather than new elements.
+	upstreamPending    bool       // Whether upstream stages still have elements to process, holding back synchronized processing time.
 }
 
 // Trigger represents a trigger for a windowing strategy.  A trigger determines when
@@ -99,7 +149,7 @@ type Trigger interface {
 	// a finished state.
 	onFire(state *StateData)
 
-	// TODO handle https://github.com/apache/beam/issues/31438 merging triggers and state for merging windows (sessions, but also custom merging windows)
+	// Trigger state for merging windows is combined by mergeStateData.
 }
 
 // triggerState retains additional state for a given trigger execution.
@@ -573,4 +623,216 @@ func (t *TriggerDefault) String() string {
 	return "Default"
 }
 
-// TODO https://github.com/apache/beam/issues/31438 Handle TriggerAfterProcessingTime
+// TimestampTransform adjusts the processing time that an AfterProcessingTime
+// trigger will fire at. If AlignToPeriod is set, the time is rounded up to the
+// next period boundary, starting from AlignToOffset. Otherwise the time is
+// delayed by Delay.
+type TimestampTransform struct {
+	Delay                        time.Duration
+	AlignToPeriod, AlignToOffset time.Duration
+}
+
+func (tt TimestampTransform) apply(t mtime.Time) mtime.Time {
+	if tt.AlignToPeriod <= 0 {
+		return t.Add(tt.Delay)
+	}
+	period := int64(tt.AlignToPeriod / time.Millisecond)
+	offset := int64(tt.AlignToOffset / time.Millisecond)
+	// Round up to the next period boundary, unless already on one.
+	sinceBoundary := (int64(t) - offset) % period
+	if sinceBoundary < 0 {
+		sinceBoundary += period
+	}
+	if sinceBoundary == 0 {
+		return t
+	}
+	return mtime.Time(int64(t) + period - sinceBoundary)
+}
+
+func (tt TimestampTransform) String() string {
+	if tt.AlignToPeriod <= 0 {
+		return fmt.Sprintf("Delay[%v]", tt.Delay)
+	}
+	return fmt.Sprintf("AlignTo[Period: %v Offset: %v]", tt.AlignToPeriod, tt.AlignToOffset)
+}
+
+// TriggerAfterProcessingTime fires once the processing time has passed the
+// firing time of the trigger. The firing time is set when the first element
+// for the key and window arrives, using the processing time of arrival
+// adjusted by the Transforms in order.
+//
+// Processing time only advances for the trigger via processing time events
+// scheduled by the stage, so elements that arrive at the same processing time
+// are never split across panes.
+//
+// Uses the extra state field to track the firing time, and if it's been reached.
+type TriggerAfterProcessingTime struct {
+	Transforms []TimestampTransform
+}
+
+func (t *TriggerAfterProcessingTime) onElement(input triggerInput, state *StateData) {
+	processingTimeOnElement(t, t.Transforms, input, input.processingTimeTick, state)
+}
+
+func (t *TriggerAfterProcessingTime) shouldFire(state *StateData) bool {
+	return processingTimeShouldFire(t, state)
+}
+
+func (t *TriggerAfterProcessingTime) onFire(state *StateData) {
+	processingTimeOnFire(t, state)
+}
+
+func (t *TriggerAfterProcessingTime) reset(state *StateData) {
+	delete(state.Trigger, t)
+}
+
+func (t *TriggerAfterProcessingTime) pendingFiringTime(state *StateData) (mtime.Time, bool) {
+	return processingTimePendingFiringTime(t, state)
+}
+
+func (t *TriggerAfterProcessingTime) String() string {
+	return fmt.Sprintf("AfterProcessingTime[%v]", t.Transforms)
+}
+
+// TriggerAfterSynchronizedProcessingTime fires once the synchronized processing
+// time has passed the time the first element for the key and window arrived.
+//
+// Prism has a single processing time clock for all stages, so the synchronized
+// processing time is the processing time, held back while upstream stages
+// still have elements to process.
+//
+// Uses the extra state field to track the firing time, and if it's been reached.
+type TriggerAfterSynchronizedProcessingTime struct{}
+
+func (t *TriggerAfterSynchronizedProcessingTime) onElement(input triggerInput, state *StateData) {
+	processingTimeOnElement(t, nil, input, input.processingTimeTick && !input.upstreamPending, state)
+}
+
+func (t *TriggerAfterSynchronizedProcessingTime) shouldFire(state *StateData) bool {
+	return processingTimeShouldFire(t, state)
+}
+
+func (t *TriggerAfterSynchronizedProcessingTime) onFire(state *StateData) {
+	processingTimeOnFire(t, state)
+}
+
+func (t *TriggerAfterSynchronizedProcessingTime) reset(state *StateData) {
+	delete(state.Trigger, t)
+}
+
+func (t *TriggerAfterSynchronizedProcessingTime) pendingFiringTime(state *StateData) (mtime.Time, bool) {
+	return processingTimePendingFiringTime(t, state)
+}
+
+func (t *TriggerAfterSynchronizedProcessingTime) String() string {
+	return "AfterSynchronizedProcessingTime"
+}
+
+// processingTimeTrigger is a Trigger that waits on the passage of processing time.
+type processingTimeTrigger interface {
+	Trigger
+	// pendingFiringTime returns the processing time the trigger is waiting for, if any.
+	pendingFiringTime(state *StateData) (mtime.Time, bool)
+}
+
+// afterProcessingTimeState is the extra state for processing time triggers.
+type afterProcessingTimeState struct {
+	firingTime mtime.Time // The processing time this trigger should fire at.
+	reached    bool       // Whether a processing time event has reached the firing time.
+}
+
+// processingTimeOnElement sets the firing time for the trigger t when an element arrives,
+// and marks the firing time as reached on processing time events, if the tick may advance it.
+func processingTimeOnElement(t Trigger, transforms []TimestampTransform, input triggerInput, canAdvance bool, state *StateData) {
+	ts := state.getTriggerState(t)
+	if ts.finished {
+		return
+	}
+	if ts.extra == nil {
+		if input.newElementCount == 0 {
+			// Processing time events don't start the delay, only elements do.
+			return
+		}
+		firingTime := input.emNow
+		for _, tt := range transforms {
+			firingTime = tt.apply(firingTime)
+		}
+		// Processing time is at the end of time when draining, so the delay can't be added.
+		firingTime = mtime.Min(firingTime, mtime.MaxTimestamp)
+		ts.extra = afterProcessingTimeState{firingTime: firingTime}
+		state.setTriggerState(t, ts)
+		return
+	}
+	pts := ts.extra.(afterProcessingTimeState)
+	if canAdvance && input.emNow >= pts.firingTime {
+		pts.reached = true
+		ts.extra = pts
+		state.setTriggerState(t, ts)
+	}
+}
+
+func processingTimeShouldFire(t Trigger, state *StateData) bool {
+	ts := state.getTriggerState(t)
+	if ts.finished || ts.extra == nil {
+		return false
+	}
+	return ts.extra.(afterProcessingTimeState).reached
+}
+
+func processingTimeOnFire(t Trigger, state *StateData) {
+	if !t.shouldFire(state) {
+		return
+	}
+	ts := state.getTriggerState(t)
+	ts.finished = true
+	ts.extra = nil
+	state.setTriggerState(t, ts)
+}
+
+func processingTimePendingFiringTime(t Trigger, state *StateData) (mtime.Time, bool) {
+	ts := state.getTriggerState(t)
+	if ts.finished || ts.extra == nil {
+		return mtime.MaxTimestamp, false
+	}
+	pts := ts.extra.(afterProcessingTimeState)
+	if pts.reached {
+		return mtime.MaxTimestamp, false
+	}
+	return pts.firingTime, true
+}
+
+// processingTimeTriggers returns all processing time triggers in the trigger tree
+// rooted at t.
+func processingTimeTriggers(t Trigger) []processingTimeTrigger {
+	switch t := t.(type) {
+	case *TriggerAfterProcessingTime:
+		return []processingTimeTrigger{t}
+	case *TriggerAfterSynchronizedProcessingTime:
+		return []processingTimeTrigger{t}
+	case *TriggerAfterAll:
+		return subProcessingTimeTriggers(t.SubTriggers...)
+	case *TriggerAfterAny:
+		return subProcessingTimeTriggers(t.SubTriggers...)
+	case *TriggerAfterEach:
+		return subProcessingTimeTriggers(t.SubTriggers...)
+	case *TriggerAfterEndOfWindow:
+		return subProcessingTimeTriggers(t.Early, t.Late)
+	case *TriggerOrFinally:
+		return subProcessingTimeTriggers(t.Main, t.Finally)
+	case *TriggerRepeatedly:
+		return subProcessingTimeTriggers(t.Repeated)
+	default:
+		return nil
+	}
+}
+
+func subProcessingTimeTriggers(subTriggers ...Trigger) []processingTimeTrigger {
+	var ret []processingTimeTrigger
+	for _, sub := range subTriggers {
+		if sub == nil {
+			continue
+		}
+		ret = append(ret, processingTimeTriggers(sub)...)
+	}
+	return ret
+}
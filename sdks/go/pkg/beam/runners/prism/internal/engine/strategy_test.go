@@ -45,6 +45,28 @@ func TestEarliestCompletion(t *testing.T) {
 	}
 }
 
+func TestTimestampTransform(t *testing.T) {
+	tests := []struct {
+		tt    TimestampTransform
+		input mtime.Time
+		want  mtime.Time
+	}{
+		{TimestampTransform{}, 1000, 1000},
+		{TimestampTransform{Delay: 5 * time.Second}, 1000, 6000},
+		{TimestampTransform{AlignToPeriod: 10 * time.Second}, 1000, 10000},
+		{TimestampTransform{AlignToPeriod: 10 * time.Second}, 10000, 10000},
+		{TimestampTransform{AlignToPeriod: 10 * time.Second, AlignToOffset: 3 * time.Second}, 1000, 3000},
+		{TimestampTransform{AlignToPeriod: 10 * time.Second, AlignToOffset: 3 * time.Second}, 3001, 13000},
+		{TimestampTransform{AlignToPeriod: 10 * time.Second}, -1000, 0},
+	}
+
+	for _, test := range tests {
+		if got, want := test.tt.apply(test.input), test.want; got != want {
+			t.Errorf("%v.apply(%v) = %v, want %v", test.tt, test.input, got, want)
+		}
+	}
+}
+
 func TestTriggers_isReady(t *testing.T) {
 	type io struct {
 		input      triggerInput
@@ -417,6 +439,52 @@ func TestTriggers_isReady(t *testing.T) {
 				{triggerInput{newElementCount: 6, endOfWindowReached: true}, true},
 				{triggerInput{newElementCount: 7, endOfWindowReached: true}, true},
 			},
+		}, {
+			name: "afterProcessingTime_delay",
+			trig: &TriggerAfterProcessingTime{
+				Transforms: []TimestampTransform{{Delay: 5 * time.Second}},
+			},
+			inputs: []io{
+				{triggerInput{newElementCount: 1, emNow: 1000}, false},       // Sets the firing time to 6000.
+				{triggerInput{newElementCount: 1, emNow: 7000}, false},       // Elements don't advance processing time.
+				{triggerInput{processingTimeTick: true, emNow: 5999}, false}, // Not yet.
+				{triggerInput{processingTimeTick: true, emNow: 6000}, true},  // Fires.
+				{triggerInput{newElementCount: 1, emNow: 8000}, false},       // Finished, and not reset.
+				{triggerInput{processingTimeTick: true, emNow: 20000}, false},
+			},
+		}, {
+			name: "afterProcessingTime_repeated",
+			trig: &TriggerRepeatedly{&TriggerAfterProcessingTime{
+				Transforms: []TimestampTransform{{Delay: 5 * time.Second}},
+			}},
+			inputs: []io{
+				{triggerInput{processingTimeTick: true, emNow: 1000}, false},  // No elements yet, so not waiting.
+				{triggerInput{newElementCount: 1, emNow: 1000}, false},        // Sets the firing time to 6000.
+				{triggerInput{processingTimeTick: true, emNow: 6000}, true},   // Fires.
+				{triggerInput{processingTimeTick: true, emNow: 12000}, false}, // Reset, and not waiting.
+				{triggerInput{newElementCount: 1, emNow: 12000}, false},       // Sets the firing time to 17000.
+				{triggerInput{processingTimeTick: true, emNow: 17000}, true},  // Fires.
+			},
+		}, {
+			name: "afterProcessingTime_alignTo",
+			trig: &TriggerAfterProcessingTime{
+				Transforms: []TimestampTransform{{AlignToPeriod: 10 * time.Second, AlignToOffset: 1 * time.Second}},
+			},
+			inputs: []io{
+				{triggerInput{newElementCount: 1, emNow: 1500}, false},        // Sets the firing time to 11000.
+				{triggerInput{processingTimeTick: true, emNow: 10999}, false}, // Not yet.
+				{triggerInput{processingTimeTick: true, emNow: 11000}, true},  // Fires.
+			},
+		}, {
+			name: "afterSynchronizedProcessingTime_repeated",
+			trig: &TriggerRepeatedly{&TriggerAfterSynchronizedProcessingTime{}},
+			inputs: []io{
+				{triggerInput{newElementCount: 1, emNow: 1000}, false},                              // Sets the firing time to 1000.
+				{triggerInput{processingTimeTick: true, emNow: 1000, upstreamPending: true}, false}, // Held back by upstream.
+				{triggerInput{processingTimeTick: true, emNow: 1000}, true},                         // Fires.
+				{triggerInput{newElementCount: 1, emNow: 2000}, false},                              // Reset, and sets the firing time to 2000.
+				{triggerInput{processingTimeTick: true, emNow: 2000}, true},                         // Fires.
+			},
 		},
 	}
 
@@ -16,6 +16,8 @@
 package engine
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
@@ -26,23 +28,35 @@ import (
 // We define our own element wrapper and similar to avoid depending on the protos within the
 // engine package. This improves compile times, and readability of this package.
 
-// TestStreamHandler manages TestStreamEvents for the ElementManager.
+// testStreamHandler manages TestStreamEvents for the ElementManager.
 //
 // TestStreams are a pipeline root like an Impulse. They kick off computation, and
 // strictly manage Watermark advancements.
 //
-// A given pipeline can only have a single TestStream due to test streams
-// requiring a single source of truth for Relative Processing Time advancements
-// and ordering emissions of Elements.
+// A pipeline may have several TestStreams, each with their own sequence of events,
+// and independent watermarks. Since test streams are the single source of truth
+// for relative processing time advancements, all streams share one processing time
+// clock. Events are executed one at a time from each stream in turn, in the order
+// the streams were added, which keeps the interleaving of streams deterministic.
+//
 // All operations with testStreamHandler are expected to be in the element manager's
 // refresh lock critical section.
 type testStreamHandler struct {
+	streams    []*testStream
+	nextStream int // index of the stream to take the next event from.
+
+	// Initialzed with normal "time.Now", so this does change by relative nature.
+	processingTime time.Time // Override for the processing time clock, for triggers and ProcessContinuations.
+
+	completed bool // indicates that all test streams have completed, and processing time is no longer overridden.
+}
+
+// testStream is the sequence of events for a single TestStream transform.
+type testStream struct {
 	ID string
 
 	nextEventIndex int
 	events         []tsEvent
-	// Initialzed with normal "time.Now", so this does change by relative nature.
-	processingTime time.Time // Override for the processing time clock, for triggers and ProcessContinuations.
 
 	tagState map[string]tagState // Map from event tag to related outputs.
 
@@ -50,8 +64,8 @@ type testStreamHandler struct {
 	completed   bool       // indicates that no further test stream events exist, and all watermarks are advanced to infinity. Used to send the final event, once.
 }
 
-func makeTestStreamHandler(id string) *testStreamHandler {
-	return &testStreamHandler{
+func makeTestStream(id string) *testStream {
+	return &testStream{
 		ID:          id,
 		tagState:    map[string]tagState{},
 		currentHold: mtime.MinTimestamp,
@@ -66,12 +80,12 @@ type tagState struct {
 
 // Now represents the overridden ProcessingTime, which is only advanced when directed by an event.
 // Overrides the elementManager "clock".
-func (ts *testStreamHandler) Now() mtime.Time {
-	return mtime.FromTime(ts.processingTime)
+func (th *testStreamHandler) Now() mtime.Time {
+	return mtime.FromTime(th.processingTime)
 }
 
 // TagsToPCollections recieves the map of local output tags to global pcollection ids.
-func (ts *testStreamHandler) TagsToPCollections(tagToPcol map[string]string) {
+func (ts *testStream) TagsToPCollections(tagToPcol map[string]string) {
 	for tag, pcol := range tagToPcol {
 		ts.tagState[tag] = tagState{
 			watermark:   mtime.MinTimestamp,
@@ -86,38 +100,59 @@ func (ts *testStreamHandler) TagsToPCollections(tagToPcol map[string]string) {
 }
 
 // AddElementEvent adds an element event to the test stream event queue.
-func (ts *testStreamHandler) AddElementEvent(tag string, elements []TestStreamElement) {
+func (ts *testStream) AddElementEvent(tag string, elements []TestStreamElement) {
 	ts.events = append(ts.events, tsElementEvent{
+		stream:   ts,
 		Tag:      tag,
 		Elements: elements,
 	})
 }
 
 // AddWatermarkEvent adds a watermark event to the test stream event queue.
-func (ts *testStreamHandler) AddWatermarkEvent(tag string, newWatermark mtime.Time) {
+func (ts *testStream) AddWatermarkEvent(tag string, newWatermark mtime.Time) {
 	ts.events = append(ts.events, tsWatermarkEvent{
+		stream:       ts,
 		Tag:          tag,
 		NewWatermark: newWatermark,
 	})
 }
 
 // AddProcessingTimeEvent adds a processing time event to the test stream event queue.
-func (ts *testStreamHandler) AddProcessingTimeEvent(d time.Duration) {
+func (ts *testStream) AddProcessingTimeEvent(d time.Duration) {
 	ts.events = append(ts.events, tsProcessingTimeEvent{
+		stream:    ts,
 		AdvanceBy: d,
 	})
 }
 
-// NextEvent returns the next event.
-// If there are no more events, returns nil.
-func (ts *testStreamHandler) NextEvent() tsEvent {
-	if ts == nil {
+// NextEvent returns the next event from the next stream that has one.
+// If there are no more events in any stream, returns nil.
+func (th *testStreamHandler) NextEvent() tsEvent {
+	if th == nil {
 		return nil
 	}
+	for range th.streams {
+		ts := th.streams[th.nextStream]
+		th.nextStream = (th.nextStream + 1) % len(th.streams)
+		if ev := ts.nextEvent(); ev != nil {
+			// Processing time is no longer overridden once the last stream sends its final event.
+			th.completed = true
+			for _, ts := range th.streams {
+				th.completed = th.completed && ts.completed
+			}
+			return ev
+		}
+	}
+	return nil
+}
+
+// nextEvent returns the next event for this stream.
+// If there are no more events, returns nil.
+func (ts *testStream) nextEvent() tsEvent {
 	if ts.nextEventIndex >= len(ts.events) {
 		if !ts.completed {
 			ts.completed = true
-			return tsFinalEvent{stageID: ts.ID}
+			return tsFinalEvent{stream: ts}
 		}
 		return nil
 	}
@@ -126,14 +161,20 @@ func (ts *testStreamHandler) NextEvent() tsEvent {
 	return ev
 }
 
+// String summarizes the state of the test streams, for debugging stuck jobs.
+func (th *testStreamHandler) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TestStreamHandler: completed %v, processingTime %v, %v\n", th.completed, th.processingTime, th.Now())
+	for _, ts := range th.streams {
+		fmt.Fprintf(&b, "\tTestStream %v: completed %v, curIndex %v of %v events: %+v\n", ts.ID, ts.completed, ts.nextEventIndex, len(ts.events), ts.events)
+	}
+	return b.String()
+}
+
 // UpdateHold restrains the watermark based on upcoming elements in the test stream queue
 // This uses the element manager's normal hold mechnanisms to avoid premature pipeline termination,
 // when there are still remaining events to process.
-func (ts *testStreamHandler) UpdateHold(em *ElementManager, newHold mtime.Time) {
-	if ts == nil {
-		return
-	}
-
+func (ts *testStream) UpdateHold(em *ElementManager, newHold mtime.Time) {
 	ss := em.stages[ts.ID]
 	ss.mu.Lock()
 	defer ss.mu.Unlock()
@@ -165,13 +206,14 @@ type tsEvent interface {
 // tsElementEvent implements an element event, inserting additional elements
 // to be pending for consuming stages.
 type tsElementEvent struct {
+	stream   *testStream
 	Tag      string
 	Elements []TestStreamElement
 }
 
 // Execute this ElementEvent by routing pending element to their consuming stages.
 func (ev tsElementEvent) Execute(em *ElementManager) {
-	t := em.testStreamHandler.tagState[ev.Tag]
+	t := ev.stream.tagState[ev.Tag]
 
 	var pending []element
 	for _, e := range ev.Elements {
@@ -200,36 +242,39 @@ func (ev tsElementEvent) Execute(em *ElementManager) {
 
 // tsWatermarkEvent sets the watermark for the new stage.
 type tsWatermarkEvent struct {
+	stream       *testStream
 	Tag          string
 	NewWatermark mtime.Time
 }
 
 // Execute this WatermarkEvent by updating the watermark for the tag, and notify affected downstream stages.
 func (ev tsWatermarkEvent) Execute(em *ElementManager) {
-	t := em.testStreamHandler.tagState[ev.Tag]
+	t := ev.stream.tagState[ev.Tag]
 
 	if ev.NewWatermark < t.watermark {
 		panic("test stream event decreases watermark. Watermarks cannot go backwards.")
 	}
 	t.watermark = ev.NewWatermark
-	em.testStreamHandler.tagState[ev.Tag] = t
+	ev.stream.tagState[ev.Tag] = t
 
 	// Update the upstream watermarks in the consumers.
 	for _, sID := range em.consumers[t.pcollection] {
 		ss := em.stages[sID]
-		ss.updateUpstreamWatermark(ss.inputID, t.watermark)
+		ss.updateUpstreamWatermark(t.pcollection, t.watermark)
 		em.changedStages.insert(sID)
 	}
 	// Clear the default hold after the inserts have occured.
-	em.testStreamHandler.UpdateHold(em, t.watermark)
+	ev.stream.UpdateHold(em, t.watermark)
 }
 
 // tsProcessingTimeEvent implements advancing the synthetic processing time.
 type tsProcessingTimeEvent struct {
+	stream    *testStream
 	AdvanceBy time.Duration
 }
 
-// Execute this ProcessingTime event by advancing the synthetic processing time.
+// Execute this ProcessingTime event by advancing the synthetic processing time,
+// which is shared by all test streams.
 func (ev tsProcessingTimeEvent) Execute(em *ElementManager) {
 	em.testStreamHandler.processingTime = em.testStreamHandler.processingTime.Add(ev.AdvanceBy)
 	if em.testStreamHandler.processingTime.After(mtime.MaxTimestamp.ToTime()) || ev.AdvanceBy == time.Duration(mtime.MaxTimestamp) {
@@ -246,19 +291,19 @@ func (ev tsProcessingTimeEvent) Execute(em *ElementManager) {
 // It's automatically inserted once the user defined events have all been executed.
 // It updates the upstream watermarks for all consumers to infinity.
 type tsFinalEvent struct {
-	stageID string
+	stream *testStream
 }
 
 func (ev tsFinalEvent) Execute(em *ElementManager) {
-	em.testStreamHandler.UpdateHold(em, mtime.MaxTimestamp)
-	ss := em.stages[ev.stageID]
+	ev.stream.UpdateHold(em, mtime.MaxTimestamp)
+	ss := em.stages[ev.stream.ID]
 	kickSet := ss.updateWatermarks(em)
-	kickSet.insert(ev.stageID)
+	kickSet.insert(ev.stream.ID)
 	em.changedStages.merge(kickSet)
 }
 
 // TestStreamBuilder builds a synthetic sequence of events for the engine to execute.
-// A pipeline may only have a single TestStream and may panic.
+// Each TestStream in a pipeline has its own builder.
 type TestStreamBuilder interface {
 	AddElementEvent(tag string, elements []TestStreamElement)
 	AddWatermarkEvent(tag string, newWatermark mtime.Time)
@@ -267,44 +312,47 @@ type TestStreamBuilder interface {
 
 type testStreamImpl struct {
 	em *ElementManager
+	ts *testStream
 }
 
 var (
 	_ TestStreamBuilder = (*testStreamImpl)(nil)
-	_ TestStreamBuilder = (*testStreamHandler)(nil)
+	_ TestStreamBuilder = (*testStream)(nil)
 )
 
 func (tsi *testStreamImpl) initHandler(id string) {
 	if tsi.em.testStreamHandler == nil {
-		tsi.em.testStreamHandler = makeTestStreamHandler(id)
+		tsi.em.testStreamHandler = &testStreamHandler{}
+	}
+	tsi.ts = makeTestStream(id)
+	tsi.em.testStreamHandler.streams = append(tsi.em.testStreamHandler.streams, tsi.ts)
 
-		ss := tsi.em.stages[id]
-		tsi.em.addPending(1) // We subtrack a pending after event execution, so add one now for the final event to avoid a race condition.
+	ss := tsi.em.stages[id]
+	tsi.em.addPending(1) // We subtrack a pending after event execution, so add one now for the final event to avoid a race condition.
 
-		// Arrest the watermark initially to prevent terminal advancement.
-		ss.watermarkHolds.Add(tsi.em.testStreamHandler.currentHold, 1)
-	}
+	// Arrest the watermark initially to prevent terminal advancement.
+	ss.watermarkHolds.Add(tsi.ts.currentHold, 1)
 }
 
 // TagsToPCollections recieves the map of local output tags to global pcollection ids.
 func (tsi *testStreamImpl) TagsToPCollections(tagToPcol map[string]string) {
-	tsi.em.testStreamHandler.TagsToPCollections(tagToPcol)
+	tsi.ts.TagsToPCollections(tagToPcol)
 }
 
 // AddElementEvent adds an element event to the test stream event queue.
 func (tsi *testStreamImpl) AddElementEvent(tag string, elements []TestStreamElement) {
-	tsi.em.testStreamHandler.AddElementEvent(tag, elements)
+	tsi.ts.AddElementEvent(tag, elements)
 	tsi.em.addPending(1)
 }
 
 // AddWatermarkEvent adds a watermark event to the test stream event queue.
 func (tsi *testStreamImpl) AddWatermarkEvent(tag string, newWatermark mtime.Time) {
-	tsi.em.testStreamHandler.AddWatermarkEvent(tag, newWatermark)
+	tsi.ts.AddWatermarkEvent(tag, newWatermark)
 	tsi.em.addPending(1)
 }
 
 // AddProcessingTimeEvent adds a processing time event to the test stream event queue.
 func (tsi *testStreamImpl) AddProcessingTimeEvent(d time.Duration) {
-	tsi.em.testStreamHandler.AddProcessingTimeEvent(d)
+	tsi.ts.AddProcessingTimeEvent(d)
 	tsi.em.addPending(1)
 }
@@ -396,6 +396,41 @@ func (th *timerHandler) Persist(fire mtime.Time, timer element, holdChanges map[
 	return 1
 }
 
+// Clear removes the existing timer for the given timer's userkey+timerID+tag+window,
+// if any, and updates the provided hold times map with changes to the hold counts.
+// Returns the change in the number of pending timers.
+func (th *timerHandler) Clear(timer element, holdChanges map[mtime.Time]int) int {
+	timers, ok := th.nextFiring[string(timer.keyBytes)]
+	if !ok {
+		return 0
+	}
+	key := timerKey{family: timer.family, tag: timer.tag, window: timer.window}
+	oldTimer, ok := timers[key]
+	if !ok {
+		return 0
+	}
+	th.removeTimer(string(timer.keyBytes), key)
+
+	byKeys := th.toFire[oldTimer.firing]
+	keyTimers := byKeys[string(oldTimer.timer.keyBytes)]
+	keyTimers.remove(key)
+	if len(keyTimers) == 0 {
+		th.timerKeySetPool.Put(keyTimers)
+		delete(byKeys, string(oldTimer.timer.keyBytes))
+	}
+	if len(byKeys) == 0 {
+		th.userKeysSetPool.Put(byKeys)
+		delete(th.toFire, oldTimer.firing)
+		th.order.Remove(oldTimer.firing)
+	}
+
+	holdChanges[oldTimer.timer.holdTimestamp] -= 1
+	if holdChanges[oldTimer.timer.holdTimestamp] == 0 {
+		delete(holdChanges, oldTimer.timer.holdTimestamp)
+	}
+	return -1
+}
+
 // FireAt returns all timers for a key able to fire at the given time.
 func (th *timerHandler) FireAt(now mtime.Time) []element {
 	if th.order.Len() == 0 {
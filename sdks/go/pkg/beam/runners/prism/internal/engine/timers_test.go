@@ -289,3 +289,43 @@ func TestTimerHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestTimerHandler_Clear(t *testing.T) {
+	fireTime1, fireTime2 := mtime.FromMilliseconds(1000), mtime.FromMilliseconds(1100)
+	holdTime1, holdTime2 := mtime.FromMilliseconds(300), mtime.FromMilliseconds(301)
+	elem := func(userKey string, holdTime mtime.Time) element {
+		return element{
+			window:        window.SingleGlobalWindow[0],
+			holdTimestamp: holdTime,
+			pane:          typex.NoFiringPane(),
+			transform:     "testtransform",
+			family:        "testfamily",
+			keyBytes:      []byte(userKey),
+		}
+	}
+
+	th := newTimerHandler()
+	holdChanges := map[mtime.Time]int{}
+	th.Persist(fireTime1, elem("userKey1", holdTime1), holdChanges)
+	th.Persist(fireTime2, elem("userKey2", holdTime2), holdChanges)
+
+	if got, want := th.Clear(elem("userKey1", 0), holdChanges), -1; got != want {
+		t.Errorf("Clear(userKey1) = %v, want %v", got, want)
+	}
+	if got, want := th.Clear(elem("userKey1", 0), holdChanges), 0; got != want {
+		t.Errorf("Clear(userKey1) again = %v, want %v", got, want)
+	}
+	if got, want := th.Clear(elem("userKey3", 0), holdChanges), 0; got != want {
+		t.Errorf("Clear(userKey3) = %v, want %v", got, want)
+	}
+	if d := cmp.Diff(map[mtime.Time]int{holdTime2: 1}, holdChanges); d != "" {
+		t.Errorf("Clear(): holds diff (-want,+got):\n%v", d)
+	}
+	if got, want := th.Peek(), fireTime2; got != want {
+		t.Errorf("Peek() = %v, want %v", got, want)
+	}
+	fired := th.FireAt(fireTime2)
+	if len(fired) != 1 || string(fired[0].keyBytes) != "userKey2" {
+		t.Errorf("FireAt(%v) = %+v, want only the userKey2 timer", fireTime2, fired)
+	}
+}
@@ -23,12 +23,16 @@ import (
 	"io"
 	"log/slog"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/metricsx"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal/engine"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal/jobservices"
@@ -40,6 +44,10 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// defaultCheckpointInterval is how often jobs with a checkpoint directory write
+// snapshots, unless overridden by the prism_checkpoint_interval experiment.
+const defaultCheckpointInterval = 10 * time.Second
+
 // RunPipeline starts the main thread fo executing this job.
 // It's analoguous to the manager side process for a distributed pipeline.
 // It will begin "workers"
@@ -92,7 +100,12 @@ func RunPipeline(j *jobservices.Job) {
 		return
 	}
 
-	j.SendMsg("pipeline completed " + j.String())
+	select {
+	case <-j.DrainRequested():
+		j.SendMsg("pipeline drained " + j.String())
+	default:
+		j.SendMsg("pipeline completed " + j.String())
+	}
 
 	j.SendMsg("terminating " + j.String())
 	j.Done()
@@ -145,19 +158,47 @@ func executePipeline(ctx context.Context, wks map[string]*worker.W, j *jobservic
 	topo := prepro.preProcessGraph(comps, j)
 	ts := comps.GetTransforms()
 
-	config := engine.Config{}
+	config := engine.Config{CheckpointInterval: defaultCheckpointInterval}
 	m := j.PipelineOptions().AsMap()
 	if experimentsSlice, ok := m["beam:option:experiments:v1"].([]interface{}); ok {
 		for _, exp := range experimentsSlice {
-			if expStr, ok := exp.(string); ok {
-				if expStr == "prism_enable_rtc" {
-					config.EnableRTC = true
-					break // Found it, no need to check the rest of the slice
+			expStr, ok := exp.(string)
+			if !ok {
+				continue
+			}
+			switch {
+			case expStr == "prism_enable_rtc":
+				config.EnableRTC = true
+			case strings.HasPrefix(expStr, "prism_memory_budget_mb="):
+				mb, err := strconv.ParseInt(strings.TrimPrefix(expStr, "prism_memory_budget_mb="), 10, 64)
+				if err != nil || mb < 0 {
+					return fmt.Errorf("prism error: invalid experiment %q, want a non-negative number of megabytes", expStr)
+				}
+				config.MemoryBudget = mb << 20
+			case strings.HasPrefix(expStr, "prism_max_bundles_per_stage="):
+				n, err := strconv.Atoi(strings.TrimPrefix(expStr, "prism_max_bundles_per_stage="))
+				if err != nil || n < 0 {
+					return fmt.Errorf("prism error: invalid experiment %q, want a non-negative number of bundles", expStr)
 				}
+				config.MaxBundlesPerStage = n
+			case strings.HasPrefix(expStr, "prism_checkpoint_interval="):
+				d, err := time.ParseDuration(strings.TrimPrefix(expStr, "prism_checkpoint_interval="))
+				if err != nil || d < 0 {
+					return fmt.Errorf("prism error: invalid experiment %q, want a non-negative duration", expStr)
+				}
+				config.CheckpointInterval = d
+			case strings.HasPrefix(expStr, "prism_checkpoint_bundles="):
+				n, err := strconv.Atoi(strings.TrimPrefix(expStr, "prism_checkpoint_bundles="))
+				if err != nil || n < 0 {
+					return fmt.Errorf("prism error: invalid experiment %q, want a non-negative number of bundles", expStr)
+				}
+				config.CheckpointBundles = n
 			}
 		}
 	}
 
+	config.CheckpointDir = j.CheckpointDir
+
 	em := engine.NewElementManager(config)
 
 	// TODO move this loop and code into the preprocessor instead.
@@ -238,9 +279,18 @@ func executePipeline(ctx context.Context, wks map[string]*worker.W, j *jobservic
 					}
 				}
 				ws := windowingStrategy(comps, tid)
+				var merger engine.WindowMerger
+				if pipepb.MergeStatus_NEEDS_MERGE == ws.GetMergeStatus() {
+					var err error
+					if merger, err = windowMerger(ctx, stage.ID, ws, comps, wks); err != nil {
+						return fmt.Errorf("prism error building stage %v: %w", stage.ID, err)
+					}
+				}
 				em.StageAggregates(stage.ID, engine.WinStrat{
 					AllowedLateness: time.Duration(ws.GetAllowedLateness()) * time.Millisecond,
 					Accumulating:    pipepb.AccumulationMode_ACCUMULATING == ws.GetAccumulationMode(),
+					MergeWindows:    merger,
+					OutputTime:      buildOutputTime(ws.GetOutputTime()),
 					Trigger:         buildTrigger(ws.GetTrigger()),
 				})
 			case urns.TransformImpulse:
@@ -332,30 +382,67 @@ func executePipeline(ctx context.Context, wks map[string]*worker.W, j *jobservic
 			if len(stage.processingTimeTimers) > 0 {
 				em.StageProcessingTimeTimers(stage.ID, stage.processingTimeTimers)
 			}
+			if len(stage.triggeredSideInputs) > 0 {
+				em.StageTriggeredSideInputs(stage.ID, stage.triggeredSideInputs)
+			}
 		default:
 			return fmt.Errorf("unknown environment[%v]", t.GetEnvironmentId())
 		}
 	}
 
-	// Prime the initial impulses, since we now know what consumes them.
-	for _, id := range impulses {
-		em.Impulse(id)
+	if j.RestoreFrom != "" {
+		// The impulses were already primed in the restored job.
+		if err := em.Restore(j.RestoreFrom); err != nil {
+			return fmt.Errorf("prism error restoring job from %v: %w", j.RestoreFrom, err)
+		}
+		j.SendMsg("restored " + j.String() + " from " + j.RestoreFrom)
+	} else {
+		// Prime the initial impulses, since we now know what consumes them.
+		for _, id := range impulses {
+			em.Impulse(id)
+		}
+	}
+
+	concurrency := func() map[string]engine.BundleConcurrency {
+		return transformBundleConcurrency(em, stages)
 	}
+	runningJobs.Store(j.JobKey(), concurrency)
+	defer runningJobs.Delete(j.JobKey())
+	j.SetRunnerMetrics(func() []*pipepb.MonitoringInfo {
+		return bundleConcurrencyMetrics(concurrency())
+	})
 
 	// Use an errgroup to limit max parallelism for the pipeline.
+	// Allow at least as many bundles as a single stage may execute concurrently.
 	eg, egctx := errgroup.WithContext(ctx)
-	eg.SetLimit(8)
+	eg.SetLimit(max(8, config.MaxBundlesPerStage))
+	defer func() {
+		// Bundles may still be finishing if the job was canceled, so only release
+		// spilled data once they're done.
+		go func() {
+			eg.Wait()
+			if err := em.Close(); err != nil {
+				j.Logger.Warn("unable to clean up spilled data", slog.Any("error", err))
+			}
+		}()
+	}()
 
 	var instID uint64
 	bundles := em.Bundles(egctx, j.CancelFn, func() string {
 		return fmt.Sprintf("inst%03d", atomic.AddUint64(&instID, 1))
 	})
+	drainRequested := j.DrainRequested()
 	for {
 		select {
 		case <-ctx.Done():
 			err := context.Cause(ctx)
 			j.Logger.Debug("context canceled", slog.Any("cause", err))
 			return err
+		case <-drainRequested:
+			// Only drain once.
+			drainRequested = nil
+			j.Logger.Debug("draining", slog.String("job", j.String()))
+			em.Drain()
 		case rb, ok := <-bundles:
 			if !ok {
 				err := eg.Wait()
@@ -376,6 +463,60 @@ func executePipeline(ctx context.Context, wks map[string]*worker.W, j *jobservic
 	}
 }
 
+// runningJobs holds a function returning the bundle concurrency of the transforms of each
+// job running in this process, keyed by job ID, so the web UI can read it directly.
+var runningJobs sync.Map
+
+// BundleConcurrency returns the active and peak bundle concurrency of the transforms of a job
+// running in this process, keyed by transform ID, or false if the job isn't running here.
+func BundleConcurrency(jobID string) (map[string]engine.BundleConcurrency, bool) {
+	fn, ok := runningJobs.Load(jobID)
+	if !ok {
+		return nil, false
+	}
+	return fn.(func() map[string]engine.BundleConcurrency)(), true
+}
+
+// transformBundleConcurrency returns the bundle concurrency of each stage for each
+// transform in the stage.
+func transformBundleConcurrency(em *engine.ElementManager, stages map[string]*stage) map[string]engine.BundleConcurrency {
+	ret := map[string]engine.BundleConcurrency{}
+	for id, c := range em.StageBundleConcurrency() {
+		s, ok := stages[id]
+		if !ok {
+			continue
+		}
+		for _, tid := range s.transforms {
+			ret[tid] = c
+		}
+	}
+	return ret
+}
+
+// bundleConcurrencyMetrics reports the bundle concurrency of each transform as
+// prism specific metrics.
+func bundleConcurrencyMetrics(concurrency map[string]engine.BundleConcurrency) []*pipepb.MonitoringInfo {
+	var infos []*pipepb.MonitoringInfo
+	for tid, c := range concurrency {
+		for urn, v := range map[string]int{
+			urns.MetricStageActiveBundles: c.Active,
+			urns.MetricStagePeakBundles:   c.Peak,
+		} {
+			payload, err := metricsx.Int64Counter(int64(v))
+			if err != nil {
+				panic(err)
+			}
+			infos = append(infos, &pipepb.MonitoringInfo{
+				Urn:     urn,
+				Type:    metricsx.UrnToType(metricsx.UrnUserSumInt64),
+				Payload: payload,
+				Labels:  map[string]string{"PTRANSFORM": tid},
+			})
+		}
+	}
+	return infos
+}
+
 func collectionPullDecoder(coldCId string, coders map[string]*pipepb.Coder, comps *pipepb.Components) func(io.Reader) []byte {
 	cID, err := lpUnknownCoders(coldCId, coders, comps.GetCoders())
 	if err != nil {
@@ -392,6 +533,25 @@ func extractKVCoderID(coldCId string, coders map[string]*pipepb.Coder) (string,
 	return "", false
 }
 
+// extractKeyCoderID returns the coder ID of the key used to process elements of the
+// collection by key. The sized element and restriction collections of splittable DoFns
+// have the form KV<KV<KV<K, V>, Restriction>, Size>, and are processed by the key of the
+// original element, since that's the key the SDK uses for state and timers.
+func extractKeyCoderID(colCID string, coders map[string]*pipepb.Coder, sized bool) (string, bool) {
+	if !sized {
+		return extractKVCoderID(colCID, coders)
+	}
+	kvERCID, ok := extractKVCoderID(colCID, coders)
+	if !ok {
+		return "", false
+	}
+	eCID, ok := extractKVCoderID(kvERCID, coders)
+	if !ok {
+		return "", false
+	}
+	return extractKVCoderID(eCID, coders)
+}
+
 func getWindowValueCoders(comps *pipepb.Components, col *pipepb.PCollection, coders map[string]*pipepb.Coder) (engine.WinCoderType, exec.WindowDecoder, exec.WindowEncoder) {
 	ws := comps.GetWindowingStrategies()[col.GetWindowingStrategyId()]
 	wcID, err := lpUnknownCoders(ws.GetWindowCoderId(), coders, comps.GetCoders())
@@ -416,6 +576,19 @@ func getOnlyValue[K comparable, V any](in map[K]V) V {
 	return v
 }
 
+// buildOutputTime converts the protocol buffer representation of an output time
+// to the engine representation. Unspecified output times are at the end of the window.
+func buildOutputTime(ot pipepb.OutputTime_Enum) engine.OutputTime {
+	switch ot {
+	case pipepb.OutputTime_EARLIEST_IN_PANE:
+		return engine.OutputTimeEarliestInPane
+	case pipepb.OutputTime_LATEST_IN_PANE:
+		return engine.OutputTimeLatestInPane
+	default:
+		return engine.OutputTimeEndOfWindow
+	}
+}
+
 // buildTrigger converts the protocol buffer representation of a trigger
 // to the engine representation.
 func buildTrigger(tpb *pipepb.Trigger) engine.Trigger {
@@ -456,8 +629,26 @@ func buildTrigger(tpb *pipepb.Trigger) engine.Trigger {
 		}
 	case *pipepb.Trigger_Repeat_:
 		return &engine.TriggerRepeatedly{Repeated: buildTrigger(at.Repeat.GetSubtrigger())}
-	case *pipepb.Trigger_AfterProcessingTime_, *pipepb.Trigger_AfterSynchronizedProcessingTime_:
-		panic(fmt.Sprintf("unsupported trigger: %v", prototext.Format(tpb)))
+	case *pipepb.Trigger_AfterProcessingTime_:
+		var transforms []engine.TimestampTransform
+		for _, tt := range at.AfterProcessingTime.GetTimestampTransforms() {
+			switch ttt := tt.GetTimestampTransform().(type) {
+			case *pipepb.TimestampTransform_Delay_:
+				transforms = append(transforms, engine.TimestampTransform{
+					Delay: time.Duration(ttt.Delay.GetDelayMillis()) * time.Millisecond,
+				})
+			case *pipepb.TimestampTransform_AlignTo_:
+				transforms = append(transforms, engine.TimestampTransform{
+					AlignToPeriod: time.Duration(ttt.AlignTo.GetPeriod()) * time.Millisecond,
+					AlignToOffset: time.Duration(ttt.AlignTo.GetOffset()) * time.Millisecond,
+				})
+			default:
+				panic(fmt.Sprintf("unsupported timestamp transform in trigger: %v", prototext.Format(tpb)))
+			}
+		}
+		return &engine.TriggerAfterProcessingTime{Transforms: transforms}
+	case *pipepb.Trigger_AfterSynchronizedProcessingTime_:
+		return &engine.TriggerAfterSynchronizedProcessingTime{}
 	default:
 		return &engine.TriggerDefault{}
 	}
@@ -25,7 +25,10 @@ import (
 	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window/trigger"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/metrics"
+	jobpb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/jobmanagement_v1"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/options/jobopts"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal"
@@ -33,8 +36,13 @@ import (
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/universal"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/teststream"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/transforms/filter"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/transforms/periodic"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/transforms/stats"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/util/grpcx"
 	"github.com/apache/beam/sdks/v2/go/test/integration/primitives"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestMain(m *testing.M) {
@@ -428,6 +436,17 @@ func TestRunner_Pipelines(t *testing.T) {
 				out := beam.ParDo(s, &selfCheckpointingDoFn{}, beam.Impulse(s))
 				passert.Count(s, out, "num ints", 10)
 			},
+		}, {
+			name: "ProcessContinuations_stateful",
+			pipeline: func(s beam.Scope) {
+				imp := beam.Impulse(s)
+				in := beam.ParDo(s, dofnKV, imp)
+				out := beam.ParDo(s, &statefulCheckpointingDoFn{}, in)
+				// Counts are shared between all restrictions for a key, across checkpoints.
+				passert.Equals(s, out,
+					1, 2, 3, 4, 5, 6, 7, 8, 9, // "a" has 1 + 3 + 5 positions.
+					1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12) // "b" has 2 + 4 + 6 positions.
+			},
 		}, {
 			name: "flatten_to_sideInput",
 			pipeline: func(s beam.Scope) {
@@ -502,6 +521,144 @@ func TestRunner_Metrics(t *testing.T) {
 			t.Errorf("pr.Metrics.Query(Name = \"count\")).Committed = %v, want %v", got, want)
 		}
 	})
+	t.Run("stringSet", func(t *testing.T) {
+		p, s := beam.NewPipelineWithRoot()
+		imp := beam.Impulse(s)
+		beam.ParDo(s, dofnStringSet, imp)
+		pr, err := executeWithT(context.Background(), t, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		qr := pr.Metrics().Query(func(sr metrics.SingleResult) bool {
+			return sr.Name() == "set"
+		})
+		if len(qr.StringSets()) == 0 {
+			t.Fatal("no metrics, expected one.")
+		}
+		if got, want := qr.StringSets()[0].Committed, []string{"a", "b"}; !cmp.Equal(got, want) {
+			t.Errorf("pr.Metrics.Query(Name = \"set\")).Committed = %v, want %v", got, want)
+		}
+	})
+	t.Run("boundedTrie", func(t *testing.T) {
+		p, s := beam.NewPipelineWithRoot()
+		imp := beam.Impulse(s)
+		beam.ParDo(s, dofnBoundedTrie, imp)
+		pr, err := executeWithT(context.Background(), t, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		qr := pr.Metrics().Query(func(sr metrics.SingleResult) bool {
+			return sr.Name() == "trie"
+		})
+		if len(qr.BoundedTries()) == 0 {
+			t.Fatal("no metrics, expected one.")
+		}
+		want := []metrics.BoundedTriePath{
+			{Segments: []string{"gcs", "bucket", "a.txt"}},
+			{Segments: []string{"gcs", "bucket", "b.txt"}},
+		}
+		if got := qr.BoundedTries()[0].Committed.Paths; !cmp.Equal(got, want) {
+			t.Errorf("pr.Metrics.Query(Name = \"trie\")).Committed.Paths = %v, want %v", got, want)
+		}
+	})
+}
+
+// TestRunner_MergingWindowTriggers validates that element count triggers observe
+// all elements of merged session windows. The first two elements fire once their
+// sessions merge, and the last element of the session fires at the end of the window.
+func TestRunner_MergingWindowTriggers(t *testing.T) {
+	initRunner(t)
+
+	con := teststream.NewConfig()
+	con.AddElements(1000, 1.0)
+	con.AddElements(4000, 2.0)
+	con.AddElements(6000, 4.0)
+	con.AdvanceWatermark(60000)
+
+	p, s := beam.NewPipelineWithRoot()
+	col := teststream.Create(s, con)
+	windowed := beam.WindowInto(s, window.NewSessions(5*time.Second), col,
+		beam.Trigger(trigger.AfterCount(2)))
+	sums := beam.WindowInto(s, window.NewGlobalWindows(), stats.Sum(s, windowed))
+	passert.Equals(s, sums, 3.0, 4.0)
+	if _, err := executeWithT(context.Background(), t, p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRunner_MultipleTestStreams validates that several test streams may be in
+// a pipeline, each advancing their watermarks independently.
+func TestRunner_MultipleTestStreams(t *testing.T) {
+	initRunner(t)
+
+	left := teststream.NewConfig()
+	left.AddElements(1000, 1.0)
+	left.AdvanceWatermark(5000)
+	left.AddElements(11000, 2.0)
+	left.AdvanceWatermark(60000)
+
+	right := teststream.NewConfig()
+	right.AddElements(2000, 10.0)
+	right.AdvanceWatermark(15000)
+	right.AddElements(16000, 20.0)
+	right.AdvanceWatermark(60000)
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.Flatten(s, teststream.Create(s, left), teststream.Create(s, right))
+	windowed := beam.WindowInto(s, window.NewFixedWindows(10*time.Second), col)
+	sums := beam.WindowInto(s, window.NewGlobalWindows(), stats.Sum(s, windowed))
+	passert.Equals(s, sums, 11.0, 22.0)
+	if _, err := executeWithT(context.Background(), t, p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRunner_TriggeredSideInput validates that a slowly updating side input from an
+// unbounded source in the global window publishes the latest firing of every key.
+// The main input starts after the side input's sequence has been fully emitted,
+// so each element sees the final sums.
+func TestRunner_TriggeredSideInput(t *testing.T) {
+	initRunner(t)
+
+	now := time.Now()
+	p, s := beam.NewPipelineWithRoot()
+	seq := periodic.Sequence(s, beam.Create(s, periodic.NewSequenceDefinition(now, now.Add(time.Second), 100*time.Millisecond)))
+	keyed := beam.WindowInto(s, window.NewGlobalWindows(), beam.ParDo(s, dofnKeyByFirstThree, seq),
+		beam.Trigger(trigger.Repeat(trigger.AfterCount(1))), beam.PanesAccumulate())
+	sums := stats.SumPerKey(s, keyed)
+
+	main := periodic.Impulse(s, now.Add(3*time.Second), now.Add(4*time.Second), 500*time.Millisecond, false)
+	got := beam.ParDo(s, dofnLatestSideSums, main, beam.SideInput{Input: sums})
+	passert.Equals(s, got, "0=3,1=42", "0=3,1=42")
+	if _, err := executeWithT(context.Background(), t, p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRunner_MaxBundlesPerStage validates that per key state remains consistent when
+// a stateful stage executes bundles for disjoint key groups concurrently.
+func TestRunner_MaxBundlesPerStage(t *testing.T) {
+	initRunner(t)
+	defer func(exps string) { *jobopts.Experiments = exps }(*jobopts.Experiments)
+	*jobopts.Experiments = "prism_max_bundles_per_stage=4"
+
+	const keys, perKey = 20, 5
+	var in []string
+	var want []int
+	for i := 0; i < perKey; i++ {
+		for k := 0; k < keys; k++ {
+			in = append(in, fmt.Sprintf("key%d", k))
+			want = append(want, i+1)
+		}
+	}
+
+	p, s := beam.NewPipelineWithRoot()
+	kvs := beam.ParDo(s, dofnKeyWithOne, beam.CreateList(s, in))
+	counts := beam.ParDo(s, &countingStateFn{}, kvs)
+	passert.EqualsList(s, counts, want)
+	if _, err := executeWithT(context.Background(), t, p); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestFailure(t *testing.T) {
@@ -519,6 +676,78 @@ func TestFailure(t *testing.T) {
 	}
 }
 
+// TestRunner_Drain validates that draining a job with an unbounded source truncates
+// the source, and fires the windows waiting on the end of time before the job is DONE.
+func TestRunner_Drain(t *testing.T) {
+	initRunner(t)
+	// Cancel the execution if the test fails, so the job service can stop.
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	p, s := beam.NewPipelineWithRoot()
+	imp := beam.Impulse(s)
+	out := beam.ParDo(s, &unboundedDoFn{}, imp)
+	// The global window only closes once the watermark advances to infinity.
+	sum := stats.Sum(s, beam.Flatten(s, beam.Create(s, int64(0)), out))
+	passert.NonEmpty(s, sum)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := executeWithT(ctx, t, p)
+		done <- err
+	}()
+
+	cc, err := grpcx.Dial(ctx, *jobopts.Endpoint, time.Minute)
+	if err != nil {
+		t.Fatalf("unable to connect to job service: %v", err)
+	}
+	defer cc.Close()
+	jobCli := jobpb.NewJobServiceClient(cc)
+
+	// Wait for the job to be running before draining it.
+	var jobID string
+	for jobID == "" {
+		resp, err := jobCli.GetJobs(ctx, &jobpb.GetJobsRequest{})
+		if err != nil {
+			t.Fatalf("GetJobs() = %v", err)
+		}
+		for _, info := range resp.GetJobInfo() {
+			if strings.HasPrefix(info.GetJobName(), strings.ToLower(t.Name())) && info.GetState() == jobpb.JobState_RUNNING {
+				jobID = info.GetJobId()
+			}
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("pipeline terminated before being drained: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	resp, err := jobCli.Drain(ctx, &jobpb.DrainJobRequest{JobId: jobID})
+	if err != nil {
+		t.Fatalf("Drain(%v) = %v", jobID, err)
+	}
+	if got, want := resp.GetState(), jobpb.JobState_DRAINING; got != want {
+		t.Errorf("Drain(%v) state = %v, want %v", jobID, got, want)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("drained pipeline failed: %v", err)
+		}
+	case <-time.After(time.Minute):
+		jobCli.Cancel(ctx, &jobpb.CancelJobRequest{JobId: jobID})
+		t.Fatal("pipeline didn't terminate after being drained")
+	}
+	state, err := jobCli.GetState(ctx, &jobpb.GetJobStateRequest{JobId: jobID})
+	if err != nil {
+		t.Fatalf("GetState(%v) = %v", jobID, err)
+	}
+	if got, want := state.GetState(), jobpb.JobState_DONE; got != want {
+		t.Errorf("GetState(%v) = %v, want %v", jobID, got, want)
+	}
+}
+
 func TestRunner_Passert(t *testing.T) {
 	initRunner(t)
 	tests := []struct {
@@ -20,12 +20,10 @@ import (
 	"fmt"
 	"io"
 	"reflect"
-	"sort"
 	"strings"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
-	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/exec"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
@@ -340,7 +338,7 @@ func gbkBytes(ws *pipepb.WindowingStrategy, wc, kc, vc *pipepb.Coder, toAggregat
 	// Pick how the timestamp of the aggregated output is computed.
 	var outputTime func(typex.Window, mtime.Time, mtime.Time) mtime.Time
 	switch ws.GetOutputTime() {
-	case pipepb.OutputTime_END_OF_WINDOW:
+	case pipepb.OutputTime_END_OF_WINDOW, pipepb.OutputTime_UNSPECIFIED:
 		outputTime = func(w typex.Window, _, _ mtime.Time) mtime.Time {
 			return w.MaxTimestamp()
 		}
@@ -359,7 +357,6 @@ func gbkBytes(ws *pipepb.WindowingStrategy, wc, kc, vc *pipepb.Coder, toAggregat
 			return cur
 		}
 	default:
-		// TODO need to correct session logic if output time is different.
 		panic(fmt.Sprintf("unsupported OutputTime behavior: %v", ws.GetOutputTime()))
 	}
 
@@ -413,53 +410,9 @@ func gbkBytes(ws *pipepb.WindowingStrategy, wc, kc, vc *pipepb.Coder, toAggregat
 		}
 	}
 
-	// If the strategy is session windows, then we need to get all the windows, sort them
-	// and see which ones need to be merged together.
-	// Each key has their windows merged separately.
-	if ws.GetWindowFn().GetUrn() == urns.WindowFnSession {
-		for _, windows := range keys {
-			ordered := make([]window.IntervalWindow, 0, len(windows))
-			for win := range windows {
-				ordered = append(ordered, win.(window.IntervalWindow))
-			}
-			// Use a decreasing sort (latest to earliest) so we can correct
-			// the output timestamp to the new end of window immeadiately.
-			sort.Slice(ordered, func(i, j int) bool {
-				return ordered[i].MaxTimestamp() > ordered[j].MaxTimestamp()
-			})
-
-			cur := ordered[0]
-			sessionData := windows[cur]
-			delete(windows, cur)
-			for _, iw := range ordered[1:] {
-				// GapSize is already incorporated into the windows,
-				// check for consecutive windows that don't overlap.
-				if cur.Start-iw.End > 0 {
-					// If so, this window is done, and we start a next window.
-					// Store current data with the current window.
-					windows[cur] = sessionData
-					// Use the incoming window instead, and clear it from the map.
-					cur = iw
-					sessionData = windows[iw]
-					delete(windows, cur)
-					// There's nothing to merge, since we've just started with this windowed data.
-					continue
-				}
-				// Extend the session with the incoming window, and merge the the incoming window's data.
-				cur.Start = iw.Start
-				toMerge := windows[iw]
-				delete(windows, iw)
-
-				// Ensure the output time matches the given function.
-				sessionData.time = outputTime(cur, toMerge.time, sessionData.time)
-				sessionData.key = toMerge.key
-				sessionData.w = cur
-				// TODO: May need to adjust the ordering here.
-				sessionData.values = append(sessionData.values, toMerge.values...)
-			}
-			windows[cur] = sessionData
-		}
-	}
+	// Merging windows, such as session windows, were already merged by the SDK's WindowFn
+	// as elements arrived at the stage, so elements carry the windows they merged into.
+
 	// Everything's aggregated!
 	// Time to turn things into a windowed KV<K, Iterable<V>>
 
@@ -88,14 +88,23 @@ type Job struct {
 	stateTime      time.Time
 	failureErr     error
 
+	// CheckpointDir is where snapshots of the job's execution state are written, if set.
+	CheckpointDir string
+	// RestoreFrom is a directory with a snapshot the job resumes from, if set.
+	RestoreFrom string
+
 	// Context used to terminate this job.
 	RootCtx  context.Context
 	CancelFn context.CancelCauseFunc
+
+	drainCh   chan struct{} // Closed when the job is requested to drain.
+	drainOnce sync.Once
 	// Logger for this job.
 	Logger *slog.Logger
 
-	metrics metricsStore
-	mw      *worker.MultiplexW
+	metrics       metricsStore
+	runnerMetrics atomic.Pointer[func() []*pipepb.MonitoringInfo]
+	mw            *worker.MultiplexW
 }
 
 func (j *Job) ArtifactEndpoint() string {
@@ -121,6 +130,13 @@ func (j *Job) AddMetricShortIDs(ids *fnpb.MonitoringInfosMetadataResponse) {
 	j.metrics.AddShortIDs(ids)
 }
 
+// SetRunnerMetrics sets a function reporting metrics from the runner itself, such
+// as bundle concurrency. They're reported with the attempted metrics of GetJobMetrics,
+// under prism specific URNs.
+func (j *Job) SetRunnerMetrics(fn func() []*pipepb.MonitoringInfo) {
+	j.runnerMetrics.Store(&fn)
+}
+
 func (j *Job) String() string {
 	return fmt.Sprintf("%v[%v]", j.key, j.jobName)
 }
@@ -194,6 +210,17 @@ func (j *Job) Canceled() {
 	j.sendState(jobpb.JobState_CANCELLED)
 }
 
+// Draining indicates that the job is draining, and signals the job to begin draining.
+func (j *Job) Draining() {
+	j.sendState(jobpb.JobState_DRAINING)
+	j.drainOnce.Do(func() { close(j.drainCh) })
+}
+
+// DrainRequested returns a channel that is closed when the job is requested to drain.
+func (j *Job) DrainRequested() <-chan struct{} {
+	return j.drainCh
+}
+
 // Failed indicates that the job completed unsuccessfully.
 func (j *Job) Failed(err error) {
 	slog.Error("job failed", slog.Any("job", j), slog.Any("error", err))
@@ -20,13 +20,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 
 	jobpb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/jobmanagement_v1"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal/urns"
-	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -83,6 +83,12 @@ func (s *Server) Prepare(ctx context.Context, req *jobpb.PrepareJobRequest) (_ *
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// A snapshot is for a single job, so later jobs are rejected rather than
+	// silently starting from scratch.
+	if s.restoreFrom != "" && s.restoredJob != "" {
+		return nil, fmt.Errorf("prism restores a single job from %v, and it was used by %v: restart prism without restore_from to run job %v", s.restoreFrom, s.restoredJob, req.GetJobName())
+	}
+
 	// Since jobs execute in the background, they should not be tied to a request's context.
 	rootCtx, cancelFn := context.WithCancelCause(context.Background())
 	// Wrap in a Once so it will only be invoked a single time for the job.
@@ -93,6 +99,7 @@ func (s *Server) Prepare(ctx context.Context, req *jobpb.PrepareJobRequest) (_ *
 		jobName:    req.GetJobName(),
 		options:    req.GetPipelineOptions(),
 		streamCond: sync.NewCond(&sync.Mutex{}),
+		drainCh:    make(chan struct{}),
 		RootCtx:    rootCtx,
 		CancelFn: func(err error) {
 			cancelFn(err)
@@ -102,6 +109,14 @@ func (s *Server) Prepare(ctx context.Context, req *jobpb.PrepareJobRequest) (_ *
 		artifactEndpoint: s.Endpoint(),
 		mw:               s.mw,
 	}
+	if s.checkpointDir != "" {
+		job.CheckpointDir = filepath.Join(s.checkpointDir, job.key)
+	}
+	if s.restoreFrom != "" {
+		job.RestoreFrom = s.restoreFrom
+		s.restoredJob = job.key
+	}
+
 	// Stop the idle timer when a new job appears.
 	if idleTimer := s.idleTimer.Load(); idleTimer != nil {
 		idleTimer.Stop()
@@ -133,7 +148,6 @@ func (s *Server) Prepare(ctx context.Context, req *jobpb.PrepareJobRequest) (_ *
 
 	// Inspect Transforms for unsupported features.
 	ts := job.Pipeline.GetComponents().GetTransforms()
-	var testStreamIds []string
 	for tid, t := range ts {
 		urn := t.GetSpec().GetUrn()
 		switch urn {
@@ -160,75 +174,16 @@ func (s *Server) Prepare(ctx context.Context, req *jobpb.PrepareJobRequest) (_ *
 				return nil, wrapped
 			}
 
-			isStateful := false
-
 			// Validate all the state features
 			for _, spec := range pardo.GetStateSpecs() {
-				isStateful = true
 				check("StateSpec.Protocol.Urn", spec.GetProtocol().GetUrn(),
 					urns.UserStateBag, urns.UserStateMultiMap, urns.UserStateOrderedList)
 			}
 			// Validate all the timer features
 			for _, spec := range pardo.GetTimerFamilySpecs() {
-				isStateful = true
 				check("TimerFamilySpecs.TimeDomain.Urn", spec.GetTimeDomain(), pipepb.TimeDomain_EVENT_TIME, pipepb.TimeDomain_PROCESSING_TIME)
 			}
 
-			// Check for a stateful SDF and direct user to https://github.com/apache/beam/issues/32139
-			if pardo.GetRestrictionCoderId() != "" && isStateful {
-				check("Splittable+Stateful DoFn", "See https://github.com/apache/beam/issues/32139 for information.")
-			}
-
-			// Validate whether the triggers on side inputs for are required for
-			// expedient data processing..
-			//
-			// Currently triggered side inputs are not supported by prism, and will
-			// not have early or late firings.
-			//
-			// This feature is required when the Side Input PCollection is unbounded
-			// and is in the a Global Window. This can cause the pipeline to fully
-			// stall while the input is being computed, and may never terminate.
-			//
-			// Other situations may not have desired results, but are valid behaviors
-			// within the model.
-			//
-			// See https://github.com/apache/beam/issues/31438 for implementation tracking.
-			for sideID := range pardo.GetSideInputs() {
-				pcolID := t.GetInputs()[sideID]
-				pcol := job.Pipeline.GetComponents().GetPcollections()[pcolID]
-				wsID := pcol.GetWindowingStrategyId()
-				ws := job.Pipeline.GetComponents().GetWindowingStrategies()[wsID]
-
-				if pcol.GetIsBounded() == pipepb.IsBounded_BOUNDED ||
-					ws.GetWindowFn().GetUrn() != urns.WindowFnGlobal {
-					continue
-				}
-
-				// Within the Unbounded GlobalWindow space is a nich of expressed
-				// user intent that they *do* want to wait for the end of the global
-				// window for output. We should permit these pipelines, as there
-				// is utility for this in testing situations anyway.
-				switch trig := ws.GetTrigger().GetTrigger().(type) {
-				case *pipepb.Trigger_Never_, *pipepb.Trigger_Default_:
-					// Only one firing, at the end of the global window, and is
-					// compatible with Prism's current execution.
-					continue
-				case *pipepb.Trigger_AfterEndOfWindow_:
-					if early := trig.AfterEndOfWindow.GetEarlyFirings(); early == nil || early.GetNever() != nil {
-						if ws.GetAllowedLateness() == 0 {
-							// Late configuration doesn't matter, and there are no early firings.
-							continue
-						}
-						if late := trig.AfterEndOfWindow.GetLateFirings(); late == nil || late.GetNever() != nil {
-							// Lateness allowed, but but no firings anyway.
-							continue
-						}
-					}
-				}
-
-				check("Unbounded GlobalWindow Triggered SideInput, are not currently supported by Prism. Sideinputs are only ready at end of window+allowed lateness. See https://github.com/apache/beam/issues/31438 for information.", prototext.Format(ws))
-			}
-
 		case urns.TransformTestStream:
 			var testStream pipepb.TestStreamPayload
 			if err := proto.Unmarshal(t.GetSpec().GetPayload(), &testStream); err != nil {
@@ -238,7 +193,6 @@ func (s *Server) Prepare(ctx context.Context, req *jobpb.PrepareJobRequest) (_ *
 			}
 
 			t.EnvironmentId = "" // Unset the environment, to ensure it's handled prism side.
-			testStreamIds = append(testStreamIds, tid)
 
 		default:
 			// Composites can often have some unknown urn, permit those.
@@ -270,27 +224,19 @@ func (s *Server) Prepare(ctx context.Context, req *jobpb.PrepareJobRequest) (_ *
 			check("PTransform.Spec.Urn", urn+" "+t.GetUniqueName(), "<doesn't exist>")
 		}
 	}
-	// At most one test stream per pipeline.
-	if len(testStreamIds) > 1 {
-		check("Multiple TestStream Transforms in Pipeline", testStreamIds)
-	}
-
 	// Inspect Windowing strategies for unsupported features.
 	for _, ws := range job.Pipeline.GetComponents().GetWindowingStrategies() {
 		// Both Closing behaviors are identical without additional trigger firings.
 		check("WindowingStrategy.ClosingBehaviour", ws.GetClosingBehavior(), pipepb.ClosingBehavior_EMIT_IF_NONEMPTY, pipepb.ClosingBehavior_EMIT_ALWAYS)
 		check("WindowingStrategy.AccumulationMode", ws.GetAccumulationMode(), pipepb.AccumulationMode_DISCARDING, pipepb.AccumulationMode_ACCUMULATING)
-		if ws.GetWindowFn().GetUrn() != urns.WindowFnSession {
-			check("WindowingStrategy.MergeStatus", ws.GetMergeStatus(), pipepb.MergeStatus_NON_MERGING)
-		} else if hasStatefulTriggers(ws.GetTrigger()) {
-			// Technically for any merging windows, but per the above, we only support session windows presently.
-			check("WindowingStrategy: Using stateful triggers with merging windows isn't currently supported in prism. See https://github.com/apache/beam/issues/31438 for information.", prototext.Format(ws))
+		if ws.GetMergeStatus() == pipepb.MergeStatus_NEEDS_MERGE {
+			// Merging is delegated to the SDK's WindowFn, but the engine needs interval windows.
+			wc := job.Pipeline.GetComponents().GetCoders()[ws.GetWindowCoderId()]
+			check("WindowingStrategy.WindowCoder for merging windows", wc.GetSpec().GetUrn(), urns.CoderIntervalWindow)
 		}
 		check("WindowingStrategy.OnTimeBehavior", ws.GetOnTimeBehavior(), pipepb.OnTimeBehavior_FIRE_IF_NONEMPTY, pipepb.OnTimeBehavior_FIRE_ALWAYS)
 
-		// Allow earliest and latest in pane to unblock running python tasks.
-		// Tests actually using the set behavior will fail.
-		check("WindowingStrategy.OutputTime", ws.GetOutputTime(), pipepb.OutputTime_END_OF_WINDOW,
+		check("WindowingStrategy.OutputTime", ws.GetOutputTime(), pipepb.OutputTime_UNSPECIFIED, pipepb.OutputTime_END_OF_WINDOW,
 			pipepb.OutputTime_EARLIEST_IN_PANE, pipepb.OutputTime_LATEST_IN_PANE)
 
 		if hasUnsupportedTriggers(ws.GetTrigger()) {
@@ -316,8 +262,15 @@ func (s *Server) Prepare(ctx context.Context, req *jobpb.PrepareJobRequest) (_ *
 func hasUnsupportedTriggers(tpb *pipepb.Trigger) bool {
 	unsupported := false
 	switch at := tpb.GetTrigger().(type) {
-	case *pipepb.Trigger_AfterProcessingTime_, *pipepb.Trigger_AfterSynchronizedProcessingTime_:
-		return true
+	case *pipepb.Trigger_AfterProcessingTime_:
+		for _, tt := range at.AfterProcessingTime.GetTimestampTransforms() {
+			switch tt.GetTimestampTransform().(type) {
+			case *pipepb.TimestampTransform_Delay_, *pipepb.TimestampTransform_AlignTo_:
+			default:
+				return true
+			}
+		}
+		return false
 	case *pipepb.Trigger_AfterAll_:
 		for _, st := range at.AfterAll.GetSubtriggers() {
 			unsupported = unsupported || hasUnsupportedTriggers(st)
@@ -346,42 +299,6 @@ func hasUnsupportedTriggers(tpb *pipepb.Trigger) bool {
 	}
 }
 
-// hasStatefulTriggers checks if the triggers use state that might not function
-// properly without proper merge handling.
-func hasStatefulTriggers(tpb *pipepb.Trigger) bool {
-	stateful := false
-	switch at := tpb.GetTrigger().(type) {
-	case *pipepb.Trigger_AfterProcessingTime_, *pipepb.Trigger_AfterSynchronizedProcessingTime_, *pipepb.Trigger_ElementCount_:
-		// These triggers have state that needs care when used with merging windows.
-		return true
-	case *pipepb.Trigger_AfterAll_:
-		for _, st := range at.AfterAll.GetSubtriggers() {
-			stateful = stateful || hasStatefulTriggers(st)
-		}
-		return stateful
-	case *pipepb.Trigger_AfterAny_:
-		for _, st := range at.AfterAny.GetSubtriggers() {
-			stateful = stateful || hasStatefulTriggers(st)
-		}
-		return stateful
-	case *pipepb.Trigger_AfterEach_:
-		for _, st := range at.AfterEach.GetSubtriggers() {
-			stateful = stateful || hasStatefulTriggers(st)
-		}
-		return stateful
-	case *pipepb.Trigger_AfterEndOfWindow_:
-		return hasStatefulTriggers(at.AfterEndOfWindow.GetEarlyFirings()) ||
-			hasStatefulTriggers(at.AfterEndOfWindow.GetLateFirings())
-	case *pipepb.Trigger_OrFinally_:
-		return hasStatefulTriggers(at.OrFinally.GetMain()) ||
-			hasStatefulTriggers(at.OrFinally.GetFinally())
-	case *pipepb.Trigger_Repeat_:
-		return hasStatefulTriggers(at.Repeat.GetSubtrigger())
-	default:
-		return false
-	}
-}
-
 func (s *Server) Run(ctx context.Context, req *jobpb.RunJobRequest) (*jobpb.RunJobResponse, error) {
 	s.mu.Lock()
 	job := s.jobs[req.GetPreparationId()]
@@ -420,6 +337,34 @@ func (s *Server) Cancel(_ context.Context, req *jobpb.CancelJobRequest) (*jobpb.
 	}, nil
 }
 
+// Drain a Job requested by the DrainJobRequest for jobs not in an already terminal state.
+// Otherwise, returns nil if Job does not exist or the Job's existing state as part of the DrainJobResponse.
+//
+// Draining jobs truncate the restrictions of splittable DoFns, and advance watermarks to
+// infinity, firing all remaining timers and windows before the job terminates as DONE.
+func (s *Server) Drain(_ context.Context, req *jobpb.DrainJobRequest) (*jobpb.DrainJobResponse, error) {
+	s.mu.Lock()
+	job, ok := s.jobs[req.GetJobId()]
+	s.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	state := job.state.Load().(jobpb.JobState_Enum)
+	switch state {
+	case jobpb.JobState_CANCELLED, jobpb.JobState_DONE, jobpb.JobState_DRAINED, jobpb.JobState_UPDATED, jobpb.JobState_FAILED,
+		jobpb.JobState_CANCELLING, jobpb.JobState_DRAINING:
+		// Already at, or moving to, a terminal state.
+		return &jobpb.DrainJobResponse{
+			State: state,
+		}, nil
+	}
+	job.SendMsg("draining " + job.String())
+	job.Draining()
+	return &jobpb.DrainJobResponse{
+		State: jobpb.JobState_DRAINING,
+	}, nil
+}
+
 // GetMessageStream subscribes to a stream of state changes and messages from the job. If throughput
 // is high, this may cause losses of messages.
 func (s *Server) GetMessageStream(req *jobpb.JobMessagesRequest, stream jobpb.JobService_GetMessageStreamServer) error {
@@ -500,9 +445,14 @@ func (s *Server) GetJobMetrics(ctx context.Context, req *jobpb.GetJobMetricsRequ
 	if j == nil {
 		return nil, fmt.Errorf("GetJobMetrics: unknown jobID: %v", req.GetJobId())
 	}
+	attempted := j.metrics.Results(tentative)
+	if fn := j.runnerMetrics.Load(); fn != nil {
+		// Runner metrics use prism specific URNs, which clients that don't know them skip.
+		attempted = append(attempted, (*fn)()...)
+	}
 	return &jobpb.GetJobMetricsResponse{
 		Metrics: &jobpb.MetricResults{
-			Attempted: j.metrics.Results(tentative),
+			Attempted: attempted,
 			Committed: j.metrics.Results(committed),
 		},
 	}, nil
@@ -209,6 +209,42 @@ func TestServer(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:         "Draining",
+			postRunState: jobpb.JobState_RUNNING,
+			noJobsCheck: func(ctx context.Context, t *testing.T, undertest *Server) {
+				id := "job-001"
+				_, err := undertest.Drain(ctx, &jobpb.DrainJobRequest{JobId: id})
+				// Drain currently returns nil, nil when Job not found
+				if err != nil {
+					t.Errorf("Drain(%q) = %v, want not found error", id, err)
+				}
+			},
+			postPrepCheck: func(ctx context.Context, t *testing.T, undertest *Server) {
+				id := "job-001"
+				resp, err := undertest.Drain(ctx, &jobpb.DrainJobRequest{JobId: id})
+				if err != nil {
+					t.Errorf("Drain(%q) = %v, want not found error", id, err)
+				}
+				if diff := cmp.Diff(&jobpb.DrainJobResponse{
+					State: jobpb.JobState_DRAINING,
+				}, resp, cmpOpts...); diff != "" {
+					t.Errorf("Drain(%q) (-want, +got):\n%s", id, diff)
+				}
+			},
+			postRunCheck: func(ctx context.Context, t *testing.T, undertest *Server, jobID string) {
+				id := "job-001"
+				resp, err := undertest.Drain(ctx, &jobpb.DrainJobRequest{JobId: id})
+				if err != nil {
+					t.Errorf("Drain(%q) = %v, want not found error", id, err)
+				}
+				if diff := cmp.Diff(&jobpb.DrainJobResponse{
+					State: jobpb.JobState_DRAINING,
+				}, resp, cmpOpts...); diff != "" {
+					t.Errorf("Drain(%q) (-want, +got):\n%s", id, diff)
+				}
+			},
+		},
 	}
 	for _, test := range tests {
 		var called sync.WaitGroup
@@ -269,6 +305,113 @@ func TestServer(t *testing.T) {
 	}
 }
 
+func TestPrepare_MergingWindowFns(t *testing.T) {
+	tests := []struct {
+		name    string
+		urn     string
+		coder   string
+		wantErr bool
+	}{
+		{name: "sessions", urn: urns.WindowFnSession, coder: urns.CoderIntervalWindow},
+		{name: "custom", urn: "beam:window_fn:custom_merging:v1", coder: urns.CoderIntervalWindow},
+		{name: "customWindowCoder", urn: "beam:window_fn:custom_merging:v1", coder: urns.CoderCustomWindow, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			undertest := NewServer(0, func(j *Job) {})
+			pipeline := &pipepb.Pipeline{
+				Components: &pipepb.Components{
+					WindowingStrategies: map[string]*pipepb.WindowingStrategy{
+						"ws": {
+							WindowFn:         &pipepb.FunctionSpec{Urn: test.urn},
+							MergeStatus:      pipepb.MergeStatus_NEEDS_MERGE,
+							WindowCoderId:    "wc",
+							Trigger:          &pipepb.Trigger{Trigger: &pipepb.Trigger_Default_{Default: &pipepb.Trigger_Default{}}},
+							AccumulationMode: pipepb.AccumulationMode_DISCARDING,
+							ClosingBehavior:  pipepb.ClosingBehavior_EMIT_IF_NONEMPTY,
+							OnTimeBehavior:   pipepb.OnTimeBehavior_FIRE_IF_NONEMPTY,
+						},
+					},
+					Coders: map[string]*pipepb.Coder{
+						"wc": {Spec: &pipepb.FunctionSpec{Urn: test.coder}},
+					},
+				},
+			}
+			_, err := undertest.Prepare(context.Background(), &jobpb.PrepareJobRequest{
+				Pipeline: pipeline,
+				JobName:  "testJob",
+			})
+			if (err != nil) != test.wantErr {
+				t.Errorf("Prepare() with merging WindowFn %v = %v, wantErr %v", test.urn, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestPrepare_RestoreFromSingleJob(t *testing.T) {
+	undertest := NewServer(0, func(j *Job) {})
+	undertest.Checkpoint("", "/snapshot/job-001")
+	prepare := func(name string) (*Job, error) {
+		resp, err := undertest.Prepare(context.Background(), &jobpb.PrepareJobRequest{
+			Pipeline: &pipepb.Pipeline{},
+			JobName:  name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return undertest.getJob(resp.GetPreparationId()), nil
+	}
+	first, err := prepare("first")
+	if err != nil {
+		t.Fatalf("Prepare(first) = %v, want nil", err)
+	}
+	if got, want := first.RestoreFrom, "/snapshot/job-001"; got != want {
+		t.Errorf("first job RestoreFrom = %q, want %q", got, want)
+	}
+	if _, err := prepare("second"); err == nil {
+		t.Errorf("Prepare(second) succeeded, want error since the snapshot was used by the first job")
+	}
+}
+
+func TestGetJobMetrics_RunnerMetrics(t *testing.T) {
+	runnerMetric := &pipepb.MonitoringInfo{
+		Urn:     urns.MetricStagePeakBundles,
+		Type:    metricsx.UrnToType(metricsx.UrnUserSumInt64),
+		Payload: []byte("\x02"),
+		Labels:  map[string]string{"PTRANSFORM": "id"},
+	}
+	var called sync.WaitGroup
+	called.Add(1)
+	ctx, _, clientConn := serveTestServer(t, func(j *Job) {
+		j.SetRunnerMetrics(func() []*pipepb.MonitoringInfo {
+			return []*pipepb.MonitoringInfo{runnerMetric}
+		})
+		called.Done()
+	})
+	jobCli := jobpb.NewJobServiceClient(clientConn)
+
+	prepResp, err := jobCli.Prepare(ctx, &jobpb.PrepareJobRequest{
+		Pipeline: &pipepb.Pipeline{},
+		JobName:  "testJob",
+	})
+	if err != nil {
+		t.Fatalf("Prepare() = %v, want nil", err)
+	}
+	runResp, err := jobCli.Run(ctx, &jobpb.RunJobRequest{PreparationId: prepResp.GetPreparationId()})
+	if err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	called.Wait()
+
+	resp, err := jobCli.GetJobMetrics(ctx, &jobpb.GetJobMetricsRequest{JobId: runResp.GetJobId()})
+	if err != nil {
+		t.Fatalf("GetJobMetrics() = %v, want nil", err)
+	}
+	if diff := cmp.Diff([]*pipepb.MonitoringInfo{runnerMetric}, resp.GetMetrics().GetAttempted(), protocmp.Transform()); diff != "" {
+		t.Errorf("GetJobMetrics() attempted (-want, +got):\n%v", diff)
+	}
+}
+
 func TestGetMessageStream(t *testing.T) {
 	wantName := "testJob"
 	wantPipeline := &pipepb.Pipeline{
@@ -26,6 +26,7 @@ import (
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/metricsx"
 	fnpb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/fnexecution_v1"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
 	"golang.org/x/exp/constraints"
@@ -183,7 +184,8 @@ func buildUrnToOpsMap(mUrn2Spec map[string]*pipepb.MonitoringInfoSpec) map[strin
 		getMetTyp(pipepb.MonitoringInfoTypeUrns_SET_STRING_TYPE): func() metricAccumulator {
 			return &stringSet{set: map[string]struct{}{}}
 		},
-		getMetTyp(pipepb.MonitoringInfoTypeUrns_PROGRESS_TYPE): func() metricAccumulator { return &progress{} },
+		getMetTyp(pipepb.MonitoringInfoTypeUrns_BOUNDED_TRIE_TYPE): func() metricAccumulator { return &boundedTrie{} },
+		getMetTyp(pipepb.MonitoringInfoTypeUrns_PROGRESS_TYPE):     func() metricAccumulator { return &progress{} },
 	}
 
 	ret := make(map[string]urnOps)
@@ -468,6 +470,32 @@ func (m *stringSet) toProto(key metricKey) *pipepb.MonitoringInfo {
 	}
 }
 
+type boundedTrie struct {
+	trie metrics.BoundedTrieValue
+}
+
+func (m *boundedTrie) accumulate(pyld []byte) error {
+	v, err := metricsx.DecodeBoundedTrie(pyld)
+	if err != nil {
+		return err
+	}
+	m.trie = m.trie.Merge(v)
+	return nil
+}
+
+func (m *boundedTrie) toProto(key metricKey) *pipepb.MonitoringInfo {
+	payload, err := metricsx.BoundedTrie(m.trie)
+	if err != nil {
+		panic(fmt.Sprintf("error encoding bounded trie: %v", err))
+	}
+	return &pipepb.MonitoringInfo{
+		Urn:     key.Urn(),
+		Type:    getMetTyp(pipepb.MonitoringInfoTypeUrns_BOUNDED_TRIE_TYPE),
+		Payload: payload,
+		Labels:  key.Labels(),
+	}
+}
+
 type durability int
 
 const (
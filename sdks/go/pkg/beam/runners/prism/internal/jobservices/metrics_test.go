@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/metrics"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/metricsx"
 	fnpb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/fnexecution_v1"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
 	"github.com/google/go-cmp/cmp"
@@ -62,6 +64,14 @@ func Test_metricsStore_ContributeMetrics(t *testing.T) {
 		return b
 	}
 
+	boundedTrie := func(bound int, paths ...metrics.BoundedTriePath) []byte {
+		b, err := metricsx.BoundedTrie(metrics.BoundedTrieValue{Bound: bound, Paths: paths})
+		if err != nil {
+			t.Fatalf("metricsx.BoundedTrie(%v) = %v", paths, err)
+		}
+		return b
+	}
+
 	progress := func(vs ...float64) []byte {
 		var buf bytes.Buffer
 		coder.EncodeInt32(int32(len(vs)), &buf)
@@ -166,6 +176,21 @@ func Test_metricsStore_ContributeMetrics(t *testing.T) {
 			want: []*pipepb.MonitoringInfo{
 				makeInfoWBytes(pipepb.MonitoringInfoSpecs_USER_SET_STRING, []byte{0, 0, 0, 1, 1, 63}),
 			},
+		}, {
+			name: "boundedTrie",
+			input: []map[string][]byte{
+				{"a": boundedTrie(2, metrics.BoundedTriePath{Segments: []string{"a", "b"}})},
+				{"a": boundedTrie(2, metrics.BoundedTriePath{Segments: []string{"c"}})},
+				{"a": boundedTrie(2, metrics.BoundedTriePath{Segments: []string{"a", "d"}})},
+			},
+			shortIDs: map[string]*pipepb.MonitoringInfo{
+				"a": makeInfo(pipepb.MonitoringInfoSpecs_USER_BOUNDED_TRIE),
+			},
+			want: []*pipepb.MonitoringInfo{
+				makeInfoWBytes(pipepb.MonitoringInfoSpecs_USER_BOUNDED_TRIE, boundedTrie(2,
+					metrics.BoundedTriePath{Segments: []string{"a"}, Truncated: true},
+					metrics.BoundedTriePath{Segments: []string{"c"}})),
+			},
 		},
 	}
 
@@ -56,6 +56,11 @@ type Server struct {
 	cancelFn           context.CancelCauseFunc
 	logger             *slog.Logger
 
+	// Checkpoint management.
+	checkpointDir string // Jobs write snapshots to a subdirectory named after the job.
+	restoreFrom   string // The first prepared job is restored from this snapshot directory.
+	restoredJob   string // The key of the job restored from restoreFrom. Later jobs are rejected.
+
 	// execute defines how a job is executed.
 	execute func(*Job)
 
@@ -130,6 +135,17 @@ func (s *Server) IdleShutdown(timeout time.Duration, cancelFn context.CancelCaus
 	s.idleTimer.Store(time.AfterFunc(timeout, s.idleShutdownCallback))
 }
 
+// Checkpoint configures jobs to write snapshots of their execution state to a subdirectory
+// of checkpointDir named after the job, and the first prepared job to resume from
+// the snapshot in restoreFrom. Since a snapshot is for a single job, jobs prepared
+// after the restored job are rejected. Either may be empty to disable that behavior.
+func (s *Server) Checkpoint(checkpointDir, restoreFrom string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpointDir = checkpointDir
+	s.restoreFrom = restoreFrom
+}
+
 // idleShutdownCallback is called by the AfterFunc timer for idle shutdown.
 func (s *Server) idleShutdownCallback() {
 	index := atomic.LoadUint32(&s.index)
@@ -0,0 +1,252 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	fnpb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/fnexecution_v1"
+	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal/engine"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal/urns"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal/worker"
+	"google.golang.org/protobuf/proto"
+)
+
+// IDs of the components of MergeWindows bundle descriptors.
+const (
+	mergeSourceID   = "merge_windows_source"
+	mergeSinkID     = "merge_windows_sink"
+	mergeInputID    = "merge_windows_input"
+	mergeOutputID   = "merge_windows_output"
+	mergeGlobalWSID = "merge_windows_global"
+)
+
+// windowMerger returns an engine.WindowMerger that merges windows with the strategy's
+// WindowFn, by executing the MergeWindows transform on the SDK worker of the
+// strategy's environment. This way the SDK determines which windows merge, for any
+// merging WindowFn, including custom ones.
+//
+// Following the MergeWindows spec, each key is sent as a nonce keying its windows,
+// as KV<nonce, iterable<window>> in the global window, and the SDK responds with
+// KV<nonce, KV<iterable<unmerged window>, iterable<KV<merged window, iterable<consumed window>>>>>.
+func windowMerger(ctx context.Context, stageID string, ws *pipepb.WindowingStrategy, comps *pipepb.Components, wks map[string]*worker.W) (engine.WindowMerger, error) {
+	wk, ok := wks[ws.GetEnvironmentId()]
+	if !ok {
+		return nil, fmt.Errorf("no environment %q to merge windows with WindowFn %v", ws.GetEnvironmentId(), ws.GetWindowFn().GetUrn())
+	}
+	// The engine only handles interval windows for merging strategies, which is
+	// validated when the job is prepared.
+	wcID := ws.GetWindowCoderId()
+	coders := map[string]*pipepb.Coder{
+		wcID: comps.GetCoders()[wcID],
+	}
+	addCoder := func(id, urn string, components ...string) string {
+		coders[id] = &pipepb.Coder{
+			Spec:              &pipepb.FunctionSpec{Urn: urn},
+			ComponentCoderIds: components,
+		}
+		return id
+	}
+	bytesID := addCoder("merge_windows_bytes", urns.CoderBytes)
+	globalID := addCoder("merge_windows_global_window", urns.CoderGlobalWindow)
+	windowsID := addCoder("merge_windows_windows", urns.CoderIterable, wcID)
+	inID := addCoder("merge_windows_in", urns.CoderKV, bytesID, windowsID)
+	mergedID := addCoder("merge_windows_merged", urns.CoderKV, wcID, windowsID)
+	resultID := addCoder("merge_windows_result", urns.CoderKV, windowsID,
+		addCoder("merge_windows_merges", urns.CoderIterable, mergedID))
+	outID := addCoder("merge_windows_out", urns.CoderKV, bytesID, resultID)
+	wInID := addCoder("merge_windows_windowed_in", urns.CoderWindowedValue, inID, globalID)
+	wOutID := addCoder("merge_windows_windowed_out", urns.CoderWindowedValue, outID, globalID)
+
+	fnBytes, err := proto.Marshal(ws.GetWindowFn())
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal WindowFn %v: %w", ws.GetWindowFn().GetUrn(), err)
+	}
+	descID := stageID + "_merge_windows"
+	wk.Descriptors[descID] = &fnpb.ProcessBundleDescriptor{
+		Id: descID,
+		Transforms: map[string]*pipepb.PTransform{
+			mergeSourceID: sourceTransform(mergeSourceID, portFor(wInID, wk), mergeInputID),
+			descID: {
+				UniqueName:    descID,
+				Spec:          &pipepb.FunctionSpec{Urn: urns.TransformMergeWindows, Payload: fnBytes},
+				Inputs:        map[string]string{"i0": mergeInputID},
+				Outputs:       map[string]string{"i0": mergeOutputID},
+				EnvironmentId: ws.GetEnvironmentId(),
+			},
+			mergeSinkID: sinkTransform(mergeSinkID, portFor(wOutID, wk), mergeOutputID),
+		},
+		Pcollections: map[string]*pipepb.PCollection{
+			mergeInputID:  {UniqueName: mergeInputID, CoderId: inID, WindowingStrategyId: mergeGlobalWSID, IsBounded: pipepb.IsBounded_BOUNDED},
+			mergeOutputID: {UniqueName: mergeOutputID, CoderId: outID, WindowingStrategyId: mergeGlobalWSID, IsBounded: pipepb.IsBounded_BOUNDED},
+		},
+		WindowingStrategies: map[string]*pipepb.WindowingStrategy{
+			mergeGlobalWSID: {
+				WindowFn:         &pipepb.FunctionSpec{Urn: urns.WindowFnGlobal},
+				MergeStatus:      pipepb.MergeStatus_NON_MERGING,
+				WindowCoderId:    globalID,
+				Trigger:          &pipepb.Trigger{Trigger: &pipepb.Trigger_Default_{Default: &pipepb.Trigger_Default{}}},
+				AccumulationMode: pipepb.AccumulationMode_DISCARDING,
+				OutputTime:       pipepb.OutputTime_END_OF_WINDOW,
+				ClosingBehavior:  pipepb.ClosingBehavior_EMIT_ALWAYS,
+				OnTimeBehavior:   pipepb.OnTimeBehavior_FIRE_ALWAYS,
+				EnvironmentId:    ws.GetEnvironmentId(),
+			},
+		},
+		Coders: coders,
+	}
+
+	return func(windows map[string][]typex.Window) (map[string]map[typex.Window][]typex.Window, error) {
+		b := &worker.B{
+			PBDID:  descID,
+			InstID: wk.NextInst(),
+
+			InputTransformID: mergeSourceID,
+			Input: []*engine.Block{{
+				Kind:  engine.BlockData,
+				Bytes: [][]byte{encodeMergeWindowsInput(windows)},
+			}},
+
+			SinkToPCollection: map[string]string{mergeSinkID: mergeOutputID},
+			OutputCount:       1,
+		}
+		b.Init()
+		defer b.Cleanup(wk)
+		dataReady := b.ProcessOn(ctx, wk)
+		resp := b.Resp
+		for dataReady != nil || resp != nil {
+			select {
+			case <-ctx.Done():
+				return nil, context.Cause(ctx)
+			case <-dataReady:
+				dataReady = nil
+			case <-resp:
+				if b.BundleErr != nil {
+					return nil, b.BundleErr
+				}
+				resp = nil
+			}
+		}
+		return decodeMergeWindowsOutput(bytes.Join(b.OutputData.Raw[mergeOutputID], nil))
+	}, nil
+}
+
+var (
+	globalWindowEnc   = exec.MakeWindowEncoder(coder.NewGlobalWindow())
+	globalWindowDec   = exec.MakeWindowDecoder(coder.NewGlobalWindow())
+	intervalWindowEnc = exec.MakeWindowEncoder(coder.NewIntervalWindow())
+	intervalWindowDec = exec.MakeWindowDecoder(coder.NewIntervalWindow())
+)
+
+// encodeMergeWindowsInput encodes each key's windows as a windowed KV<nonce, iterable<window>>,
+// using the key as the nonce.
+func encodeMergeWindowsInput(windows map[string][]typex.Window) []byte {
+	var buf bytes.Buffer
+	for key, ws := range windows {
+		exec.EncodeWindowedValueHeader(globalWindowEnc, window.SingleGlobalWindow, mtime.MinTimestamp, typex.NoFiringPane(), &buf)
+		coder.EncodeBytes([]byte(key), &buf)
+		coder.EncodeInt32(int32(len(ws)), &buf)
+		for _, w := range ws {
+			intervalWindowEnc.EncodeSingle(w, &buf)
+		}
+	}
+	return buf.Bytes()
+}
+
+// decodeMergeWindowsOutput decodes the merged windows of each key, from windowed
+// KV<nonce, KV<iterable<unmerged window>, iterable<KV<merged window, iterable<consumed window>>>>>.
+func decodeMergeWindowsOutput(data []byte) (map[string]map[typex.Window][]typex.Window, error) {
+	merges := map[string]map[typex.Window][]typex.Window{}
+	r := bytes.NewReader(data)
+	for {
+		if _, _, _, err := exec.DecodeWindowedValueHeader(globalWindowDec, r); err == io.EOF {
+			return merges, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to decode merged windows: %w", err)
+		}
+		key, err := coder.DecodeBytes(r)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode merged windows nonce: %w", err)
+		}
+		// Windows that didn't merge need no changes.
+		if err := decodeIterable(r, func() error {
+			_, err := intervalWindowDec.DecodeSingle(r)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("unable to decode unmerged windows: %w", err)
+		}
+		byMerged := map[typex.Window][]typex.Window{}
+		if err := decodeIterable(r, func() error {
+			merged, err := intervalWindowDec.DecodeSingle(r)
+			if err != nil {
+				return err
+			}
+			return decodeIterable(r, func() error {
+				w, err := intervalWindowDec.DecodeSingle(r)
+				if err != nil {
+					return err
+				}
+				byMerged[merged] = append(byMerged[merged], w)
+				return nil
+			})
+		}); err != nil {
+			return nil, fmt.Errorf("unable to decode merged windows: %w", err)
+		}
+		if len(byMerged) > 0 {
+			merges[string(key)] = byMerged
+		}
+	}
+}
+
+// decodeIterable calls decodeElm for each element of an encoded iterable, which
+// is either length prefixed, or a sequence of length prefixed chunks ending in 0.
+func decodeIterable(r io.Reader, decodeElm func() error) error {
+	n, err := coder.DecodeInt32(r)
+	if err != nil {
+		return err
+	}
+	if n >= 0 {
+		for i := int32(0); i < n; i++ {
+			if err := decodeElm(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for {
+		chunk, err := coder.DecodeVarInt(r)
+		if err != nil {
+			return err
+		}
+		if chunk == 0 {
+			return nil
+		}
+		for i := int64(0); i < chunk; i++ {
+			if err := decodeElm(); err != nil {
+				return err
+			}
+		}
+	}
+}
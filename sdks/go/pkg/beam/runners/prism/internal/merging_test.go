@@ -0,0 +1,103 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/exec"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/typex"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeWindowsInput(t *testing.T) {
+	iw := func(start, end mtime.Time) typex.Window {
+		return window.IntervalWindow{Start: start, End: end}
+	}
+	windows := []typex.Window{iw(0, 10), iw(50, 60)}
+	data := encodeMergeWindowsInput(map[string][]typex.Window{"key": windows})
+
+	// Decode as the SDK would, with the input coder.
+	c := coder.NewW(coder.NewKV([]*coder.Coder{coder.NewBytes(), coder.NewI(coder.NewIntervalWindowCoder())}), coder.NewGlobalWindow())
+	got, err := exec.MakeElementDecoder(c).Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode input: %v", err)
+	}
+	if d := cmp.Diff("key", string(got.Elm.([]byte))); d != "" {
+		t.Errorf("nonce diff (-want, +got):\n%v", d)
+	}
+	if d := cmp.Diff(windows, got.Elm2); d != "" {
+		t.Errorf("windows diff (-want, +got):\n%v", d)
+	}
+}
+
+func TestDecodeMergeWindowsOutput(t *testing.T) {
+	iw := func(start, end mtime.Time) typex.Window {
+		return window.IntervalWindow{Start: start, End: end}
+	}
+	encodeWindows := func(buf *bytes.Buffer, ws ...typex.Window) {
+		coder.EncodeInt32(int32(len(ws)), buf)
+		for _, w := range ws {
+			intervalWindowEnc.EncodeSingle(w, buf)
+		}
+	}
+	var buf bytes.Buffer
+	// A WindowFn that merges disjoint windows, unlike sessions.
+	exec.EncodeWindowedValueHeader(globalWindowEnc, window.SingleGlobalWindow, mtime.MinTimestamp, typex.NoFiringPane(), &buf)
+	coder.EncodeBytes([]byte("a"), &buf)
+	encodeWindows(&buf, iw(20, 30))
+	coder.EncodeInt32(1, &buf)
+	intervalWindowEnc.EncodeSingle(iw(0, 60), &buf)
+	encodeWindows(&buf, iw(0, 10), iw(50, 60))
+
+	// Iterables may also be encoded in chunks.
+	exec.EncodeWindowedValueHeader(globalWindowEnc, window.SingleGlobalWindow, mtime.MinTimestamp, typex.NoFiringPane(), &buf)
+	coder.EncodeBytes([]byte("b"), &buf)
+	coder.EncodeInt32(-1, &buf)
+	coder.EncodeVarInt(0, &buf)
+	coder.EncodeInt32(-1, &buf)
+	coder.EncodeVarInt(1, &buf)
+	intervalWindowEnc.EncodeSingle(iw(100, 130), &buf)
+	coder.EncodeInt32(-1, &buf)
+	coder.EncodeVarInt(1, &buf)
+	intervalWindowEnc.EncodeSingle(iw(100, 110), &buf)
+	coder.EncodeVarInt(1, &buf)
+	intervalWindowEnc.EncodeSingle(iw(120, 130), &buf)
+	coder.EncodeVarInt(0, &buf)
+	coder.EncodeVarInt(0, &buf)
+
+	// Keys without merges are omitted.
+	exec.EncodeWindowedValueHeader(globalWindowEnc, window.SingleGlobalWindow, mtime.MinTimestamp, typex.NoFiringPane(), &buf)
+	coder.EncodeBytes([]byte("c"), &buf)
+	encodeWindows(&buf, iw(0, 10))
+	coder.EncodeInt32(0, &buf)
+
+	got, err := decodeMergeWindowsOutput(buf.Bytes())
+	if err != nil {
+		t.Fatalf("decodeMergeWindowsOutput() = %v, want nil error", err)
+	}
+	want := map[string]map[typex.Window][]typex.Window{
+		"a": {iw(0, 60): {iw(0, 10), iw(50, 60)}},
+		"b": {iw(100, 130): {iw(100, 110), iw(120, 130)}},
+	}
+	if d := cmp.Diff(want, got); d != "" {
+		t.Errorf("decodeMergeWindowsOutput() diff (-want, +got):\n%v", d)
+	}
+}
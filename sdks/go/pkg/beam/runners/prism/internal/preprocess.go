@@ -457,10 +457,13 @@ func finalizeStage(stg *stage, comps *pipepb.Components, pipelineFacts *fusionFa
 				if pardo.GetRequestsFinalization() {
 					stg.finalize = true
 				}
-				if len(pardo.GetTimerFamilySpecs())+len(pardo.GetStateSpecs())+len(pardo.GetOnWindowExpirationTimerFamilySpec()) > 0 {
+				// The expanded SDF components carry the original payload, but only the element
+				// processing transform uses state and timers.
+				processesElements := t.GetSpec().GetUrn() == urns.TransformParDo || t.GetSpec().GetUrn() == urns.TransformProcessSizedElements
+				if processesElements && len(pardo.GetTimerFamilySpecs())+len(pardo.GetStateSpecs())+len(pardo.GetOnWindowExpirationTimerFamilySpec()) > 0 {
 					stg.stateful = true
 				}
-				if pardo.GetOnWindowExpirationTimerFamilySpec() != "" {
+				if processesElements && pardo.GetOnWindowExpirationTimerFamilySpec() != "" {
 					stg.onWindowExpiration = engine.StaticTimerID{TransformID: link.Transform, TimerFamily: pardo.GetOnWindowExpirationTimerFamilySpec()}
 				}
 				sis = pardo.GetSideInputs()
@@ -74,6 +74,10 @@ type stage struct {
 	hasTimers            []engine.StaticTimerID
 	processingTimeTimers map[string]bool
 
+	// triggeredSideInputs are side inputs that publish each trigger firing,
+	// rather than only being ready at the end of their window.
+	triggeredSideInputs []engine.LinkID
+
 	// stateTypeLen maps state values to encoded lengths for the type.
 	// Only used for OrderedListState which must manipulate individual state datavalues.
 	stateTypeLen map[engine.LinkID]func([]byte) int
@@ -82,6 +86,7 @@ type stage struct {
 	inputTransformID string
 	inputInfo        engine.PColInfo
 	desc             *fnpb.ProcessBundleDescriptor
+	drainDescID      string // Descriptor used while draining, which truncates restrictions. Empty if unneeded.
 	prepareSides     func(b *worker.B, watermark mtime.Time)
 
 	SinkToPCollection map[string]string
@@ -142,8 +147,12 @@ func (s *stage) Execute(ctx context.Context, j *jobservices.Job, wk *worker.W, c
 		dataReady = closed
 	case wk.Env:
 		input, estimatedElements := em.DataAndTimerInputForBundle(rb, s.inputInfo)
+		pbdID := s.ID
+		if s.drainDescID != "" && em.Draining() {
+			pbdID = s.drainDescID
+		}
 		b = &worker.B{
-			PBDID:  s.ID,
+			PBDID:  pbdID,
 			InstID: rb.BundleID,
 
 			InputTransformID: s.inputTransformID,
@@ -412,12 +421,17 @@ func buildDescriptor(stg *stage, comps *pipepb.Components, wk *worker.W, em *eng
 	}
 
 	// Update coders for Stateful transforms.
+	var sizedInput bool // Whether the primary input is a sized element and restriction for an SDF.
 	for _, tid := range stg.transforms {
 		t := comps.GetTransforms()[tid]
 
 		transforms[tid] = t
 
-		if t.GetSpec().GetUrn() != urns.TransformParDo {
+		switch t.GetSpec().GetUrn() {
+		case urns.TransformParDo:
+		case urns.TransformProcessSizedElements, urns.TransformTruncate:
+			sizedInput = true
+		default:
 			continue
 		}
 
@@ -534,7 +548,9 @@ func buildDescriptor(stg *stage, comps *pipepb.Components, wk *worker.W, em *eng
 		ed := collectionPullDecoder(col.GetCoderId(), coders, comps)
 
 		var kd func(io.Reader) []byte
-		if kcid, ok := extractKVCoderID(col.GetCoderId(), coders); ok {
+		producerURN := comps.GetTransforms()[o.Transform].GetSpec().GetUrn()
+		sizedOutput := producerURN == urns.TransformSplitAndSize || producerURN == urns.TransformTruncate
+		if kcid, ok := extractKeyCoderID(col.GetCoderId(), coders, sizedOutput); ok {
 			kd = collectionPullDecoder(kcid, coders, comps)
 		}
 
@@ -574,12 +590,15 @@ func buildDescriptor(stg *stage, comps *pipepb.Components, wk *worker.W, em *eng
 			transforms[si.Transform].GetInputs()[si.Local] = newGlobal
 			// TODO: replace si.Global with newGlobal?
 		}
-		prepSide, err := handleSideInput(si, comps, transforms, pcollections, coders, em)
+		prepSide, err := handleSideInput(stg.ID, si, comps, transforms, pcollections, coders, em)
 		if err != nil {
 			slog.Error("buildDescriptor: handleSideInputs", "error", err, slog.String("transformID", si.Transform))
 			return err
 		}
 		prepareSides = append(prepareSides, prepSide)
+		if isTriggeredSideInput(comps, si.Global) {
+			stg.triggeredSideInputs = append(stg.triggeredSideInputs, si)
+		}
 	}
 
 	// Finally, the parallel input, which is it's own special snowflake, that needs a datasource.
@@ -602,7 +621,7 @@ func buildDescriptor(stg *stage, comps *pipepb.Components, wk *worker.W, em *eng
 	winCoder, wDec, wEnc := getWindowValueCoders(comps, col, coders)
 
 	var kd func(io.Reader) []byte
-	if kcid, ok := extractKVCoderID(col.GetCoderId(), coders); ok {
+	if kcid, ok := extractKeyCoderID(col.GetCoderId(), coders, sizedInput); ok {
 		kd = collectionPullDecoder(kcid, coders, comps)
 	}
 
@@ -664,11 +683,112 @@ func buildDescriptor(stg *stage, comps *pipepb.Components, wk *worker.W, em *eng
 	stg.inputInfo = inputInfo
 
 	wk.Descriptors[stg.ID] = stg.desc
+
+	drainDesc, err := buildDrainDescriptor(stg, desc)
+	if err != nil {
+		return err
+	}
+	if drainDesc != nil {
+		stg.drainDescID = drainDesc.GetId()
+		wk.Descriptors[stg.drainDescID] = drainDesc
+	}
 	return nil
 }
 
+// buildDrainDescriptor constructs the ProcessBundleDescriptor for bundles of a stage
+// processing a splittable DoFn while the job is draining. It routes the primary input
+// through a TRUNCATE_SIZED_RESTRICTION transform before it's processed, so unbounded
+// restrictions can be finished or dropped. Returns nil if the stage doesn't process
+// a splittable DoFn.
+func buildDrainDescriptor(stg *stage, desc *fnpb.ProcessBundleDescriptor) (*fnpb.ProcessBundleDescriptor, error) {
+	for _, tid := range stg.transforms {
+		t := desc.GetTransforms()[tid]
+		if t.GetSpec().GetUrn() != urns.TransformProcessSizedElements {
+			continue
+		}
+		pardo := &pipepb.ParDoPayload{}
+		if err := (proto.UnmarshalOptions{}).Unmarshal(t.GetSpec().GetPayload(), pardo); err != nil {
+			return nil, fmt.Errorf("unable to decode ParDoPayload for %v in stage %v", tid, stg.ID)
+		}
+		var inputLocalID string
+		for local, global := range t.GetInputs() {
+			if _, ok := pardo.GetSideInputs()[local]; !ok && global == stg.primaryInput {
+				inputLocalID = local
+				break
+			}
+		}
+		if inputLocalID == "" {
+			// The splittable DoFn isn't the root of this stage.
+			continue
+		}
+
+		// Truncation only needs the element and restriction, so any side inputs,
+		// state, and timers are only for processing.
+		pardo.SideInputs = nil
+		pardo.StateSpecs = nil
+		pardo.TimerFamilySpecs = nil
+		pyld, err := proto.MarshalOptions{}.Marshal(pardo)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode truncation ParDoPayload for %v in stage %v", tid, stg.ID)
+		}
+
+		drain := proto.Clone(desc).(*fnpb.ProcessBundleDescriptor)
+		drain.Id = stg.ID + "_drain"
+
+		truncatedID := stg.primaryInput + "_truncated"
+		col := proto.Clone(drain.GetPcollections()[stg.primaryInput]).(*pipepb.PCollection)
+		col.UniqueName = truncatedID
+		drain.Pcollections[truncatedID] = col
+
+		truncateID := tid + "_truncate"
+		drain.Transforms[truncateID] = &pipepb.PTransform{
+			UniqueName: truncateID,
+			Spec: &pipepb.FunctionSpec{
+				Urn:     urns.TransformTruncate,
+				Payload: pyld,
+			},
+			Inputs: map[string]string{
+				inputLocalID: stg.primaryInput,
+			},
+			Outputs: map[string]string{
+				"i0": truncatedID,
+			},
+			EnvironmentId: t.GetEnvironmentId(),
+		}
+		drain.Transforms[tid].Inputs[inputLocalID] = truncatedID
+		return drain, nil
+	}
+	return nil, nil
+}
+
+// isTriggeredSideInput returns whether the side input PCollection is unbounded, in the
+// global window, and has a trigger that may fire before the end of the window.
+//
+// Such side inputs would otherwise never be ready until the end of the global window,
+// stalling the consuming stage, so each of their trigger firings is published instead.
+func isTriggeredSideInput(comps *pipepb.Components, pcolID string) bool {
+	pcol := comps.GetPcollections()[pcolID]
+	ws := comps.GetWindowingStrategies()[pcol.GetWindowingStrategyId()]
+	if pcol.GetIsBounded() == pipepb.IsBounded_BOUNDED ||
+		ws.GetWindowFn().GetUrn() != urns.WindowFnGlobal {
+		return false
+	}
+	switch trig := ws.GetTrigger().GetTrigger().(type) {
+	case *pipepb.Trigger_Never_, *pipepb.Trigger_Default_:
+		// Only one firing, at the end of the global window.
+		return false
+	case *pipepb.Trigger_AfterEndOfWindow_:
+		// Late firings in the global window only occur after the end of the window,
+		// so only early firings matter.
+		if early := trig.AfterEndOfWindow.GetEarlyFirings(); early == nil || early.GetNever() != nil {
+			return false
+		}
+	}
+	return true
+}
+
 // handleSideInput returns a closure that will look up the data for a side input appropriate for the given watermark.
-func handleSideInput(link engine.LinkID, comps *pipepb.Components, transforms map[string]*pipepb.PTransform, pcols map[string]*pipepb.PCollection, coders map[string]*pipepb.Coder, em *engine.ElementManager) (func(b *worker.B, watermark mtime.Time), error) {
+func handleSideInput(stageID string, link engine.LinkID, comps *pipepb.Components, transforms map[string]*pipepb.PTransform, pcols map[string]*pipepb.PCollection, coders map[string]*pipepb.Coder, em *engine.ElementManager) (func(b *worker.B, watermark mtime.Time), error) {
 	t := transforms[link.Transform]
 	sis, err := getSideInputs(t)
 	if err != nil {
@@ -691,7 +811,7 @@ func handleSideInput(link engine.LinkID, comps *pipepb.Components, transforms ma
 		// May be of zero length, but that's OK. Side inputs can be emp
 		return func(b *worker.B, watermark mtime.Time) {
 			// May be of zero length, but that's OK. Side inputs can be empty.
-			data := em.GetSideData(b.PBDID, link.Transform, link.Local, watermark)
+			data := em.GetSideData(stageID, link.Transform, link.Local, watermark)
 			if b.IterableSideInputData == nil {
 				b.IterableSideInputData = map[worker.SideInputKey]map[typex.Window][][]byte{}
 			}
@@ -721,7 +841,7 @@ func handleSideInput(link engine.LinkID, comps *pipepb.Components, transforms ma
 		getWindowValueCoders(comps, col, coders)
 		return func(b *worker.B, watermark mtime.Time) {
 			// May be of zero length, but that's OK. Side inputs can be empty.
-			data := em.GetSideData(b.PBDID, link.Transform, link.Local, watermark)
+			data := em.GetSideData(stageID, link.Transform, link.Local, watermark)
 			if b.MultiMapSideInputData == nil {
 				b.MultiMapSideInputData = map[worker.SideInputKey]map[typex.Window]map[string][][]byte{}
 			}
@@ -18,11 +18,14 @@ package internal_test
 import (
 	"context"
 	"fmt"
+	"math"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/apache/beam/sdks/v2/go/pkg/beam"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/sdf"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/state"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/rtrackers/offsetrange"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
@@ -57,6 +60,8 @@ func init() {
 	register.Function2x0(dofnKV3)
 	register.Function3x0(dofnGBK3)
 	register.Function3x0(dofn1Counter)
+	register.Function3x0(dofnStringSet)
+	register.Function3x0(dofnBoundedTrie)
 	register.Function2x0(dofnSink)
 	register.Function3x1(doFnFail)
 
@@ -278,6 +283,19 @@ func dofn1Counter(ctx context.Context, _ []byte, emit func(int64)) {
 	beam.NewCounter(ns, "count").Inc(ctx, 1)
 }
 
+func dofnStringSet(ctx context.Context, _ []byte, emit func(int64)) {
+	set := beam.NewStringSet(ns, "set")
+	set.Add(ctx, "b")
+	set.Add(ctx, "a")
+	set.Add(ctx, "b")
+}
+
+func dofnBoundedTrie(ctx context.Context, _ []byte, emit func(int64)) {
+	trie := beam.NewBoundedTrie(ns, "trie")
+	trie.Add(ctx, "gcs", "bucket", "a.txt")
+	trie.Add(ctx, "gcs", "bucket", "b.txt")
+}
+
 func doFnFail(ctx context.Context, _ []byte, emit func(int64)) error {
 	beam.NewCounter(ns, "count").Inc(ctx, 1)
 	return fmt.Errorf("doFnFail: failing as intended")
@@ -399,3 +417,158 @@ func (fn *selfCheckpointingDoFn) ProcessElement(rt *sdf.LockRTracker, _ []byte,
 		}
 	}
 }
+
+func init() {
+	register.DoFn5x1[*sdf.LockRTracker, state.Provider, string, int64, func(int), sdf.ProcessContinuation](&statefulCheckpointingDoFn{})
+	register.Emitter1[int]()
+}
+
+// statefulCheckpointingDoFn is a stateful SDF that counts the positions it has
+// claimed for each key, checkpointing after every claim.
+type statefulCheckpointingDoFn struct {
+	Count state.Value[int]
+}
+
+// CreateInitialRestriction creates a restriction of [0, n) for the element.
+func (fn *statefulCheckpointingDoFn) CreateInitialRestriction(_ string, n int64) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: n}
+}
+
+// CreateTracker wraps the given restriction into a LockRTracker type.
+func (fn *statefulCheckpointingDoFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	return sdf.NewLockRTracker(offsetrange.NewTracker(rest))
+}
+
+// SplitRestriction returns the restriction unsplit.
+func (fn *statefulCheckpointingDoFn) SplitRestriction(_ string, _ int64, rest offsetrange.Restriction) []offsetrange.Restriction {
+	return []offsetrange.Restriction{rest}
+}
+
+// RestrictionSize returns the size of the current restriction.
+func (fn *statefulCheckpointingDoFn) RestrictionSize(_ string, _ int64, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+// ProcessElement claims a single position, increments and emits the count for the key,
+// and then checkpoints the remainder of the restriction.
+func (fn *statefulCheckpointingDoFn) ProcessElement(rt *sdf.LockRTracker, sp state.Provider, _ string, _ int64, emit func(int)) sdf.ProcessContinuation {
+	if !rt.TryClaim(rt.GetRestriction().(offsetrange.Restriction).Start) {
+		return sdf.StopProcessing()
+	}
+	count, _, err := fn.Count.Read(sp)
+	if err != nil {
+		panic(err)
+	}
+	count++
+	if err := fn.Count.Write(sp, count); err != nil {
+		panic(err)
+	}
+	emit(count)
+	return sdf.ResumeProcessingIn(0)
+}
+
+func init() {
+	register.DoFn3x1[*sdf.LockRTracker, []byte, func(int64), sdf.ProcessContinuation](&unboundedDoFn{})
+	register.Emitter1[int64]()
+}
+
+// unboundedDoFn is an SDF that emits a position after each checkpoint forever,
+// and only terminates when its restriction is truncated by a drain.
+type unboundedDoFn struct{}
+
+// unboundedEstimator estimates that the unbounded restriction never ends.
+type unboundedEstimator struct{}
+
+// Estimate returns the estimated end of the restriction.
+func (unboundedEstimator) Estimate() int64 {
+	return math.MaxInt64
+}
+
+// CreateInitialRestriction creates an unbounded restriction.
+func (fn *unboundedDoFn) CreateInitialRestriction(_ []byte) offsetrange.Restriction {
+	return offsetrange.Restriction{Start: 0, End: math.MaxInt64}
+}
+
+// CreateTracker wraps the given restriction into a LockRTracker type.
+func (fn *unboundedDoFn) CreateTracker(rest offsetrange.Restriction) *sdf.LockRTracker {
+	tracker, err := offsetrange.NewGrowableTracker(rest, unboundedEstimator{})
+	if err != nil {
+		panic(err)
+	}
+	return sdf.NewLockRTracker(tracker)
+}
+
+// SplitRestriction returns the restriction unsplit.
+func (fn *unboundedDoFn) SplitRestriction(_ []byte, rest offsetrange.Restriction) []offsetrange.Restriction {
+	return []offsetrange.Restriction{rest}
+}
+
+// RestrictionSize returns the size of the current restriction.
+func (fn *unboundedDoFn) RestrictionSize(_ []byte, rest offsetrange.Restriction) float64 {
+	return rest.Size()
+}
+
+// ProcessElement claims and emits a single position, and then checkpoints the remainder
+// of the restriction. Since the restriction is unbounded, the default truncation on drain
+// discards it.
+func (fn *unboundedDoFn) ProcessElement(rt *sdf.LockRTracker, _ []byte, emit func(int64)) sdf.ProcessContinuation {
+	position := rt.GetRestriction().(offsetrange.Restriction).Start
+	if !rt.TryClaim(position) {
+		return sdf.StopProcessing()
+	}
+	emit(position)
+	return sdf.ResumeProcessingIn(0)
+}
+
+func init() {
+	register.DoFn4x0[state.Provider, string, int, func(int)](&countingStateFn{})
+	register.Function2x0(dofnKeyWithOne)
+	register.Emitter2[string, int]()
+}
+
+func dofnKeyWithOne(key string, emit func(string, int)) {
+	emit(key, 1)
+}
+
+// countingStateFn counts the elements it has seen for each key, and emits the
+// running count for each element.
+type countingStateFn struct {
+	Count state.Value[int]
+}
+
+func (fn *countingStateFn) ProcessElement(sp state.Provider, _ string, _ int, emit func(int)) {
+	count, _, err := fn.Count.Read(sp)
+	if err != nil {
+		panic(err)
+	}
+	count++
+	if err := fn.Count.Write(sp, count); err != nil {
+		panic(err)
+	}
+	emit(count)
+}
+
+func init() {
+	register.Function1x2(dofnKeyByFirstThree)
+	register.Function3x0(dofnLatestSideSums)
+	register.Iter2[int64, int64]()
+}
+
+// dofnKeyByFirstThree keys the first three elements of a sequence with 0, and the rest with 1.
+func dofnKeyByFirstThree(i int64) (int64, int64) {
+	if i < 3 {
+		return 0, i
+	}
+	return 1, i
+}
+
+// dofnLatestSideSums emits the per key sums in the side input, ordered by key.
+func dofnLatestSideSums(_ []byte, sums func(*int64, *int64) bool, emit func(string)) {
+	var k, v int64
+	var got []string
+	for sums(&k, &v) {
+		got = append(got, fmt.Sprintf("%d=%d", k, v))
+	}
+	sort.Strings(got)
+	emit(strings.Join(got, ","))
+}
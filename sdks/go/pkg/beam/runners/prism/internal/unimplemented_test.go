@@ -41,8 +41,6 @@ func TestUnimplemented(t *testing.T) {
 	tests := []struct {
 		pipeline func(s beam.Scope)
 	}{
-		// {pipeline: primitives.Drain}, // Can't test drain automatically yet.
-
 		// Implemented but the Go SDK doesn't fully handle panes and
 		// their associated valid behaviors for these triggers, leading
 		// to variable results.
@@ -51,9 +49,8 @@ func TestUnimplemented(t *testing.T) {
 		{pipeline: primitives.TriggerOrFinally},
 		{pipeline: primitives.TriggerAlways},
 
-		// Currently unimplemented triggers.
-		// https://github.com/apache/beam/issues/31438
-		{pipeline: primitives.TriggerAfterSynchronizedProcessingTime},
+		// Expects the trigger to fire before processing time has passed its delay,
+		// where Prism fires all four elements at the end of the window instead.
 		{pipeline: primitives.TriggerAfterProcessingTime},
 	}
 
@@ -85,6 +82,7 @@ func TestImplemented(t *testing.T) {
 		{pipeline: primitives.CoGBK},
 		{pipeline: primitives.ReshuffleKV},
 		{pipeline: primitives.ParDoProcessElementBundleFinalizer},
+		{pipeline: primitives.ParDoErrorHandler},
 
 		{pipeline: primitives.TriggerNever},
 		{pipeline: primitives.Panes},
@@ -93,6 +91,8 @@ func TestImplemented(t *testing.T) {
 		{pipeline: primitives.TriggerAfterEach},
 		{pipeline: primitives.TriggerAfterEndOfWindow},
 		{pipeline: primitives.TriggerRepeat},
+		{pipeline: primitives.TriggerAfterProcessingTimeRepeat},
+		{pipeline: primitives.TriggerAfterSynchronizedProcessingTime},
 	}
 
 	for _, test := range tests {
@@ -123,6 +123,8 @@ func TestStateAPI(t *testing.T) {
 		{pipeline: primitives.MapStateParDoClear},
 		{pipeline: primitives.SetStateParDo},
 		{pipeline: primitives.SetStateParDoClear},
+		{pipeline: primitives.OrderedListStateParDo},
+		{pipeline: primitives.OrderedListStateParDoClear},
 	}
 
 	for _, test := range tests {
@@ -142,6 +142,10 @@ var (
 	CapabilityMonitoringInfoShortIDs           = runProcUrn(pipepb.StandardRunnerProtocols_MONITORING_INFO_SHORT_IDS)
 	CapabilityControlResponseElementsEmbedding = runProcUrn(pipepb.StandardRunnerProtocols_CONTROL_RESPONSE_ELEMENTS_EMBEDDING)
 
+	// Prism metrics, reported by the runner rather than the SDK.
+	MetricStageActiveBundles = "beam:metric:prism:stage_active_bundles:v1"
+	MetricStagePeakBundles   = "beam:metric:prism:stage_peak_bundles:v1"
+
 	// Environment types
 	EnvDocker   = envUrn(pipepb.StandardEnvironments_DOCKER)
 	EnvProcess  = envUrn(pipepb.StandardEnvironments_PROCESS)
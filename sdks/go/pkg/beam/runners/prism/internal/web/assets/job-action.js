@@ -24,6 +24,9 @@ const JOB_ACTION = '.job-action'
 /** Element class for cancel button. */
 const CANCEL = '.cancel'
 
+/** Element class for drain button. */
+const DRAIN = '.drain'
+
 /** Element class assigned to RUNNING Job state. */
 const RUNNING = 'RUNNING'
 
@@ -39,6 +42,11 @@ const PATH = {
     /** CANCEL maps to the backend endpoint to cancel a Job. Terminates with '/' to prevent ServeMux 301 redirect. */
     get CANCEL() {
         return `${this.ROOT_}/cancel/`
+    },
+
+    /** DRAIN maps to the backend endpoint to drain a Job. Terminates with '/' to prevent ServeMux 301 redirect. */
+    get DRAIN() {
+        return `${this.ROOT_}/drain/`
     }
 }
 
@@ -80,6 +88,34 @@ const jobManager = {
                 console.error(`Error occurred while sending job cancellation request for Job: ${jobId}`, error)
             })
     },
+
+    /**
+     * Drain a Job.
+     * Invokes backend handler to request a Job drain state.
+     * @param jobId
+     */
+    drain: function (jobId) {
+        console.debug(`drain button for Job: ${jobId} clicked`)
+        const path = PATH.DRAIN
+        const request = {
+            method: HTTP_POST,
+            body: JSON.stringify(new DrainJobRequest(jobId))
+        }
+        fetch(path, request)
+            .then(response => {
+                const requestJson = JSON.stringify(request)
+                const responseJson = JSON.stringify(response)
+                if (response.ok) {
+                    console.debug(`Job drain request to ${path} of ${requestJson} for Job: ${jobId} sent successfully, response: ${responseJson}`)
+                    uiStateProvider.onJobDrain(response)
+                } else {
+                    console.error(`Failed to send job drain request to ${path} of ${requestJson} for Job: ${jobId}, response: ${responseJson}`)
+                }
+            })
+            .catch(error => {
+                console.error(`Error occurred while sending job drain request for Job: ${jobId}`, error)
+            })
+    },
 }
 
 /**
@@ -113,12 +149,26 @@ const uiStateProvider = {
         return element
     },
 
+    /**
+     * Queries Job Action container DOM for the drain button.
+     * Logs an error if not found.
+     * @returns {Element}
+     */
+    get drainButton() {
+        let element = this.jobAction.querySelector(DRAIN)
+        if (element === null) {
+            console.error(`no element found at ${DRAIN} within ${this.jobAction}`)
+        }
+        return element
+    },
+
     /**
      * Initializes the uiStateManager.
      * Called from the window's load event.
      */
     init() {
         this.cancelButton.disabled = this.isStateRunning === false
+        this.drainButton.disabled = this.isStateRunning === false
     },
 
     /**
@@ -153,6 +203,21 @@ const uiStateProvider = {
             .catch(error => {
                 console.error(`error Response.json() ${error}`)
             })
+    },
+
+    /**
+     * Callback for successful Job Drain requests.
+     * @param response {Response}
+     */
+    onJobDrain(response) {
+        response.json().then(json => {
+            console.debug(`job drain response json: ${JSON.stringify(json)}`)
+            uiStateProvider.jobStateElement.textContent = JobState_Enum[json.state]
+            uiStateProvider.drainButton.disabled = true
+        })
+            .catch(error => {
+                console.error(`error Response.json() ${error}`)
+            })
     }
 }
 
@@ -162,6 +227,7 @@ const uiStateProvider = {
 window.addEventListener("load", function () {
     console.debug(JOB_ACTION, uiStateProvider.jobAction)
     console.debug(CANCEL, uiStateProvider.cancelButton)
+    console.debug(DRAIN, uiStateProvider.drainButton)
     uiStateProvider.init()
 })
 
@@ -192,6 +258,33 @@ class CancelJobRequest {
     }
 }
 
+/**
+ * DrainJobRequest models a request to drain a Job.
+ *
+ * Models after its proto namesake in:
+ * https://github.com/apache/beam/blob/master/model/job-management/src/main/proto/org/apache/beam/model/job_management/v1/beam_job_api.proto
+ */
+class DrainJobRequest {
+    job_id_;
+
+    constructor(jobId) {
+        this.job_id_ = jobId
+    }
+
+    /**
+     * The ID of the Job to drain.
+     * @return {string}
+     */
+    get job_id() {
+        return this.job_id_
+    }
+
+    /** toJSON overrides JSON.stringify serialization behavior. */
+    toJSON() {
+        return {job_id: this.job_id}
+    }
+}
+
 /** Maps JobState_Enum from Job Management server response to the Job State name. See proto for more details:
  * https://github.com/apache/beam/blob/master/model/job-management/src/main/proto/org/apache/beam/model/job_management/v1/beam_job_api.proto
  */
@@ -35,11 +35,15 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/metrics"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/metricsx"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/pipelinex"
 	jobpb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/jobmanagement_v1"
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal/engine"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/runners/prism/internal/urns"
 	"golang.org/x/exp/maps"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/protobuf/proto"
@@ -187,6 +191,12 @@ func (h *jobDetailsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	mets := metsResp.GetMetrics()
 	results := metricsx.FromMonitoringInfos(pipeResp.GetPipeline(), mets.GetAttempted(), mets.GetCommitted())
+	// Jobs running in this process report their bundle concurrency directly,
+	// and others with their metrics.
+	concurrency, ok := internal.BundleConcurrency(jobID)
+	if !ok {
+		concurrency = bundleConcurrencies(mets.GetAttempted())
+	}
 
 	pcols := map[metrics.StepKey]metrics.PColResult{}
 	allMetsPCol := results.AllMetrics().PCols()
@@ -251,6 +261,11 @@ func (h *jobDetailsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			strMets = append(strMets, msecMets...)
 		}
 
+		if c, ok := concurrency[id]; ok && c.Peak > 0 {
+			strMets = append(strMets, "Stage bundle concurrency")
+			strMets = append(strMets, fmt.Sprintf("\n- active: %v, peak: %v", c.Active, c.Peak))
+		}
+
 		var userMetrics []string
 		for _, ctr := range counters[id] {
 			userMetrics = append(userMetrics, fmt.Sprintf("\n- %s.%s: %v", ctr.Namespace(), ctr.Name(), ctr.Result()))
@@ -280,6 +295,30 @@ func (h *jobDetailsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	renderPage(jobPage, &data, w)
 }
 
+// bundleConcurrencies returns prism's bundle concurrency metrics, keyed by transform.
+func bundleConcurrencies(infos []*pipepb.MonitoringInfo) map[string]engine.BundleConcurrency {
+	ret := map[string]engine.BundleConcurrency{}
+	for _, info := range infos {
+		if info.GetUrn() != urns.MetricStageActiveBundles && info.GetUrn() != urns.MetricStagePeakBundles {
+			continue
+		}
+		v, err := coder.DecodeVarInt(bytes.NewReader(info.GetPayload()))
+		if err != nil {
+			slog.Debug("unable to decode bundle concurrency metric", slog.Any("error", err), slog.Any("info", info))
+			continue
+		}
+		tid := info.GetLabels()["PTRANSFORM"]
+		c := ret[tid]
+		if info.GetUrn() == urns.MetricStageActiveBundles {
+			c.Active = int(v)
+		} else {
+			c.Peak = int(v)
+		}
+		ret[tid] = c
+	}
+	return ret
+}
+
 func toTransformMap[E interface{ Transform() string }](mets []E) map[string][]E {
 	ret := map[string][]E{}
 	for _, met := range mets {
@@ -374,44 +413,77 @@ type jobCancelHandler struct {
 	Jobcli jobpb.JobServiceClient
 }
 
-type cancelJobRequest struct {
+func (h *jobCancelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cancelRequest, ok := readJobActionRequest(w, r)
+	if !ok {
+		return
+	}
+	// Forward JobId from POST body avoids direct json Unmarshall on composite types containing protobuf message types.
+	resp, err := h.Jobcli.Cancel(r.Context(), &jobpb.CancelJobRequest{
+		JobId: cancelRequest.JobID,
+	})
+	writeJobActionResponse(w, "Cancel", cancelRequest, resp, err)
+}
+
+type jobDrainHandler struct {
+	Jobcli jobpb.JobServiceClient
+}
+
+func (h *jobDrainHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	drainRequest, ok := readJobActionRequest(w, r)
+	if !ok {
+		return
+	}
+	// Forward JobId from POST body avoids direct json Unmarshall on composite types containing protobuf message types.
+	resp, err := h.Jobcli.Drain(r.Context(), &jobpb.DrainJobRequest{
+		JobId: drainRequest.JobID,
+	})
+	writeJobActionResponse(w, "Drain", drainRequest, resp, err)
+}
+
+// jobActionRequest models the POST body of requests to take an action on a job.
+type jobActionRequest struct {
 	JobID string `json:"job_id"`
 }
 
-func (h *jobCancelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var cancelRequest *cancelJobRequest
+// readJobActionRequest parses the job action request from the POST body.
+// Returns false if the request is invalid, after an error is written to the response.
+func readJobActionRequest(w http.ResponseWriter, r *http.Request) (*jobActionRequest, bool) {
+	var req *jobActionRequest
 	if r.Method != http.MethodPost {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
-		return
+		return nil, false
 	}
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		err = fmt.Errorf("could not read request body: %w", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, false
 	}
 	if len(body) == 0 {
 		http.Error(w, "empty request body", http.StatusBadRequest)
-		return
+		return nil, false
 	}
-	if err := json.Unmarshal(body, &cancelRequest); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		err = fmt.Errorf("error parsing JSON: %s of request: %w", body, err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+		return nil, false
 	}
+	return req, true
+}
 
-	// Forward JobId from POST body avoids direct json Unmarshall on composite types containing protobuf message types.
-	resp, err := h.Jobcli.Cancel(r.Context(), &jobpb.CancelJobRequest{
-		JobId: cancelRequest.JobID,
-	})
+// writeJobActionResponse writes the response of a job action, or the error converted
+// to an appropriate HTTP status.
+func writeJobActionResponse(w http.ResponseWriter, action string, req *jobActionRequest, resp any, err error) {
 	if err != nil {
 		statusCode := status.Code(err)
 		httpCode := http.StatusInternalServerError
 		if c, ok := grpcToHttpCodes[statusCode]; ok {
 			httpCode = c
 		}
-		err = fmt.Errorf("error Cancel(%+v) = %w", cancelRequest, err)
+		err = fmt.Errorf("error %v(%+v) = %w", action, req, err)
 		http.Error(w, err.Error(), httpCode)
+		return
 	}
 
 	w.Header().Add(kContentType, kApplicationJson)
@@ -429,6 +501,7 @@ func Initialize(ctx context.Context, port int, jobcli jobpb.JobServiceClient) er
 
 	mux.Handle("/assets/", assetsFs)
 	mux.Handle("/job/cancel/", &jobCancelHandler{Jobcli: jobcli})
+	mux.Handle("/job/drain/", &jobDrainHandler{Jobcli: jobcli})
 	mux.Handle("/job/", &jobDetailsHandler{Jobcli: jobcli})
 	mux.Handle("/debugz", &debugzHandler{})
 	mux.Handle("/", &jobsConsoleHandler{Jobcli: jobcli})
@@ -67,6 +67,13 @@ type Options struct {
 	// CancelFn allows Prism to terminate the program due to it's internal state, such as via the idle shutdown timeout.
 	// If unset, os.Exit(1) will be called instead.
 	CancelFn context.CancelCauseFunc
+
+	// CheckpointDir is where jobs write snapshots of their execution state, in a subdirectory per job.
+	// If unset, snapshots are not written.
+	CheckpointDir string
+	// RestoreFrom is a job snapshot directory that the first job submitted resumes from.
+	// Later jobs are rejected.
+	RestoreFrom string
 }
 
 // CreateJobServer returns a Beam JobServicesClient connected to an in memory JobServer.
@@ -77,6 +84,9 @@ func CreateJobServer(ctx context.Context, opts Options) (jobpb.JobServiceClient,
 	if opts.IdleShutdownTimeout > 0 {
 		s.IdleShutdown(opts.IdleShutdownTimeout, opts.CancelFn)
 	}
+	if opts.CheckpointDir != "" || opts.RestoreFrom != "" {
+		s.Checkpoint(opts.CheckpointDir, opts.RestoreFrom)
+	}
 	go s.Serve()
 	clientConn, err := grpc.DialContext(ctx, s.Endpoint(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
 	if err != nil {
@@ -0,0 +1,310 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package batch contains transforms for grouping the values of keys into
+// batches, such as to amortize the cost of writes to external services.
+//
+// The transforms use user state and timers, so they require a runner that
+// supports them, such as Prism or Dataflow. The deprecated direct runner
+// doesn't support them, so pipelines using the transforms fail on it.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/state"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/timers"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+)
+
+func init() {
+	register.DoFn7x1[beam.Window, beam.EventTime, state.Provider, timers.Provider, beam.X, beam.Y, func(beam.X, []beam.Y), error]((*groupIntoBatchesFn)(nil))
+	register.Emitter2[beam.X, []beam.Y]()
+	register.DoFn3x1[beam.X, beam.Y, func([]byte, beam.Y), error]((*shardKeyFn)(nil))
+	register.Emitter2[[]byte, beam.Y]()
+	register.DoFn3x1[[]byte, []beam.Y, func(beam.X, []beam.Y), error]((*unshardKeyFn)(nil))
+}
+
+// Option is an optional setting of GroupIntoBatches.
+type Option func(*config)
+
+type config struct {
+	batchSizeBytes       int64
+	maxBufferingDuration time.Duration
+}
+
+// WithBatchSizeBytes limits the size of batches to the given number of bytes,
+// as measured by the encoded size of their values. A batch is output once it
+// reaches the limit, so batches may exceed it by at most one value.
+func WithBatchSizeBytes(n int64) Option {
+	return func(c *config) {
+		c.batchSizeBytes = n
+	}
+}
+
+// WithMaxBufferingDuration limits how long values are buffered before being
+// output, in processing time. Without it, incomplete batches are only output
+// at the end of their windows.
+func WithMaxBufferingDuration(d time.Duration) Option {
+	return func(c *config) {
+		c.maxBufferingDuration = d
+	}
+}
+
+// GroupIntoBatches groups the values of each key of a PCollection<KV<K,V>>
+// into batches of up to size values. It returns a PCollection<KV<K,[]V>>.
+// Incomplete batches are output at the end of each window, or once they have
+// been buffered for the duration set by WithMaxBufferingDuration. A size of 0
+// leaves the number of values unlimited, if WithBatchSizeBytes is set.
+//
+// Each key is batched independently, so the parallelism of the transform is
+// limited by the number of keys. GroupIntoBatchesWithShardedKey spreads the
+// values of hot keys across workers.
+//
+// Example use:
+//
+//	col := beam.ParDo(s, func(v string) (string, string) { return userOf(v), v }, events)
+//	batches := batch.GroupIntoBatches(s, 100, col, batch.WithMaxBufferingDuration(time.Minute))
+func GroupIntoBatches(s beam.Scope, size int64, col beam.PCollection, opts ...Option) beam.PCollection {
+	s = s.Scope(fmt.Sprintf("batch.GroupIntoBatches(%v)", size))
+
+	_, v := beam.ValidateKVType(col)
+	return beam.ParDo(s, newGroupIntoBatchesFn(size, v, opts), col)
+}
+
+// GroupIntoBatchesWithShardedKey is GroupIntoBatches, except that the values
+// of each key are spread over shards, which are batched independently. So
+// the values of a key may be output in more batches, of up to size values
+// each, but hot keys aren't limited to a single worker.
+func GroupIntoBatchesWithShardedKey(s beam.Scope, size int64, col beam.PCollection, opts ...Option) beam.PCollection {
+	s = s.Scope(fmt.Sprintf("batch.GroupIntoBatchesWithShardedKey(%v)", size))
+
+	k, v := beam.ValidateKVType(col)
+	fn := newGroupIntoBatchesFn(size, v, opts)
+	sharded := beam.ParDo(s, &shardKeyFn{KeyType: beam.EncodedType{T: k.Type()}}, col)
+	batches := beam.ParDo(s, fn, sharded)
+	return beam.ParDo(s, &unshardKeyFn{KeyType: beam.EncodedType{T: k.Type()}}, batches, beam.TypeDefinition{Var: beam.XType, T: k.Type()})
+}
+
+func newGroupIntoBatchesFn(size int64, t beam.FullType, opts []Option) *groupIntoBatchesFn {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if size < 0 {
+		panic(fmt.Sprintf("batch size must be >= 0: %v", size))
+	}
+	if c.batchSizeBytes < 0 {
+		panic(fmt.Sprintf("batch size in bytes must be >= 0: %v", c.batchSizeBytes))
+	}
+	if size == 0 && c.batchSizeBytes == 0 {
+		panic("batch size must be > 0, unless the batch size in bytes is set")
+	}
+	if c.maxBufferingDuration < 0 {
+		panic(fmt.Sprintf("max buffering duration must be >= 0: %v", c.maxBufferingDuration))
+	}
+	return &groupIntoBatchesFn{
+		BatchSize:            size,
+		BatchSizeBytes:       c.batchSizeBytes,
+		MaxBufferingDuration: c.maxBufferingDuration,
+		Type:                 beam.EncodedType{T: t.Type()},
+
+		Batch:        state.MakeBagState[[]byte]("batch"),
+		Count:        state.MakeValueState[int64]("count"),
+		Size:         state.MakeValueState[int64]("size"),
+		MinTimestamp: state.MakeValueState[int64]("minTimestamp"),
+
+		EndOfWindow: timers.InEventTime("endOfWindow"),
+		Buffering:   timers.InProcessingTime("buffering"),
+	}
+}
+
+// groupIntoBatchesFn buffers the encoded values of each key and window in
+// state, until a batch is full, the buffering timer fires, or the end of
+// window timer fires. The end of window timer holds the watermark at the
+// earliest timestamp of the buffered values.
+type groupIntoBatchesFn struct {
+	BatchSize            int64
+	BatchSizeBytes       int64
+	MaxBufferingDuration time.Duration
+	// Type is the value type V.
+	Type beam.EncodedType
+
+	Batch        state.Bag[[]byte]
+	Count        state.Value[int64]
+	Size         state.Value[int64]
+	MinTimestamp state.Value[int64]
+
+	EndOfWindow timers.EventTime
+	Buffering   timers.ProcessingTime
+
+	enc beam.ElementEncoder
+	dec beam.ElementDecoder
+}
+
+func (fn *groupIntoBatchesFn) Setup() {
+	fn.enc = beam.NewElementEncoder(fn.Type.T)
+	fn.dec = beam.NewElementDecoder(fn.Type.T)
+}
+
+func (fn *groupIntoBatchesFn) ProcessElement(w beam.Window, ts beam.EventTime, sp state.Provider, tp timers.Provider, key beam.X, value beam.Y, emit func(beam.X, []beam.Y)) error {
+	var buf bytes.Buffer
+	if err := fn.enc.Encode(value, &buf); err != nil {
+		return fmt.Errorf("batch.GroupIntoBatches: encoding value %v: %w", value, err)
+	}
+	if err := fn.Batch.Add(sp, buf.Bytes()); err != nil {
+		return err
+	}
+	count, err := fn.add(sp, &fn.Count, 1)
+	if err != nil {
+		return err
+	}
+	var size int64
+	if fn.BatchSizeBytes > 0 {
+		if size, err = fn.add(sp, &fn.Size, int64(buf.Len())); err != nil {
+			return err
+		}
+	}
+	if (fn.BatchSize > 0 && count >= fn.BatchSize) || (fn.BatchSizeBytes > 0 && size >= fn.BatchSizeBytes) {
+		return fn.flush(sp, tp, key, emit)
+	}
+
+	minTs, ok, err := fn.MinTimestamp.Read(sp)
+	if err != nil {
+		return err
+	}
+	if !ok || int64(ts) < minTs {
+		minTs = int64(ts)
+		if err := fn.MinTimestamp.Write(sp, minTs); err != nil {
+			return err
+		}
+		fn.EndOfWindow.Set(tp, w.MaxTimestamp().ToTime(), timers.WithOutputTimestamp(mtime.Time(minTs).ToTime()))
+	}
+	if count == 1 && fn.MaxBufferingDuration > 0 {
+		fn.Buffering.Set(tp, time.Now().Add(fn.MaxBufferingDuration), timers.WithOutputTimestamp(mtime.Time(minTs).ToTime()))
+	}
+	return nil
+}
+
+func (fn *groupIntoBatchesFn) OnTimer(ctx context.Context, ts beam.EventTime, sp state.Provider, tp timers.Provider, key beam.X, timer timers.Context, emit func(beam.X, []beam.Y)) error {
+	switch timer.Family {
+	case fn.EndOfWindow.Family, fn.Buffering.Family:
+		return fn.flush(sp, tp, key, emit)
+	default:
+		return fmt.Errorf("batch.GroupIntoBatches: unexpected timer family %v", timer.Family)
+	}
+}
+
+// add adds n to the value of the state, and returns the sum.
+func (fn *groupIntoBatchesFn) add(sp state.Provider, s *state.Value[int64], n int64) (int64, error) {
+	v, _, err := s.Read(sp)
+	if err != nil {
+		return 0, err
+	}
+	v += n
+	if err := s.Write(sp, v); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// flush outputs the buffered values, if any, and clears the state and timers.
+func (fn *groupIntoBatchesFn) flush(sp state.Provider, tp timers.Provider, key beam.X, emit func(beam.X, []beam.Y)) error {
+	data, ok, err := fn.Batch.Read(sp)
+	if err != nil {
+		return err
+	}
+	if ok && len(data) > 0 {
+		values := make([]beam.Y, 0, len(data))
+		for _, d := range data {
+			v, err := fn.dec.Decode(bytes.NewBuffer(d))
+			if err != nil {
+				return fmt.Errorf("batch.GroupIntoBatches: decoding value: %w", err)
+			}
+			values = append(values, v)
+		}
+		emit(key, values)
+	}
+	for _, err := range []error{fn.Batch.Clear(sp), fn.Count.Clear(sp), fn.Size.Clear(sp), fn.MinTimestamp.Clear(sp)} {
+		if err != nil {
+			return err
+		}
+	}
+	fn.EndOfWindow.Clear(tp)
+	if fn.MaxBufferingDuration > 0 {
+		fn.Buffering.Clear(tp)
+	}
+	return nil
+}
+
+// shardIDLen is the length of the random shard id prefixed to the encoded
+// keys of GroupIntoBatchesWithShardedKey.
+const shardIDLen = 16
+
+// shardKeyFn replaces keys with the shard id of the DoFn instance, followed
+// by the encoded key. So the values of a key are sharded across the workers
+// that process them.
+type shardKeyFn struct {
+	// KeyType is the key type K.
+	KeyType beam.EncodedType
+
+	enc     beam.ElementEncoder
+	shardID []byte
+}
+
+func (fn *shardKeyFn) Setup() error {
+	fn.enc = beam.NewElementEncoder(fn.KeyType.T)
+	fn.shardID = make([]byte, shardIDLen)
+	if _, err := rand.Read(fn.shardID); err != nil {
+		return fmt.Errorf("batch.GroupIntoBatchesWithShardedKey: generating shard id: %w", err)
+	}
+	return nil
+}
+
+func (fn *shardKeyFn) ProcessElement(key beam.X, value beam.Y, emit func([]byte, beam.Y)) error {
+	buf := bytes.NewBuffer(append([]byte(nil), fn.shardID...))
+	if err := fn.enc.Encode(key, buf); err != nil {
+		return fmt.Errorf("batch.GroupIntoBatchesWithShardedKey: encoding key %v: %w", key, err)
+	}
+	emit(buf.Bytes(), value)
+	return nil
+}
+
+// unshardKeyFn restores the keys replaced by shardKeyFn.
+type unshardKeyFn struct {
+	// KeyType is the key type K.
+	KeyType beam.EncodedType
+
+	dec beam.ElementDecoder
+}
+
+func (fn *unshardKeyFn) Setup() {
+	fn.dec = beam.NewElementDecoder(fn.KeyType.T)
+}
+
+func (fn *unshardKeyFn) ProcessElement(shardedKey []byte, values []beam.Y, emit func(beam.X, []beam.Y)) error {
+	key, err := fn.dec.Decode(bytes.NewBuffer(shardedKey[shardIDLen:]))
+	if err != nil {
+		return fmt.Errorf("batch.GroupIntoBatchesWithShardedKey: decoding key: %w", err)
+	}
+	emit(key, values)
+	return nil
+}
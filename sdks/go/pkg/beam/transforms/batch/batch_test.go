@@ -0,0 +1,192 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/register"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/passert"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/teststream"
+)
+
+func init() {
+	register.Function1x2(keyByHot)
+	register.Function1x2(keyByParity)
+	register.Function1x2(timestampBySecond)
+	register.Function3x0(flattenBatch)
+	register.Function2x1(batchLen)
+	register.Function2x1(keyedBatchLen)
+	register.Emitter1[int]()
+}
+
+func TestMain(m *testing.M) {
+	ptest.Main(m)
+}
+
+func keyByHot(v int) (string, int) {
+	return "hot", v
+}
+
+func keyByParity(v int) (int, int) {
+	return v % 2, v
+}
+
+// timestampBySecond gives each value a timestamp of that many seconds.
+func timestampBySecond(v int) (beam.EventTime, int) {
+	return mtime.FromDuration(time.Duration(v) * time.Second), v
+}
+
+func flattenBatch(_ string, vs []int, emit func(int)) {
+	for _, v := range vs {
+		emit(v)
+	}
+}
+
+func batchLen(_ string, vs []int) int {
+	return len(vs)
+}
+
+type keyedLen struct {
+	Key, Len int
+}
+
+func keyedBatchLen(k int, vs []int) keyedLen {
+	return keyedLen{Key: k, Len: len(vs)}
+}
+
+func seq(n int) []int {
+	var vs []int
+	for i := 0; i < n; i++ {
+		vs = append(vs, i)
+	}
+	return vs
+}
+
+func TestGroupIntoBatches(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, keyByHot, beam.CreateList(s, seq(10)))
+	batches := GroupIntoBatches(s, 4, col)
+
+	passert.Equals(s, beam.ParDo(s, batchLen, batches), 4, 4, 2)
+	passert.EqualsList(s, beam.ParDo(s, flattenBatch, batches), seq(10))
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestGroupIntoBatches_PerKey(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, keyByParity, beam.CreateList(s, seq(10)))
+	batches := GroupIntoBatches(s, 3, col)
+
+	passert.Equals(s, beam.ParDo(s, keyedBatchLen, batches),
+		keyedLen{0, 3}, keyedLen{0, 2}, keyedLen{1, 3}, keyedLen{1, 2})
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestGroupIntoBatches_Bytes(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, keyByHot, beam.CreateList(s, seq(10)))
+	var buf bytes.Buffer
+	if err := beam.NewElementEncoder(reflect.TypeOf(0)).Encode(9, &buf); err != nil {
+		t.Fatalf("encoding 9 failed: %v", err)
+	}
+	// Small ints have the same encoded size.
+	batches := GroupIntoBatches(s, 0, col, WithBatchSizeBytes(3*int64(buf.Len())))
+
+	passert.Equals(s, beam.ParDo(s, batchLen, batches), 3, 3, 3, 1)
+	passert.EqualsList(s, beam.ParDo(s, flattenBatch, batches), seq(10))
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestGroupIntoBatches_Windowed(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, timestampBySecond, beam.CreateList(s, seq(10)))
+	col = beam.WindowInto(s, window.NewFixedWindows(5*time.Second), col)
+	col = beam.ParDo(s, keyByHot, col)
+	// Incomplete batches are output at the end of their windows.
+	batches := GroupIntoBatches(s, 3, col, WithMaxBufferingDuration(time.Hour))
+
+	batches = beam.WindowInto(s, window.NewGlobalWindows(), batches)
+	passert.Equals(s, beam.ParDo(s, batchLen, batches), 3, 2, 3, 2)
+	passert.EqualsList(s, beam.ParDo(s, flattenBatch, batches), seq(10))
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestGroupIntoBatches_MaxBufferingDuration(t *testing.T) {
+	con := teststream.NewConfig()
+	con.AddElements(1000, 0, 1, 2)
+	con.AdvanceWatermark(1500)
+	// The buffered values are output once processing time passes the max buffering duration.
+	con.AdvanceProcessingTime(int64(time.Minute / time.Millisecond))
+	con.AddElements(2000, 3, 4)
+	con.AdvanceWatermarkToInfinity()
+
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, keyByHot, teststream.Create(s, con))
+	batches := GroupIntoBatches(s, 10, col, WithMaxBufferingDuration(10*time.Second))
+
+	passert.Equals(s, beam.ParDo(s, batchLen, batches), 3, 2)
+	passert.EqualsList(s, beam.ParDo(s, flattenBatch, batches), seq(5))
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestGroupIntoBatchesWithShardedKey(t *testing.T) {
+	p, s := beam.NewPipelineWithRoot()
+	col := beam.ParDo(s, keyByHot, beam.CreateList(s, seq(100)))
+	batches := GroupIntoBatchesWithShardedKey(s, 8, col)
+
+	passert.AllWithinBounds(s, beam.ParDo(s, batchLen, batches), 1, 8)
+	passert.EqualsList(s, beam.ParDo(s, flattenBatch, batches), seq(100))
+
+	ptest.RunAndValidate(t, p)
+}
+
+func TestGroupIntoBatches_Bad(t *testing.T) {
+	tests := []struct {
+		name string
+		size int64
+		opts []Option
+	}{
+		{"negative size", -1, nil},
+		{"no limit", 0, nil},
+		{"negative bytes", 10, []Option{WithBatchSizeBytes(-1)}},
+		{"negative duration", 10, []Option{WithMaxBufferingDuration(-time.Second)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("GroupIntoBatches(%v) succeeded, want panic", test.size)
+				}
+			}()
+			_, s := beam.NewPipelineWithRoot()
+			col := beam.ParDo(s, keyByHot, beam.Create(s, 1))
+			GroupIntoBatches(s, test.size, col, test.opts...)
+		})
+	}
+}
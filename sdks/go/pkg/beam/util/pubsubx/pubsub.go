@@ -19,11 +19,16 @@ package pubsubx
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"cloud.google.com/go/pubsub"
+	vkit "cloud.google.com/go/pubsub/apiv1"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/internal/errors"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/log"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // MakeQualifiedTopicName returns a fully-qualified topic name for
@@ -38,6 +43,21 @@ func MakeQualifiedSubscriptionName(project, subscription string) string {
 	return fmt.Sprintf("projects/%s/subscriptions/%s", project, subscription)
 }
 
+// NewSubscriberClient returns a client for the low level subscriber API, which allows
+// pulling and acknowledging individual messages. Like pubsub.NewClient, it connects to
+// the emulator at PUBSUB_EMULATOR_HOST instead, if the environment variable is set.
+func NewSubscriberClient(ctx context.Context) (*vkit.SubscriberClient, error) {
+	var opts []option.ClientOption
+	if addr := os.Getenv("PUBSUB_EMULATOR_HOST"); addr != "" {
+		opts = append(opts,
+			option.WithEndpoint(addr),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+			option.WithoutAuthentication(),
+		)
+	}
+	return vkit.NewSubscriberClient(ctx, opts...)
+}
+
 // EnsureTopic creates a new topic, if it doesn't exist.
 func EnsureTopic(ctx context.Context, client *pubsub.Client, topic string) (*pubsub.Topic, error) {
 	ret := client.Topic(topic)
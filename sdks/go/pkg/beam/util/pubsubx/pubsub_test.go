@@ -23,6 +23,7 @@ import (
 	"testing"
 
 	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/apiv1/pubsubpb"
 	"cloud.google.com/go/pubsub/pstest"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/api/option"
@@ -189,3 +190,22 @@ func TestPublish(t *testing.T) {
 		t.Fatalf("publish failed: diff\n%v", d)
 	}
 }
+
+func TestNewSubscriberClient_Emulator(t *testing.T) {
+	srv := pstest.NewServer()
+	defer srv.Close()
+	t.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr)
+
+	ctx := context.Background()
+	client, err := NewSubscriberClient(ctx)
+	if err != nil {
+		t.Fatalf("NewSubscriberClient() failed: %v", err)
+	}
+	defer client.Close()
+
+	// The subscription doesn't exist, so the emulator must report it as not found.
+	_, err = client.Pull(ctx, &pubsubpb.PullRequest{Subscription: MakeQualifiedSubscriptionName("project", "missing")})
+	if grpc.Code(err) != codes.NotFound {
+		t.Fatalf("Pull() = %v, want NotFound from the emulator", err)
+	}
+}
@@ -103,6 +103,7 @@ var directFilters = []string{
 	"TestMapStateClear",
 	"TestSetState",
 	"TestSetStateClear",
+	"TestOrderedListState.*",
 	"TestTimers.*", // no timer support for the go direct runner.
 
 	// no support for BundleFinalizer
@@ -129,11 +130,12 @@ var portableFilters = []string{
 	"TestFhirIO.*",
 	// OOMs currently only lead to heap dumps on Dataflow runner
 	"TestOomParDo",
-	// The portable runner does not support user map states.
+	// The portable runner does not support user map or ordered list states.
 	"TestMapState",
 	"TestMapStateClear",
 	"TestSetState",
 	"TestSetStateClear",
+	"TestOrderedListState.*",
 
 	// The portable runner does not uniquify timers. (data elements re-fired)
 	"TestTimers.*",
@@ -178,11 +180,12 @@ var flinkFilters = []string{
 	"TestFhirIO.*",
 	// OOMs currently only lead to heap dumps on Dataflow runner
 	"TestOomParDo",
-	// Flink does not support map based state types.
+	// Flink does not support map based or ordered list state types.
 	"TestMapState",
 	"TestMapStateClear",
 	"TestSetStateClear",
 	"TestSetState",
+	"TestOrderedListState.*",
 
 	// With TestStream Flink adds extra length prefixs some data types, causing SDK side failures.
 	"TestTestStreamStrings",
@@ -235,6 +238,7 @@ var samzaFilters = []string{
 	"TestMapStateClear",
 	"TestSetState",
 	"TestSetStateClear",
+	"TestOrderedListState.*",
 	// TODO(https://github.com/apache/beam/issues/26126): Java runner issue (AcitveBundle has no regsitered handler)
 	"TestDebeziumIO_BasicRead",
 
@@ -269,11 +273,12 @@ var sparkFilters = []string{
 	"TestFhirIO.*",
 	// OOMs currently only lead to heap dumps on Dataflow runner
 	"TestOomParDo",
-	// Spark does not support map based state types.
+	// Spark does not support map based or ordered list state types.
 	"TestMapState",
 	"TestMapStateClear",
 	"TestSetStateClear",
 	"TestSetState",
+	"TestOrderedListState.*",
 
 	"TestTimers_EventTime_Unbounded",     // Side inputs in executable stage not supported.
 	"TestTimers_ProcessingTime_Infinity", // Spark doesn't support test stream.
@@ -288,6 +293,7 @@ var dataflowFilters = []string{
 	"TestJDBCIO_PostgresReadWrite",
 	"TestDebeziumIO_BasicRead",
 	"TestMongoDBIO.*",
+	"TestAzBlobFS.*",
 	// TODO(BEAM-11576): TestFlattenDup failing on this runner.
 	"TestFlattenDup",
 	// The Dataflow runner does not support the TestStream primitive
@@ -47,6 +47,12 @@ func WithPorts(ports []string) ContainerOptionFn {
 	}
 }
 
+func WithCmd(cmd []string) ContainerOptionFn {
+	return func(option *testcontainers.ContainerRequest) {
+		option.Cmd = cmd
+	}
+}
+
 func WithWaitStrategy(waitStrategy wait.Strategy) ContainerOptionFn {
 	return func(option *testcontainers.ContainerRequest) {
 		option.WaitingFor = waitStrategy
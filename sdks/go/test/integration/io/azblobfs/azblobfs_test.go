@@ -0,0 +1,153 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblobfs
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/apache/beam/sdks/v2/go/pkg/beam"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem"
+	_ "github.com/apache/beam/sdks/v2/go/pkg/beam/io/filesystem/azblob"
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/testing/ptest"
+	"github.com/apache/beam/sdks/v2/go/test/integration"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const containerName = "beam"
+
+// newFS starts Azurite and returns an azblob filesystem configured for it.
+func newFS(ctx context.Context, t *testing.T) filesystem.Interface {
+	t.Helper()
+
+	connectionString := setUpTestContainer(ctx, t)
+	createContainer(ctx, t, connectionString, containerName)
+	t.Setenv("AZURE_STORAGE_CONNECTION_STRING", connectionString)
+
+	fs, err := filesystem.New(ctx, "azblob://"+containerName)
+	if err != nil {
+		t.Fatalf("filesystem.New() error = %v", err)
+	}
+	t.Cleanup(func() { fs.Close() })
+	return fs
+}
+
+func uri(blob string) string {
+	return "azblob://" + containerName + "/" + blob
+}
+
+func TestAzBlobFS_ReadWriteList(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	fs := newFS(ctx, t)
+
+	// Account for a timestamp resolution of one second.
+	t1 := time.Now().Truncate(time.Second)
+	files := map[string]string{"dir/file-1.txt": "content", "dir/file-2.txt": "", "dir/file-3.csv": "content"}
+	for blob, content := range files {
+		if err := filesystem.Write(ctx, fs, uri(blob), []byte(content)); err != nil {
+			t.Fatalf("filesystem.Write(%q) error = %v", uri(blob), err)
+		}
+	}
+	t2 := time.Now().Add(time.Second)
+
+	glob := uri("dir/*.txt")
+	got, err := fs.List(ctx, glob)
+	if err != nil {
+		t.Fatalf("List(%q) error = %v", glob, err)
+	}
+	if want := []string{uri("dir/file-1.txt"), uri("dir/file-2.txt")}; !cmp.Equal(got, want) {
+		t.Errorf("List(%q) = %v, want %v", glob, got, want)
+	}
+
+	glob = uri("dir/file-?.csv")
+	infos, err := fs.(filesystem.MetadataLister).ListMetadata(ctx, glob)
+	if err != nil {
+		t.Fatalf("ListMetadata(%q) error = %v", glob, err)
+	}
+	wantInfos := []filesystem.FileInfo{{Path: uri("dir/file-3.csv"), Size: 7}}
+	if d := cmp.Diff(wantInfos, infos, cmpopts.IgnoreFields(filesystem.FileInfo{}, "LastModified")); d != "" {
+		t.Errorf("ListMetadata(%q) mismatch (-want +got):\n%s", glob, d)
+	}
+
+	filename := uri("dir/file-1.txt")
+	data, err := filesystem.Read(ctx, fs, filename)
+	if err != nil || string(data) != "content" {
+		t.Errorf("filesystem.Read(%q) = %q, %v, want %q, nil", filename, data, err, "content")
+	}
+	if size, err := fs.Size(ctx, filename); err != nil || size != 7 {
+		t.Errorf("Size(%q) = %v, %v, want 7, nil", filename, size, err)
+	}
+	mtime, err := fs.(filesystem.LastModifiedGetter).LastModified(ctx, filename)
+	if err != nil || mtime.Before(t1) || mtime.After(t2) {
+		t.Errorf("LastModified(%q) = %v, %v, want in range [%v, %v]", filename, mtime, err, t1, t2)
+	}
+
+	missing := uri("dir/missing.txt")
+	if _, err := fs.OpenRead(ctx, missing); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("OpenRead(%q) error = %v, want %v", missing, err, os.ErrNotExist)
+	}
+}
+
+func TestAzBlobFS_CopyRenameRemove(t *testing.T) {
+	integration.CheckFilters(t)
+
+	ctx := context.Background()
+	fs := newFS(ctx, t)
+
+	oldpath, copypath, newpath := uri("file.txt"), uri("copy/file.txt"), uri("renamed/file.txt")
+	if err := filesystem.Write(ctx, fs, oldpath, []byte("content")); err != nil {
+		t.Fatalf("filesystem.Write(%q) error = %v", oldpath, err)
+	}
+
+	if err := filesystem.Copy(ctx, fs, oldpath, copypath); err != nil {
+		t.Fatalf("filesystem.Copy(%q, %q) error = %v", oldpath, copypath, err)
+	}
+	if data, err := filesystem.Read(ctx, fs, copypath); err != nil || string(data) != "content" {
+		t.Errorf("filesystem.Read(%q) = %q, %v, want %q, nil", copypath, data, err, "content")
+	}
+
+	if err := filesystem.Rename(ctx, fs, oldpath, newpath); err != nil {
+		t.Fatalf("filesystem.Rename(%q, %q) error = %v", oldpath, newpath, err)
+	}
+	if data, err := filesystem.Read(ctx, fs, newpath); err != nil || string(data) != "content" {
+		t.Errorf("filesystem.Read(%q) = %q, %v, want %q, nil", newpath, data, err, "content")
+	}
+	if _, err := fs.Size(ctx, oldpath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Size(%q) after Rename error = %v, want %v", oldpath, err, os.ErrNotExist)
+	}
+
+	remover := fs.(filesystem.Remover)
+	if err := remover.Remove(ctx, copypath); err != nil {
+		t.Fatalf("Remove(%q) error = %v", copypath, err)
+	}
+	if err := remover.Remove(ctx, copypath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Remove(%q) of removed blob error = %v, want %v", copypath, err, os.ErrNotExist)
+	}
+}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	beam.Init()
+
+	ptest.MainRet(m)
+}
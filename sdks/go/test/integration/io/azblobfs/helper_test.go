@@ -0,0 +1,74 @@
+// Licensed to the Apache Software Foundation (ASF) under one or more
+// contributor license agreements.  See the NOTICE file distributed with
+// this work for additional information regarding copyright ownership.
+// The ASF licenses this file to You under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance with
+// the License.  You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azblobfs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/apache/beam/sdks/v2/go/test/integration/internal/containers"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	azuriteImage = "mcr.microsoft.com/azure-storage/azurite:3.34.0"
+	blobPort     = "10000/tcp"
+	maxRetries   = 5
+
+	// The well known account of the Azurite emulator.
+	accountName = "devstoreaccount1"
+	accountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+// setUpTestContainer starts Azurite and returns the connection string of its blob service.
+func setUpTestContainer(ctx context.Context, t *testing.T) string {
+	t.Helper()
+
+	container := containers.NewContainer(
+		ctx,
+		t,
+		azuriteImage,
+		maxRetries,
+		containers.WithPorts([]string{blobPort}),
+		containers.WithCmd([]string{"azurite-blob", "--blobHost", "0.0.0.0", "--skipApiVersionCheck"}),
+		containers.WithWaitStrategy(wait.ForLog("Azurite Blob service is successfully listening")),
+	)
+
+	hostIP, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("error getting Azurite host: %v", err)
+	}
+	port := containers.Port(ctx, t, container, blobPort)
+
+	return fmt.Sprintf(
+		"DefaultEndpointsProtocol=http;AccountName=%s;AccountKey=%s;BlobEndpoint=http://%s:%s/%s;",
+		accountName, accountKey, hostIP, port, accountName,
+	)
+}
+
+func createContainer(ctx context.Context, t *testing.T, connectionString string, name string) {
+	t.Helper()
+
+	client, err := azblob.NewClientFromConnectionString(connectionString, nil)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	if _, err := client.CreateContainer(ctx, name, nil); err != nil {
+		t.Fatalf("error creating container %s: %v", name, err)
+	}
+}
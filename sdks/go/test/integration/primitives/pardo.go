@@ -16,8 +16,11 @@
 package primitives
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"reflect"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -34,6 +37,8 @@ func init() {
 	register.Function3x2(asymJoinFn)
 	register.Function5x0(splitByName)
 	register.Function2x0(emitPipelineOptions)
+	register.Function2x1(parseIntFn)
+	register.Function1x2(errorElementFn)
 	register.DoFn2x0[beam.BundleFinalization, []byte]((*processElemBundleFinalizer)(nil))
 	register.DoFn2x0[beam.BundleFinalization, []byte]((*finalizerInFinishBundle)(nil))
 	register.DoFn2x0[beam.BundleFinalization, []byte]((*finalizerInAll)(nil))
@@ -272,3 +277,33 @@ func (fn *finalizerInAll) FinishBundle(bf beam.BundleFinalization) {
 		return nil
 	})
 }
+
+func parseIntFn(s string, emit func(int)) error {
+	if s == "panic" {
+		panic("parseIntFn: panic")
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	emit(v)
+	return nil
+}
+
+func errorElementFn(r beam.ErrorRecord) (string, error) {
+	v, err := beam.NewElementDecoder(reflect.TypeOf("")).Decode(bytes.NewReader(r.Element))
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// ParDoErrorHandler tests outputting the elements that fail in a ParDo to
+// an ErrorHandler, whether the DoFn returns an error or panics.
+func ParDoErrorHandler(s beam.Scope) {
+	eh := beam.NewErrorHandler()
+	in := beam.Create(s, "1", "2", "three", "4", "panic")
+	ints := beam.ParDo(s, parseIntFn, in, beam.WithErrorHandler(eh))
+	passert.Sum(s, ints, "ints", 3, 7)
+	passert.Equals(s, beam.ParDo(s, errorElementFn, eh.Errors(s)), "three", "panic")
+}
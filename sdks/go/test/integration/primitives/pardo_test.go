@@ -49,6 +49,13 @@ func TestParDoPipelineOptions(t *testing.T) {
 	ptest.RunAndValidate(t, ParDoPipelineOptions())
 }
 
+func TestParDoErrorHandler(t *testing.T) {
+	integration.CheckFilters(t)
+	p, s := beam.NewPipelineWithRoot()
+	ParDoErrorHandler(s)
+	ptest.RunAndValidate(t, p)
+}
+
 func TestParDoBundleFinalizer(t *testing.T) {
 	integration.CheckFilters(t)
 	if !jobopts.IsLoopback() {
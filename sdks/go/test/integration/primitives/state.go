@@ -39,6 +39,8 @@ func init() {
 	register.DoFn3x1[state.Provider, string, int, string](&mapStateClearFn{})
 	register.DoFn3x1[state.Provider, string, int, string](&setStateFn{})
 	register.DoFn3x1[state.Provider, string, int, string](&setStateClearFn{})
+	register.DoFn3x1[state.Provider, string, int, string](&orderedListStateFn{})
+	register.DoFn3x1[state.Provider, string, int, string](&orderedListStateClearFn{})
 	register.Function2x0(pairWithOne)
 	register.Emitter2[string, int]()
 	register.Combiner1[int](&combine1{})
@@ -520,3 +522,91 @@ func SetStateParDoClear(s beam.Scope) {
 	counts := beam.ParDo(s, &setStateClearFn{State1: state.MakeSetState[string]("key1")}, keyed)
 	passert.Equals(s, counts, "apple: [apple]", "pear: [pear]", "peach: [peach]", "apple: [apple1 apple2 apple3]", "apple: []", "pear: [pear1 pear2 pear3]")
 }
+
+// orderedListTimestamp returns the timestamp of the nth value added to ordered list state,
+// alternating around the epoch so that values aren't added in timestamp order.
+func orderedListTimestamp(n int) time.Time {
+	if n%2 == 1 {
+		return time.UnixMilli(int64(-n))
+	}
+	return time.UnixMilli(int64(n))
+}
+
+func orderedListValues(vs []state.TimestampedValue[int]) []int {
+	var values []int
+	for i, v := range vs {
+		if i > 0 && v.Timestamp.Before(vs[i-1].Timestamp) {
+			panic(fmt.Sprintf("ordered list values out of order: %v", vs))
+		}
+		values = append(values, v.Value)
+	}
+	return values
+}
+
+type orderedListStateFn struct {
+	State1 state.OrderedList[int]
+}
+
+func (f *orderedListStateFn) ProcessElement(s state.Provider, w string, c int) string {
+	i, _, err := f.State1.Read(s, state.Range{})
+	if err != nil {
+		panic(err)
+	}
+	err = f.State1.Add(s, orderedListTimestamp(len(i)), len(i))
+	if err != nil {
+		panic(err)
+	}
+	j, _, err := f.State1.Read(s, state.Range{})
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s: %v", w, orderedListValues(j))
+}
+
+// OrderedListStateParDo tests a DoFn that uses ordered list state.
+func OrderedListStateParDo(s beam.Scope) {
+	in := beam.Create(s, "apple", "pear", "peach", "apple", "apple", "pear")
+	keyed := beam.ParDo(s, pairWithOne, in)
+	counts := beam.ParDo(s, &orderedListStateFn{State1: state.MakeOrderedListState[int]("key1")}, keyed)
+	passert.Equals(s, counts, "apple: [0]", "pear: [0]", "peach: [0]", "apple: [1 0]", "apple: [1 0 2]", "pear: [1 0]")
+}
+
+type orderedListStateClearFn struct {
+	State1 state.OrderedList[int]
+}
+
+func (f *orderedListStateClearFn) ProcessElement(s state.Provider, w string, c int) string {
+	i, _, err := f.State1.Read(s, state.Range{})
+	if err != nil {
+		panic(err)
+	}
+	err = f.State1.Add(s, orderedListTimestamp(len(i)), len(i))
+	if err != nil {
+		panic(err)
+	}
+	if len(i) == 2 {
+		err = f.State1.ClearRange(s, state.Range{Start: time.UnixMilli(-1), End: time.UnixMilli(1)})
+		if err != nil {
+			panic(err)
+		}
+	}
+	if len(i) == 1 && i[0].Value == 2 {
+		err = f.State1.Clear(s)
+		if err != nil {
+			panic(err)
+		}
+	}
+	j, _, err := f.State1.Read(s, state.Range{End: time.UnixMilli(3)})
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%s: %v", w, orderedListValues(j))
+}
+
+// OrderedListStateParDoClear tests clearing ranges of a DoFn that uses ordered list state.
+func OrderedListStateParDoClear(s beam.Scope) {
+	in := beam.Create(s, "apple", "pear", "apple", "apple", "pear", "apple", "apple")
+	keyed := beam.ParDo(s, pairWithOne, in)
+	counts := beam.ParDo(s, &orderedListStateClearFn{State1: state.MakeOrderedListState[int]("key1")}, keyed)
+	passert.Equals(s, counts, "apple: [0]", "apple: [1 0]", "apple: [2]", "apple: []", "apple: [0]", "pear: [0]", "pear: [1 0]")
+}
@@ -235,6 +235,27 @@ func TriggerAfterProcessingTime(s beam.Scope) {
 		}, 6.0)
 }
 
+// TriggerAfterProcessingTimeRepeat tests a repeated AfterProcessingTime Trigger. The watermark advance lets the
+// first three elements reach the aggregation, so they fire together once processing time has advanced by more
+// than 5 seconds. The last element fires in its own pane.
+// Not yet supported by the flink runner:
+// java.lang.UnsupportedOperationException: Advancing Processing time is not supported by the Flink Runner.
+func TriggerAfterProcessingTimeRepeat(s beam.Scope) {
+	con := teststream.NewConfig()
+	con.AdvanceProcessingTime(100)
+	con.AddElements(1000, 1.0, 2.0, 3.0)
+	con.AdvanceWatermark(2000)
+	con.AdvanceProcessingTime(6000)
+	con.AddElements(22000, 4.0)
+
+	col := teststream.Create(s, con)
+
+	validateEquals(s.Scope("Global"), window.NewGlobalWindows(), col,
+		[]beam.WindowIntoOption{
+			beam.Trigger(trigger.Repeat(trigger.AfterProcessingTime().PlusDelay(5 * time.Second))),
+		}, 6.0, 4.0)
+}
+
 // TriggerRepeat tests the repeat trigger. As of now is it is configure to take only one trigger as a subtrigger.
 // In the below test, it is expected to receive three output panes with two elements each.
 func TriggerRepeat(s beam.Scope) {
@@ -345,8 +366,8 @@ func TriggerAfterAny(s beam.Scope) {
 		}, 5)
 }
 
-// TriggerAfterSynchronizedProcessingTime tests AfterSynchronizedProcessingTime trigger. It fires at the window
-// expiration since the times doesn't synchronize in this test case.
+// TriggerAfterSynchronizedProcessingTime tests AfterSynchronizedProcessingTime trigger. It fires once for
+// each window, since the elements of each window arrive at the same processing time.
 func TriggerAfterSynchronizedProcessingTime(s beam.Scope) {
 	con := teststream.NewConfig()
 	con.AddElements(1000, 1.0, 2.0, 3.0)
@@ -24,12 +24,15 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"math/big"
 	"os"
 	"reflect"
 	"runtime/debug"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/coder"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/mtime"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/graph/window"
 	"github.com/apache/beam/sdks/v2/go/pkg/beam/core/runtime/exec"
@@ -39,6 +42,7 @@ import (
 	pipepb "github.com/apache/beam/sdks/v2/go/pkg/beam/model/pipeline_v1"
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/text/encoding/charmap"
+	"google.golang.org/protobuf/proto"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -49,11 +53,7 @@ var unimplementedCoders = map[string]bool{
 }
 
 var filteredCases = []struct{ filter, reason string }{
-	{"logical", "BEAM-9615: Support logical types"},
 	{"30ea5a25-dcd8-4cdb-abeb-5332d15ab4b9", "https://github.com/apache/beam/issues/21206: Support encoding position."},
-	{"80be749a-5700-4ede-89d8-dd9a4433a3f8", "https://github.com/apache/beam/issues/19817: Support millis_instant."},
-	{"800c44ae-a1b7-4def-bbf6-6217cca89ec4", "https://github.com/apache/beam/issues/19817: Support decimal."},
-	{"f0ffb3a4-f46f-41ca-a942-85e3e939452a", "https://github.com/apache/beam/issues/23526: Support char/varchar, binary/varbinary."},
 }
 
 // Coder is a representation a serialized beam coder.
@@ -177,6 +177,12 @@ var cmpOpts = []cmp.Option{
 	cmp.Transformer("bytes2string", func(in []byte) (out string) {
 		return string(in)
 	}),
+	cmp.Comparer(func(a, b big.Rat) bool {
+		return a.Cmp(&b) == 0
+	}),
+	cmp.Comparer(func(a, b coder.MicrosInstant) bool {
+		return time.Time(a).Equal(time.Time(b))
+	}),
 }
 
 func diff(c Coder, elem *exec.FullValue, eg yaml.MapItem) bool {
@@ -302,20 +308,37 @@ func diff(c Coder, elem *exec.FullValue, eg yaml.MapItem) bool {
 		return pass
 	case "beam:coder:row:v1":
 		fs := eg.Value.(yaml.MapSlice)
+		var sch pipepb.Schema
+		if err := proto.Unmarshal([]byte(c.Payload), &sch); err != nil {
+			panic(err)
+		}
 		var rfs []reflect.StructField
 		// There are only 2 pointer examples, but they reuse field names,
 		// so we key off the proto hash to know which example we're handling.
 		ptrEg := strings.Contains(c.Payload, "51ace21c7393")
-		for _, rf := range fs {
+		for i, rf := range fs {
 			name := rf.Key.(string)
 			t := nameToType[name]
+			if name == "f_timestamp" && strings.Contains(c.Payload, "micros_instant") {
+				t = microsInstantType
+			}
+			tag := fmt.Sprintf("beam:\"%v\"", name)
+			// Fields of logical types with a length retain it in their tag.
+			ft := sch.GetFields()[i].GetType()
+			if lt, ok := lengthTypes[ft.GetLogicalType().GetUrn()]; ok {
+				t = lt
+				if ft.GetNullable() {
+					t = reflect.PtrTo(t)
+				}
+				tag = fmt.Sprintf("beam:\"%v,length=%d\"", name, ft.GetLogicalType().GetArgument().GetAtomicValue().GetInt32())
+			}
 			if ptrEg {
 				t = reflect.PtrTo(t)
 			}
 			rfs = append(rfs, reflect.StructField{
 				Name: strings.ToUpper(name[:1]) + name[1:],
 				Type: t,
-				Tag:  reflect.StructTag(fmt.Sprintf("beam:\"%v\"", name)),
+				Tag:  reflect.StructTag(tag),
 			})
 		}
 		rv := reflect.New(reflect.StructOf(rfs)).Elem()
@@ -421,8 +444,29 @@ var nameToType = map[string]reflect.Type{
 	"f_bytes": reflect.PtrTo(reflectx.ByteSlice),
 	"f_map":   reflect.MapOf(reflectx.String, reflect.PtrTo(reflectx.Int64)),
 	"f_float": reflectx.Float32,
+
+	"f_timestamp": timeType,
+	"f_string":    reflectx.String,
+	"f_int":       reflectx.Int64,
+	"f_decimal":   ratType,
+	"f_char":      reflect.PtrTo(reflectx.String),
+	"f_varchar":   reflect.PtrTo(reflectx.String),
+	"f_varbytes":  reflect.PtrTo(reflectx.ByteSlice),
+}
+
+// lengthTypes are the Go types of the standard logical types with a length.
+var lengthTypes = map[string]reflect.Type{
+	"beam:logical_type:fixed_char:v1":  reflect.TypeOf((*coder.FixedChar)(nil)).Elem(),
+	"beam:logical_type:fixed_bytes:v1": reflect.TypeOf((*coder.FixedBytes)(nil)).Elem(),
+	"beam:logical_type:var_bytes:v1":   reflect.TypeOf((*coder.VarBytes)(nil)).Elem(),
 }
 
+var (
+	timeType          = reflect.TypeOf((*time.Time)(nil)).Elem()
+	microsInstantType = reflect.TypeOf((*coder.MicrosInstant)(nil)).Elem()
+	ratType           = reflect.TypeOf((*big.Rat)(nil)).Elem()
+)
+
 func setField(rv reflect.Value, i int, v any) {
 	if v == nil {
 		return
@@ -433,10 +477,35 @@ func setField(rv reflect.Value, i int, v any) {
 		rf.Set(reflect.New(rf.Type().Elem()))
 		rf = rf.Elem()
 	}
+	switch rf.Type() {
+	case timeType:
+		// Examples are the shifted millis, as encoded.
+		rf.Set(reflect.ValueOf(time.UnixMilli(int64(v.(int)) - math.MinInt64)))
+		return
+	case microsInstantType:
+		var secs, micros int64
+		for _, a := range v.(yaml.MapSlice) {
+			switch a.Key.(string) {
+			case "seconds":
+				secs = int64(a.Value.(int))
+			case "micros":
+				micros = int64(a.Value.(int))
+			}
+		}
+		rf.Set(reflect.ValueOf(coder.MicrosInstant(time.Unix(secs, micros*1000))))
+		return
+	case ratType:
+		var r big.Rat
+		if _, ok := r.SetString(v.(string)); !ok {
+			panic(fmt.Sprintf("invalid decimal %q", v))
+		}
+		rf.Set(reflect.ValueOf(r))
+		return
+	}
 	switch rf.Kind() {
 	case reflect.String:
 		rf.SetString(v.(string))
-	case reflect.Int32:
+	case reflect.Int32, reflect.Int64:
 		rf.SetInt(int64(v.(int)))
 	case reflect.Float32:
 		c, err := strconv.ParseFloat(v.(string), 32)
@@ -451,8 +520,8 @@ func setField(rv reflect.Value, i int, v any) {
 		}
 		rf.SetFloat(c)
 	case reflect.Slice:
-		if rf.Type() == reflectx.ByteSlice {
-			rf.Set(reflect.ValueOf([]byte(v.(string))))
+		if rf.Type().Elem() == reflectx.Uint8 {
+			rf.SetBytes([]byte(v.(string)))
 			break
 		}
 		// Value is a []any with string values.